@@ -8,6 +8,7 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"os"
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"github.com/go-git/go-billy/v5/memfs"
@@ -36,6 +37,7 @@ var (
 	verifyingKeyVersion   = flag.String("verifying-key-version", "", "KMS crypto key version for verification")
 	overwriteAttestations = flag.Bool("overwrite-attestations", false, "whether to overwrite existing attestations")
 	gcbPrivatePoolName    = flag.String("gcb-private-pool-name", "", "Resource name of GCB private pool to use, if configured")
+	networkAuditPolicy    = flag.String("network-audit-policy", "", "path to a proxy policy JSON file to evaluate in audit mode during rebuilds")
 	gcbPrivatePoolRegion  = flag.String("gcb-private-pool-region", "", "GCP location to use for GCB private pool builds, if configured. Note: This should generally be the same as the region where the private pool is located.")
 )
 
@@ -137,6 +139,13 @@ func AnalyzerInit(ctx context.Context) (*analyzerservice.AnalyzerDeps, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing service location")
 	}
+	var auditPolicy []byte
+	if *networkAuditPolicy != "" {
+		auditPolicy, err = os.ReadFile(*networkAuditPolicy)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading network audit policy")
+		}
+	}
 	return &analyzerservice.AnalyzerDeps{
 		HTTPClient:                 httpClient,
 		Signer:                     signer,
@@ -149,6 +158,7 @@ func AnalyzerInit(ctx context.Context) (*analyzerservice.AnalyzerDeps, error) {
 		DebugStoreBuilder:          debugStoreBuilder,
 		RemoteMetadataStoreBuilder: remoteMetadataStoreBuilder,
 		OverwriteAttestations:      *overwriteAttestations,
+		NetworkAuditPolicy:         string(auditPolicy),
 	}, nil
 }
 
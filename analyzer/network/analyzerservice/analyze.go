@@ -10,17 +10,23 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"io"
+	"net/http"
+	"net/url"
 	"path"
+	"sort"
+	"strings"
 
 	"github.com/google/oss-rebuild/internal/api"
 	"github.com/google/oss-rebuild/internal/cache"
 	"github.com/google/oss-rebuild/internal/hashext"
 	"github.com/google/oss-rebuild/internal/httpx"
+	"github.com/google/oss-rebuild/internal/netclassify"
 	"github.com/google/oss-rebuild/internal/verifier"
 	"github.com/google/oss-rebuild/pkg/archive"
 	"github.com/google/oss-rebuild/pkg/attestation"
 	"github.com/google/oss-rebuild/pkg/build"
 	buildgcb "github.com/google/oss-rebuild/pkg/build/gcb"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
 	"github.com/google/oss-rebuild/pkg/rebuild/meta"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/google/oss-rebuild/pkg/rebuild/schema"
@@ -46,6 +52,8 @@ type AnalyzerDeps struct {
 	DebugStoreBuilder          func(ctx context.Context) (rebuild.LocatableAssetStore, error)
 	RemoteMetadataStoreBuilder func(ctx context.Context, uuid string) (rebuild.LocatableAssetStore, error)
 	OverwriteAttestations      bool
+	// NetworkAuditPolicy is a JSON-encoded proxy policy to audit during rebuilds, if any.
+	NetworkAuditPolicy string
 }
 
 func Analyze(ctx context.Context, req schema.AnalyzeRebuildRequest, deps *AnalyzerDeps) (*api.NoReturn, error) {
@@ -104,7 +112,7 @@ func analyzeRebuild(ctx context.Context, t rebuild.Target, deps *AnalyzerDeps) (
 }
 
 // getRebuildAttestation fetches and parses the rebuild attestation from the store
-func getRebuildAttestation(ctx context.Context, store rebuild.AssetStore, t rebuild.Target, verifier *dsse.EnvelopeVerifier) (*attestation.RebuildAttestation, error) {
+func getRebuildAttestation(ctx context.Context, store rebuild.AssetStore, t rebuild.Target, dsseVerifier *dsse.EnvelopeVerifier) (*attestation.RebuildAttestation, error) {
 	bundleReader, err := store.Reader(ctx, rebuild.AttestationBundleAsset.For(t))
 	if err != nil {
 		return nil, errors.Wrap(err, "reading attestation bundle")
@@ -114,10 +122,15 @@ func getRebuildAttestation(ctx context.Context, store rebuild.AssetStore, t rebu
 	if err != nil {
 		return nil, errors.Wrap(err, "reading bundle data")
 	}
-	bundle, err := attestation.NewBundle(ctx, bundleData, verifier)
+	bundle, err := attestation.NewBundle(ctx, bundleData, dsseVerifier)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing bundle")
 	}
+	if revocation, err := verifier.CheckRevocation(ctx, store, t, bundleData, dsseVerifier); err != nil {
+		return nil, errors.Wrap(err, "checking revocation")
+	} else if revocation != nil {
+		return nil, api.AsStatus(codes.FailedPrecondition, errors.Errorf("attestation bundle revoked: %s", revocation.Predicate.Reason))
+	}
 	rebuildAttestation, err := attestation.FilterForOne[attestation.RebuildAttestation](
 		bundle,
 		attestation.WithBuildType(attestation.BuildTypeRebuildV01),
@@ -219,9 +232,10 @@ func executeNetworkRebuild(ctx context.Context, deps *AnalyzerDeps, t rebuild.Ta
 		Strategy: strategy,
 	}
 	h, err := deps.GCBExecutor.Start(ctx, in, build.Options{
-		BuildID:         obID,
-		UseTimewarp:     meta.AllRebuilders[t.Ecosystem].UsesTimewarp(in),
-		UseNetworkProxy: true, // The whole point of the analyzer
+		BuildID:            obID,
+		UseTimewarp:        meta.AllRebuilders[t.Ecosystem].UsesTimewarp(in),
+		UseNetworkProxy:    true, // The whole point of the analyzer
+		NetworkAuditPolicy: deps.NetworkAuditPolicy,
 		Resources: build.Resources{
 			AssetStore:       buildStore,
 			ToolURLs:         toolURLs,
@@ -297,6 +311,11 @@ func createNetworkAttestations(ctx context.Context, t rebuild.Target, strategy r
 	}
 	defer netlogReader.Close()
 	hasher := hashext.NewTypedHash(crypto.SHA256)
+	var activity netlog.NetworkActivityLog
+	if err := json.NewDecoder(io.TeeReader(netlogReader, hasher)).Decode(&activity); err != nil {
+		return nil, nil, errors.Wrap(err, "decoding network log")
+	}
+	// Consume any trailing content so the digest covers the full log.
 	if _, err := io.Copy(hasher, netlogReader); err != nil {
 		return nil, nil, errors.Wrap(err, "hashing network log")
 	}
@@ -327,6 +346,7 @@ func createNetworkAttestations(ctx context.Context, t rebuild.Target, strategy r
 			verifier.ToNISTName(bundleHasher.Algorithm): hex.EncodeToString(bundleHasher.Sum(nil)),
 		},
 	}
+	deps.Downloads = downloadDescriptors(activity)
 	if inst, err := strategy.GenerateFor(t, rebuild.BuildEnv{TimewarpHost: "example.internal"}); err == nil {
 		if inst.Location.Ref != "" {
 			deps.Source = &slsa1.ResourceDescriptor{
@@ -454,3 +474,37 @@ func publishNetworkBundle(ctx context.Context, store rebuild.AssetStore, signer
 	}
 	return nil
 }
+
+// downloadDescriptors returns a descriptor for each distinct resource successfully fetched during the build.
+//
+// Only GET requests with a fully-read, successful response have a meaningful
+// digest so all others are omitted.
+func downloadDescriptors(activity netlog.NetworkActivityLog) []slsa1.ResourceDescriptor {
+	seen := make(map[[2]string]bool)
+	var rds []slsa1.ResourceDescriptor
+	for _, req := range activity.HTTPRequests {
+		if req.Method != http.MethodGet || req.StatusCode != http.StatusOK {
+			continue
+		}
+		algo, value, ok := strings.Cut(req.Digest, ":")
+		if !ok || algo != "sha256" {
+			continue
+		}
+		u := (&url.URL{Scheme: req.Scheme, Host: req.Host, Path: req.Path}).String()
+		if seen[[2]string{u, value}] {
+			continue
+		}
+		seen[[2]string{u, value}] = true
+		rd := slsa1.ResourceDescriptor{
+			Name:      u,
+			Digest:    common.DigestSet{"sha256": value},
+			MediaType: req.ContentType,
+		}
+		if c, err := netclassify.ClassifyURL(u); err == nil {
+			rd.URI = c.PURL
+		}
+		rds = append(rds, rd)
+	}
+	sort.SliceStable(rds, func(i, j int) bool { return rds[i].Name < rds[j].Name })
+	return rds
+}
@@ -17,16 +17,19 @@ import (
 	"time"
 
 	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/internal/gcb/gcbtest"
 	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
 	"github.com/google/oss-rebuild/pkg/archive"
 	"github.com/google/oss-rebuild/pkg/archive/archivetest"
 	"github.com/google/oss-rebuild/pkg/attestation"
 	buildgcb "github.com/google/oss-rebuild/pkg/build/gcb"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
 	"github.com/google/oss-rebuild/pkg/rebuild/pypi"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/google/oss-rebuild/pkg/rebuild/schema"
 	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 	"google.golang.org/api/cloudbuild/v1"
@@ -182,6 +185,46 @@ func TestAnalyze(t *testing.T) {
 			})),
 			networkLog: `{"summary":{"totalRequests":3,"uniqueHosts":["pypi.org","files.pythonhosted.org","github.com"]}}`,
 		},
+		{
+			name:   "python wheel network analysis with response digests",
+			target: rebuild.Target{Ecosystem: rebuild.PyPI, Package: "requests", Version: "2.31.0", Artifact: "requests-2.31.0-py3-none-any.whl"},
+			calls: []httpxtest.Call{
+				{
+					URL: "https://pypi.org/pypi/requests/2.31.0/json",
+					Response: &http.Response{
+						StatusCode: 200,
+						Body: httpxtest.Body(`{
+							"info": {
+								"name": "requests",
+								"version": "2.31.0"
+							},
+							"urls": [
+								{
+									"filename": "requests-2.31.0-py3-none-any.whl",
+									"url": "https://files.pythonhosted.org/packages/70/8e/0e2d847013cb52cd35b38c009bb167a1a26b2ce6cd6965bf26b47bc0bf44e/requests-2.31.0-py3-none-any.whl"
+								}
+							]
+						}`),
+					},
+				},
+				{
+					URL: "https://files.pythonhosted.org/packages/70/8e/0e2d847013cb52cd35b38c009bb167a1a26b2ce6cd6965bf26b47bc0bf44e/requests-2.31.0-py3-none-any.whl",
+					Response: &http.Response{
+						StatusCode: 200,
+						Body: io.NopCloser(must(archivetest.ZipFile([]archive.ZipEntry{
+							{FileHeader: &zip.FileHeader{Name: "requests/__init__.py", Modified: time.UnixMilli(0)}, Body: []byte("# requests")},
+						}))),
+					},
+				},
+			},
+			strategy: &pypi.PureWheelBuild{
+				Location: rebuild.Location{Repo: "https://github.com/psf/requests", Ref: "aaaabbbbccccddddeeeeaaaabbbbccccddddeeee", Dir: "."},
+			},
+			file: must(archivetest.ZipFile([]archive.ZipEntry{
+				{FileHeader: &zip.FileHeader{Name: "requests/__init__.py", Modified: time.UnixMilli(0)}, Body: []byte("# requests")},
+			})),
+			networkLog: `{"HTTPRequests":[{"Method":"GET","Scheme":"https","Host":"pypi.org","Path":"/simple/setuptools/","StatusCode":200,"ContentLength":2,"ContentType":"text/html","Digest":"sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a","StartTime":"2025-01-01T00:00:00Z","Duration":1000}]}`,
+		},
 		{
 			name:   "analysis already exists",
 			target: rebuild.Target{Ecosystem: rebuild.PyPI, Package: "existing", Version: "1.0.0", Artifact: "existing-1.0.0-py3-none-any.whl"},
@@ -322,6 +365,33 @@ func TestAnalyze(t *testing.T) {
 	}
 }
 
+func TestDownloadDescriptors(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	activity := netlog.NetworkActivityLog{HTTPRequests: []netlog.HTTPRequestLog{
+		{Method: "GET", Scheme: "https", Host: "pypi.org", Path: "/simple/setuptools/", StatusCode: 200, ContentType: "text/html", Digest: digest},
+		{Method: "GET", Scheme: "https", Host: "files.pythonhosted.org", Path: "/setuptools.whl", StatusCode: 200, Digest: digest},
+		{Method: "GET", Scheme: "https", Host: "registry.npmjs.org", Path: "/left-pad/-/left-pad-1.3.0.tgz", StatusCode: 200, ContentType: "application/octet-stream", Digest: digest},
+		{Method: "GET", Scheme: "https", Host: "pypi.org", Path: "/simple/setuptools/", StatusCode: 200, ContentType: "text/html", Digest: digest},
+		{Method: "GET", Scheme: "https", Host: "pypi.org", Path: "/simple/missing/", StatusCode: 404, Digest: digest},
+		{Method: "GET", Scheme: "https", Host: "pypi.org", Path: "/simple/partial/", StatusCode: 200},
+		{Method: "POST", Scheme: "https", Host: "pypi.org", Path: "/upload", StatusCode: 200, Digest: digest},
+	}}
+	want := NetworkRebuildDeps{
+		AttestationBundle: slsa1.ResourceDescriptor{Name: string(rebuild.AttestationBundleAsset), Digest: common.DigestSet{"sha256": "1234"}},
+		Downloads: []slsa1.ResourceDescriptor{
+			{Name: "https://files.pythonhosted.org/setuptools.whl", Digest: common.DigestSet{"sha256": strings.Repeat("ab", 32)}},
+			{Name: "https://pypi.org/simple/setuptools/", Digest: common.DigestSet{"sha256": strings.Repeat("ab", 32)}, MediaType: "text/html"},
+			{Name: "https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz", URI: "pkg:npm/left-pad@1.3.0", Digest: common.DigestSet{"sha256": strings.Repeat("ab", 32)}, MediaType: "application/octet-stream"},
+		},
+	}
+	deps := NetworkRebuildDeps{AttestationBundle: want.AttestationBundle, Downloads: downloadDescriptors(activity)}
+	var got NetworkRebuildDeps
+	must1(json.Unmarshal(must(json.Marshal(deps)), &got))
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NetworkRebuildDeps mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func must[T any](t T, err error) T {
 	must1(err)
 	return t
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/google/oss-rebuild/pkg/attestation"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/in-toto/in-toto-golang/in_toto"
 	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 )
@@ -33,6 +34,8 @@ type NetworkRebuildDeps struct {
 	Source *slsa1.ResourceDescriptor
 	// Images contains container image descriptors used in the build
 	Images []slsa1.ResourceDescriptor
+	// Downloads contains the content fetched over the network during the build
+	Downloads []slsa1.ResourceDescriptor
 }
 
 // MarshalJSON flattens the deps into a ResourceDescriptors slice for compatibility with SLSA Provenance.
@@ -43,6 +46,7 @@ func (d NetworkRebuildDeps) MarshalJSON() ([]byte, error) {
 		rd = append(rd, *d.Source)
 	}
 	rd = append(rd, d.Images...)
+	rd = append(rd, d.Downloads...)
 	return json.Marshal(rd)
 }
 
@@ -53,9 +57,14 @@ func (d *NetworkRebuildDeps) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	for _, desc := range descriptors {
-		if strings.HasPrefix(desc.Name, "git+") {
+		switch {
+		case desc.Name == string(rebuild.AttestationBundleAsset):
+			d.AttestationBundle = desc
+		case strings.HasPrefix(desc.Name, "git+"):
 			d.Source = &desc
-		} else {
+		case strings.HasPrefix(desc.Name, "http://") || strings.HasPrefix(desc.Name, "https://"):
+			d.Downloads = append(d.Downloads, desc)
+		default:
 			d.Images = append(d.Images, desc)
 		}
 	}
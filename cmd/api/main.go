@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 
 	"cloud.google.com/go/firestore"
@@ -23,6 +24,8 @@ import (
 	"github.com/google/oss-rebuild/internal/httpegress"
 	"github.com/google/oss-rebuild/internal/serviceid"
 	"github.com/google/oss-rebuild/internal/uri"
+	"github.com/google/oss-rebuild/internal/verifier"
+	"github.com/google/oss-rebuild/pkg/attestation"
 	buildgcb "github.com/google/oss-rebuild/pkg/build/gcb"
 	"github.com/google/oss-rebuild/pkg/kmsdsse"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
@@ -50,6 +53,9 @@ var (
 	buildDefRepo          = flag.String("build-def-repo", "", "repository for build definitions")
 	buildDefRepoDir       = flag.String("build-def-repo-dir", ".", "relpath within the build definitions repository")
 	overwriteAttestations = flag.Bool("overwrite-attestations", false, "whether to overwrite existing attestations when writing to GCS")
+	publishFailures       = flag.Bool("publish-failures", false, "whether to publish signed attestations for failed rebuilds")
+	vsaVerifierID         = flag.String("vsa-verifier-id", "", "if provided, the verifier ID with which to publish a SLSA VSA alongside each attestation bundle")
+	rekorURL              = flag.String("rekor-url", "", "if provided, the URL of a Rekor transparency log in which to record published bundles")
 	blockLocalRepoPublish = flag.Bool("block-local-repo-publish", true, "whether to prevent attestation publishing when the BuildRepo property points to a file:// URI")
 	gcbPrivatePoolName    = flag.String("gcb-private-pool-name", "", "Resoure name of GCB private pool to use, if configured")
 	gcbPrivatePoolRegion  = flag.String("gcb-private-pool-region", "", "GCP location to use for GCB private pool builds, if configured. Note: This should generally be the same as the region where the private pool is located.")
@@ -58,6 +64,7 @@ var (
 	agentSessionsBucket   = flag.String("agent-sessions-bucket", "", "GCS bucket for agent session data")
 	agentMetadataBucket   = flag.String("agent-metadata-bucket", "", "GCS bucket for agent build metadata")
 	agentLogsBucket       = flag.String("agent-logs-bucket", "", "GCS bucket for agent build logs")
+	registryConfig        = flag.String("registry-config", "", "if provided, the path of a YAML file describing non-public package registries to use")
 	agentTimeoutSeconds   = flag.Int("agent-timeout-seconds", 3600, "Seconds to allow agent to run")
 )
 
@@ -91,20 +98,20 @@ func RebuildSmoketestInit(ctx context.Context) (*apiservice.RebuildSmoketestDeps
 	return &d, nil
 }
 
-func makeKMSSigner(ctx context.Context, cryptoKeyVersion string) (*dsse.EnvelopeSigner, error) {
+func makeKMSSigner(ctx context.Context, cryptoKeyVersion string) (*kmsdsse.CloudKMSSignerVerifier, *dsse.EnvelopeSigner, error) {
 	kc, err := kms.NewKeyManagementClient(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating KMS client")
+		return nil, nil, errors.Wrap(err, "creating KMS client")
 	}
 	kmsSigner, err := kmsdsse.NewCloudKMSSignerVerifier(ctx, kc, cryptoKeyVersion)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating Cloud KMS signer")
+		return nil, nil, errors.Wrap(err, "creating Cloud KMS signer")
 	}
 	dsseSigner, err := dsse.NewEnvelopeSigner(kmsSigner)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating envelope signer")
+		return nil, nil, errors.Wrap(err, "creating envelope signer")
 	}
-	return dsseSigner, nil
+	return kmsSigner, dsseSigner, nil
 }
 
 func RebuildPackageInit(ctx context.Context) (*apiservice.RebuildPackageDeps, error) {
@@ -118,10 +125,40 @@ func RebuildPackageInit(ctx context.Context) (*apiservice.RebuildPackageDeps, er
 	if err != nil {
 		return nil, errors.Wrap(err, "creating firestore client")
 	}
-	d.Signer, err = makeKMSSigner(ctx, *signingKeyVersion)
+	var kmsSigner *kmsdsse.CloudKMSSignerVerifier
+	kmsSigner, d.Signer, err = makeKMSSigner(ctx, *signingKeyVersion)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating signer")
 	}
+	d.Verifier, err = dsse.NewEnvelopeVerifier(kmsSigner)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating envelope verifier")
+	}
+	if *vsaVerifierID != "" {
+		d.VSA = &verifier.VSAConfig{
+			Verifier:       d.Verifier,
+			VerifierID:     *vsaVerifierID,
+			VerifiedLevels: []string{attestation.LevelRebuildV01},
+		}
+	}
+	if *rekorURL != "" {
+		u, err := url.Parse(*rekorURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing rekor URL")
+		}
+		d.TransparencyLog = &verifier.RekorLog{Client: d.HTTPClient, URL: u, Signer: kmsSigner}
+	}
+	if *registryConfig != "" {
+		f, err := os.Open(*registryConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening registry config")
+		}
+		defer f.Close()
+		d.RegistryConfig, err = rebuild.ParseRegistryConfig(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing registry config")
+		}
+	}
 	svc, err := cloudbuild.NewService(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating CloudBuild service")
@@ -173,6 +210,10 @@ func RebuildPackageInit(ctx context.Context) (*apiservice.RebuildPackageDeps, er
 	if err != nil {
 		return nil, errors.Wrap(err, "creating attestation uploader")
 	}
+	d.DigestIndex, err = rebuild.NewGCSDigestIndex(ctx, "gs://"+*attestationBucket)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating digest index")
+	}
 	d.LocalMetadataStore = rebuild.NewFilesystemAssetStore(memfs.New())
 	// TODO: This can be optional once LocalMetadata and DebugStore are combined into a cached store.
 	if *debugStorage == "" {
@@ -188,6 +229,7 @@ func RebuildPackageInit(ctx context.Context) (*apiservice.RebuildPackageDeps, er
 		return rebuild.NewGCSStore(context.WithValue(ctx, rebuild.RunID, uuid), "gs://"+*metadataBucket)
 	}
 	d.OverwriteAttestations = *overwriteAttestations
+	d.PublishFailures = *publishFailures
 	u, err := url.Parse(*inferenceURL)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing inference URL")
@@ -201,6 +243,35 @@ func RebuildPackageInit(ctx context.Context) (*apiservice.RebuildPackageDeps, er
 	return &d, nil
 }
 
+func RevokeBundleInit(ctx context.Context) (*apiservice.RevokeBundleDeps, error) {
+	var d apiservice.RevokeBundleDeps
+	var err error
+	var kmsSigner *kmsdsse.CloudKMSSignerVerifier
+	kmsSigner, d.Signer, err = makeKMSSigner(ctx, *signingKeyVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating signer")
+	}
+	d.Verifier, err = dsse.NewEnvelopeVerifier(kmsSigner)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating envelope verifier")
+	}
+	if *vsaVerifierID != "" {
+		d.VSA = &verifier.VSAConfig{
+			Verifier:   d.Verifier,
+			VerifierID: *vsaVerifierID,
+		}
+	}
+	d.AttestationStore, err = rebuild.NewGCSStore(context.WithValue(ctx, rebuild.RunID, ""), "gs://"+*attestationBucket)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating attestation uploader")
+	}
+	d.DigestIndex, err = rebuild.NewGCSDigestIndex(ctx, "gs://"+*attestationBucket)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating digest index")
+	}
+	return &d, nil
+}
+
 func VersionInit(ctx context.Context) (*apiservice.VersionDeps, error) {
 	var d apiservice.VersionDeps
 	var err error
@@ -270,6 +341,7 @@ func main() {
 	flag.Parse()
 	http.HandleFunc("/smoketest", api.Handler(RebuildSmoketestInit, apiservice.RebuildSmoketest))
 	http.HandleFunc("/rebuild", api.Handler(RebuildPackageInit, apiservice.RebuildPackage))
+	http.HandleFunc("/revoke", api.Handler(RevokeBundleInit, apiservice.RevokeBundle))
 	http.HandleFunc("/version", api.Handler(VersionInit, apiservice.Version))
 	http.HandleFunc("/runs", api.Handler(CreateRunInit, apiservice.CreateRun))
 	http.HandleFunc("/agent", api.Handler(AgentCreateInit, apiservice.AgentCreate))
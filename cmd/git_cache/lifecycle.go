@@ -0,0 +1,103 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ttlConfig provides the maximum age of cache entries.
+type ttlConfig struct {
+	// Default is the TTL for repos not matching any prefix. Zero means no TTL.
+	Default time.Duration
+	// Prefixes maps repo prefixes e.g. "github.com/org" to their TTL.
+	Prefixes map[string]time.Duration
+}
+
+// parseTTLs parses a comma-separated list of <repo-prefix>=<duration> pairs.
+func parseTTLs(s string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	if s == "" {
+		return ttls, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		prefix, dur, found := strings.Cut(entry, "=")
+		if !found || prefix == "" {
+			return nil, errors.Errorf("malformed TTL entry %q", entry)
+		}
+		d, err := time.ParseDuration(dur)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing TTL for %s", prefix)
+		}
+		ttls[strings.ToLower(strings.Trim(prefix, "/"))] = d
+	}
+	return ttls, nil
+}
+
+// For returns the TTL for the repo using the longest matching prefix.
+func (c ttlConfig) For(repo string) time.Duration {
+	repo = strings.ToLower(repo)
+	ttl, longest := c.Default, -1
+	for prefix, d := range c.Prefixes {
+		if (repo == prefix || strings.HasPrefix(repo, prefix+"/")) && len(prefix) > longest {
+			ttl, longest = d, len(prefix)
+		}
+	}
+	return ttl
+}
+
+// accessGranularity is the minimum interval between recorded accesses of an
+// object to avoid a metadata write for every request.
+const accessGranularity = time.Hour
+
+// evictor removes the least recently used objects when their total size
+// exceeds a budget.
+type evictor struct {
+	store    objectStore
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+// Evict deletes objects, least recently used first, until the total size of
+// the store is within budget. The object named keep is never deleted.
+//
+// Only one eviction runs at a time and concurrent calls return immediately.
+func (e *evictor) Evict(ctx context.Context, keep string) error {
+	if e.maxBytes <= 0 || !e.mu.TryLock() {
+		return nil
+	}
+	defer e.mu.Unlock()
+	attrs, err := e.store.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing objects")
+	}
+	var total int64
+	for _, a := range attrs {
+		total += a.Size
+	}
+	slices.SortFunc(attrs, func(a, b objectAttrs) int {
+		return a.LastUse().Compare(b.LastUse())
+	})
+	for _, a := range attrs {
+		if total <= e.maxBytes {
+			break
+		}
+		if a.Name == keep {
+			continue
+		}
+		log.Printf("Evicting %s: last used %s\n", a.Name, a.LastUse().Format(time.RFC3339))
+		if err := e.store.Delete(ctx, a.Name); err != nil {
+			return errors.Wrapf(err, "deleting %s", a.Name)
+		}
+		total -= a.Size
+	}
+	return nil
+}
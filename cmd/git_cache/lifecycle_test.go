@@ -0,0 +1,120 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTTLs(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		input   string
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{"empty", "", map[string]time.Duration{}, false},
+		{"single", "github.com/org=1h", map[string]time.Duration{"github.com/org": time.Hour}, false},
+		{"normalized", "GitHub.com/Org/=30m,gitlab.com=2h", map[string]time.Duration{"github.com/org": 30 * time.Minute, "gitlab.com": 2 * time.Hour}, false},
+		{"missing duration", "github.com/org", nil, true},
+		{"missing prefix", "=1h", nil, true},
+		{"invalid duration", "github.com/org=soon", nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseTTLs(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseTTLs() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("parseTTLs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTTLConfigFor(t *testing.T) {
+	c := ttlConfig{
+		Default: 24 * time.Hour,
+		Prefixes: map[string]time.Duration{
+			"github.com":            time.Hour,
+			"github.com/org":        time.Minute,
+			"github.com/org/pinned": 0,
+		},
+	}
+	for _, tc := range []struct {
+		repo string
+		want time.Duration
+	}{
+		{"gitlab.com/org/repo", 24 * time.Hour},
+		{"github.com/other/repo", time.Hour},
+		{"GitHub.com/Org/Repo", time.Minute},
+		{"github.com/org/pinned", 0},
+		// Prefixes only match whole path components.
+		{"github.com/organization/repo", time.Hour},
+	} {
+		if got := c.For(tc.repo); got != tc.want {
+			t.Errorf("For(%q) = %v, want %v", tc.repo, got, tc.want)
+		}
+	}
+}
+
+// fakeStore is an objectStore supporting only the operations used by evictor.
+type fakeStore struct {
+	objectStore
+	objects map[string]objectAttrs
+	deleted []string
+}
+
+func (s *fakeStore) List(ctx context.Context) ([]objectAttrs, error) {
+	var attrs []objectAttrs
+	for _, a := range s.objects {
+		attrs = append(attrs, a)
+	}
+	return attrs, nil
+}
+
+func (s *fakeStore) Delete(ctx context.Context, name string) error {
+	delete(s.objects, name)
+	s.deleted = append(s.deleted, name)
+	return nil
+}
+
+func TestEvictorEvict(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	objects := func() map[string]objectAttrs {
+		return map[string]objectAttrs{
+			"oldest": {Name: "oldest", Size: 10, Created: base},
+			// Recently accessed so used after "newer" despite being created first.
+			"accessed": {Name: "accessed", Size: 10, Created: base.Add(time.Minute), Accessed: base.Add(time.Hour)},
+			"newer":    {Name: "newer", Size: 10, Created: base.Add(2 * time.Minute)},
+			"newest":   {Name: "newest", Size: 10, Created: base.Add(3 * time.Hour)},
+		}
+	}
+	for _, tc := range []struct {
+		name        string
+		maxBytes    int64
+		keep        string
+		wantDeleted []string
+	}{
+		{"within budget", 40, "", nil},
+		{"disabled", 0, "", nil},
+		{"least recently used first", 20, "", []string{"oldest", "newer"}},
+		{"keep skipped", 20, "oldest", []string{"newer", "accessed"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &fakeStore{objects: objects()}
+			e := &evictor{store: s, maxBytes: tc.maxBytes}
+			if err := e.Evict(context.Background(), tc.keep); err != nil {
+				t.Fatalf("Evict() error = %v", err)
+			}
+			if diff := cmp.Diff(tc.wantDeleted, s.deleted); diff != "" {
+				t.Errorf("deleted mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,20 +1,27 @@
 // Copyright 2025 Google LLC
 // SPDX-License-Identifier: Apache-2.0
 
-// Package main implements a git repo cache on GCS.
+// Package main implements a git repo cache on GCS or a local filesystem.
 //
 // The served API is as follows:
 //
-//	/get: Redirect to the GCS repo metadata cache object, populating the cache if necessary.
+//	/get: Redirect to the repo cache object, populating the cache if necessary.
 //	  - uri: Git repo URI e.g. github.com/org/repo
 //	  - contains: The RFC3339-formatted time after which a cache entry must have been created.
 //	  - ref: Git reference (branch/tag) to cache. If provided, creates a separate cache entry per ref.
+//	/object/<path>: Serve the content of a cache object. Only when using -dir.
 //
 // # Object Format
 //
 // The repo cache is stored as a gzipped tar archive of the .git/ directory
 // from an empty checkout of the upstream repo.
 //
+// # Storage
+//
+// With -bucket, cache objects are stored in GCS and /get redirects to the GCS
+// download URL. With -dir, cache objects are stored in a local directory and
+// /get redirects to the /object/ endpoint of this service.
+//
 // # Data Races
 //
 // Racing requests for the same resource will write and return different copies
@@ -27,11 +34,14 @@
 //
 // # Cache Lifecycle
 //
-// If the caller provides the "contains" parameter that is more recent than the
-// most recent cache entry, it will be re-fetched and overwritten.
+// A cache entry is refreshed when it was created before the "contains"
+// parameter or when it is older than its repo's TTL (see -ttl and -repo-ttls).
+// With -incremental, a refresh fetches new objects into the existing clone
+// rather than recloning the repo.
 //
-// There is currently no TTL for cache entries nor a size limitation for the
-// backing storage. These are areas for future work.
+// With -max-bytes, the least recently used entries are evicted after each
+// write until the total size of the cache is within budget. Use is tracked in
+// object metadata and is recorded at most once per hour per entry.
 package main
 
 import (
@@ -39,12 +49,12 @@ import (
 	"compress/gzip"
 	"context"
 	"flag"
-	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -52,17 +62,24 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-billy/v5/util"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/filesystem"
 	"github.com/google/oss-rebuild/internal/uri"
+	"github.com/google/oss-rebuild/pkg/archive"
 	"github.com/pkg/errors"
 )
 
 var (
-	bucket = flag.String("bucket", "", "the bucket to use as the git cache")
+	bucket      = flag.String("bucket", "", "the bucket to use as the git cache")
+	dir         = flag.String("dir", "", "a local directory to use as the git cache instead of a bucket")
+	defaultTTL  = flag.Duration("ttl", 0, "the age after which cache entries are refreshed. zero means no TTL")
+	repoTTLs    = flag.String("repo-ttls", "", "comma-separated <repo-prefix>=<duration> pairs overriding -ttl e.g. github.com/org=6h")
+	maxBytes    = flag.Int64("max-bytes", 0, "the total cache size above which least recently used entries are evicted. zero means no limit")
+	incremental = flag.Bool("incremental", false, "refresh cache entries by fetching into the cached clone instead of recloning")
 )
 
 var thresholdFudgeFactor = 24 * time.Hour
@@ -89,7 +106,15 @@ func parseGetRequest(v url.Values) (r getRequest, err error) {
 	return r, nil
 }
 
-func HandleGet(rw http.ResponseWriter, req *http.Request) {
+// server handles cache requests.
+type server struct {
+	store       objectStore
+	ttls        ttlConfig
+	evictor     *evictor
+	incremental bool
+}
+
+func (s *server) HandleGet(rw http.ResponseWriter, req *http.Request) {
 	ctx := context.Background()
 	if err := req.ParseForm(); err != nil {
 		log.Printf("Failed to parse form data: %v", err)
@@ -117,14 +142,9 @@ func HandleGet(rw http.ResponseWriter, req *http.Request) {
 		http.Error(rw, "Unsupported repo URI", 400)
 		return
 	}
-	c, err := storage.NewClient(ctx)
-	if err != nil {
-		http.Error(rw, "Internal Error", 500)
-		return
-	}
 	// Normalize repo URI to provide the following interface:
-	// gs://<bucket>/<host>/<org>/<repo>/repo.tgz (default branch)
-	// gs://<bucket>/<host>/<org>/<repo>/<ref>/repo.tgz (specific ref)
+	// <host>/<org>/<repo>/repo.tgz (default branch)
+	// <host>/<org>/<repo>/<ref>/repo.tgz (specific ref)
 	var p string
 	if r.Ref != "" {
 		// Include ref in path to create separate cache entries per ref
@@ -133,52 +153,105 @@ func HandleGet(rw http.ResponseWriter, req *http.Request) {
 	} else {
 		p = filepath.Join(strings.ToLower(u), "repo.tgz")
 	}
-	o := c.Bucket(*bucket).Object(p)
-	a, err := o.Attrs(ctx)
-	if err == nil && a.Updated.Before(r.Threshold) {
+	a, err := s.store.Attrs(ctx, p)
+	var stale bool
+	switch {
+	case err == nil && a.Created.Before(r.Threshold):
 		// Overwrite cache entry that isn't sufficiently recent.
-		log.Printf("Refreshing cache for %s: entry fetched %s before requested %s\n", p, a.Updated.Format(time.RFC3339), r.Threshold.Format(time.RFC3339))
-		err = storage.ErrObjectNotExist
+		log.Printf("Refreshing cache for %s: entry fetched %s before requested %s\n", p, a.Created.Format(time.RFC3339), r.Threshold.Format(time.RFC3339))
+		stale = true
+	case err == nil && s.ttls.For(u) > 0 && time.Since(a.Created) > s.ttls.For(u):
+		log.Printf("Refreshing cache for %s: entry fetched %s exceeds TTL %s\n", p, a.Created.Format(time.RFC3339), s.ttls.For(u))
+		stale = true
+	case err == nil:
+	case errors.Is(err, errNotExist):
+		stale = true
+	case errors.Is(err, storage.ErrBucketNotExist):
+		log.Printf("Configured cache bucket not found: %s\n", *bucket)
+		http.Error(rw, "Internal Error", 500)
+		return
+	default:
+		log.Printf("Unknown error fetching %s: %v\n", p, err)
+		http.Error(rw, "Internal Error", 500)
+		return
 	}
-	if err != nil {
-		switch err {
-		case storage.ErrBucketNotExist:
-			log.Printf("Configured cache bucket not found: %s\n", *bucket)
-			http.Error(rw, "Internal Error", 500)
-			return
-		case storage.ErrObjectNotExist:
-			if err := populateCache(ctx, u, r.Ref, o); err != nil {
-				log.Printf("Failed to populate cache: %v\n", err)
-				if errors.Is(err, transport.ErrAuthenticationRequired) {
-					http.Error(rw, err.Error(), 400)
-				} else {
-					if err := o.Delete(ctx); err != nil {
-						log.Printf("Issue cleaning up failed write: %v\n", err)
-					}
-					http.Error(rw, "Internal Error", 500)
-				}
-				return
-			}
-			a, err = o.Attrs(ctx)
-			if err != nil {
-				log.Printf("Failed to access metadata for gs://%s/%s: %v\n", o.BucketName(), o.ObjectName(), err)
+	if stale {
+		a, err = s.refresh(ctx, u, r.Ref, p, a)
+		if err != nil {
+			log.Printf("Failed to populate cache: %v\n", err)
+			if errors.Is(err, transport.ErrAuthenticationRequired) {
+				http.Error(rw, err.Error(), 400)
+			} else {
 				http.Error(rw, "Internal Error", 500)
-				return
 			}
-		default:
-			log.Printf("Unknown error fetching gs://%s/%s: %v\n", *bucket, p, err)
-			http.Error(rw, "Internal Error", 500)
 			return
 		}
+		if s.evictor != nil {
+			go func() {
+				if err := s.evictor.Evict(context.Background(), p); err != nil {
+					log.Printf("Failed to evict cache entries: %v\n", err)
+				}
+			}()
+		}
+	} else if now := time.Now(); now.Sub(a.LastUse()) > accessGranularity {
+		if err := s.store.Touch(ctx, p, now); err != nil {
+			log.Printf("Failed to record access of %s: %v\n", p, err)
+		}
 	}
-	redirect := url.URL{
-		Scheme:   "https",
-		Host:     "storage.googleapis.com",
-		Path:     fmt.Sprintf("download/storage/v1/b/%s/o/%s", *bucket, url.QueryEscape(p)),
-		RawQuery: fmt.Sprintf("generation=%d&alt=media", a.Generation),
+	http.Redirect(rw, req, s.store.URL(*a), http.StatusFound)
+}
+
+// refresh writes an up-to-date archive of the repo to the named object.
+//
+// If incremental updates are enabled and the object exists, the cached clone
+// is updated using a fetch rather than being recloned.
+func (s *server) refresh(ctx context.Context, repo, ref, name string, existing *objectAttrs) (*objectAttrs, error) {
+	var m billy.Filesystem
+	if s.incremental && existing != nil {
+		var err error
+		m, err = s.update(ctx, name)
+		if err != nil {
+			log.Printf("Failed to update %s, recloning: %v\n", name, err)
+			m = nil
+		}
 	}
-	redirect.RawPath = redirect.Path
-	http.Redirect(rw, req, redirect.String(), http.StatusFound)
+	if m == nil {
+		var err error
+		m, err = cloneRepo(ctx, repo, ref)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.Write(ctx, name, func(w io.Writer) error { return writeArchive(m, w) }); err != nil {
+		return nil, errors.Wrapf(err, "failure archiving files in %s", repo)
+	}
+	return s.store.Attrs(ctx, name)
+}
+
+// update fetches upstream changes into the clone archived at the named object.
+func (s *server) update(ctx context.Context, name string) (billy.Filesystem, error) {
+	r, err := s.store.Reader(ctx, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading cached archive")
+	}
+	defer r.Close()
+	m := memfs.New()
+	dotGit, err := m.Chroot(git.GitDirName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failure allocating .git/")
+	}
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "gzip read error")
+	}
+	defer gr.Close()
+	if err := archive.ExtractTar(tar.NewReader(gr), dotGit, archive.ExtractOptions{SubDir: git.GitDirName}); err != nil {
+		return nil, errors.Wrap(err, "tar extract error")
+	}
+	if err := fetchRepo(ctx, dotGit); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 // nilCache is a fake local cache for git.
@@ -194,12 +267,12 @@ func doClone(ctx context.Context, mfs billy.Filesystem, cloneOpts *git.CloneOpti
 	return err
 }
 
-// populateCache writes an archived bare checkout of the repo to the GCS object.
-func populateCache(ctx context.Context, repo, ref string, o *storage.ObjectHandle) error {
+// cloneRepo returns a filesystem containing an empty checkout of the repo.
+func cloneRepo(ctx context.Context, repo, ref string) (billy.Filesystem, error) {
 	m := memfs.New()
 	dotGit, err := m.Chroot(git.GitDirName)
 	if err != nil {
-		return errors.Wrap(err, "failure allocating .git/")
+		return nil, errors.Wrap(err, "failure allocating .git/")
 	}
 	cloneOpts := &git.CloneOptions{URL: "https://" + repo, NoCheckout: true}
 	if ref != "" {
@@ -210,16 +283,54 @@ func populateCache(ctx context.Context, repo, ref string, o *storage.ObjectHandl
 	log.Printf("Cloning with opts: %v", cloneOpts)
 	if err := doClone(ctx, dotGit, cloneOpts); err != nil {
 		if ref != "" {
-			return errors.Wrapf(err, "failure cloning %s at ref %s", repo, ref)
+			return nil, errors.Wrapf(err, "failure cloning %s at ref %s", repo, ref)
 		}
-		return errors.Wrapf(err, "failure cloning %s", repo)
+		return nil, errors.Wrapf(err, "failure cloning %s", repo)
 	}
 	log.Println("Clone successful")
-	w := o.NewWriter(ctx)
+	return m, nil
+}
+
+// fetchRepo fetches upstream changes into the cloned .git/ directory.
+//
+// The branch checked out by HEAD is advanced to match its upstream.
+func fetchRepo(ctx context.Context, dotGit billy.Filesystem) error {
+	r, err := git.Open(filesystem.NewStorage(dotGit, nilCache{}), nil)
+	if err != nil {
+		return errors.Wrap(err, "git open error")
+	}
+	err = r.FetchContext(ctx, &git.FetchOptions{Tags: git.AllTags, Force: true})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return errors.Wrap(err, "failure fetching")
+	}
+	head, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return errors.Wrap(err, "reading HEAD")
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		// Detached HEAD e.g. a tag will not have moved.
+		return nil
+	}
+	branch := head.Target()
+	upstream, err := r.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch.Short()), true)
+	if err != nil {
+		return errors.Wrap(err, "resolving upstream of HEAD")
+	}
+	if err := r.Storer.SetReference(plumbing.NewHashReference(branch, upstream.Hash())); err != nil {
+		return errors.Wrap(err, "updating HEAD")
+	}
+	log.Println("Fetch successful")
+	return nil
+}
+
+// writeArchive writes a gzipped tar of the filesystem to w.
+//
+// Files are removed from the filesystem as they are written.
+func writeArchive(m billy.Filesystem, w io.Writer) error {
 	gw := gzip.NewWriter(w)
 	tw := tar.NewWriter(gw)
 	var bytesWritten int64
-	err = util.Walk(m, m.Root(), func(path string, info fs.FileInfo, err error) error {
+	err := util.Walk(m, m.Root(), func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			// Fail on any path handling issue (see filepath.WalkFunc docs).
 			return err
@@ -255,7 +366,7 @@ func populateCache(ctx context.Context, repo, ref string, o *storage.ObjectHandl
 			} else {
 				bytesWritten += written
 			}
-			// Periodically flush to GCS.
+			// Periodically flush to storage.
 			if bytesWritten > 1_000_000 {
 				bytesWritten = 0
 				if err := gw.Flush(); err != nil {
@@ -270,23 +381,48 @@ func populateCache(ctx context.Context, repo, ref string, o *storage.ObjectHandl
 		return nil
 	})
 	if err != nil {
-		return errors.Wrapf(err, "failure archiving files in %s", repo)
+		return err
 	}
 	if err := tw.Close(); err != nil {
-		return errors.Wrapf(err, "failure archiving to gs://%s/%s", o.BucketName(), o.ObjectName())
+		return errors.Wrap(err, "failure archiving")
 	}
 	if err := gw.Close(); err != nil {
-		return errors.Wrapf(err, "failure compressing to gs://%s/%s", o.BucketName(), o.ObjectName())
-	}
-	if err := w.Close(); err != nil {
-		return errors.Wrapf(err, "failure uploading to gs://%s/%s", o.BucketName(), o.ObjectName())
+		return errors.Wrap(err, "failure compressing")
 	}
 	return nil
 }
 
 func main() {
 	flag.Parse()
-	http.HandleFunc("/get", HandleGet)
+	ctx := context.Background()
+	prefixes, err := parseTTLs(*repoTTLs)
+	if err != nil {
+		log.Fatalln(errors.Wrap(err, "parsing -repo-ttls"))
+	}
+	s := &server{ttls: ttlConfig{Default: *defaultTTL, Prefixes: prefixes}, incremental: *incremental}
+	switch {
+	case *bucket != "" && *dir != "":
+		log.Fatalln("only one of -bucket and -dir may be provided")
+	case *dir != "":
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			log.Fatalln(errors.Wrap(err, "creating cache directory"))
+		}
+		fsstore := &fsStore{fs: osfs.New(*dir)}
+		http.HandleFunc(objectPath, fsstore.ServeObject)
+		s.store = fsstore
+	case *bucket != "":
+		c, err := storage.NewClient(ctx)
+		if err != nil {
+			log.Fatalln(errors.Wrap(err, "creating storage client"))
+		}
+		s.store = &gcsStore{client: c, bucket: *bucket}
+	default:
+		log.Fatalln("one of -bucket or -dir must be provided")
+	}
+	if *maxBytes > 0 {
+		s.evictor = &evictor{store: s.store, maxBytes: *maxBytes}
+	}
+	http.HandleFunc("/get", s.HandleGet)
 	if err := http.ListenAndServe(":8080", nil); err != nil {
 		log.Fatalln(err)
 	}
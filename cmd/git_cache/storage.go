@@ -0,0 +1,297 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// errNotExist is returned when a cache object does not exist.
+var errNotExist = errors.New("object does not exist")
+
+// objectAttrs is the metadata of a cache object.
+type objectAttrs struct {
+	Name string
+	Size int64
+	// Created is the time at which the object was written.
+	Created time.Time
+	// Accessed is the time at which the object was last served, if recorded.
+	Accessed time.Time
+	// Generation identifies the written version of the object.
+	Generation int64
+}
+
+// LastUse returns the time at which the object was last written or served.
+func (a objectAttrs) LastUse() time.Time {
+	if a.Accessed.After(a.Created) {
+		return a.Accessed
+	}
+	return a.Created
+}
+
+// objectStore is the backing storage for cache objects.
+type objectStore interface {
+	// Attrs returns the metadata of the named object or errNotExist.
+	Attrs(ctx context.Context, name string) (*objectAttrs, error)
+	// Reader returns the content of the named object.
+	Reader(ctx context.Context, name string) (io.ReadCloser, error)
+	// Write replaces the content of the named object with that written by fn.
+	// The existing object is retained if fn returns an error.
+	Write(ctx context.Context, name string, fn func(io.Writer) error) error
+	// Touch records an access of the named object at the provided time.
+	Touch(ctx context.Context, name string, t time.Time) error
+	// Delete removes the named object.
+	Delete(ctx context.Context, name string) error
+	// List returns the metadata of all objects.
+	List(ctx context.Context) ([]objectAttrs, error)
+	// URL returns the location from which the object's content can be downloaded.
+	URL(a objectAttrs) string
+}
+
+// accessedKey is the GCS object metadata key recording the last access time.
+const accessedKey = "accessed"
+
+// gcsStore is an objectStore backed by a GCS bucket.
+type gcsStore struct {
+	client *storage.Client
+	bucket string
+}
+
+func (s *gcsStore) toAttrs(a *storage.ObjectAttrs) *objectAttrs {
+	oa := &objectAttrs{Name: a.Name, Size: a.Size, Created: a.Created, Generation: a.Generation}
+	if t, err := time.Parse(time.RFC3339, a.Metadata[accessedKey]); err == nil {
+		oa.Accessed = t
+	}
+	return oa
+}
+
+func (s *gcsStore) Attrs(ctx context.Context, name string) (*objectAttrs, error) {
+	a, err := s.client.Bucket(s.bucket).Object(name).Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, errNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	return s.toAttrs(a), nil
+}
+
+func (s *gcsStore) Reader(ctx context.Context, name string) (io.ReadCloser, error) {
+	return s.client.Bucket(s.bucket).Object(name).NewReader(ctx)
+}
+
+func (s *gcsStore) Write(ctx context.Context, name string, fn func(io.Writer) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := s.client.Bucket(s.bucket).Object(name).NewWriter(ctx)
+	if err := fn(w); err != nil {
+		// Cancelling the context aborts the upload.
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrapf(err, "failure uploading to gs://%s/%s", s.bucket, name)
+	}
+	return nil
+}
+
+func (s *gcsStore) Touch(ctx context.Context, name string, t time.Time) error {
+	_, err := s.client.Bucket(s.bucket).Object(name).Update(ctx, storage.ObjectAttrsToUpdate{
+		Metadata: map[string]string{accessedKey: t.Format(time.RFC3339)},
+	})
+	return err
+}
+
+func (s *gcsStore) Delete(ctx context.Context, name string) error {
+	return s.client.Bucket(s.bucket).Object(name).Delete(ctx)
+}
+
+func (s *gcsStore) List(ctx context.Context) ([]objectAttrs, error) {
+	var attrs []objectAttrs
+	it := s.client.Bucket(s.bucket).Objects(ctx, nil)
+	for {
+		a, err := it.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, *s.toAttrs(a))
+	}
+	return attrs, nil
+}
+
+func (s *gcsStore) URL(a objectAttrs) string {
+	u := url.URL{
+		Scheme:   "https",
+		Host:     "storage.googleapis.com",
+		Path:     fmt.Sprintf("download/storage/v1/b/%s/o/%s", s.bucket, url.QueryEscape(a.Name)),
+		RawQuery: fmt.Sprintf("generation=%d&alt=media", a.Generation),
+	}
+	u.RawPath = u.Path
+	return u.String()
+}
+
+// objectPath is the path prefix from which fsStore objects are served.
+const objectPath = "/object/"
+
+const (
+	// accessedSuffix is appended to an fsStore object's name to form the file
+	// recording its last access time.
+	accessedSuffix = ".accessed"
+	// tempPrefix is the filename prefix of in-progress fsStore writes.
+	tempPrefix = ".tmp-"
+)
+
+// fsStore is an objectStore backed by a local filesystem.
+//
+// Objects are served by the cache service itself at objectPath.
+type fsStore struct {
+	fs billy.Filesystem
+}
+
+func (s *fsStore) Attrs(ctx context.Context, name string) (*objectAttrs, error) {
+	fi, err := s.fs.Stat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errNotExist
+	} else if err != nil {
+		return nil, err
+	}
+	return s.toAttrs(name, fi), nil
+}
+
+// fsGeneration identifies the written version of a file.
+//
+// Since writes replace the file, its modification time changes with each.
+func fsGeneration(fi fs.FileInfo) int64 {
+	return fi.ModTime().UnixNano()
+}
+
+func (s *fsStore) toAttrs(name string, fi fs.FileInfo) *objectAttrs {
+	a := &objectAttrs{Name: name, Size: fi.Size(), Created: fi.ModTime(), Generation: fsGeneration(fi)}
+	if b, err := util.ReadFile(s.fs, name+accessedSuffix); err == nil {
+		if t, err := time.Parse(time.RFC3339, string(b)); err == nil {
+			a.Accessed = t
+		}
+	}
+	return a
+}
+
+func (s *fsStore) Reader(ctx context.Context, name string) (io.ReadCloser, error) {
+	f, err := s.fs.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errNotExist
+	}
+	return f, err
+}
+
+func (s *fsStore) Write(ctx context.Context, name string, fn func(io.Writer) error) error {
+	dir := path.Dir(name)
+	if err := s.fs.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := util.TempFile(s.fs, dir, tempPrefix)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		s.fs.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		s.fs.Remove(f.Name())
+		return err
+	}
+	// Rename to atomically replace any existing object.
+	return s.fs.Rename(f.Name(), name)
+}
+
+func (s *fsStore) Touch(ctx context.Context, name string, t time.Time) error {
+	return util.WriteFile(s.fs, name+accessedSuffix, []byte(t.Format(time.RFC3339)), 0o644)
+}
+
+func (s *fsStore) Delete(ctx context.Context, name string) error {
+	if err := s.fs.Remove(name + accessedSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.fs.Remove(name)
+}
+
+func (s *fsStore) List(ctx context.Context) ([]objectAttrs, error) {
+	var attrs []objectAttrs
+	err := util.Walk(s.fs, "/", func(p string, fi fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		base := path.Base(p)
+		if fi.IsDir() || strings.HasSuffix(base, accessedSuffix) || strings.HasPrefix(base, tempPrefix) {
+			return nil
+		}
+		attrs = append(attrs, *s.toAttrs(strings.TrimPrefix(p, "/"), fi))
+		return nil
+	})
+	return attrs, err
+}
+
+func (s *fsStore) URL(a objectAttrs) string {
+	return objectPath + a.Name + fmt.Sprintf("?generation=%d", a.Generation)
+}
+
+// ServeObject serves the content of an object to the requester.
+func (s *fsStore) ServeObject(rw http.ResponseWriter, req *http.Request) {
+	p := path.Clean(req.URL.Path)
+	if !strings.HasPrefix(p, objectPath) {
+		http.NotFound(rw, req)
+		return
+	}
+	name := strings.TrimPrefix(p, objectPath)
+	if strings.HasSuffix(name, accessedSuffix) || strings.HasPrefix(path.Base(name), tempPrefix) {
+		http.NotFound(rw, req)
+		return
+	}
+	f, err := s.fs.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(rw, req)
+		return
+	} else if err != nil {
+		http.Error(rw, "Internal Error", 500)
+		return
+	}
+	defer f.Close()
+	var fi fs.FileInfo
+	// Stat the opened file, where supported, so the generation checked is that of the content served.
+	if sf, ok := f.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		fi, err = sf.Stat()
+	} else {
+		fi, err = s.fs.Stat(name)
+	}
+	if err != nil {
+		http.Error(rw, "Internal Error", 500)
+		return
+	}
+	// As with GCS, a generation that has since been replaced is not found.
+	if gen := req.URL.Query().Get("generation"); gen != "" && gen != strconv.FormatInt(fsGeneration(fi), 10) {
+		http.NotFound(rw, req)
+		return
+	}
+	http.ServeContent(rw, req, path.Base(name), fi.ModTime(), f)
+}
+
+var _ objectStore = &gcsStore{}
+var _ objectStore = &fsStore{}
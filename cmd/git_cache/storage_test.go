@@ -0,0 +1,124 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFSStore(t *testing.T) {
+	ctx := context.Background()
+	s := &fsStore{fs: osfs.New(t.TempDir())}
+	const name = "github.com/org/repo/repo.tgz"
+	if _, err := s.Attrs(ctx, name); !errors.Is(err, errNotExist) {
+		t.Fatalf("Attrs() of missing object error = %v, want errNotExist", err)
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := s.Write(ctx, name, func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	write("v1")
+	// A failed write retains the existing object.
+	if err := s.Write(ctx, name, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New("failed")
+	}); err == nil {
+		t.Fatal("Write() with failing fn succeeded")
+	}
+	r, err := s.Reader(ctx, name)
+	if err != nil {
+		t.Fatalf("Reader() error = %v", err)
+	}
+	b, _ := io.ReadAll(r)
+	r.Close()
+	if string(b) != "v1" {
+		t.Errorf("content = %q, want %q", b, "v1")
+	}
+	accessed := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.Touch(ctx, name, accessed); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	a, err := s.Attrs(ctx, name)
+	if err != nil {
+		t.Fatalf("Attrs() error = %v", err)
+	}
+	if a.Size != 2 || !a.Accessed.Equal(accessed) || a.Generation == 0 {
+		t.Errorf("Attrs() = %+v", a)
+	}
+	if want := objectPath + name + "?generation=" + strconv.FormatInt(a.Generation, 10); s.URL(*a) != want {
+		t.Errorf("URL() = %q, want %q", s.URL(*a), want)
+	}
+	// Neither access records nor temporary files are listed as objects.
+	list, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if diff := cmp.Diff([]objectAttrs{*a}, list); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+	if err := s.Delete(ctx, name); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if list, err := s.List(ctx); err != nil || len(list) != 0 {
+		t.Errorf("List() after Delete() = %v, %v; want empty", list, err)
+	}
+}
+
+func TestFSStoreServeObject(t *testing.T) {
+	ctx := context.Background()
+	s := &fsStore{fs: osfs.New(t.TempDir())}
+	const name = "github.com/org/repo/repo.tgz"
+	if err := s.Write(ctx, name, func(w io.Writer) error {
+		_, err := io.WriteString(w, "content")
+		return err
+	}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := s.Touch(ctx, name, time.Now()); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+	a, err := s.Attrs(ctx, name)
+	if err != nil {
+		t.Fatalf("Attrs() error = %v", err)
+	}
+	for _, tc := range []struct {
+		name     string
+		url      string
+		wantCode int
+		wantBody string
+	}{
+		{"current generation", s.URL(*a), http.StatusOK, "content"},
+		{"no generation", objectPath + name, http.StatusOK, "content"},
+		{"replaced generation", objectPath + name + "?generation=" + strconv.FormatInt(a.Generation-1, 10), http.StatusNotFound, ""},
+		{"missing", objectPath + "github.com/org/other/repo.tgz", http.StatusNotFound, ""},
+		{"access record", objectPath + name + accessedSuffix, http.StatusNotFound, ""},
+		{"outside object path", "/other/" + name, http.StatusNotFound, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			s.ServeObject(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if rr.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d", rr.Code, tc.wantCode)
+			}
+			if tc.wantCode == http.StatusOK && rr.Body.String() != tc.wantBody {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tc.wantBody)
+			}
+		})
+	}
+}
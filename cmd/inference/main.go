@@ -9,14 +9,17 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 
 	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/google/oss-rebuild/internal/api"
 	"github.com/google/oss-rebuild/internal/api/cratesregistryservice"
 	"github.com/google/oss-rebuild/internal/api/inferenceservice"
 	"github.com/google/oss-rebuild/internal/gitx"
 	"github.com/google/oss-rebuild/internal/httpegress"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/pkg/errors"
 	"google.golang.org/api/idtoken"
 	gapihttp "google.golang.org/api/transport/http"
@@ -24,7 +27,9 @@ import (
 
 var (
 	gitCacheURL       = flag.String("git-cache-url", "", "if provided, the git-cache service to use to fetch repos")
+	cloneFilter       = flag.String("clone-filter", "", "if provided, the partial clone filter to use when cloning repos (only blob:none is supported)")
 	cratesRegistryURL = flag.String("crates-registry-service-url", "", "if provided, the crates registry service to use for Rust crate index resolution")
+	registryConfig    = flag.String("registry-config", "", "if provided, the path of a YAML file describing non-public package registries to use")
 )
 
 var httpcfg = httpegress.Config{}
@@ -51,6 +56,18 @@ func InferInit(ctx context.Context) (*inferenceservice.InferDeps, error) {
 		}
 		d.GitCache = &gitx.Cache{IDClient: c, APIClient: sc, URL: u}
 	}
+	if *cloneFilter != "" {
+		switch f := packp.Filter(*cloneFilter); f {
+		case gitx.BlobNone:
+			d.CloneFilter = f
+		case gitx.TreeNone:
+			// Searches walk every tree in the repo which, when trees are
+			// omitted, would require fetching them one at a time.
+			return nil, errors.Errorf("unsupported clone filter for inference: %s", *cloneFilter)
+		default:
+			return nil, errors.Errorf("unsupported clone filter: %s", *cloneFilter)
+		}
+	}
 	d.RepoOptF = func() *gitx.RepositoryOptions {
 		return &gitx.RepositoryOptions{
 			Worktree: memfs.New(),
@@ -68,6 +85,17 @@ func InferInit(ctx context.Context) (*inferenceservice.InferDeps, error) {
 		}
 		d.CratesRegistryStub = api.Stub[cratesregistryservice.FindRegistryCommitRequest, cratesregistryservice.FindRegistryCommitResponse](c, u.JoinPath("resolve"))
 	}
+	if *registryConfig != "" {
+		f, err := os.Open(*registryConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening registry config")
+		}
+		defer f.Close()
+		d.RegistryConfig, err = rebuild.ParseRegistryConfig(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing registry config")
+		}
+	}
 	return &d, nil
 }
 
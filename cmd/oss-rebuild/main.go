@@ -13,11 +13,13 @@ import (
 	"os"
 	"path"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	gcs "cloud.google.com/go/storage"
 	"github.com/fatih/color"
+	"github.com/google/oss-rebuild/internal/verifier"
 	"github.com/google/oss-rebuild/pkg/attestation"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/pkg/errors"
@@ -28,17 +30,23 @@ import (
 )
 
 var (
-	output       = flag.String("output", "summary", "Output format [summary, bundle, payload, dockerfile, build, steps]")
+	output       = flag.String("output", "summary", "Output format [summary, bundle, payload, dockerfile, build, steps, vsa]")
 	bucket       = flag.String("bucket", "google-rebuild-attestations", "GCS bucket from which to pull rebuild attestations")
 	verify       = flag.Bool("verify", true, "whether to verify rebuild attestation signatures")
 	verifyWith   = flag.String("verify-with", ossRebuildKeyURI, "comma-separated list of key URIs used to verify rebuild attestation signatures")
 	verifyOnline = flag.Bool("verify-online", false, "whether to always fetch --verify-with key contents, ignoring embedded contents")
+	requireTlog  = flag.Bool("require-tlog", false, "whether to fail verification when no transparency log proof was published")
+	tlogKey      = flag.String("tlog-public-key", "", "path to the PEM-encoded public key with which the transparency log signs its checkpoints")
+	vsaVerifier  = flag.String("vsa-verifier-id", "", "verifier ID to record in the VSA produced by -output=vsa")
+	digest       = flag.String("digest", "", "artifact digest of the form sha256:<hex> with which to look up the attestation in place of its coordinates")
+	vsaKey       = flag.String("vsa-signing-key", "", "path to a PEM-encoded ECDSA private key with which to sign the VSA produced by -output=vsa")
 )
 
 var (
 	yellow = color.New(color.FgYellow).SprintFunc()
 	green  = color.New(color.FgGreen).SprintFunc()
 	white  = color.New(color.FgWhite).SprintFunc()
+	red    = color.New(color.FgRed).SprintFunc()
 )
 
 var rootCmd = &cobra.Command{
@@ -59,12 +67,160 @@ func writeIndentedJson(out io.Writer, b []byte) error {
 	return nil
 }
 
+// targetFromArgs parses the <ecosystem> <package> <version> [<artifact>] positional arguments.
+func targetFromArgs(cmd *cobra.Command, args []string) (rebuild.Target, error) {
+	if len(args) > 4 {
+		return rebuild.Target{}, errors.New("Too many arguments")
+	}
+	ecosystem := rebuild.Ecosystem(args[0])
+	pkg := args[1]
+	version := args[2]
+	var artifact string
+	if len(args) < 4 {
+		switch ecosystem {
+		case rebuild.CratesIO:
+			artifact = fmt.Sprintf("%s-%s.crate", pkg, version)
+		case rebuild.PyPI:
+			artifact = fmt.Sprintf("%s-%s-py3-none-any.whl", strings.ReplaceAll(pkg, "-", "_"), version)
+		case rebuild.NPM:
+			artifact = fmt.Sprintf("%s-%s.tgz", pkg, version)
+		default:
+			return rebuild.Target{}, errors.Errorf("Unsupported ecosystem: \"%s\"", ecosystem)
+		}
+		fmt.Fprintln(cmd.OutOrStderr(), yellow("NOTE:"), white(fmt.Sprintf(" artifact is being inferred as \"%s\"", artifact)))
+	} else {
+		artifact = args[3]
+	}
+	return rebuild.Target{
+		Ecosystem: ecosystem,
+		Package:   pkg,
+		Version:   version,
+		Artifact:  artifact,
+	}, nil
+}
+
+// storeContext configures ctx for unauthenticated access to the attestation bucket.
+func storeContext(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, rebuild.RunID, "")
+	return context.WithValue(ctx, rebuild.GCSClientOptionsID, []option.ClientOption{option.WithoutAuthentication()})
+}
+
+// envelopeVerifier constructs the verifier for the keys selected by the verification flags.
+func envelopeVerifier(ctx context.Context) (*dsse.EnvelopeVerifier, error) {
+	var verifiers []dsse.Verifier
+	if !*verify {
+		verifiers = append(verifiers, &trustAllVerifier{})
+	} else {
+		keysToAdd := slices.DeleteFunc(strings.Split(*verifyWith, ","), func(s string) bool { return s == "" })
+		var keysAdded []string
+		if !*verifyOnline {
+			for _, key := range embeddedKeys {
+				if !slices.Contains(keysToAdd, key.ID) {
+					continue
+				}
+				verifiers = append(verifiers, &keyVerifier{key})
+				keysAdded = append(keysAdded, key.ID)
+			}
+		}
+		for _, uri := range keysToAdd {
+			if slices.Contains(keysAdded, uri) {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(uri, kmsV1API):
+				kmsVerifier, err := makeKMSVerifier(ctx, ossRebuildKeyResource)
+				if err != nil {
+					return nil, err
+				}
+				verifiers = append(verifiers, kmsVerifier)
+			default:
+				return nil, errors.Errorf("unsupported key URI: %s", uri)
+			}
+			keysAdded = append(keysAdded, uri)
+		}
+	}
+	dsseVerifier, err := dsse.NewEnvelopeVerifier(verifiers...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating EnvelopeVerifier")
+	}
+	return dsseVerifier, nil
+}
+
+// fetchBundle reads and verifies the attestation bundle stored as asset a.
+func fetchBundle(ctx context.Context, store rebuild.ReadOnlyAssetStore, a rebuild.Asset) (*attestation.Bundle, []byte, error) {
+	dsseVerifier, err := envelopeVerifier(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := store.Reader(ctx, a)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "creating attestation reader")
+	}
+	defer r.Close()
+	bundleBytes, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "creating attestation reader")
+	}
+	bundle, err := attestation.NewBundle(ctx, bundleBytes, dsseVerifier)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "creating bundle")
+	}
+	return bundle, bundleBytes, nil
+}
+
+// fetchRevocation returns the verified revocation of the bundle contents, if one was published.
+func fetchRevocation(ctx context.Context, store rebuild.ReadOnlyAssetStore, t rebuild.Target, bundleBytes []byte) (*attestation.Revocation, error) {
+	dsseVerifier, err := envelopeVerifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return verifier.CheckRevocation(ctx, store, t, bundleBytes, dsseVerifier)
+}
+
+// printRevocation reports the details of a bundle's revocation.
+func printRevocation(w io.Writer, r *attestation.Revocation) {
+	fmt.Fprintln(w, red("Bundle revoked!"))
+	fmt.Fprintln(w, yellow("Revoked at"), ":", white(r.Predicate.RevokedAt.Format(time.RFC3339)))
+	fmt.Fprintln(w, yellow("Reason"), ":", white(r.Predicate.Reason))
+	if r.Predicate.SupersededBy != nil {
+		fmt.Fprintln(w, yellow("Superseded by"), ":", white(r.Predicate.SupersededBy.Name+" (sha256:"+r.Predicate.SupersededBy.Digest["sha256"]+")"))
+	}
+}
+
+// targetFromDigest resolves an artifact digest to the unique target whose attestation describes it.
+func targetFromDigest(ctx context.Context, digest string) (rebuild.Target, error) {
+	idx, err := rebuild.NewGCSDigestIndex(ctx, "gs://"+*bucket)
+	if err != nil {
+		return rebuild.Target{}, errors.Wrap(err, "initializing digest index")
+	}
+	targets, err := idx.Lookup(ctx, digest)
+	if errors.Is(err, rebuild.ErrDigestNotFound) {
+		return rebuild.Target{}, errors.Errorf("no attestation found for %s", digest)
+	} else if err != nil {
+		return rebuild.Target{}, errors.Wrap(err, "looking up digest")
+	}
+	if len(targets) != 1 {
+		var coords []string
+		for _, t := range targets {
+			coords = append(coords, strings.Join([]string{string(t.Ecosystem), t.Package, t.Version, t.Artifact}, " "))
+		}
+		return rebuild.Target{}, errors.Errorf("%s matches multiple artifacts, specify one of:\n  %s", digest, strings.Join(coords, "\n  "))
+	}
+	return targets[0], nil
+}
+
 var getCmd = &cobra.Command{
-	Use:   "get <ecosystem> <package> <version> [<artifact>] [-output=summary|bundle|payload|dockerfile|build|steps]",
+	Use:   "get (<ecosystem> <package> <version> [<artifact>] | --digest sha256:<hex>) [-output=summary|bundle|payload|dockerfile|build|steps|vsa]",
 	Short: "Get rebuild attestation for a specific artifact.",
 	Long: `Get rebuild attestation for a specific ecosystem/package/version/artifact.
-The ecosystem is one of npm, pypi, or cratesio. For npm the artifact is the <package>-<version>.tar.gz file. For pypi the artifact is the wheel file. For cratesio the artifact is the <package>-<version>.crate file.`,
-	Args: cobra.MinimumNArgs(3),
+The ecosystem is one of npm, pypi, or cratesio. For npm the artifact is the <package>-<version>.tar.gz file. For pypi the artifact is the wheel file. For cratesio the artifact is the <package>-<version>.crate file.
+Alternatively, the artifact may be identified by its sha256 digest using --digest.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if *digest != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(3)(cmd, args)
+	},
 	// Silence errors because we will print the error ourselves in main.
 	SilenceErrors: true,
 	// Don't show usage for every error.
@@ -74,95 +230,37 @@ The ecosystem is one of npm, pypi, or cratesio. For npm the artifact is the <pac
 		printlnAll := func(all ...string) {
 			fmt.Fprintln(cmd.OutOrStdout(), strings.Join(all, ""))
 		}
-		if len(args) > 4 {
-			return errors.New("Too many arguments")
-		}
+		ctx := storeContext(cmd.Context())
 		var t rebuild.Target
-		{
-			ecosystem := rebuild.Ecosystem(args[0])
-			pkg := args[1]
-			version := args[2]
-			var artifact string
-			if len(args) < 4 {
-				switch ecosystem {
-				case rebuild.CratesIO:
-					artifact = fmt.Sprintf("%s-%s.crate", pkg, version)
-				case rebuild.PyPI:
-					artifact = fmt.Sprintf("%s-%s-py3-none-any.whl", strings.ReplaceAll(pkg, "-", "_"), version)
-				case rebuild.NPM:
-					artifact = fmt.Sprintf("%s-%s.tgz", pkg, version)
-				default:
-					return errors.Errorf("Unsupported ecosystem: \"%s\"", ecosystem)
-				}
-				fmt.Fprintln(cmd.OutOrStderr(), yellow("NOTE:"), white(fmt.Sprintf(" artifact is being inferred as \"%s\"", artifact)))
-			} else {
-				artifact = args[3]
-			}
-			t = rebuild.Target{
-				Ecosystem: ecosystem,
-				Package:   pkg,
-				Version:   version,
-				Artifact:  artifact,
+		var err error
+		if *digest != "" {
+			t, err = targetFromDigest(ctx, *digest)
+			if err == nil {
+				fmt.Fprintln(cmd.OutOrStderr(), yellow("NOTE:"), white(fmt.Sprintf(" digest resolved to %s %s %s %s", t.Ecosystem, t.Package, t.Version, t.Artifact)))
 			}
+		} else {
+			t, err = targetFromArgs(cmd, args)
 		}
-		var bundle *attestation.Bundle
-		var bundleBytes []byte
-		{
-			ctx := cmd.Context()
-			ctx = context.WithValue(ctx, rebuild.RunID, "")
-			ctx = context.WithValue(ctx, rebuild.GCSClientOptionsID, []option.ClientOption{option.WithoutAuthentication()})
-			attestations, err := rebuild.NewGCSStore(ctx, "gs://"+*bucket)
-			if err != nil {
-				return errors.Wrap(err, "initializing GCS store")
-			}
-			var verifiers []dsse.Verifier
-			if !*verify {
-				verifiers = append(verifiers, &trustAllVerifier{})
-			} else {
-				keysToAdd := slices.DeleteFunc(strings.Split(*verifyWith, ","), func(s string) bool { return s == "" })
-				var keysAdded []string
-				if !*verifyOnline {
-					for _, key := range embeddedKeys {
-						if !slices.Contains(keysToAdd, key.ID) {
-							continue
-						}
-						verifiers = append(verifiers, &keyVerifier{key})
-						keysAdded = append(keysAdded, key.ID)
-					}
-				}
-				for _, uri := range keysToAdd {
-					if slices.Contains(keysAdded, uri) {
-						continue
-					}
-					switch {
-					case strings.HasPrefix(uri, kmsV1API):
-						verifier, err := makeKMSVerifier(ctx, ossRebuildKeyResource)
-						if err != nil {
-							return err
-						}
-						verifiers = append(verifiers, verifier)
-					default:
-						return errors.Errorf("unsupported key URI: %s", uri)
-					}
-					keysAdded = append(keysAdded, uri)
-				}
-			}
-			dsseVerifier, err := dsse.NewEnvelopeVerifier(verifiers...)
-			if err != nil {
-				return errors.Wrap(err, "creating EnvelopeVerifier")
-			}
-			r, err := attestations.Reader(ctx, rebuild.AttestationBundleAsset.For(t))
-			if err != nil {
-				return errors.Wrap(err, "creating attestation reader")
-			}
-			bundleBytes, err = io.ReadAll(r)
-			if err != nil {
-				return errors.Wrap(err, "creating attestation reader")
-			}
-			bundle, err = attestation.NewBundle(ctx, bundleBytes, dsseVerifier)
-			if err != nil {
-				return errors.Wrap(err, "creating bundle")
-			}
+		if err != nil {
+			return err
+		}
+		attestations, err := rebuild.NewGCSStore(ctx, "gs://"+*bucket)
+		if err != nil {
+			return errors.Wrap(err, "initializing GCS store")
+		}
+		bundleURI := attestations.URL(rebuild.AttestationBundleAsset.For(t)).String()
+		bundle, bundleBytes, err := fetchBundle(ctx, attestations, rebuild.AttestationBundleAsset.For(t))
+		if errors.Is(err, rebuild.ErrAssetNotFound) {
+			return getFailure(cmd, ctx, attestations, t)
+		} else if err != nil {
+			return err
+		}
+		revocation, err := fetchRevocation(ctx, attestations, t, bundleBytes)
+		if err != nil {
+			return err
+		}
+		if revocation != nil {
+			printRevocation(cmd.OutOrStderr(), revocation)
 		}
 		switch *output {
 		case "summary":
@@ -178,7 +276,9 @@ The ecosystem is one of npm, pypi, or cratesio. For npm the artifact is the <pac
 			if err != nil {
 				return err
 			}
-			fmt.Fprintln(cmd.OutOrStderr(), green("Rebuild found!"))
+			if revocation == nil {
+				fmt.Fprintln(cmd.OutOrStderr(), green("Rebuild found!"))
+			}
 			pp := func(label string, value any) {
 				printlnAll(yellow(label), ": ", white(value))
 			}
@@ -236,6 +336,17 @@ The ecosystem is one of npm, pypi, or cratesio. For npm the artifact is the <pac
 			if err := writeIndentedJson(cmd.OutOrStdout(), steps.Content); err != nil {
 				return errors.Wrap(err, "writing dockerfile")
 			}
+		case "vsa":
+			if revocation != nil {
+				return errors.New("cannot summarize a revoked bundle")
+			}
+			envelope, err := signVSA(ctx, bundle, bundleURI, *vsaVerifier, *vsaKey)
+			if err != nil {
+				return err
+			}
+			if err := json.NewEncoder(cmd.OutOrStdout()).Encode(envelope); err != nil {
+				return errors.Wrap(err, "writing VSA")
+			}
 		default:
 			return errors.New("unsupported format: " + *output)
 		}
@@ -243,9 +354,140 @@ The ecosystem is one of npm, pypi, or cratesio. For npm the artifact is the <pac
 	},
 }
 
+// getFailure outputs the failure attestation for t, if the rebuild was attempted.
+func getFailure(cmd *cobra.Command, ctx context.Context, store rebuild.ReadOnlyAssetStore, t rebuild.Target) error {
+	bundle, bundleBytes, err := fetchBundle(ctx, store, rebuild.RebuildFailureAsset.For(t))
+	if errors.Is(err, rebuild.ErrAssetNotFound) {
+		return errors.New("no rebuild attempt found")
+	} else if err != nil {
+		return err
+	}
+	fa, err := attestation.FilterForOne[attestation.RebuildFailureAttestation](
+		bundle,
+		attestation.WithPredicateType(attestation.PredicateRebuildFailureV01))
+	if err != nil {
+		return err
+	}
+	switch *output {
+	case "summary":
+		details, err := fa.Predicate.RunDetails.Byproducts.Details()
+		if err != nil {
+			return errors.Wrap(err, "decoding failure details")
+		}
+		fmt.Fprintln(cmd.OutOrStderr(), red("Rebuild attempted and failed"))
+		pp := func(label string, value any) {
+			fmt.Fprintln(cmd.OutOrStdout(), yellow(label)+": "+white(value))
+		}
+		pp("Reason", details.Reason)
+		pp("Message", strconv.Quote(details.Message))
+		if d := details.ContentDiff; d != nil {
+			pp("Files only in rebuild", len(d.RebuildOnly))
+			pp("Files only upstream", len(d.UpstreamOnly))
+			pp("Files modified", len(d.Modified))
+			for _, f := range d.Modified {
+				pp("  modified", f)
+			}
+		}
+	case "bundle":
+		cmd.OutOrStdout().Write(bundleBytes)
+	case "payload":
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		for _, s := range bundle.Statements() {
+			if err := encoder.Encode(s); err != nil {
+				return errors.Wrap(err, "pprinting payload")
+			}
+		}
+	case "build":
+		strategy := fa.Predicate.RunDetails.Byproducts.BuildStrategy
+		if strategy == nil {
+			return errors.New("failed before a strategy was determined")
+		}
+		if err := writeIndentedJson(cmd.OutOrStdout(), strategy.Content); err != nil {
+			return errors.Wrap(err, "writing strategy")
+		}
+	default:
+		return errors.New("unsupported format for failed rebuild: " + *output)
+	}
+	return nil
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <ecosystem> <package> <version> [<artifact>]",
+	Short: "Verify the rebuild attestation for a specific artifact.",
+	Long: `Verify the signatures of the rebuild attestation bundle for a specific ecosystem/package/version/artifact,
+that the bundle has not been revoked and, if one was published, the proof of the bundle's inclusion in a transparency log.`,
+	Args: cobra.RangeArgs(3, 4),
+	// Silence errors because we will print the error ourselves in main.
+	SilenceErrors: true,
+	// Don't show usage for every error.
+	SilenceUsage: true,
+	// RunE because we want errors to affect the return status.
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := targetFromArgs(cmd, args)
+		if err != nil {
+			return err
+		}
+		ctx := storeContext(cmd.Context())
+		attestations, err := rebuild.NewGCSStore(ctx, "gs://"+*bucket)
+		if err != nil {
+			return errors.Wrap(err, "initializing GCS store")
+		}
+		_, bundleBytes, err := fetchBundle(ctx, attestations, rebuild.AttestationBundleAsset.For(t))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), green("Bundle signatures verified"))
+		if revocation, err := fetchRevocation(ctx, attestations, t, bundleBytes); err != nil {
+			return err
+		} else if revocation != nil {
+			printRevocation(cmd.OutOrStdout(), revocation)
+			return errors.New("bundle has been revoked")
+		}
+		r, err := attestations.Reader(ctx, rebuild.TransparencyLogProofAsset.For(t))
+		if errors.Is(err, rebuild.ErrAssetNotFound) {
+			if *requireTlog {
+				return errors.New("no transparency log proof found")
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), yellow("No transparency log proof found"))
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "reading transparency log proof")
+		}
+		defer r.Close()
+		var proof verifier.TransparencyLogProof
+		if err := json.NewDecoder(r).Decode(&proof); err != nil {
+			return errors.Wrap(err, "decoding transparency log proof")
+		}
+		if *tlogKey == "" {
+			if *requireTlog {
+				return errors.New("--tlog-public-key is required to verify the transparency log proof")
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), yellow("Transparency log proof not verified: no --tlog-public-key provided"))
+			return nil
+		}
+		keyPEM, err := os.ReadFile(*tlogKey)
+		if err != nil {
+			return errors.Wrap(err, "reading transparency log key")
+		}
+		logKey, err := parsePKIX(string(keyPEM))
+		if err != nil {
+			return errors.Wrap(err, "parsing transparency log key")
+		}
+		if err := verifier.VerifyLogProof(&proof, bundleBytes, logKey); err != nil {
+			return errors.Wrap(err, "verifying transparency log proof")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), green("Transparency log inclusion verified"))
+		fmt.Fprintln(cmd.OutOrStdout(), yellow("Log"), ":", white(proof.LogID))
+		fmt.Fprintln(cmd.OutOrStdout(), yellow("Index"), ":", white(proof.LogIndex))
+		fmt.Fprintln(cmd.OutOrStdout(), yellow("Integrated at"), ":", white(proof.IntegratedTime.Format(time.RFC3339)))
+		return nil
+	},
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list <ecosystem> <package> [<version>]",
-	Short: "List artifacts with rebuild attestations for a given query",
+	Short: "List artifacts with rebuild or rebuild failure attestations for a given query",
 	Args:  cobra.MaximumNArgs(3),
 	// Silence errors because we will print the error ourselves in main.
 	SilenceErrors: true,
@@ -273,7 +515,14 @@ var listCmd = &cobra.Command{
 			if err != nil {
 				return errors.Wrap(err, "listing objects")
 			}
-			io.WriteString(cmd.OutOrStdout(), obj.Name+"\n")
+			switch path.Base(obj.Name) {
+			case string(rebuild.RebuildFailureAsset):
+				io.WriteString(cmd.OutOrStdout(), obj.Name+" "+red("(failed)")+"\n")
+			case string(rebuild.RevocationAsset):
+				io.WriteString(cmd.OutOrStdout(), obj.Name+" "+red("(revocation)")+"\n")
+			default:
+				io.WriteString(cmd.OutOrStdout(), obj.Name+"\n")
+			}
 		}
 		return nil
 	},
@@ -284,9 +533,20 @@ func init() {
 
 	getCmd.Flags().AddGoFlag(flag.Lookup("output"))
 	getCmd.Flags().AddGoFlag(flag.Lookup("bucket"))
+	getCmd.Flags().AddGoFlag(flag.Lookup("digest"))
 	getCmd.Flags().AddGoFlag(flag.Lookup("verify"))
 	getCmd.Flags().AddGoFlag(flag.Lookup("verify-with"))
 	getCmd.Flags().AddGoFlag(flag.Lookup("verify-online"))
+	getCmd.Flags().AddGoFlag(flag.Lookup("vsa-verifier-id"))
+	getCmd.Flags().AddGoFlag(flag.Lookup("vsa-signing-key"))
+
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().AddGoFlag(flag.Lookup("bucket"))
+	verifyCmd.Flags().AddGoFlag(flag.Lookup("verify-with"))
+	verifyCmd.Flags().AddGoFlag(flag.Lookup("verify-online"))
+	verifyCmd.Flags().AddGoFlag(flag.Lookup("require-tlog"))
+	verifyCmd.Flags().AddGoFlag(flag.Lookup("tlog-public-key"))
 
 	rootCmd.AddCommand(listCmd)
 
@@ -0,0 +1,106 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+
+	"github.com/google/oss-rebuild/pkg/attestation"
+	"github.com/pkg/errors"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// ecdsaSigner is a dsse.Signer backed by a local ECDSA P-256 private key.
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+	id  string
+}
+
+func (s *ecdsaSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	h := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, s.key, h[:])
+}
+
+func (s *ecdsaSigner) KeyID() (string, error) {
+	return s.id, nil
+}
+
+func (s *ecdsaSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+var _ dsse.Signer = (*ecdsaSigner)(nil)
+
+// loadECDSASigner reads a PEM-encoded PKCS#8 or SEC 1 ECDSA private key from path.
+func loadECDSASigner(path string) (*ecdsaSigner, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading key file")
+	}
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, errors.New("failed to decode PEM private key")
+	}
+	var key *ecdsa.PrivateKey
+	switch blk.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(blk.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing EC private key")
+		}
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(blk.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing PKCS#8 private key")
+		}
+		var ok bool
+		if key, ok = k.(*ecdsa.PrivateKey); !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+	default:
+		return nil, errors.Errorf("unsupported PEM block type: %s", blk.Type)
+	}
+	return &ecdsaSigner{key: key, id: path}, nil
+}
+
+// signVSA generates a VSA for the verified bundle and signs it with the key at keyPath.
+func signVSA(ctx context.Context, bundle *attestation.Bundle, bundleURI, verifierID, keyPath string) (*dsse.Envelope, error) {
+	if verifierID == "" {
+		return nil, errors.New("--vsa-verifier-id is required")
+	}
+	if keyPath == "" {
+		return nil, errors.New("--vsa-signing-key is required")
+	}
+	signer, err := loadECDSASigner(keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading signing key")
+	}
+	// The bundle passed signature verification and contains a reproduced
+	// artifact, satisfying the default rebuild policy.
+	outcome := attestation.PolicyOutcome{Passed: true, VerifiedLevels: []string{attestation.LevelRebuildV01}}
+	vsa, err := attestation.NewVSA(bundle, outcome, attestation.VSAOptions{
+		Verifier:  attestation.VSAVerifier{ID: verifierID},
+		BundleURI: bundleURI,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "creating VSA")
+	}
+	payload, err := json.Marshal(vsa)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling VSA")
+	}
+	es, err := dsse.NewEnvelopeSigner(signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating envelope signer")
+	}
+	return es.SignPayload(ctx, attestation.InTotoPayloadType, payload)
+}
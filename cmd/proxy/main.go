@@ -17,28 +17,40 @@ import (
 	"time"
 
 	"github.com/elazarl/goproxy"
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/google/oss-rebuild/pkg/proxy/cert"
 	"github.com/google/oss-rebuild/pkg/proxy/docker"
 	"github.com/google/oss-rebuild/pkg/proxy/policy"
 	"github.com/google/oss-rebuild/pkg/proxy/proxy"
+	"github.com/google/oss-rebuild/pkg/proxy/replay"
 )
 
 var (
 	verbose       = flag.Bool("verbose", true, "whether to output log events for each request")
 	httpProxyAddr = flag.String("http_addr", "localhost:3128", "address for HTTP proxy")
 	tlsProxyAddr  = flag.String("tls_addr", "localhost:3129", "address for TLS proxy")
+	enableHTTP2   = flag.Bool("http2", false, "whether to intercept the streams of TLS clients offering HTTP/2, such as gRPC clients, rather than serving them HTTP/1.1")
 	ctrlAddr      = flag.String("ctrl_addr", "localhost:3127", "address for administrative endpoint")
+	dnsAddr       = flag.String("dns_addr", "", "if provided, address for the DNS forwarder, served over both UDP and TCP")
+	dnsUpstream   = flag.String("dns_upstream", "8.8.8.8:53", "address of the resolver to which the DNS forwarder relays queries")
+	tcpAddr       = flag.String("tcp_addr", "", "if provided, address for the TCP connection logger to which other redirected IPv4 or IPv6 TCP traffic should be sent")
 	dockerAddr    = flag.String("docker_addr", "", "address for docker proxy endpoint in the format host:port or tcp://host:port for tcp, or unix:///file for unix domain sockets.")
 	// TODO: Add support for tcp sockets.
-	dockerSocket               = flag.String("docker_socket", "/var/run/docker.sock", "path to the docker socket")
+	dockerSocket               = flag.String("docker_socket", "", "path to the container engine's API socket. Defaults to the standard socket of -docker_runtime")
+	dockerRuntime              = flag.String("docker_runtime", "docker", "container engine whose API is proxied at -docker_addr. Options: docker, podman, containerd")
+	containerdStateDir         = flag.String("containerd_state_dir", "", "path to containerd's state directory, used to patch container filesystems when -docker_runtime=containerd. Defaults to /run/containerd")
+	containerdVolumeDir        = flag.String("containerd_volume_dir", "", "host directory in which volumes for containers are created when -docker_runtime=containerd. Defaults to the system temporary directory")
 	dockerNetwork              = flag.String("docker_network", "", "if provided, the docker network to use for all proxied containers")
 	dockerEnvVars              = flag.String("docker_env_vars", "", "comma-separated key-value pair env vars to patch into containers")
 	dockerTruststoreEnvVars    = flag.String("docker_truststore_env_vars", "", "comma-separated env vars to populate with the proxy cert and patch into containers")
+	dockerTruststores          = flag.String("docker_truststores", "", "comma-separated additional truststores to patch with the proxy cert in containers. Options: "+strings.Join(docker.TruststoreProviders(), ", "))
 	dockerJavaTruststoreEnvVar = flag.Bool("docker_java_truststore", false, "whether to patch containers with Java proxy cert truststore file and env var")
 	dockerBazelTruststore      = flag.Bool("docker_bazel_truststore", false, "whether to patch containers with global .bazelrc file pointing to the Java proxy cert truststore")
 	dockerProxySocket          = flag.Bool("docker_recursive_proxy", false, "whether to patch containers with a unix domain socket which proxies docker requests from created containers")
-	policyMode                 = flag.String("policy_mode", "disabled", "mode to run the proxy in. Options: disabled, enforce")
+	policyMode                 = flag.String("policy_mode", "disabled", "mode to run the proxy in. Options: disabled, enforce, audit")
 	policyFile                 = flag.String("policy_file", "", "path to a json file specifying the policy to apply to the proxy")
+	cacheMode                  = flag.String("cache_mode", "disabled", "mode in which to use the response cache. Options: disabled, record, replay")
+	cacheDir                   = flag.String("cache_dir", "", "path to the directory of the response cache used by -cache_mode")
 )
 
 func main() {
@@ -53,7 +65,7 @@ func main() {
 		log.Printf("Server starting up! - configured to listen on http interface %s and https interface %s", *httpProxyAddr, *tlsProxyAddr)
 	}
 	p := proxy.NewTransparentProxyServer(*verbose)
-	policy.RegisterRule("URLMatchRule", func() policy.Rule { return &policy.URLMatchRule{} })
+	policy.RegisterBuiltinRules()
 	var pl policy.Policy
 	if *policyFile != "" {
 		content, err := os.ReadFile(*policyFile)
@@ -65,33 +77,77 @@ func main() {
 			log.Fatalf("Error unmarshaling policy file content: %v", err)
 		}
 	}
+	var cache *replay.Cache
+	switch mode := replay.Mode(*cacheMode); {
+	case !mode.IsValid():
+		log.Fatalf("Invalid cache mode specified: %v", mode)
+	case mode != replay.DisabledMode && *cacheDir == "":
+		log.Fatalf("cache_dir must be provided with cache_mode %v", mode)
+	case mode != replay.DisabledMode:
+		if err := os.MkdirAll(*cacheDir, 0755); err != nil {
+			log.Fatalf("Error creating cache directory: %v", err)
+		}
+		cache = replay.NewCache(osfs.New(*cacheDir))
+	}
 	proxyService := proxy.NewTransparentProxyService(p, ca, proxy.PolicyMode(*policyMode), proxy.TransparentProxyServiceOpts{
 		Policy: &pl,
+		Cache:  cache,
+		HTTP2:  *enableHTTP2,
 	})
 	proxyService.Proxy.OnRequest().DoFunc(
 		func(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
 			return proxyService.ApplyNetworkPolicy(req, ctx)
 		})
+	// NOTE: Registered after the policy so blocked requests are neither recorded nor replayed.
+	// The response policy is applied by the service ahead of the cache.
+	switch replay.Mode(*cacheMode) {
+	case replay.RecordMode:
+		cache.Record(proxyService.Proxy)
+	case replay.ReplayMode:
+		cache.Replay(proxyService.Proxy)
+	}
 	// Administrative endpoint.
 	go proxyService.ServeAdmin(*ctrlAddr)
 	// Start proxy server endpoints.
 	go proxyService.ProxyTLS(*tlsProxyAddr)
 	go proxyService.ProxyHTTP(*httpProxyAddr)
+	if *dnsAddr != "" {
+		go proxyService.ProxyDNS(*dnsAddr, *dnsUpstream)
+	}
+	if *tcpAddr != "" {
+		go proxyService.ProxyTCP(*tcpAddr)
+	}
 	if len(*dockerAddr) > 0 {
-		var envVars, truststoreEnvVars []string
+		var envVars, truststoreEnvVars, truststores []string
 		if *dockerEnvVars != "" {
 			envVars = strings.Split(*dockerEnvVars, ",")
 		}
 		if *dockerTruststoreEnvVars != "" {
 			truststoreEnvVars = strings.Split(*dockerTruststoreEnvVars, ",")
 		}
+		if *dockerTruststores != "" {
+			truststores = strings.Split(*dockerTruststores, ",")
+		}
+		var runtime docker.Runtime
+		switch *dockerRuntime {
+		case "docker":
+			runtime = docker.DockerRuntime{}
+		case "podman":
+			runtime = docker.PodmanRuntime{}
+		case "containerd":
+			runtime = docker.ContainerdRuntime{StateDir: *containerdStateDir, VolumeDir: *containerdVolumeDir}
+		default:
+			log.Fatalf("Invalid docker runtime specified: %v", *dockerRuntime)
+		}
 		ctp, err := docker.NewContainerTruststorePatcher(*ca.Leaf, docker.ContainerTruststorePatcherOpts{
+			Runtime:              runtime,
 			EnvVars:              envVars,
 			TruststoreEnvVars:    truststoreEnvVars,
 			JavaTruststoreEnvVar: *dockerJavaTruststoreEnvVar,
 			BazelTruststore:      *dockerBazelTruststore,
 			RecursiveProxy:       *dockerProxySocket,
 			NetworkOverride:      *dockerNetwork,
+			Truststores:          truststores,
 		})
 		if err != nil {
 			log.Fatalf("creating docker patcher: %v", err)
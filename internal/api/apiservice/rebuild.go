@@ -23,6 +23,7 @@ import (
 	"github.com/google/oss-rebuild/internal/httpx"
 	"github.com/google/oss-rebuild/internal/verifier"
 	"github.com/google/oss-rebuild/pkg/archive"
+	"github.com/google/oss-rebuild/pkg/attestation"
 	"github.com/google/oss-rebuild/pkg/build"
 	buildgcb "github.com/google/oss-rebuild/pkg/build/gcb"
 	"github.com/google/oss-rebuild/pkg/builddef"
@@ -75,6 +76,7 @@ type RebuildPackageDeps struct {
 	HTTPClient                 httpx.BasicClient
 	FirestoreClient            *firestore.Client
 	Signer                     *dsse.EnvelopeSigner
+	Verifier                   *dsse.EnvelopeVerifier
 	GCBExecutor                *buildgcb.Executor
 	PrebuildConfig             rebuild.PrebuildConfig
 	ServiceRepo                rebuild.Location
@@ -86,9 +88,28 @@ type RebuildPackageDeps struct {
 	DebugStoreBuilder          func(ctx context.Context) (rebuild.AssetStore, error)
 	RemoteMetadataStoreBuilder func(ctx context.Context, uuid string) (rebuild.LocatableAssetStore, error)
 	OverwriteAttestations      bool
+	PublishFailures            bool
+	VSA                        *verifier.VSAConfig
+	TransparencyLog            verifier.TransparencyLog
+	DigestIndex                rebuild.DigestIndex
 	InferStub                  api.StubT[schema.InferenceRequest, schema.StrategyOneOf]
+	// RegistryConfig describes non-public registries from which to fetch packages.
+	RegistryConfig rebuild.RegistryConfig
 }
 
+// rebuildFailure is an error from a rebuild that was attempted and failed, as opposed to one that could not be attempted.
+type rebuildFailure struct {
+	reason attestation.FailureReason
+	// diff summarizes content differences for content mismatches.
+	diff *attestation.ContentDiffSummary
+	// up summarizes the upstream artifact, if it was fetched.
+	up  *verifier.ArtifactSummary
+	err error
+}
+
+func (f *rebuildFailure) Error() string { return f.err.Error() }
+func (f *rebuildFailure) Unwrap() error { return f.err }
+
 type repoEntry struct {
 	// BuildDefinition found in the build def repo.
 	schema.BuildDefinition
@@ -151,7 +172,10 @@ func getStrategy(ctx context.Context, deps *RebuildPackageDeps, t rebuild.Target
 	}
 	if strategy == nil {
 		s, err := deps.InferStub(ctx, ireq)
-		if err != nil {
+		if errors.Is(err, api.ErrNotOK) {
+			// The inference service responded but was unable to produce a strategy.
+			return nil, nil, &rebuildFailure{reason: attestation.FailureReasonInference, err: errors.Wrap(err, "fetching inference")}
+		} else if err != nil {
 			// TODO: Surface better error than Internal.
 			return nil, nil, errors.Wrap(err, "fetching inference")
 		}
@@ -239,7 +263,7 @@ func buildAndAttest(ctx context.Context, deps *RebuildPackageDeps, mux rebuild.R
 	if err != nil {
 		return errors.Wrap(err, "waiting for build")
 	} else if result.Error != nil {
-		return errors.Wrap(result.Error, "executing rebuild")
+		return &rebuildFailure{reason: attestation.FailureReasonBuild, err: errors.Wrap(result.Error, "executing rebuild")}
 	}
 	upstreamURI, err := rebuilder.UpstreamURL(ctx, t, mux)
 	if err != nil {
@@ -253,14 +277,26 @@ func buildAndAttest(ctx context.Context, deps *RebuildPackageDeps, mux rebuild.R
 	exactMatch := bytes.Equal(rb.Hash.Sum(nil), up.Hash.Sum(nil))
 	stabilizedMatch := bytes.Equal(rb.StabilizedHash.Sum(nil), up.StabilizedHash.Sum(nil))
 	if !exactMatch && !stabilizedMatch {
-		return api.AsStatus(codes.FailedPrecondition, errors.New("rebuild content mismatch"))
+		var diff *attestation.ContentDiffSummary
+		if deps.PublishFailures {
+			diff, err = verifier.SummarizeContentDiff(ctx, remoteMetadata, t, rb, up, stabilizers)
+			if err != nil {
+				log.Println(errors.Wrap(err, "summarizing content diff"))
+			}
+		}
+		return &rebuildFailure{
+			reason: attestation.FailureReasonContentMismatch,
+			diff:   diff,
+			up:     &up,
+			err:    api.AsStatus(codes.FailedPrecondition, errors.New("rebuild content mismatch")),
+		}
 	}
 	if u, err := url.Parse(deps.ServiceRepo.Repo); err != nil {
 		return errors.Wrap(err, "bad ServiceRepo URL")
 	} else if (u.Scheme == "file" || u.Scheme == "") && !deps.PublishForLocalServiceRepo {
 		return errors.New("disallowed file:// ServiceRepo URL")
 	}
-	eqStmt, buildStmt, err := verifier.CreateAttestations(ctx, t, buildDef, strategy, obID, rb, up, deps.LocalMetadataStore, deps.ServiceRepo, deps.PrebuildRepo, buildDefRepo, deps.PrebuildConfig)
+	eqStmt, buildStmt, err := verifier.CreateAttestations(ctx, t, mux.RegistryURL(t), buildDef, strategy, obID, rb, up, deps.LocalMetadataStore, deps.ServiceRepo, deps.PrebuildRepo, buildDefRepo, deps.PrebuildConfig)
 	if err != nil {
 		return errors.Wrap(err, "creating attestations")
 	}
@@ -270,13 +306,64 @@ func buildAndAttest(ctx context.Context, deps *RebuildPackageDeps, mux rebuild.R
 	return nil
 }
 
+// maybePublishFailure publishes an attestation for err if it describes an attempted rebuild.
+//
+// Publication is best-effort: errors are logged rather than affecting the verdict.
+func maybePublishFailure(ctx context.Context, deps *RebuildPackageDeps, mux rebuild.RegistryMux, a verifier.Attestor, t rebuild.Target, strategy rebuild.Strategy, entry *repoEntry, err error) {
+	var f *rebuildFailure
+	if !deps.PublishFailures || !errors.As(err, &f) {
+		return
+	}
+	if u, err := url.Parse(deps.ServiceRepo.Repo); err != nil || ((u.Scheme == "file" || u.Scheme == "") && !deps.PublishForLocalServiceRepo) {
+		log.Println("skipping failure publication for local ServiceRepo")
+		return
+	}
+	up := f.up
+	if up == nil {
+		rebuilder, ok := meta.AllRebuilders[t.Ecosystem]
+		if !ok {
+			return
+		}
+		upstreamURI, err := rebuilder.UpstreamURL(ctx, t, mux)
+		if err != nil {
+			log.Println(errors.Wrap(err, "getting upstream url"))
+			return
+		}
+		summary, err := verifier.SummarizeUpstream(ctx, upstreamURI, []crypto.Hash{crypto.SHA256})
+		if err != nil {
+			log.Println(errors.Wrap(err, "summarizing upstream"))
+			return
+		}
+		up = &summary
+	}
+	var buildDef *schema.BuildDefinition
+	var buildDefLoc rebuild.Location
+	if entry != nil {
+		buildDef = &entry.BuildDefinition
+		buildDefLoc = entry.BuildDefLoc
+	}
+	details := attestation.RebuildFailureDetails{Reason: f.reason, Message: f.Error(), ContentDiff: f.diff}
+	stmt, err := verifier.CreateFailureAttestation(t, mux.RegistryURL(t), buildDef, strategy, uuid.New().String(), *up, details, deps.ServiceRepo, deps.PrebuildRepo, buildDefLoc, deps.PrebuildConfig)
+	if err != nil {
+		log.Println(errors.Wrap(err, "creating failure attestation"))
+		return
+	}
+	if err := a.PublishFailure(ctx, t, stmt); err != nil {
+		log.Println(errors.Wrap(err, "publishing failure attestation"))
+	}
+}
+
 func rebuildPackage(ctx context.Context, req schema.RebuildPackageRequest, deps *RebuildPackageDeps) (*schema.Verdict, error) {
 	t := rebuild.Target{Ecosystem: req.Ecosystem, Package: req.Package, Version: req.Version, Artifact: req.Artifact}
 	if req.Ecosystem == rebuild.Debian && strings.TrimSpace(req.Artifact) == "" {
 		return nil, api.AsStatus(codes.InvalidArgument, errors.New("debian requires artifact"))
 	}
 	ctx = context.WithValue(ctx, rebuild.HTTPBasicClientID, deps.HTTPClient)
-	mux := meta.NewRegistryMux(httpx.NewCachedClient(deps.HTTPClient, &cache.CoalescingMemoryCache{}))
+	regclient := httpx.NewCachedClient(deps.HTTPClient, &cache.CoalescingMemoryCache{})
+	mux, err := meta.NewRegistryMux(regclient).WithConfig(regclient, deps.RegistryConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "configuring registries")
+	}
 	if err := populateArtifact(ctx, &t, mux); err != nil {
 		// If we fail to populate artifact, the verdict has an incomplete target, which might prevent the storage of the verdict.
 		// For this reason, we don't return a nil error and expect no verdict to be written.
@@ -286,19 +373,26 @@ func rebuildPackage(ctx context.Context, req schema.RebuildPackageRequest, deps
 		Target: t,
 	}
 	signer := verifier.InTotoEnvelopeSigner{EnvelopeSigner: deps.Signer}
-	a := verifier.Attestor{Store: deps.AttestationStore, Signer: signer, AllowOverwrite: deps.OverwriteAttestations}
+	a := verifier.Attestor{Store: deps.AttestationStore, Signer: signer, AllowOverwrite: deps.OverwriteAttestations, Verifier: deps.Verifier, VSA: deps.VSA, Log: deps.TransparencyLog, Index: deps.DigestIndex}
 	if !deps.OverwriteAttestations {
 		if exists, err := a.BundleExists(ctx, t); err != nil {
 			v.Message = errors.Wrap(err, "checking existing bundle").Error()
 			return &v, nil
 		} else if exists {
-			v.Message = api.AsStatus(codes.AlreadyExists, errors.New("conflict with existing attestation bundle")).Error()
-			return &v, nil
+			// A revoked bundle may be superseded.
+			if revoked, err := a.BundleRevoked(ctx, t); err != nil {
+				v.Message = errors.Wrap(err, "checking bundle revocation").Error()
+				return &v, nil
+			} else if !revoked {
+				v.Message = api.AsStatus(codes.AlreadyExists, errors.New("conflict with existing attestation bundle")).Error()
+				return &v, nil
+			}
 		}
 	}
 	strategy, entry, err := getStrategy(ctx, deps, t, req.UseRepoDefinition)
 	if err != nil {
 		v.Message = errors.Wrap(err, "getting strategy").Error()
+		maybePublishFailure(ctx, deps, mux, a, t, nil, nil, err)
 		return &v, nil
 	}
 	if strategy != nil {
@@ -307,6 +401,7 @@ func rebuildPackage(ctx context.Context, req schema.RebuildPackageRequest, deps
 	err = buildAndAttest(ctx, deps, mux, a, t, strategy, entry, req.UseNetworkProxy, req.UseSyscallMonitor)
 	if err != nil {
 		v.Message = errors.Wrap(err, "executing rebuild").Error()
+		maybePublishFailure(ctx, deps, mux, a, t, strategy, entry, err)
 		return &v, nil
 	}
 	return &v, nil
@@ -0,0 +1,47 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package apiservice
+
+import (
+	"context"
+
+	"github.com/google/oss-rebuild/internal/api"
+	"github.com/google/oss-rebuild/internal/verifier"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
+	"github.com/google/oss-rebuild/pkg/rebuild/schema"
+	"github.com/pkg/errors"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"google.golang.org/grpc/codes"
+)
+
+type RevokeBundleDeps struct {
+	Signer           *dsse.EnvelopeSigner
+	Verifier         *dsse.EnvelopeVerifier
+	AttestationStore rebuild.AssetStore
+	VSA              *verifier.VSAConfig
+	DigestIndex      rebuild.DigestIndex
+}
+
+func RevokeBundle(ctx context.Context, req schema.RevokeBundleRequest, deps *RevokeBundleDeps) (*schema.RevokeBundleResponse, error) {
+	t := rebuild.Target{Ecosystem: req.Ecosystem, Package: req.Package, Version: req.Version, Artifact: req.Artifact}
+	a := verifier.Attestor{Store: deps.AttestationStore, Signer: verifier.InTotoEnvelopeSigner{EnvelopeSigner: deps.Signer}, Verifier: deps.Verifier, VSA: deps.VSA, Index: deps.DigestIndex}
+	if exists, err := a.BundleExists(ctx, t); err != nil {
+		return nil, errors.Wrap(err, "checking existing bundle")
+	} else if !exists {
+		return nil, api.AsStatus(codes.NotFound, errors.New("no attestation bundle found"))
+	}
+	if revoked, err := a.BundleRevoked(ctx, t); err != nil {
+		return nil, errors.Wrap(err, "checking bundle revocation")
+	} else if revoked {
+		return nil, api.AsStatus(codes.AlreadyExists, errors.New("bundle already revoked"))
+	}
+	revocation, err := a.Revoke(ctx, t, req.Reason)
+	if err != nil {
+		return nil, errors.Wrap(err, "revoking bundle")
+	}
+	return &schema.RevokeBundleResponse{
+		BundleDigest: revocation.Subject[0].Digest["sha256"],
+		RevokedAt:    revocation.Predicate.RevokedAt,
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"log"
 
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
 	"github.com/google/oss-rebuild/internal/api"
 	"github.com/google/oss-rebuild/internal/api/cratesregistryservice"
 	"github.com/google/oss-rebuild/internal/gitx"
@@ -48,8 +49,11 @@ func doInfer(ctx context.Context, rebuilder rebuild.Rebuilder, t rebuild.Target,
 type InferDeps struct {
 	HTTPClient         httpx.BasicClient
 	GitCache           *gitx.Cache
+	CloneFilter        packp.Filter
 	RepoOptF           func() *gitx.RepositoryOptions
 	CratesRegistryStub api.StubT[cratesregistryservice.FindRegistryCommitRequest, cratesregistryservice.FindRegistryCommitResponse]
+	// RegistryConfig describes non-public registries from which to fetch packages.
+	RegistryConfig rebuild.RegistryConfig
 }
 
 func Infer(ctx context.Context, req schema.InferenceRequest, deps *InferDeps) (*schema.StrategyOneOf, error) {
@@ -69,11 +73,17 @@ func Infer(ctx context.Context, req schema.InferenceRequest, deps *InferDeps) (*
 	if deps.GitCache != nil {
 		ctx = context.WithValue(ctx, rebuild.RepoCacheClientID, *deps.GitCache)
 	}
+	if deps.CloneFilter != "" {
+		ctx = context.WithValue(ctx, rebuild.CloneFilterID, deps.CloneFilter)
+	}
 	ctx = context.WithValue(ctx, rebuild.HTTPBasicClientID, deps.HTTPClient)
 	if deps.CratesRegistryStub != nil {
 		ctx = context.WithValue(ctx, rebuild.CratesRegistryStubID, deps.CratesRegistryStub)
 	}
-	mux := meta.NewRegistryMux(deps.HTTPClient)
+	mux, err := meta.NewRegistryMux(deps.HTTPClient).WithConfig(deps.HTTPClient, deps.RegistryConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "configuring registries")
+	}
 	var s rebuild.Strategy
 	t := rebuild.Target{
 		Ecosystem: req.Ecosystem,
@@ -85,7 +95,7 @@ func Infer(ctx context.Context, req schema.InferenceRequest, deps *InferDeps) (*
 	if !ok {
 		return nil, api.AsStatus(codes.InvalidArgument, errors.New("unsupported ecosystem"))
 	}
-	s, err := doInfer(ctx, rebuilder, t, mux, req.LocationHint(), repoOpt)
+	s, err = doInfer(ctx, rebuilder, t, mux, req.LocationHint(), repoOpt)
 	if err != nil {
 		log.Printf("No inference for [pkg=%s, version=%v]: %v\n", req.Package, req.Version, err)
 		return nil, api.AsStatus(codes.Internal, errors.Wrap(err, "failed to infer strategy"))
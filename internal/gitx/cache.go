@@ -35,12 +35,12 @@ type Cache struct {
 	DefaultFreshness time.Time
 }
 
-// GetLink returns a GCS link to the cached repo resource.
+// GetLink returns a link to the cached repo resource.
 func (c Cache) GetLink(repo string, contains time.Time) (uri string, err error) {
 	return c.GetLinkWithRef(repo, contains, "")
 }
 
-// GetLinkWithRef returns a GCS link to the cached repo resource for a specific ref.
+// GetLinkWithRef returns a link to the cached repo resource for a specific ref.
 func (c Cache) GetLinkWithRef(repo string, contains time.Time, ref string) (uri string, err error) {
 	u, err := c.URL.Parse("/get")
 	if err != nil {
@@ -69,6 +69,10 @@ func (c Cache) GetLinkWithRef(repo string, contains time.Time, ref string) (uri
 	switch resp.StatusCode {
 	case http.StatusFound:
 		uri = resp.Header.Get("Location")
+		// Locations served by the cache service itself may be relative.
+		if loc, err := url.Parse(uri); err == nil && !loc.IsAbs() {
+			uri = u.ResolveReference(loc).String()
+		}
 		// FIXME: Figure out why this URL parsing artifact is being reintroduced.
 		return strings.ReplaceAll(uri, "%252F", "%2F"), nil
 	case http.StatusBadRequest:
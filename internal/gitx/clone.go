@@ -28,16 +28,22 @@ var (
 )
 
 // Reuse reuses the existing git repo in Storer and Filesystem.
+//
+// If the existing repo is a partial clone, omitted objects will be fetched on demand using opt.Auth.
 func Reuse(ctx context.Context, s storage.Storer, fs billy.Filesystem, opt *git.CloneOptions) (*git.Repository, error) {
-	if opt.Auth != nil || opt.RemoteName != "" || opt.ReferenceName != "" || opt.SingleBranch || opt.Depth != 0 || opt.Tags != git.InvalidTagMode || opt.InsecureSkipTLS || len(opt.CABundle) > 0 {
-		// No support for non-trivial opts aside from NoCheckout.
+	if opt.RemoteName != "" || opt.ReferenceName != "" || opt.SingleBranch || opt.Depth != 0 || opt.Tags != git.InvalidTagMode || opt.InsecureSkipTLS || len(opt.CABundle) > 0 {
+		// No support for non-trivial opts aside from Auth and NoCheckout.
 		return nil, errors.New("Unsupported opt")
 	}
 	u, err := uri.CanonicalizeRepoURI(opt.URL)
 	if err != nil {
 		return nil, err
 	}
-	repo, err := git.Open(s, fs)
+	ps, err := NewPromisorStorer(s, opt.Auth)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := git.Open(ps, fs)
 	if err != nil {
 		return nil, err
 	}
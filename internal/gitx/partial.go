@@ -0,0 +1,440 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package gitx
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/sideband"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/pkg/errors"
+)
+
+var (
+	// BlobNone is a partial clone filter omitting all blobs.
+	BlobNone = packp.FilterBlobNone()
+	// TreeNone is a partial clone filter omitting all trees and blobs.
+	TreeNone = packp.FilterTreeDepth(0)
+)
+
+const (
+	extensionsSection = "extensions"
+	partialCloneKey   = "partialclone"
+	remoteSection     = "remote"
+	promisorKey       = "promisor"
+	filterKey         = "partialclonefilter"
+)
+
+// PartialClone returns a CloneFunc performing a partial clone that omits the
+// objects matched by filter e.g. BlobNone.
+//
+// Omitted objects are fetched from the remote on demand when accessed through
+// the returned Repository. Use NewPromisorStorer to restore this behavior when
+// reopening the repository.
+//
+// If the remote does not support filtering, a full clone is performed.
+// Submodules are not cloned.
+func PartialClone(filter packp.Filter) CloneFunc {
+	return func(ctx context.Context, s storage.Storer, fs billy.Filesystem, opt *git.CloneOptions) (*git.Repository, error) {
+		return partialClone(ctx, s, fs, opt, filter)
+	}
+}
+
+func partialClone(ctx context.Context, s storage.Storer, fs billy.Filesystem, opt *git.CloneOptions, filter packp.Filter) (*git.Repository, error) {
+	if opt.RemoteName != "" || opt.Depth != 0 || opt.Tags == git.NoTags || opt.InsecureSkipTLS || len(opt.CABundle) > 0 {
+		// No support for non-trivial opts aside from Auth, NoCheckout, ReferenceName, and SingleBranch.
+		return nil, errors.New("Unsupported opt")
+	}
+	ep, err := transport.NewEndpoint(opt.URL)
+	if err != nil {
+		return nil, err
+	}
+	remote := promisorRemote{Endpoint: ep, Auth: opt.Auth}
+	sess, ar, err := remote.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Close()
+	if !ar.Capabilities.Supports(capability.Filter) {
+		log.Printf("Remote does not support filtering, performing full clone [repo=%s]\n", opt.URL)
+		return Clone(ctx, s, fs, opt)
+	}
+	refs, err := ar.AllReferences()
+	if err != nil {
+		return nil, err
+	}
+	// Resolve the ref to check out along with the refs to fetch.
+	head := plumbing.HEAD
+	if opt.ReferenceName != "" {
+		head = opt.ReferenceName
+	}
+	headRef, err := storer.ResolveReference(refs, head)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving %s", head)
+	}
+	var branch plumbing.ReferenceName
+	if opt.ReferenceName == "" || opt.ReferenceName == plumbing.HEAD {
+		if h, ok := refs[plumbing.HEAD]; ok && h.Type() == plumbing.SymbolicReference {
+			branch = h.Target()
+		}
+		if branch == "" {
+			// Without a symref, use a branch at the same commit as HEAD.
+			for _, name := range []plumbing.ReferenceName{plumbing.Main, plumbing.Master} {
+				if ref, ok := refs[name]; ok && ref.Hash() == headRef.Hash() {
+					branch = name
+					break
+				}
+			}
+		}
+	} else if opt.ReferenceName.IsBranch() {
+		branch = opt.ReferenceName
+	}
+	var wants []plumbing.Hash
+	seen := make(map[plumbing.Hash]bool)
+	for name, ref := range refs {
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+		if opt.SingleBranch && name != headRef.Name() {
+			continue
+		}
+		if !name.IsBranch() && !name.IsTag() && name != headRef.Name() {
+			continue
+		}
+		if !seen[ref.Hash()] {
+			seen[ref.Hash()] = true
+			wants = append(wants, ref.Hash())
+		}
+	}
+	r, err := git.Init(s, fs)
+	if err != nil {
+		return nil, err
+	}
+	if err := remote.fetch(ctx, sess, ar, s, wants, filter); err != nil {
+		return nil, errors.Wrap(err, "fetching packfile")
+	}
+	refspec := config.RefSpec("+refs/heads/*:refs/remotes/origin/*")
+	if opt.SingleBranch && branch != "" {
+		refspec = config.RefSpec("+" + branch.String() + ":" + plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch.Short()).String())
+	}
+	if _, err := r.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{opt.URL}, Fetch: []config.RefSpec{refspec}}); err != nil {
+		return nil, err
+	}
+	cfg, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Raw.Section(extensionsSection).SetOption(partialCloneKey, git.DefaultRemoteName)
+	cfg.Raw.Section(remoteSection).Subsection(git.DefaultRemoteName).SetOption(promisorKey, "true").SetOption(filterKey, string(filter))
+	if err := s.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	// Write the refs of a normal clone.
+	for name, ref := range refs {
+		if ref.Type() != plumbing.HashReference {
+			continue
+		}
+		var local plumbing.ReferenceName
+		switch {
+		case name.IsBranch() && (!opt.SingleBranch || name == branch):
+			local = plumbing.NewRemoteReferenceName(git.DefaultRemoteName, name.Short())
+		case name.IsTag() && !opt.SingleBranch:
+			local = name
+		default:
+			continue
+		}
+		if err := s.SetReference(plumbing.NewHashReference(local, ref.Hash())); err != nil {
+			return nil, err
+		}
+	}
+	if branch != "" {
+		if err := s.SetReference(plumbing.NewHashReference(branch, headRef.Hash())); err != nil {
+			return nil, err
+		}
+		if err := s.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+			return nil, err
+		}
+	} else if err := s.SetReference(plumbing.NewHashReference(plumbing.HEAD, headRef.Hash())); err != nil {
+		return nil, err
+	}
+	ps := &PromisorStorer{Storer: s, remote: remote, filter: filter}
+	r, err = git.Open(ps, fs)
+	if err != nil {
+		return nil, err
+	}
+	if !opt.NoCheckout && fs != nil {
+		c, err := r.CommitObject(headRef.Hash())
+		if err != nil {
+			return nil, errors.Wrap(err, "loading HEAD commit")
+		}
+		// Fetch all blobs in the checkout in a single request.
+		if err := ps.FetchTree(ctx, c, nil); err != nil {
+			return nil, errors.Wrap(err, "fetching checkout")
+		}
+		wt, err := r.Worktree()
+		if err != nil {
+			return nil, err
+		}
+		if err := wt.Reset(&git.ResetOptions{Commit: headRef.Hash(), Mode: git.HardReset}); err != nil {
+			return nil, errors.Wrap(err, "checkout error")
+		}
+	}
+	return r, nil
+}
+
+var _ CloneFunc = PartialClone(BlobNone)
+
+// promisorRemote fetches objects from a remote repository.
+type promisorRemote struct {
+	Endpoint *transport.Endpoint
+	Auth     transport.AuthMethod
+}
+
+func (p promisorRemote) open(ctx context.Context) (transport.UploadPackSession, *packp.AdvRefs, error) {
+	cli, err := client.NewClient(p.Endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	sess, err := cli.NewUploadPackSession(p.Endpoint, p.Auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	ar, err := sess.AdvertisedReferencesContext(ctx)
+	if err != nil {
+		sess.Close()
+		return nil, nil, err
+	}
+	return sess, ar, nil
+}
+
+// fetch requests the wanted objects and writes the resulting packfile to s.
+func (p promisorRemote) fetch(ctx context.Context, sess transport.UploadPackSession, ar *packp.AdvRefs, s storage.Storer, wants []plumbing.Hash, filter packp.Filter) error {
+	req := packp.NewUploadPackRequestFromCapabilities(ar.Capabilities)
+	req.Wants = wants
+	if filter != "" {
+		if err := req.Capabilities.Set(capability.Filter); err != nil {
+			return err
+		}
+		req.Filter = filter
+	}
+	reader, err := sess.UploadPack(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	var r io.Reader = reader
+	switch {
+	case req.Capabilities.Supports(capability.Sideband64k):
+		r = sideband.NewDemuxer(sideband.Sideband64k, reader)
+	case req.Capabilities.Supports(capability.Sideband):
+		r = sideband.NewDemuxer(sideband.Sideband, reader)
+	}
+	return packfile.UpdateObjectStorage(s, r)
+}
+
+// PromisorStorer is a Storer for a partial clone that fetches the objects
+// omitted from the clone when they are accessed.
+type PromisorStorer struct {
+	storage.Storer
+	remote promisorRemote
+	filter packp.Filter
+	mu     sync.Mutex
+	// missing records objects the remote did not return when requested.
+	missing map[plumbing.Hash]bool
+}
+
+// NewPromisorStorer returns a PromisorStorer for s if it contains a partial
+// clone. Otherwise, s is returned.
+//
+// The auth is used for on-demand fetches and should match that of the clone
+// as credentials are not persisted in the repository's config.
+func NewPromisorStorer(s storage.Storer, auth transport.AuthMethod) (storage.Storer, error) {
+	if _, ok := s.(*PromisorStorer); ok {
+		return s, nil
+	}
+	cfg, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.Raw.Section(extensionsSection).Option(partialCloneKey)
+	if name == "" {
+		return s, nil
+	}
+	rc, ok := cfg.Remotes[name]
+	if !ok || len(rc.URLs) == 0 {
+		return nil, errors.Errorf("promisor remote %s not found", name)
+	}
+	ep, err := transport.NewEndpoint(rc.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	filter := packp.Filter(cfg.Raw.Section(remoteSection).Subsection(name).Option(filterKey))
+	return &PromisorStorer{Storer: s, remote: promisorRemote{Endpoint: ep, Auth: auth}, filter: filter}, nil
+}
+
+// omits returns whether objects of type t may have been omitted from the clone.
+func (s *PromisorStorer) omits(t plumbing.ObjectType) bool {
+	switch t {
+	case plumbing.BlobObject, plumbing.AnyObject:
+		return true
+	case plumbing.TreeObject:
+		return strings.HasPrefix(string(s.filter), "tree:")
+	default:
+		return false
+	}
+}
+
+// EncodedObject returns the object from storage, fetching it if necessary.
+func (s *PromisorStorer) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, err := s.Storer.EncodedObject(t, h)
+	if err != plumbing.ErrObjectNotFound || !s.omits(t) {
+		return obj, err
+	}
+	if err := s.Fetch(context.Background(), []plumbing.Hash{h}); err != nil {
+		return nil, err
+	}
+	return s.Storer.EncodedObject(t, h)
+}
+
+// Fetch retrieves the given objects from the remote in a single request.
+//
+// Objects already present or that the remote previously did not return are
+// skipped. Failed requests are not recorded so they may be retried.
+func (s *PromisorStorer) Fetch(ctx context.Context, hashes []plumbing.Hash) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var wants []plumbing.Hash
+	for _, h := range hashes {
+		if s.missing[h] || s.Storer.HasEncodedObject(h) == nil {
+			continue
+		}
+		wants = append(wants, h)
+	}
+	if len(wants) == 0 {
+		return nil
+	}
+	sess, ar, err := s.remote.open(ctx)
+	if err == nil {
+		defer sess.Close()
+		err = s.remote.fetch(ctx, sess, ar, s.Storer, wants, "")
+	}
+	if err != nil {
+		return errors.Wrapf(err, "fetching %d objects", len(wants))
+	}
+	for _, h := range wants {
+		if s.Storer.HasEncodedObject(h) != nil {
+			if s.missing == nil {
+				s.missing = make(map[plumbing.Hash]bool)
+			}
+			s.missing[h] = true
+		}
+	}
+	return nil
+}
+
+// FetchTree retrieves the blobs in the commit's tree whose paths match the
+// predicate in a single request. A nil predicate matches all paths.
+func (s *PromisorStorer) FetchTree(ctx context.Context, c *object.Commit, match func(string) bool) error {
+	t, err := c.Tree()
+	if err != nil {
+		return err
+	}
+	var hashes []plumbing.Hash
+	tw := object.NewTreeWalker(t, true, nil)
+	defer tw.Close()
+	for {
+		name, e, err := tw.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if e.Mode == filemode.Dir || e.Mode == filemode.Submodule {
+			continue
+		}
+		if match == nil || match(name) {
+			hashes = append(hashes, e.Hash)
+		}
+	}
+	return s.Fetch(ctx, hashes)
+}
+
+// FetchPaths retrieves the blobs in the commit's tree whose paths match the
+// predicate if the repository is a partial clone.
+//
+// As with on-demand fetches, the request is made without a deadline.
+func FetchPaths(r *git.Repository, c *object.Commit, match func(string) bool) error {
+	if ps, ok := r.Storer.(*PromisorStorer); ok {
+		return ps.FetchTree(context.Background(), c, match)
+	}
+	return nil
+}
+
+// FetchBlobs retrieves the given blobs if the repository is a partial clone.
+func FetchBlobs(r *git.Repository, hashes []plumbing.Hash) error {
+	if ps, ok := r.Storer.(*PromisorStorer); ok {
+		return ps.Fetch(context.Background(), hashes)
+	}
+	return nil
+}
+
+// Files returns the files in the commit's tree whose paths match the predicate.
+//
+// Unlike object.Tree.Files, the contents of non-matching files are not loaded
+// so only the matching blobs are fetched from a partial clone's remote.
+func Files(r *git.Repository, c *object.Commit, match func(string) bool) ([]*object.File, error) {
+	t, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	var entries []object.TreeEntry
+	tw := object.NewTreeWalker(t, true, nil)
+	defer tw.Close()
+	for {
+		name, e, err := tw.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if e.Mode == filemode.Dir || e.Mode == filemode.Submodule || !match(name) {
+			continue
+		}
+		names = append(names, name)
+		entries = append(entries, e)
+	}
+	hashes := make([]plumbing.Hash, len(entries))
+	for i, e := range entries {
+		hashes[i] = e.Hash
+	}
+	if err := FetchBlobs(r, hashes); err != nil {
+		return nil, errors.Wrap(err, "fetching blobs")
+	}
+	files := make([]*object.File, len(entries))
+	for i, e := range entries {
+		b, err := r.BlobObject(e.Hash)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading %s", names[i])
+		}
+		files[i] = object.NewFile(names[i], e.Mode, b)
+	}
+	return files, nil
+}
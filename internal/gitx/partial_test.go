@@ -0,0 +1,162 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package gitx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// newUpstream creates an on-disk repo served with filter support.
+func newUpstream(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git-upload-pack"); err != nil {
+		t.Skip("git-upload-pack not available")
+	}
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Raw.Section("uploadpack").SetOption("allowFilter", "true").SetOption("allowAnySHA1InWant", "true")
+	if err := r.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := util.WriteFile(wt.Filesystem, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := wt.Commit("initial", &git.CommitOptions{Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPartialClone(t *testing.T) {
+	ctx := context.Background()
+	upstream := newUpstream(t, map[string]string{"a.txt": "a", "dir/b.txt": "b", "dir/c.txt": "c", "d.txt": "d"})
+	s := memory.NewStorage()
+	r, err := PartialClone(BlobNone)(ctx, s, nil, &git.CloneOptions{URL: upstream, NoCheckout: true})
+	if err != nil {
+		t.Fatalf("PartialClone() error = %v", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	c, err := r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("CommitObject() error = %v", err)
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	entry, err := tree.FindEntry("a.txt")
+	if err != nil {
+		t.Fatalf("FindEntry() error = %v", err)
+	}
+	if _, err := s.EncodedObject(plumbing.BlobObject, entry.Hash); err != plumbing.ErrObjectNotFound {
+		t.Fatalf("blob present after partial clone: %v", err)
+	}
+	// Batch fetch only the matching blobs.
+	if err := FetchPaths(r, c, func(p string) bool { return p == "dir/b.txt" }); err != nil {
+		t.Fatalf("FetchPaths() error = %v", err)
+	}
+	b, _ := tree.FindEntry("dir/b.txt")
+	if _, err := s.EncodedObject(plumbing.BlobObject, b.Hash); err != nil {
+		t.Errorf("blob missing after FetchPaths(): %v", err)
+	}
+	if _, err := s.EncodedObject(plumbing.BlobObject, entry.Hash); err != plumbing.ErrObjectNotFound {
+		t.Errorf("unrequested blob fetched: %v", err)
+	}
+	files, err := Files(r, c, func(p string) bool { return p == "dir/c.txt" })
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "dir/c.txt" {
+		t.Fatalf("Files() = %v, want [dir/c.txt]", files)
+	}
+	if _, err := s.EncodedObject(plumbing.BlobObject, entry.Hash); err != plumbing.ErrObjectNotFound {
+		t.Errorf("unrequested blob fetched by Files(): %v", err)
+	}
+	// Fetch on demand.
+	f, err := tree.File("a.txt")
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if content, err := f.Contents(); err != nil || content != "a" {
+		t.Errorf("Contents() = %q, %v; want %q", content, err, "a")
+	}
+	// Objects absent from the remote are reported with the fetch error.
+	if _, err := r.BlobObject(plumbing.NewHash("0123456789012345678901234567890123456789")); err == nil || err == plumbing.ErrObjectNotFound {
+		t.Errorf("BlobObject() for missing object error = %v, want fetch error", err)
+	}
+	// Reopening the storage restores on-demand fetching with the clone's auth.
+	auth := &githttp.BasicAuth{Username: "user", Password: "token"}
+	reopened, err := NewPromisorStorer(s, auth)
+	if err != nil {
+		t.Fatalf("NewPromisorStorer() error = %v", err)
+	}
+	ps, ok := reopened.(*PromisorStorer)
+	if !ok {
+		t.Fatalf("NewPromisorStorer() = %T, want *PromisorStorer", reopened)
+	}
+	if ps.remote.Auth != auth {
+		t.Errorf("NewPromisorStorer() auth = %v, want %v", ps.remote.Auth, auth)
+	}
+	ps.remote.Auth = nil
+	// A failed fetch is returned and retried on the next access.
+	ep := ps.remote.Endpoint
+	ps.remote.Endpoint, err = transport.NewEndpoint(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := tree.FindEntry("d.txt")
+	if err != nil {
+		t.Fatalf("FindEntry() error = %v", err)
+	}
+	if _, err := ps.EncodedObject(plumbing.BlobObject, d.Hash); err == nil {
+		t.Fatal("EncodedObject() from unavailable remote succeeded")
+	}
+	ps.remote.Endpoint = ep
+	if _, err := ps.EncodedObject(plumbing.BlobObject, d.Hash); err != nil {
+		t.Errorf("EncodedObject() after failed fetch error = %v", err)
+	}
+}
+
+func TestPartialCloneCheckout(t *testing.T) {
+	upstream := newUpstream(t, map[string]string{"a.txt": "a", "dir/b.txt": "b"})
+	fs := memfs.New()
+	if _, err := PartialClone(BlobNone)(context.Background(), memory.NewStorage(), fs, &git.CloneOptions{URL: upstream}); err != nil {
+		t.Fatalf("PartialClone() error = %v", err)
+	}
+	content, err := util.ReadFile(fs, "dir/b.txt")
+	if err != nil || string(content) != "b" {
+		t.Errorf("ReadFile() = %q, %v; want %q", content, err, "b")
+	}
+}
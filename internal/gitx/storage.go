@@ -4,7 +4,12 @@
 package gitx
 
 import (
+	"io/fs"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
 )
 
 // Storer augments go-git's Storer to provide the capability to re-initialize the underlying state.
@@ -24,3 +29,42 @@ func NewStorer(init func() storage.Storer) *Storer {
 func (s *Storer) Reset() {
 	s.Storer = s.cbk()
 }
+
+// StorageSize returns the approximate number of bytes held by the Storer.
+//
+// Filesystem storage is measured on disk while other storage is measured as
+// the total size of its uncompressed objects.
+func StorageSize(s storage.Storer) (int64, error) {
+	for unwrapped := false; !unwrapped; {
+		switch st := s.(type) {
+		case *Storer:
+			s = st.Storer
+		case *PromisorStorer:
+			s = st.Storer
+		default:
+			unwrapped = true
+		}
+	}
+	var size int64
+	if fss, ok := s.(*filesystem.Storage); ok {
+		err := util.Walk(fss.Filesystem(), "/", func(_ string, fi fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() {
+				size += fi.Size()
+			}
+			return nil
+		})
+		return size, err
+	}
+	iter, err := s.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return 0, err
+	}
+	err = iter.ForEach(func(o plumbing.EncodedObject) error {
+		size += o.Size()
+		return nil
+	})
+	return size, err
+}
@@ -34,6 +34,26 @@ func (c *WithUserAgent) Do(req *http.Request) (*http.Response, error) {
 	return c.BasicClient.Do(req)
 }
 
+// WithAuthorization is a basic HTTP client that adds an Authorization header to requests to Host.
+//
+// Restricting the header to a single host prevents credentials from being
+// sent to other hosts, like artifact CDNs, to which the registry redirects.
+type WithAuthorization struct {
+	BasicClient
+	Host          string
+	Authorization string
+}
+
+var _ BasicClient = &WithAuthorization{}
+
+// Do adds the Authorization header if the request is to Host and sends the request.
+func (c *WithAuthorization) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == c.Host {
+		req.Header.Set("Authorization", c.Authorization)
+	}
+	return c.BasicClient.Do(req)
+}
+
 // CachedClient is a BasicClient that caches responses.
 type CachedClient struct {
 	BasicClient
@@ -199,3 +199,36 @@ func TestCachedClient(t *testing.T) {
 	}
 
 }
+
+type clientFunc func(*http.Request) (*http.Response, error)
+
+func (f clientFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestWithAuthorization(t *testing.T) {
+	for _, tc := range []struct {
+		url  string
+		want string
+	}{
+		{"https://registry.example.com/pkg", "Bearer token"},
+		{"https://cdn.example.com/pkg.tgz", ""},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			var got string
+			c := &WithAuthorization{
+				BasicClient: clientFunc(func(req *http.Request) (*http.Response, error) {
+					got = req.Header.Get("Authorization")
+					return &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body("")}, nil
+				}),
+				Host:          "registry.example.com",
+				Authorization: "Bearer token",
+			}
+			req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+			if _, err := c.Do(req); err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Authorization = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ package netclassify
 
 import (
 	"fmt"
+	"net/url"
+	"path"
 	"regexp"
 	"strings"
 
@@ -51,6 +53,9 @@ var (
 var (
 	cratesAPIRegex  = regexp.MustCompile(`^https?://crates\.io/api/v1/crates/([^/]+)/([^/]+)(?:/\w+)?$`)
 	cratesFileRegex = regexp.MustCompile(`^https?://crates\.io/api/v1/crates/([^/]+)/([^/]+)/download$`)
+	// static.crates.io serves both the legacy download path and the canonical file path.
+	cratesStaticRegex = regexp.MustCompile(`^https://static\.crates\.io/crates/(?P<name>[^/]+)/(?:(?P<version>[^/]+)/download|(?P<file>[^/]+)\.crate)$`)
+	cratesIndexRegex  = regexp.MustCompile(`^https://index\.crates\.io/`)
 )
 
 // GCS
@@ -77,6 +82,39 @@ var (
 	gitObjectRegex      = regexp.MustCompile(`^/objects/[a-f0-9]{2}/(?P<digeststub>[a-f0-9]{38}|[a-f0-9]{62})$`)
 )
 
+// Debian
+var (
+	debianHosts = `^https?://(?:deb|security|ftp\.[a-z]+|snapshot)\.debian\.org/(?:archive/[^/]+/[^/]+/|debian(?:-security)?/)`
+	// https://www.debian.org/doc/debian-policy/ch-controlfields.html
+	debianPoolRegex   = regexp.MustCompile(debianHosts + `pool/(?:updates/)?[^/]+/[^/]+/(?P<source>[^/]+)/(?P<file>[^/]+)$`)
+	debianDistsRegex  = regexp.MustCompile(debianHosts + `dists/`)
+	debianBinaryRegex = regexp.MustCompile(`^(?P<package>[^_]+)_(?P<version>[^_]+)_(?P<arch>[^_]+)\.u?deb$`)
+	debianSourceRegex = regexp.MustCompile(`^(?P<package>[^_]+)_(?P<version>[^_]+?)(?:\.orig(?:-[^.]+)?\.tar\.\w+|\.debian\.tar\.\w+|\.diff\.gz|\.tar\.\w+|\.dsc)$`)
+)
+
+// Toolchains
+var (
+	gradleDistRegex    = regexp.MustCompile(`^https://(?:services|downloads)\.gradle(?:-dn)?\.org/distributions(?:-snapshots)?/(?P<file>gradle-(?P<version>[^/]+?)-(?:bin|all|src|docs)\.zip)$`)
+	gradleAPIRegex     = regexp.MustCompile(`^https://(?:services|downloads)\.gradle(?:-dn)?\.org/(?:versions/|distributions(?:-snapshots)?/[^/]+\.sha256$)`)
+	nodeDistRegex      = regexp.MustCompile(`^https://nodejs\.org/dist/v(?P<version>[^/]+)/(?P<file>[^/]+\.(?:tar\.gz|tar\.xz|zip|msi|pkg|7z))$`)
+	nodeAPIRegex       = regexp.MustCompile(`^https://nodejs\.org/dist/`)
+	rustDistRegex      = regexp.MustCompile(`^https://static\.rust-lang\.org/dist/(?:\d{4}-\d{2}-\d{2}/)?(?P<file>(?P<component>[a-z][a-z0-9_]*(?:-[a-z][a-z0-9_]*)*)-(?P<version>\d+\.\d+\.\d+|beta|nightly)(?:-[^/]+?)?\.tar\.(?:gz|xz))$`)
+	rustupRegex        = regexp.MustCompile(`^https://static\.rust-lang\.org/rustup/(?:archive/(?P<version>[^/]+)|dist)/[^/]+/(?P<file>rustup-init(?:\.exe)?)$`)
+	rustAPIRegex       = regexp.MustCompile(`^https://static\.rust-lang\.org/(?:dist|rustup)/`)
+	pythonDistRegex    = regexp.MustCompile(`^https://www\.python\.org/ftp/python/(?P<version>\d+\.\d+\.\d+)/(?P<file>[^/]+)$`)
+	pythonSigRegex     = regexp.MustCompile(`\.(?:asc|sig|sigstore|crt|sha256)$`)
+	githubReleaseRegex = regexp.MustCompile(`^https://github\.com/(?P<repo>[^/]+/[^/]+)/releases/download/(?P<ref>[^/]+)/(?P<file>[^/]+)$`)
+	githubArchiveRegex = regexp.MustCompile(`^https://github\.com/(?P<repo>[^/]+/[^/]+)/archive/(?:refs/(?:tags|heads)/)?(?P<ref>.+)\.(?:tar\.gz|zip)$`)
+	codeloadRegex      = regexp.MustCompile(`^https://codeload\.github\.com/(?P<repo>[^/]+/[^/]+)/(?:legacy\.)?(?:tar\.gz|zip)/(?:refs/(?:tags|heads)/)?(?P<ref>.+)$`)
+)
+
+// Go
+var (
+	goProxyHosts      = `^https://(?:proxy\.golang\.org|goproxy\.io|goproxy\.cn)/`
+	goModuleFileRegex = regexp.MustCompile(goProxyHosts + `(?P<module>.+)/@v/(?P<version>[^/]+)\.zip$`)
+	goAPIRegex        = regexp.MustCompile(`^https://(?:proxy\.golang\.org|goproxy\.io|goproxy\.cn|sum\.golang\.org)/`)
+)
+
 // misc
 var (
 	dockerAPITokenURL = "https://auth.docker.io/token"
@@ -89,35 +127,101 @@ var (
 	ErrBadPyWheel   = errors.New("bad python wheel format")
 )
 
-func ClassifyURL(rawURL string) (string, error) {
+// Classification identifies the artifact fetched from a URL.
+type Classification struct {
+	// Ecosystem is the pURL type of the artifact, e.g. npm or maven.
+	Ecosystem string
+	// Name is the name of the package including any pURL namespace, e.g. org.apache.commons/commons-lang3.
+	Name string
+	// Version is the version of the package, if known.
+	Version string
+	// Artifact is the file name of the fetched artifact, if known.
+	Artifact string
+	// PURL is the package URL identifying the artifact.
+	PURL string
+}
+
+func newClassification(ecosystem, name, version, artifact string, qualifiers url.Values) Classification {
+	purl := fmt.Sprintf("pkg:%s/%s", ecosystem, name)
+	if version != "" {
+		purl += "@" + version
+	}
+	if len(qualifiers) > 0 {
+		purl += "?" + qualifiers.Encode()
+	}
+	return Classification{Ecosystem: ecosystem, Name: name, Version: version, Artifact: artifact, PURL: purl}
+}
+
+// ClassifyURL identifies the package artifact fetched from rawURL.
+// Returns ErrSkipped for known URLs that do not serve package artifacts, such
+// as registry metadata, and ErrUnclassified for unknown URLs.
+func ClassifyURL(rawURL string) (Classification, error) {
 	if pat := matchOCIRegistry(rawURL); pat != nil {
 		return classifyOCIRegistryURL(rawURL, pat)
+	} else if githubReleaseRegex.MatchString(rawURL) {
+		return classifyGitHubRefURL(rawURL, githubReleaseRegex)
+	} else if githubArchiveRegex.MatchString(rawURL) {
+		return classifyGitHubRefURL(rawURL, githubArchiveRegex)
+	} else if codeloadRegex.MatchString(rawURL) {
+		return classifyGitHubRefURL(rawURL, codeloadRegex)
 	} else if pat := matchGitHost(rawURL); pat != nil {
 		return classifyGitURL(rawURL, pat)
 	} else if alpineRegex.MatchString(rawURL) {
 		return classifyAlpineURL(rawURL)
+	} else if debianPoolRegex.MatchString(rawURL) {
+		return classifyDebianURL(rawURL)
+	} else if debianDistsRegex.MatchString(rawURL) {
+		return Classification{}, ErrSkipped
 	} else if pypiFileRegex.MatchString(rawURL) {
 		return classifyPyPIURL(rawURL)
 	} else if pypiAPIRegex.MatchString(rawURL) {
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	} else if npmFileRegex.MatchString(rawURL) {
 		return classifyNPMURL(rawURL)
 	} else if npmAPIRegex.MatchString(rawURL) {
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	} else if cratesFileRegex.MatchString(rawURL) {
 		return classifyCratesURL(rawURL)
 	} else if cratesAPIRegex.MatchString(rawURL) {
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
+	} else if cratesStaticRegex.MatchString(rawURL) {
+		return classifyCratesStaticURL(rawURL)
+	} else if cratesIndexRegex.MatchString(rawURL) {
+		return Classification{}, ErrSkipped
 	} else if mavenRegex.MatchString(rawURL) {
 		return classifyMavenURL(rawURL)
+	} else if goModuleFileRegex.MatchString(rawURL) {
+		return classifyGoModuleURL(rawURL)
+	} else if goAPIRegex.MatchString(rawURL) {
+		return Classification{}, ErrSkipped
+	} else if gradleDistRegex.MatchString(rawURL) {
+		return classifyToolchainURL(rawURL, gradleDistRegex, "gradle/gradle")
+	} else if gradleAPIRegex.MatchString(rawURL) {
+		return Classification{}, ErrSkipped
+	} else if nodeDistRegex.MatchString(rawURL) {
+		return classifyToolchainURL(rawURL, nodeDistRegex, "nodejs/node")
+	} else if nodeAPIRegex.MatchString(rawURL) {
+		return Classification{}, ErrSkipped
+	} else if rustDistRegex.MatchString(rawURL) {
+		matches := rustDistRegex.FindStringSubmatch(rawURL)
+		return classifyToolchainURL(rawURL, rustDistRegex, "rust-lang/"+matches[rustDistRegex.SubexpIndex("component")])
+	} else if rustupRegex.MatchString(rawURL) {
+		return classifyToolchainURL(rawURL, rustupRegex, "rust-lang/rustup")
+	} else if rustAPIRegex.MatchString(rawURL) {
+		return Classification{}, ErrSkipped
+	} else if pythonDistRegex.MatchString(rawURL) {
+		if pythonSigRegex.MatchString(rawURL) {
+			return Classification{}, ErrSkipped
+		}
+		return classifyToolchainURL(rawURL, pythonDistRegex, "python/python")
 	} else if gcsJSONRegex.MatchString(rawURL) {
 		return classifyGCSURL(rawURL, gcsJSONRegex)
 	} else if gcsXMLRegex.MatchString(rawURL) {
 		return classifyGCSURL(rawURL, gcsXMLRegex)
 	} else if rawURL == dockerAPITokenURL {
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	} else {
-		return "", ErrUnclassified
+		return Classification{}, ErrUnclassified
 	}
 }
 
@@ -130,23 +234,23 @@ func matchOCIRegistry(url string) *regexp.Regexp {
 	return nil
 }
 
-func classifyOCIRegistryURL(rawURL string, registry *regexp.Regexp) (string, error) {
+func classifyOCIRegistryURL(rawURL string, registry *regexp.Regexp) (Classification, error) {
 	loc := registry.FindStringIndex(rawURL)
 	if loc == nil {
-		return "", errors.New("invalid registry pattern")
+		return Classification{}, errors.New("invalid registry pattern")
 	}
 	part := rawURL[loc[1]-1:]
 	switch {
 	case ociManifestRegex.MatchString(part):
 		matches := ociManifestRegex.FindStringSubmatch(part)
 		image, tag := matches[1], matches[2]
-		return fmt.Sprintf("pkg:docker/%s@%s", image, tag), nil
+		return newClassification("docker", image, tag, "", nil), nil
 	case ociBlobRegex.MatchString(part):
 		matches := ociBlobRegex.FindStringSubmatch(part)
 		image, digest := matches[1], matches[2]
-		return fmt.Sprintf("pkg:docker-blob/%s@%s", image, digest), nil
+		return newClassification("docker-blob", image, digest, "", nil), nil
 	default:
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	}
 }
 
@@ -159,21 +263,21 @@ func matchGitHost(url string) *regexp.Regexp {
 	return nil
 }
 
-func classifyGitURL(rawURL string, host *regexp.Regexp) (string, error) {
+func classifyGitURL(rawURL string, host *regexp.Regexp) (Classification, error) {
 	loc := host.FindStringIndex(rawURL)
 	if loc == nil {
-		return "", errors.New("invalid git host pattern")
+		return Classification{}, errors.New("invalid git host pattern")
 	}
 	part := rawURL[loc[1]:]
 	switch {
 	case gitRefsRegex.MatchString(part):
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	case gitReceivePackRegex.MatchString(part):
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	case gitHeadRegex.MatchString(part):
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	case gitObjectInfoRegex.MatchString(part):
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	case gitUploadPackRegex.MatchString(part):
 		fallthrough
 	case gitObjectRegex.MatchString(part):
@@ -182,90 +286,173 @@ func classifyGitURL(rawURL string, host *regexp.Regexp) (string, error) {
 		matches := host.FindStringSubmatch(rawURL)
 		repo := matches[host.SubexpIndex("repo")]
 		// TODO: Change from github when other supported hosts.
-		return fmt.Sprintf("pkg:github/%s", repo), nil
+		return newClassification("github", repo, "", "", nil), nil
 	default:
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
+	}
+}
+
+// classifyGitHubRefURL classifies release assets and source archives fetched for a GitHub ref.
+func classifyGitHubRefURL(rawURL string, pattern *regexp.Regexp) (Classification, error) {
+	matches := pattern.FindStringSubmatch(rawURL)
+	repo, ref := matches[pattern.SubexpIndex("repo")], matches[pattern.SubexpIndex("ref")]
+	var artifact string
+	if i := pattern.SubexpIndex("file"); i >= 0 {
+		artifact = matches[i]
+	} else if pattern != codeloadRegex {
+		artifact = path.Base(rawURL)
 	}
+	return newClassification("github", repo, ref, artifact, nil), nil
 }
 
-func classifyAlpineURL(rawURL string) (string, error) {
+func classifyAlpineURL(rawURL string) (Classification, error) {
 	matches := alpineRegex.FindStringSubmatch(rawURL)
 	if matches == nil {
-		return "", fmt.Errorf("invalid Alpine URL format")
+		return Classification{}, fmt.Errorf("invalid Alpine URL format")
 	}
-	return fmt.Sprintf("pkg:alpine/%s@%s", matches[alpineRegex.SubexpIndex("package")], matches[alpineRegex.SubexpIndex("version")]), nil
+	return newClassification("alpine", matches[alpineRegex.SubexpIndex("package")], matches[alpineRegex.SubexpIndex("version")], path.Base(rawURL), nil), nil
 }
 
-func classifyPyPIURL(rawURL string) (string, error) {
+func classifyDebianURL(rawURL string) (Classification, error) {
+	matches := debianPoolRegex.FindStringSubmatch(rawURL)
+	file := matches[debianPoolRegex.SubexpIndex("file")]
+	var pkg, version, arch string
+	if m := debianBinaryRegex.FindStringSubmatch(file); m != nil {
+		pkg, version, arch = m[debianBinaryRegex.SubexpIndex("package")], m[debianBinaryRegex.SubexpIndex("version")], m[debianBinaryRegex.SubexpIndex("arch")]
+	} else if m := debianSourceRegex.FindStringSubmatch(file); m != nil {
+		pkg, version, arch = m[debianSourceRegex.SubexpIndex("package")], m[debianSourceRegex.SubexpIndex("version")], "source"
+	} else {
+		return Classification{}, ErrUnclassified
+	}
+	// NOTE: Clients may percent-encode characters such as '+' in file names.
+	if v, err := url.PathUnescape(version); err == nil {
+		version = v
+	}
+	return newClassification("deb", "debian/"+pkg, version, file, url.Values{"arch": {arch}}), nil
+}
+
+func classifyPyPIURL(rawURL string) (Classification, error) {
 	matches := pypiFileRegex.FindStringSubmatch(rawURL)
 	if matches == nil {
-		return "", fmt.Errorf("invalid PyPI URL format")
+		return Classification{}, fmt.Errorf("invalid PyPI URL format")
 	}
 	return classifyPyPIFile(matches[pypiFileRegex.SubexpIndex("file")])
 }
 
-func classifyPyPIFile(fname string) (string, error) {
+func classifyPyPIFile(fname string) (Classification, error) {
 	switch {
 	case strings.HasSuffix(fname, ".metadata"):
-		return "", ErrSkipped
+		return Classification{}, ErrSkipped
 	case strings.HasSuffix(fname, ".egg"):
-		return "", ErrUnclassified // TODO: Revisit support if this is observed to be sufficiently common.
+		return Classification{}, ErrUnclassified // TODO: Revisit support if this is observed to be sufficiently common.
 	case strings.HasSuffix(fname, ".whl"):
 		matches := pythonWheelRegex.FindStringSubmatch(fname)
 		if matches == nil {
-			return "", ErrBadPyWheel
+			return Classification{}, ErrBadPyWheel
 		}
 		// NOTE: Case and hyphens may differ from PyPI.
-		// TODO: Add file name to pURL.
-		return fmt.Sprintf("pkg:pypi/%s@%s", matches[pythonWheelRegex.SubexpIndex("package")], matches[pythonWheelRegex.SubexpIndex("version")]), nil
+		return newClassification("pypi", matches[pythonWheelRegex.SubexpIndex("package")], matches[pythonWheelRegex.SubexpIndex("version")], fname, nil), nil
 	default:
 		matches := pythonSourceRegex.FindStringSubmatch(fname)
 		if matches == nil {
-			return "", ErrBadPySource
+			return Classification{}, ErrBadPySource
 		}
 		// NOTE: Case and hyphens may differ from PyPI.
-		// TODO: Add file name to pURL.
-		return fmt.Sprintf("pkg:pypi/%s@%s", matches[pythonSourceRegex.SubexpIndex("package")], matches[pythonSourceRegex.SubexpIndex("version")]), nil
+		return newClassification("pypi", matches[pythonSourceRegex.SubexpIndex("package")], matches[pythonSourceRegex.SubexpIndex("version")], fname, nil), nil
 	}
 }
 
-func classifyNPMURL(rawURL string) (string, error) {
+func classifyNPMURL(rawURL string) (Classification, error) {
 	matches := npmFileRegex.FindStringSubmatch(rawURL)
 	if len(matches) < 5 {
-		return "", errors.New("invalid NPM download URL format")
+		return Classification{}, errors.New("invalid NPM download URL format")
 	}
 	packagePath := matches[2]
 	version := matches[5]
-	return fmt.Sprintf("pkg:npm/%s@%s", packagePath, version), nil
+	return newClassification("npm", packagePath, version, path.Base(rawURL), nil), nil
 }
 
-func classifyCratesURL(rawURL string) (string, error) {
+func classifyCratesURL(rawURL string) (Classification, error) {
 	matches := cratesFileRegex.FindStringSubmatch(rawURL)
 	if len(matches) < 3 {
-		return "", errors.New("invalid Cargo URL format")
+		return Classification{}, errors.New("invalid Cargo URL format")
 	}
 	name := matches[1]
 	version := matches[2]
-	return fmt.Sprintf("pkg:cargo/%s@%s", name, version), nil
+	return newClassification("cargo", name, version, fmt.Sprintf("%s-%s.crate", name, version), nil), nil
 }
 
-func classifyMavenURL(rawURL string) (string, error) {
+func classifyCratesStaticURL(rawURL string) (Classification, error) {
+	matches := cratesStaticRegex.FindStringSubmatch(rawURL)
+	name := matches[cratesStaticRegex.SubexpIndex("name")]
+	version := matches[cratesStaticRegex.SubexpIndex("version")]
+	if file := matches[cratesStaticRegex.SubexpIndex("file")]; file != "" {
+		var ok bool
+		if version, ok = strings.CutPrefix(file, name+"-"); !ok {
+			return Classification{}, errors.New("invalid Cargo URL format")
+		}
+	}
+	return newClassification("cargo", name, version, fmt.Sprintf("%s-%s.crate", name, version), nil), nil
+}
+
+func classifyMavenURL(rawURL string) (Classification, error) {
 	matches := mavenRegex.FindStringSubmatch(rawURL)
 	if len(matches) < 6 {
-		return "", errors.New("invalid Maven URL format")
+		return Classification{}, errors.New("invalid Maven URL format")
 	}
 	pathSegments := strings.Split(matches[2], "/")
 	if len(pathSegments) < 2 {
-		return "", errors.New("invalid Maven path format")
+		return Classification{}, errors.New("invalid Maven path format")
 	}
 	name := matches[3]
 	version := matches[4]
 	namespace := strings.Join(pathSegments, ".")
-	return fmt.Sprintf("pkg:maven/%s/%s@%s", namespace, name, version), nil
+	return newClassification("maven", namespace+"/"+name, version, matches[5], nil), nil
+}
+
+func classifyGoModuleURL(rawURL string) (Classification, error) {
+	matches := goModuleFileRegex.FindStringSubmatch(rawURL)
+	module, err := unescapeGoModulePath(matches[goModuleFileRegex.SubexpIndex("module")])
+	if err != nil {
+		return Classification{}, err
+	}
+	version, err := unescapeGoModulePath(matches[goModuleFileRegex.SubexpIndex("version")])
+	if err != nil {
+		return Classification{}, err
+	}
+	return newClassification("golang", module, version, path.Base(rawURL), nil), nil
+}
+
+// unescapeGoModulePath reverses the module proxy's case encoding, in which
+// each uppercase letter is replaced by '!' followed by its lowercase form.
+func unescapeGoModulePath(escaped string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(escaped); i++ {
+		c := escaped[i]
+		switch {
+		case c == '!':
+			i++
+			if i == len(escaped) || escaped[i] < 'a' || escaped[i] > 'z' {
+				return "", errors.Errorf("invalid escaped module path: %s", escaped)
+			}
+			b.WriteByte(escaped[i] - 'a' + 'A')
+		case c >= 'A' && c <= 'Z':
+			return "", errors.Errorf("invalid escaped module path: %s", escaped)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// classifyToolchainURL classifies a toolchain distribution as a generic pURL with the given name.
+func classifyToolchainURL(rawURL string, pattern *regexp.Regexp, name string) (Classification, error) {
+	matches := pattern.FindStringSubmatch(rawURL)
+	return newClassification("generic", name, matches[pattern.SubexpIndex("version")], matches[pattern.SubexpIndex("file")], nil), nil
 }
 
-func classifyGCSURL(rawURL string, pattern *regexp.Regexp) (string, error) {
+func classifyGCSURL(rawURL string, pattern *regexp.Regexp) (Classification, error) {
 	matches := pattern.FindStringSubmatch(rawURL)
 	bucket, object := matches[pattern.SubexpIndex("bucket")], matches[pattern.SubexpIndex("object")]
-	return fmt.Sprintf("pkg:generic/gcs/%s/%s", bucket, object), nil
+	return newClassification("generic", fmt.Sprintf("gcs/%s/%s", bucket, object), "", path.Base(object), nil), nil
 }
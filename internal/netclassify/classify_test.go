@@ -162,12 +162,155 @@ func TestClassifyURL(t *testing.T) {
 			url:     "https://crates.io/api/v1/crates/rand/0.7.2",
 			wantErr: ErrSkipped,
 		},
+		{
+			name: "crates_static_download",
+			url:  "https://static.crates.io/crates/rand/rand-0.7.2.crate",
+			want: "pkg:cargo/rand@0.7.2",
+		},
+		{
+			name: "crates_static_legacy_download",
+			url:  "https://static.crates.io/crates/rand/0.7.2/download",
+			want: "pkg:cargo/rand@0.7.2",
+		},
+		{
+			name:    "crates_sparse_index",
+			url:     "https://index.crates.io/ra/nd/rand",
+			wantErr: ErrSkipped,
+		},
 		{
 			name:    "crates_api_deps",
 			url:     "https://crates.io/api/v1/crates/rand/0.7.2/dependencies",
 			wantErr: ErrSkipped,
 		},
 
+		// Debian test cases
+		{
+			name: "debian_binary",
+			url:  "https://deb.debian.org/debian/pool/main/g/git/git_2.39.2-1.1_amd64.deb",
+			want: "pkg:deb/debian/git@2.39.2-1.1?arch=amd64",
+		},
+		{
+			name: "debian_binary_tilde",
+			url:  "https://deb.debian.org/debian/pool/main/o/openssl/libssl3_3.0.11-1~deb12u2_amd64.deb",
+			want: "pkg:deb/debian/libssl3@3.0.11-1~deb12u2?arch=amd64",
+		},
+		{
+			name: "debian_binary_encoded",
+			url:  "https://snapshot.debian.org/archive/debian/20240101T000000Z/pool/main/p/perl/perl-base_5.36.0-7%2Bdeb12u1_amd64.deb",
+			want: "pkg:deb/debian/perl-base@5.36.0-7+deb12u1?arch=amd64",
+		},
+		{
+			name: "debian_security_binary",
+			url:  "https://security.debian.org/debian-security/pool/updates/main/c/curl/curl_7.88.1-10+deb12u5_arm64.deb",
+			want: "pkg:deb/debian/curl@7.88.1-10+deb12u5?arch=arm64",
+		},
+		{
+			name: "debian_source",
+			url:  "https://deb.debian.org/debian/pool/main/g/git/git_2.39.2.orig.tar.xz",
+			want: "pkg:deb/debian/git@2.39.2?arch=source",
+		},
+		{
+			name:    "debian_index",
+			url:     "https://deb.debian.org/debian/dists/bookworm/main/binary-amd64/Packages.xz",
+			wantErr: ErrSkipped,
+		},
+
+		// Go test cases
+		{
+			name: "go_module_zip",
+			url:  "https://proxy.golang.org/github.com/!burnt!sushi/toml/@v/v1.3.2.zip",
+			want: "pkg:golang/github.com/BurntSushi/toml@v1.3.2",
+		},
+		{
+			name:    "go_module_info",
+			url:     "https://proxy.golang.org/github.com/!burnt!sushi/toml/@v/v1.3.2.info",
+			wantErr: ErrSkipped,
+		},
+		{
+			name:    "go_sumdb",
+			url:     "https://sum.golang.org/lookup/github.com/!burnt!sushi/toml@v1.3.2",
+			wantErr: ErrSkipped,
+		},
+
+		// Toolchain test cases
+		{
+			name: "gradle_distribution",
+			url:  "https://services.gradle.org/distributions/gradle-8.5-bin.zip",
+			want: "pkg:generic/gradle/gradle@8.5",
+		},
+		{
+			name: "gradle_rc_distribution",
+			url:  "https://downloads.gradle.org/distributions/gradle-8.6-rc-1-all.zip",
+			want: "pkg:generic/gradle/gradle@8.6-rc-1",
+		},
+		{
+			name:    "gradle_checksum",
+			url:     "https://services.gradle.org/distributions/gradle-8.5-bin.zip.sha256",
+			wantErr: ErrSkipped,
+		},
+		{
+			name: "node_distribution",
+			url:  "https://nodejs.org/dist/v20.11.0/node-v20.11.0-linux-x64.tar.xz",
+			want: "pkg:generic/nodejs/node@20.11.0",
+		},
+		{
+			name:    "node_index",
+			url:     "https://nodejs.org/dist/index.json",
+			wantErr: ErrSkipped,
+		},
+		{
+			name: "rust_component",
+			url:  "https://static.rust-lang.org/dist/2023-12-28/rust-std-1.75.0-x86_64-unknown-linux-gnu.tar.xz",
+			want: "pkg:generic/rust-lang/rust-std@1.75.0",
+		},
+		{
+			name: "rust_targetless_component",
+			url:  "https://static.rust-lang.org/dist/rust-src-nightly.tar.gz",
+			want: "pkg:generic/rust-lang/rust-src@nightly",
+		},
+		{
+			name: "rustup_init",
+			url:  "https://static.rust-lang.org/rustup/archive/1.26.0/x86_64-unknown-linux-gnu/rustup-init",
+			want: "pkg:generic/rust-lang/rustup@1.26.0",
+		},
+		{
+			name:    "rust_channel_manifest",
+			url:     "https://static.rust-lang.org/dist/channel-rust-stable.toml",
+			wantErr: ErrSkipped,
+		},
+		{
+			name: "python_source",
+			url:  "https://www.python.org/ftp/python/3.12.1/Python-3.12.1.tgz",
+			want: "pkg:generic/python/python@3.12.1",
+		},
+		{
+			name:    "python_signature",
+			url:     "https://www.python.org/ftp/python/3.12.1/Python-3.12.1.tgz.asc",
+			wantErr: ErrSkipped,
+		},
+
+		// GitHub test cases
+		{
+			name: "github_release_asset",
+			url:  "https://github.com/protocolbuffers/protobuf/releases/download/v25.1/protoc-25.1-linux-x86_64.zip",
+			want: "pkg:github/protocolbuffers/protobuf@v25.1",
+		},
+		{
+			name: "github_archive",
+			url:  "https://github.com/abseil/abseil-py/archive/refs/tags/v2.1.0.tar.gz",
+			want: "pkg:github/abseil/abseil-py@v2.1.0",
+		},
+		{
+			name: "codeload_tarball",
+			url:  "https://codeload.github.com/abseil/abseil-py/tar.gz/refs/tags/v2.1.0",
+			want: "pkg:github/abseil/abseil-py@v2.1.0",
+		},
+		{
+			name: "codeload_commit",
+			url:  "https://codeload.github.com/abseil/abseil-py/legacy.tar.gz/0123456789abcdef0123456789abcdef01234567",
+			want: "pkg:github/abseil/abseil-py@0123456789abcdef0123456789abcdef01234567",
+		},
+
 		// gcs URL tests
 		{
 			name: "valid GCS URL",
@@ -206,8 +349,47 @@ func TestClassifyURL(t *testing.T) {
 				}
 			} else if err != nil {
 				t.Errorf("ClassifyURL() unexpected error = %v", err)
-			} else if got != tt.want {
-				t.Errorf("ClassifyURL() = %v, want %v", got, tt.want)
+			} else if got.PURL != tt.want {
+				t.Errorf("ClassifyURL() = %v, want %v", got.PURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyURLFields(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Classification
+	}{
+		{
+			url:  "https://repo1.maven.org/maven2/org/apache/commons/commons-lang3/3.12.0/commons-lang3-3.12.0.jar",
+			want: Classification{Ecosystem: "maven", Name: "org.apache.commons/commons-lang3", Version: "3.12.0", Artifact: "commons-lang3-3.12.0.jar", PURL: "pkg:maven/org.apache.commons/commons-lang3@3.12.0"},
+		},
+		{
+			url:  "https://registry.npmjs.org/@invisionag/eslint-config-ivx/-/eslint-config-ivx-0.0.2.tgz",
+			want: Classification{Ecosystem: "npm", Name: "@invisionag/eslint-config-ivx", Version: "0.0.2", Artifact: "eslint-config-ivx-0.0.2.tgz", PURL: "pkg:npm/@invisionag/eslint-config-ivx@0.0.2"},
+		},
+		{
+			url:  "https://deb.debian.org/debian/pool/main/g/git/git_2.39.2-1.1_amd64.deb",
+			want: Classification{Ecosystem: "deb", Name: "debian/git", Version: "2.39.2-1.1", Artifact: "git_2.39.2-1.1_amd64.deb", PURL: "pkg:deb/debian/git@2.39.2-1.1?arch=amd64"},
+		},
+		{
+			url:  "https://github.com/protocolbuffers/protobuf/releases/download/v25.1/protoc-25.1-linux-x86_64.zip",
+			want: Classification{Ecosystem: "github", Name: "protocolbuffers/protobuf", Version: "v25.1", Artifact: "protoc-25.1-linux-x86_64.zip", PURL: "pkg:github/protocolbuffers/protobuf@v25.1"},
+		},
+		{
+			url:  "https://github.com/foo/bar/git-upload-pack",
+			want: Classification{Ecosystem: "github", Name: "foo/bar", PURL: "pkg:github/foo/bar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ClassifyURL(tt.url)
+			if err != nil {
+				t.Fatalf("ClassifyURL() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ClassifyURL() = %+v, want %+v", got, tt.want)
 			}
 		})
 	}
@@ -263,8 +445,8 @@ func TestClassifyPyPIFile(t *testing.T) {
 				}
 			} else if err != nil {
 				t.Errorf("classifyPyPIFile() unexpected error = %v", err)
-			} else if got != tt.want {
-				t.Errorf("classifyPyPIFile() = %v, want %v", got, tt.want)
+			} else if got.PURL != tt.want {
+				t.Errorf("classifyPyPIFile() = %v, want %v", got.PURL, tt.want)
 			}
 		})
 	}
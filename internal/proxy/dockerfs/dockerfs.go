@@ -27,6 +27,16 @@ const (
 	statHeader     = "X-Docker-Container-Path-Stat"
 )
 
+// FS provides access to the files of a container.
+type FS interface {
+	Open(path string) (*File, error)
+	Stat(path string) (*FileInfo, error)
+	OpenAndResolve(path string) (*File, error)
+	WriteFile(f *File) error
+}
+
+var _ FS = Filesystem{}
+
 // Filesystem exposes files in a Docker container via a FS-compatible API.
 // NOTE: Client accesses will not use Scheme or Host URI elements.
 type Filesystem struct {
@@ -118,14 +128,19 @@ func (c Filesystem) Stat(path string) (*FileInfo, error) {
 // OpenAndResolve returns the file from the given container, resolving any symlinks encountered.
 func (c Filesystem) OpenAndResolve(path string) (*File, error) {
 	log.Printf("OpenAndResolve for path: %s", path)
+	return openAndResolve(c, path)
+}
+
+// openAndResolve opens path from fsys, resolving any symlinks encountered.
+func openAndResolve(fsys FS, path string) (*File, error) {
 	// NOTE: 255 is the repetition threshold used by filepath.EvalSymlinks.
 	for range 255 {
-		fi, err := c.Stat(path)
+		fi, err := fsys.Stat(path)
 		if err != nil {
 			return nil, err
 		}
 		if fi.Mode()&fs.ModeSymlink == 0 {
-			return c.Open(path)
+			return fsys.Open(path)
 		}
 		linkPath := fi.LinkTarget
 		if !filepath.IsAbs(linkPath) {
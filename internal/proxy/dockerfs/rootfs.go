@@ -0,0 +1,117 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package dockerfs
+
+import (
+	"archive/tar"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/pkg/errors"
+)
+
+// RootFS exposes files in a container whose root filesystem is mounted on the host.
+// NOTE: Symlinks are resolved relative to Root so paths never escape the container.
+type RootFS struct {
+	// Root is the host path of the container's root filesystem.
+	Root string
+	// Mounts maps container paths to the host directories mounted over them.
+	Mounts map[string]string
+}
+
+var _ FS = RootFS{}
+
+// mountedPath returns the host directory on which the given container path
+// resides along with the path relative to that directory.
+func (r RootFS) mountedPath(path string) (root, rel string, err error) {
+	if !filepath.IsAbs(path) {
+		return "", "", fs.ErrInvalid
+	}
+	path = filepath.Clean(path)
+	root, rel = r.Root, path
+	var mount string
+	for dst, src := range r.Mounts {
+		dst = filepath.Clean(dst)
+		if (path == dst || strings.HasPrefix(path, dst+"/")) && len(dst) > len(mount) {
+			mount, root, rel = dst, src, "/"+strings.TrimPrefix(path, dst)
+		}
+	}
+	return root, rel, nil
+}
+
+// hostPath returns the host path of the given container path.
+// Symlinks are resolved in all but the final path element.
+func (r RootFS) hostPath(path string) (string, error) {
+	root, rel, err := r.mountedPath(path)
+	if err != nil {
+		return "", err
+	}
+	dir, err := securejoin.SecureJoin(root, filepath.Dir(rel))
+	if err != nil {
+		return "", errors.Wrap(err, "resolving path")
+	}
+	return filepath.Join(dir, filepath.Base(rel)), nil
+}
+
+// Open returns a File from the container.
+func (r RootFS) Open(path string) (*File, error) {
+	log.Printf("Open for path: %s", path)
+	hp, err := r.hostPath(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Lstat(hp)
+	if err != nil {
+		return nil, err
+	}
+	var link string
+	var contents []byte
+	switch {
+	case fi.Mode()&fs.ModeSymlink != 0:
+		if link, err = os.Readlink(hp); err != nil {
+			return nil, errors.Wrap(err, "reading link")
+		}
+	case fi.Mode().IsRegular():
+		if contents, err = os.ReadFile(hp); err != nil {
+			return nil, errors.Wrap(err, "reading file")
+		}
+	default:
+		return nil, fs.ErrInvalid // NOTE: dirs are unsupported.
+	}
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return nil, errors.Wrap(err, "constructing file header")
+	}
+	return &File{path, *hdr, contents}, nil
+}
+
+// Stat returns the FileInfo of a file from the container.
+func (r RootFS) Stat(path string) (*FileInfo, error) {
+	log.Printf("Stat for path: %s", path)
+	hp, err := r.hostPath(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Lstat(hp)
+	if err != nil {
+		return nil, err
+	}
+	var link string
+	if fi.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(hp); err != nil {
+			return nil, errors.Wrap(err, "reading link")
+		}
+	}
+	return &FileInfo{name: fi.Name(), size: fi.Size(), mode: fi.Mode(), modTime: fi.ModTime(), LinkTarget: link}, nil
+}
+
+// OpenAndResolve returns the file from the container, resolving any symlinks encountered.
+func (r RootFS) OpenAndResolve(path string) (*File, error) {
+	log.Printf("OpenAndResolve for path: %s", path)
+	return openAndResolve(r, path)
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package dockerfs
+
+import (
+	"archive/tar"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// WriteFile writes the contents of the file to the container.
+//
+// NOTE: The container may have run untrusted code so every path element is
+// resolved within the container and a symlink at the final element is
+// replaced, for links, or refused, for regular files, rather than followed.
+func (r RootFS) WriteFile(f *File) error {
+	log.Printf("WriteFile for file: %s", f.Path)
+	root, rel, err := r.mountedPath(f.Path)
+	if err != nil {
+		return err
+	}
+	dir, err := securejoin.OpenInRoot(root, filepath.Dir(rel))
+	if err != nil {
+		return errors.Wrap(err, "resolving path")
+	}
+	defer dir.Close()
+	dirfd, name := int(dir.Fd()), filepath.Base(rel)
+	switch f.Metadata.Typeflag {
+	case tar.TypeReg:
+		mode := f.Metadata.FileInfo().Mode().Perm()
+		// NOTE: O_NONBLOCK avoids hanging on a FIFO which is then refused below.
+		fd, err := unix.Openat(dirfd, name, unix.O_WRONLY|unix.O_CREAT|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, uint32(mode))
+		if errors.Is(err, unix.ELOOP) {
+			return errors.Errorf("refusing to write through symlink: %s", f.Path)
+		} else if err != nil {
+			return errors.Wrap(&fs.PathError{Op: "open", Path: f.Path, Err: err}, "writing file")
+		}
+		file := os.NewFile(uintptr(fd), f.Path)
+		defer file.Close()
+		if fi, err := file.Stat(); err != nil {
+			return errors.Wrap(err, "writing file")
+		} else if !fi.Mode().IsRegular() {
+			return errors.Errorf("refusing to write non-regular file: %s", f.Path)
+		}
+		if err := file.Truncate(0); err != nil {
+			return errors.Wrap(err, "writing file")
+		}
+		if _, err := file.Write(f.Contents); err != nil {
+			return errors.Wrap(err, "writing file")
+		}
+		// NOTE: The creation mode only applies to newly-created files.
+		return errors.Wrap(file.Chmod(mode), "setting file mode")
+	case tar.TypeSymlink:
+		if err := unix.Unlinkat(dirfd, name, 0); err != nil && !errors.Is(err, unix.ENOENT) {
+			return errors.Wrap(err, "replacing link")
+		}
+		return errors.Wrap(unix.Symlinkat(f.Metadata.Linkname, dirfd, name), "writing link")
+	default:
+		return errors.Errorf("unsupported file type: %c", f.Metadata.Typeflag)
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package dockerfs
+
+import (
+	"github.com/pkg/errors"
+)
+
+// WriteFile writes the contents of the file to the container.
+func (r RootFS) WriteFile(f *File) error {
+	return errors.New("writing to a container root filesystem is only supported on linux")
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package dockerfs
+
+import (
+	"archive/tar"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRootFS(t *testing.T) {
+	root := t.TempDir()
+	vol := t.TempDir()
+	wantContents := "NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.16.0\n"
+	must1(os.MkdirAll(filepath.Join(root, "etc"), 0755))
+	must1(os.MkdirAll(filepath.Join(root, "usr/lib"), 0755))
+	must1(os.WriteFile(filepath.Join(root, "usr/lib/os-release"), []byte(wantContents), 0644))
+	must1(os.Symlink("../usr/lib/os-release", filepath.Join(root, "etc/os-release")))
+	// An absolute link must be resolved within the root, not on the host.
+	must1(os.Symlink("/usr/lib", filepath.Join(root, "lib")))
+	f := RootFS{Root: root, Mounts: map[string]string{"/var/cache": vol}}
+
+	fi := must(f.Stat("/etc/os-release"))
+	if fi.Mode()&fs.ModeSymlink == 0 || fi.LinkTarget != "../usr/lib/os-release" {
+		t.Fatalf("Unexpected Stat result: got=%+v", *fi)
+	}
+	got := must(f.OpenAndResolve("/etc/os-release"))
+	if string(got.Contents) != wantContents {
+		t.Fatalf("Unexpected OpenAndResolve contents: want=%s got=%s", wantContents, string(got.Contents))
+	}
+	if got.Path != "/usr/lib/os-release" {
+		t.Fatalf("Unexpected OpenAndResolve path: want=%s got=%s", "/usr/lib/os-release", got.Path)
+	}
+	got = must(f.Open("/lib/os-release"))
+	if string(got.Contents) != wantContents {
+		t.Fatalf("Unexpected Open contents through link: want=%s got=%s", wantContents, string(got.Contents))
+	}
+	if _, err := f.Open("/missing"); !os.IsNotExist(err) {
+		t.Fatalf("Unexpected Open error for missing file: want=%v got=%v", fs.ErrNotExist, err)
+	}
+	if _, err := f.Open("/etc"); err != fs.ErrInvalid {
+		t.Fatalf("Unexpected Open error for directory: want=%v got=%v", fs.ErrInvalid, err)
+	}
+
+	got.Contents = []byte(wantContents + "EXTRA=PROPERTY\n")
+	must1(f.WriteFile(got))
+	if b := must(os.ReadFile(filepath.Join(root, "usr/lib/os-release"))); string(b) != string(got.Contents) {
+		t.Fatalf("Unexpected WriteFile contents: want=%s got=%s", got.Contents, b)
+	}
+
+	// Writes beneath a mount are made to the mounted directory.
+	got.Path = "/var/cache/proxy.crt"
+	must1(f.WriteFile(got))
+	if _, err := os.Stat(filepath.Join(vol, "proxy.crt")); err != nil {
+		t.Fatalf("Unexpected Stat error for mounted file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "var/cache/proxy.crt")); !os.IsNotExist(err) {
+		t.Fatalf("Unexpected Stat result for shadowed file: want=%v got=%v", fs.ErrNotExist, err)
+	}
+}
+
+func TestRootFSWriteFileDoesNotFollowLinks(t *testing.T) {
+	root := t.TempDir()
+	host := filepath.Join(t.TempDir(), "host-file")
+	must1(os.WriteFile(host, []byte("HOST\n"), 0600))
+	must1(os.MkdirAll(filepath.Join(root, "etc/ssl/certs"), 0755))
+	// A link planted by the container at a patched path must not redirect writes to the host.
+	must1(os.Symlink(host, filepath.Join(root, "etc/ssl/certs/ca-certificates.crt")))
+	must1(os.Symlink(host, filepath.Join(root, "etc/ssl/cert.pem")))
+	f := RootFS{Root: root}
+
+	file := &File{Path: "/etc/ssl/certs/ca-certificates.crt", Metadata: tar.Header{Typeflag: tar.TypeReg, Mode: 0644}, Contents: []byte("PATCHED\n")}
+	if err := f.WriteFile(file); err == nil {
+		t.Fatal("WriteFile through symlink succeeded, want error")
+	}
+	if b := must(os.ReadFile(host)); string(b) != "HOST\n" {
+		t.Fatalf("Host file modified: got=%q", b)
+	}
+	if fi := must(os.Stat(host)); fi.Mode().Perm() != 0600 {
+		t.Fatalf("Host file mode modified: got=%v", fi.Mode().Perm())
+	}
+
+	// Links are replaced rather than followed.
+	link := &File{Path: "/etc/ssl/cert.pem", Metadata: tar.Header{Typeflag: tar.TypeSymlink, Linkname: "certs/ca-certificates.crt"}}
+	must1(f.WriteFile(link))
+	if target := must(os.Readlink(filepath.Join(root, "etc/ssl/cert.pem"))); target != "certs/ca-certificates.crt" {
+		t.Fatalf("Unexpected link target: got=%s", target)
+	}
+	if b := must(os.ReadFile(host)); string(b) != "HOST\n" {
+		t.Fatalf("Host file modified: got=%q", b)
+	}
+}
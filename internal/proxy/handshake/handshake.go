@@ -56,6 +56,7 @@ const (
 	typeClientHello uint8 = 1
 	// TLS extension numbers.
 	extensionServerName uint16 = 0
+	extensionALPN       uint16 = 16
 	// TLS record types.
 	recordTypeAlert     uint8 = 21
 	recordTypeHandshake uint8 = 22
@@ -70,6 +71,7 @@ type ClientHelloMsg struct {
 	CipherSuites       []uint16
 	CompressionMethods []uint8
 	ServerName         string
+	ALPNProtocols      []string
 }
 
 type peekedConn struct {
@@ -203,6 +205,19 @@ func newClientHelloMsg(data []byte) *ClientHelloMsg {
 					return nil
 				}
 			}
+		case extensionALPN:
+			// RFC 7301, Section 3.1
+			var protoList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&protoList) || protoList.Empty() {
+				return nil
+			}
+			for !protoList.Empty() {
+				var proto cryptobyte.String
+				if !protoList.ReadUint8LengthPrefixed(&proto) || proto.Empty() {
+					return nil
+				}
+				m.ALPNProtocols = append(m.ALPNProtocols, string(proto))
+			}
 		default:
 			// Ignore all other extensions.
 			continue
@@ -145,3 +145,23 @@ func TestPeekClientHello_NotHttp(t *testing.T) {
 		t.Fatalf("Expected handshake error, got %v", err)
 	}
 }
+
+func TestPeekClientHello_ALPN(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	go func() {
+		defer client.Close()
+		// The handshake cannot complete since the server only peeks.
+		tls.Client(client, &tls.Config{ServerName: exampleHost, NextProtos: []string{"h2", "http/1.1"}}).Handshake()
+	}()
+	_, h, err := PeekClientHello(server)
+	if err != nil {
+		t.Fatalf("PeekClientHello failed: %v", err)
+	}
+	if h.ServerName != exampleHost {
+		t.Errorf("Bad ClientHello metadata: got %s, expected %s", h.ServerName, exampleHost)
+	}
+	if want := []string{"h2", "http/1.1"}; strings.Join(h.ALPNProtocols, ",") != strings.Join(want, ",") {
+		t.Errorf("Bad ClientHello ALPN protocols: got %v, expected %v", h.ALPNProtocols, want)
+	}
+}
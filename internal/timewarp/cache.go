@@ -0,0 +1,357 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package timewarp
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/oss-rebuild/internal/httpx"
+	"github.com/pkg/errors"
+)
+
+// ErrCacheMiss is returned by a Cache that has no response for a key.
+var ErrCacheMiss = errors.New("cache miss")
+
+// mutableWindow is the period before the present during which the registry
+// state at an instant may still change, e.g. due to publication delays.
+const mutableWindow = 24 * time.Hour
+
+// CachedResponse is an HTTP response stored in a Cache.
+type CachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Cache stores HTTP responses by key.
+type Cache interface {
+	// Get returns the response stored for key or ErrCacheMiss if there is none.
+	Get(key string) (*CachedResponse, error)
+	// Put stores the response for key.
+	Put(key string, resp *CachedResponse) error
+}
+
+// defaultMemoryCacheBytes is the bound on the bodies held by a MemoryCache without MaxBytes.
+const defaultMemoryCacheBytes = 256 << 20
+
+// MemoryCache is a Cache held in memory.
+//
+// Once the bodies held exceed MaxBytes, the least recently used responses are evicted.
+type MemoryCache struct {
+	// MaxBytes bounds the total size of the bodies held. If zero, defaultMemoryCacheBytes is used.
+	MaxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     list.List // of *memoryCacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+var _ Cache = &MemoryCache{}
+
+// Get returns the response stored for key.
+func (c *MemoryCache) Get(key string) (*CachedResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).resp, nil
+}
+
+// Put stores the response for key, evicting others as required to remain within MaxBytes.
+func (c *MemoryCache) Put(key string, resp *CachedResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	limit := c.MaxBytes
+	if limit == 0 {
+		limit = defaultMemoryCacheBytes
+	}
+	if int64(len(resp.Body)) > limit {
+		return nil
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+	}
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	c.size += int64(len(resp.Body))
+	for c.size > limit {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+func (c *MemoryCache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*memoryCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.resp.Body))
+}
+
+// FSCache is a Cache stored in a directory.
+//
+// Each response is stored as a JSON file named by the SHA-256 digest of its key.
+type FSCache struct {
+	Dir string
+}
+
+var _ Cache = &FSCache{}
+
+type fsCacheEntry struct {
+	Key string `json:"key"`
+	CachedResponse
+}
+
+func (c *FSCache) path(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(digest[:])
+	return filepath.Join(c.Dir, name[:2], name+".json")
+}
+
+// Get returns the response stored for key.
+func (c *FSCache) Get(key string) (*CachedResponse, error) {
+	b, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheMiss
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading entry")
+	}
+	var entry fsCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, errors.Wrap(err, "parsing entry")
+	}
+	if entry.Key != key {
+		return nil, errors.Errorf("entry key mismatch: %q", entry.Key)
+	}
+	return &entry.CachedResponse, nil
+}
+
+// Put stores the response for key.
+func (c *FSCache) Put(key string, resp *CachedResponse) error {
+	b, err := json.Marshal(fsCacheEntry{Key: key, CachedResponse: *resp})
+	if err != nil {
+		return errors.Wrap(err, "serializing entry")
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "creating directory")
+	}
+	// NOTE: Write to a temporary file and rename so readers never observe a partial entry.
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "creating entry")
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return errors.Wrap(err, "writing entry")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "closing entry")
+	}
+	return errors.Wrap(os.Rename(f.Name(), path), "renaming entry")
+}
+
+// responseCacheKey returns the key under which the response to r may be
+// cached and whether it is cacheable.
+//
+// Responses are keyed by the platform, instant, path, and the headers that
+// select their content. They are only cacheable once immutable: Registry
+// states at an instant within mutableWindow of the present may still change
+// while those pinned to an index commit, like the cargo index archive, never do.
+//
+// The upload-pack requests of cargogit are also cacheable since the pack
+// served is determined by the request body, whose digest is added to the key.
+// The body is read and replaced on r to do so.
+func responseCacheKey(r *http.Request) (string, bool) {
+	platform, moment, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	var bodyDigest string
+	switch {
+	case r.Method == http.MethodGet:
+	case r.Method == http.MethodPost && platform == "cargogit" && strings.HasSuffix(r.URL.Path, "/"+gitUploadPackService):
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxCachedRequestSize+1))
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		if err != nil || len(body) > maxCachedRequestSize {
+			return "", false
+		}
+		digest := sha256.Sum256(body)
+		bodyDigest = hex.EncodeToString(digest[:])
+	default:
+		return "", false
+	}
+	switch platform {
+	case "cargogit", "cargogitarchive", "cargosparse":
+		if !commitHashRegex.MatchString(moment) {
+			return "", false
+		}
+	default:
+		if unescaped, err := url.QueryUnescape(moment); err == nil {
+			moment = unescaped
+		}
+		t, err := parseTime(moment)
+		if err != nil || time.Since(*t) < mutableWindow {
+			return "", false
+		}
+	}
+	return strings.Join([]string{platform, moment, r.Method, r.URL.RequestURI(), r.Header.Get("Accept"), r.Header.Get("X-Package-Names"), r.Header.Get("Content-Encoding"), bodyDigest}, "\n"), true
+}
+
+// maxCachedRequestSize is the size above which request bodies are not read to key the cache.
+const maxCachedRequestSize = 64 << 10
+
+// maxCachedBodySize is the size above which responses, like artifact tarballs, are not cached.
+const maxCachedBodySize = 8 << 20
+
+// responseRecorder is an http.ResponseWriter that forwards the response while
+// buffering it to be cached, provided the body is within maxCachedBodySize.
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+var _ http.ResponseWriter = &responseRecorder{}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+		rr.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.WriteHeader(http.StatusOK)
+	if !rr.overflow {
+		if rr.body.Len()+len(b) > maxCachedBodySize {
+			rr.overflow = true
+			rr.body = bytes.Buffer{}
+		} else {
+			rr.body.Write(b)
+		}
+	}
+	return rr.ResponseWriter.Write(b)
+}
+
+// Unwrap supports http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// response returns the recorded response or false if the body was too large to be recorded.
+func (rr *responseRecorder) response() (*CachedResponse, bool) {
+	if rr.overflow {
+		return nil, false
+	}
+	status := rr.status
+	// Handlers that write nothing implicitly respond 200 OK.
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &CachedResponse{StatusCode: status, Header: rr.Header().Clone(), Body: bytes.Clone(rr.body.Bytes())}, true
+}
+
+func writeCachedResponse(rw http.ResponseWriter, resp *CachedResponse) {
+	for key, values := range resp.Header {
+		for _, value := range values {
+			rw.Header().Add(key, value)
+		}
+	}
+	rw.WriteHeader(resp.StatusCode)
+	rw.Write(resp.Body)
+}
+
+// upstreamCacheKey returns the key under which the upstream response to req is stored.
+func upstreamCacheKey(req *http.Request) string {
+	return strings.Join([]string{req.Method, req.URL.String(), req.Header.Get("Accept")}, "\n")
+}
+
+// RecordingClient is a BasicClient that stores successful upstream responses
+// in Cache so they can later be served by an OfflineClient.
+type RecordingClient struct {
+	httpx.BasicClient
+	Cache Cache
+}
+
+var _ httpx.BasicClient = &RecordingClient{}
+
+// Do sends the request and stores the response.
+func (c *RecordingClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.BasicClient.Do(req)
+	if err != nil || req.Method != http.MethodGet || resp.StatusCode/100 == 5 {
+		return resp, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response")
+	}
+	cached := &CachedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: body}
+	if err := c.Cache.Put(upstreamCacheKey(req), cached); err != nil {
+		return nil, errors.Wrap(err, "recording response")
+	}
+	return cached.toHTTP(req)
+}
+
+// OfflineClient is a BasicClient that serves responses exclusively from
+// those previously stored in Cache by a RecordingClient.
+type OfflineClient struct {
+	Cache Cache
+}
+
+var _ httpx.BasicClient = &OfflineClient{}
+
+// Do returns the stored response to the request.
+func (c *OfflineClient) Do(req *http.Request) (*http.Response, error) {
+	cached, err := c.Cache.Get(upstreamCacheKey(req))
+	if errors.Is(err, ErrCacheMiss) {
+		return nil, errors.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	} else if err != nil {
+		return nil, err
+	}
+	return cached.toHTTP(req)
+}
+
+func (cr *CachedResponse) toHTTP(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", cr.StatusCode, http.StatusText(cr.StatusCode)),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cr.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	return resp, nil
+}
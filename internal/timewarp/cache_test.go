@@ -0,0 +1,271 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package timewarp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/pkg/errors"
+)
+
+const npmPackumentJSON = `{
+	"_id": "some-package",
+	"time": {
+		"created": "2021-01-01T00:00:00Z",
+		"modified": "2023-01-01T00:00:00Z",
+		"1.0.0": "2021-06-01T00:00:00Z",
+		"2.0.0": "2022-06-01T00:00:00Z"
+	},
+	"versions": {
+		"1.0.0": {"version": "1.0.0"},
+		"2.0.0": {"version": "2.0.0"}
+	}
+}`
+
+func TestCaches(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cache Cache
+	}{
+		{"memory", &MemoryCache{}},
+		{"fs", &FSCache{Dir: t.TempDir()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.cache.Get("key"); !errors.Is(err, ErrCacheMiss) {
+				t.Fatalf("Get() error = %v, want ErrCacheMiss", err)
+			}
+			want := &CachedResponse{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte("{}")}
+			if err := tc.cache.Put("key", want); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			got, err := tc.cache.Get("key")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Get() mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := tc.cache.Get("other"); !errors.Is(err, ErrCacheMiss) {
+				t.Errorf("Get() error = %v, want ErrCacheMiss", err)
+			}
+		})
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	c := &MemoryCache{MaxBytes: 10}
+	put := func(key, body string) {
+		t.Helper()
+		if err := c.Put(key, &CachedResponse{StatusCode: http.StatusOK, Body: []byte(body)}); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+	put("a", "aaaa")
+	put("b", "bbbb")
+	// Use "a" so that "b" is the least recently used.
+	if _, err := c.Get("a"); err != nil {
+		t.Fatalf("Get(a) error = %v", err)
+	}
+	put("c", "cccc")
+	put("huge", "this body exceeds the bound")
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "huge": false} {
+		if _, err := c.Get(key); (err == nil) != want {
+			t.Errorf("Get(%s) error = %v, want present = %v", key, err, want)
+		}
+	}
+	// Replacing an entry releases the size of the previous response.
+	put("a", "a")
+	put("d", "dddd")
+	for key, want := range map[string]bool{"a": true, "c": true, "d": true} {
+		if _, err := c.Get(key); (err == nil) != want {
+			t.Errorf("Get(%s) error = %v, want present = %v", key, err, want)
+		}
+	}
+}
+
+func TestResponseRecorder(t *testing.T) {
+	t.Run("implicit status", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		rec := &responseRecorder{ResponseWriter: rr}
+		rec.Header().Set("Content-Type", "text/plain")
+		resp, ok := rec.response()
+		if !ok || resp.StatusCode != http.StatusOK {
+			t.Fatalf("response() = %v, %v, want 200", resp, ok)
+		}
+		// Replaying the recorded response must not panic on an unset status.
+		writeCachedResponse(httptest.NewRecorder(), resp)
+	})
+	t.Run("oversized body", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		rec := &responseRecorder{ResponseWriter: rr}
+		body := bytes.Repeat([]byte("x"), maxCachedBodySize/2+1)
+		rec.Write(body)
+		rec.Write(body)
+		if _, ok := rec.response(); ok {
+			t.Error("response() recorded an oversized body")
+		}
+		if rr.Code != http.StatusOK || rr.Body.Len() != 2*len(body) {
+			t.Errorf("forwarded %d with %d bytes, want 200 with %d bytes", rr.Code, rr.Body.Len(), 2*len(body))
+		}
+	})
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	testCases := []struct {
+		name      string
+		method    string
+		basicAuth []string
+		wantOK    bool
+	}{
+		{"old instant", http.MethodGet, []string{"npm", "2022-01-01T00:00:00Z"}, true},
+		{"recent instant", http.MethodGet, []string{"npm", recent}, false},
+		{"invalid instant", http.MethodGet, []string{"npm", "yesterday"}, false},
+		{"cargo commit", http.MethodGet, []string{"cargogitarchive", "0123456789abcdef0123456789abcdef01234567"}, true},
+		{"cargo instant", http.MethodGet, []string{"cargogitarchive", "2022-01-01T00:00:00Z"}, false},
+		{"no credentials", http.MethodGet, nil, false},
+		{"non-GET", http.MethodHead, []string{"npm", "2022-01-01T00:00:00Z"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://localhost:8081/some-package", nil)
+			if tc.basicAuth != nil {
+				req.SetBasicAuth(tc.basicAuth[0], tc.basicAuth[1])
+			}
+			if _, ok := responseCacheKey(req); ok != tc.wantOK {
+				t.Errorf("responseCacheKey() ok = %v, want %v", ok, tc.wantOK)
+			}
+		})
+	}
+	t.Run("varies by Accept", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost:8081/some-package", nil)
+		req.SetBasicAuth("npm", "2022-01-01T00:00:00Z")
+		full, _ := responseCacheKey(req)
+		req.Header.Set("Accept", npmAbbreviatedContentType)
+		abbreviated, _ := responseCacheKey(req)
+		if full == abbreviated {
+			t.Errorf("responseCacheKey() = %q for both formats", full)
+		}
+	})
+	t.Run("upload-pack keyed by body", func(t *testing.T) {
+		key := func(body string) string {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost:8081/foo/git-upload-pack", strings.NewReader(body))
+			req.SetBasicAuth("cargogit", testIndexCommit)
+			key, ok := responseCacheKey(req)
+			if !ok {
+				t.Fatal("responseCacheKey() not cacheable")
+			}
+			// The body must remain readable by the handler.
+			if got, _ := io.ReadAll(req.Body); string(got) != body {
+				t.Errorf("request body = %q, want %q", got, body)
+			}
+			return key
+		}
+		if key("want a") == key("want b") {
+			t.Error("responseCacheKey() equal for different bodies")
+		}
+	})
+}
+
+func TestHandlerCache(t *testing.T) {
+	client := &httpxtest.MockClient{
+		Calls: []httpxtest.Call{
+			{
+				Method: "GET",
+				URL:    "https://registry.npmjs.org/some-package",
+				Response: &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       httpxtest.Body(npmPackumentJSON),
+				},
+			},
+		},
+		URLValidator: httpxtest.NewURLValidator(t),
+	}
+	handler := Handler{Client: client, Cache: &FSCache{Dir: t.TempDir()}}
+	var bodies []string
+	for range 2 {
+		req := httptest.NewRequest("GET", "http://localhost:8081/some-package", nil)
+		req.SetBasicAuth("npm", "2022-01-01T00:00:00Z")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("ServeHTTP() status = %d, want %d", rr.Code, http.StatusOK)
+		}
+		if got := rr.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("ServeHTTP() Content-Type = %q, want application/json", got)
+		}
+		bodies = append(bodies, rr.Body.String())
+	}
+	if diff := cmp.Diff(bodies[0], bodies[1]); diff != "" {
+		t.Errorf("cached response mismatch (-first +second):\n%s", diff)
+	}
+	if client.CallCount() != 1 {
+		t.Errorf("upstream calls = %d, want 1", client.CallCount())
+	}
+}
+
+func TestRecordingAndOfflineClients(t *testing.T) {
+	snapshot := &FSCache{Dir: t.TempDir()}
+	recorder := &RecordingClient{
+		BasicClient: &httpxtest.MockClient{
+			Calls: []httpxtest.Call{
+				{
+					Method: "GET",
+					URL:    "https://registry.npmjs.org/some-package",
+					Response: &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": []string{"application/json"}},
+						Body:       httpxtest.Body(npmPackumentJSON),
+					},
+				},
+			},
+			URLValidator: httpxtest.NewURLValidator(t),
+		},
+		Cache: snapshot,
+	}
+	serve := func(h Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://localhost:8081/some-package", nil)
+		req.SetBasicAuth("npm", "2022-01-01T00:00:00Z")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+	online := serve(Handler{Client: recorder})
+	if online.Code != http.StatusOK {
+		t.Fatalf("online status = %d, want %d", online.Code, http.StatusOK)
+	}
+	offline := serve(Handler{Client: &OfflineClient{Cache: snapshot}})
+	if offline.Code != http.StatusOK {
+		t.Fatalf("offline status = %d, want %d", offline.Code, http.StatusOK)
+	}
+	if diff := cmp.Diff(online.Body.String(), offline.Body.String()); diff != "" {
+		t.Errorf("offline response mismatch (-online +offline):\n%s", diff)
+	}
+	t.Run("miss", func(t *testing.T) {
+		rr := serve(Handler{Client: &OfflineClient{Cache: &FSCache{Dir: t.TempDir()}}})
+		if rr.Code != http.StatusBadGateway {
+			t.Errorf("status = %d, want %d", rr.Code, http.StatusBadGateway)
+		}
+	})
+	t.Run("response body", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "https://registry.npmjs.org/some-package", nil)
+		resp, err := (&OfflineClient{Cache: snapshot}).Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		got, _ := io.ReadAll(resp.Body)
+		if !bytes.Equal(got, []byte(npmPackumentJSON)) || resp.Status != "200 OK" {
+			t.Errorf("Do() = %q %q, want recorded response", resp.Status, got)
+		}
+	})
+}
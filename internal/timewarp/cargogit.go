@@ -0,0 +1,260 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package timewarp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/pkg/errors"
+)
+
+const (
+	gitUploadPackService       = "git-upload-pack"
+	gitUploadPackAdvertisement = "application/x-git-upload-pack-advertisement"
+	gitUploadPackRequest       = "application/x-git-upload-pack-request"
+	gitUploadPackResult        = "application/x-git-upload-pack-result"
+)
+
+// cargoGitCommitTime is the time of the commit served by cargogit.
+// NOTE: Each request of a fetch rebuilds the repository so its commit must be
+// deterministic for the ref advertised in one request to be served by the next.
+var cargoGitCommitTime = time.Unix(0, 0).UTC()
+
+// maxCargoIndexCrates bounds the crates served in a cargogit repository
+// including those referenced transitively.
+const maxCargoIndexCrates = 5000
+
+// cargoIndexEntry is the subset of a line of a crates.io index file used to find referenced crates.
+type cargoIndexEntry struct {
+	Deps []struct {
+		Name     string  `json:"name"`
+		Package  *string `json:"package"`
+		Kind     string  `json:"kind"`
+		Registry *string `json:"registry"`
+	} `json:"deps"`
+}
+
+// serveCargoGit serves the smart HTTP git protocol for a crates.io index
+// repository at indexCommit pruned to the crates in the request's path and
+// those they reference.
+//
+// The repository path is a comma-separated list of root crates e.g.
+// "/serde,tokio" serves "/serde,tokio/info/refs" and "/serde,tokio/git-upload-pack".
+// Reference: https://git-scm.com/docs/http-protocol#_smart_clients
+func (h Handler) serveCargoGit(rw http.ResponseWriter, r *http.Request, indexCommit string) error {
+	var repoPath string
+	var advertise bool
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/info/refs"):
+		if r.Method != http.MethodGet {
+			return herror{errors.New("unsupported method"), http.StatusMethodNotAllowed}
+		}
+		if r.URL.Query().Get("service") != gitUploadPackService {
+			return herror{errors.New("only the smart git-upload-pack service is supported"), http.StatusForbidden}
+		}
+		repoPath, advertise = strings.TrimSuffix(r.URL.Path, "/info/refs"), true
+	case strings.HasSuffix(r.URL.Path, "/"+gitUploadPackService):
+		if r.Method != http.MethodPost {
+			return herror{errors.New("unsupported method"), http.StatusMethodNotAllowed}
+		}
+		if ct := r.Header.Get("Content-Type"); ct != gitUploadPackRequest {
+			return herror{errors.Errorf("unexpected content type: %s", ct), http.StatusUnsupportedMediaType}
+		}
+		repoPath = strings.TrimSuffix(r.URL.Path, "/"+gitUploadPackService)
+	default:
+		return herror{errors.New("invalid path for cargogit"), http.StatusNotFound}
+	}
+	var crates []string
+	for _, name := range strings.Split(strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			crates = append(crates, name)
+		}
+	}
+	if len(crates) == 0 {
+		return herror{errors.New("no crates in repository path"), http.StatusBadRequest}
+	}
+	ep, _ := transport.NewEndpoint("/")
+	storer, err := h.createCargoIndexRepo(indexCommit, crates)
+	if errors.Is(err, errTooManyCrates) {
+		return herror{err, http.StatusBadRequest}
+	} else if err != nil {
+		return herror{errors.Wrap(err, "creating cargo index repo"), http.StatusBadGateway}
+	}
+	sess, err := server.NewServer(server.MapLoader{ep.String(): storer}).NewUploadPackSession(ep, nil)
+	if err != nil {
+		return herror{errors.Wrap(err, "creating upload-pack session"), http.StatusInternalServerError}
+	}
+	defer sess.Close()
+	rw.Header().Set("Cache-Control", "no-cache")
+	if advertise {
+		ar, err := sess.AdvertisedReferencesContext(r.Context())
+		if err != nil {
+			return herror{errors.Wrap(err, "advertising references"), http.StatusInternalServerError}
+		}
+		ar.Prefix = [][]byte{[]byte("# service=" + gitUploadPackService), pktline.Flush}
+		rw.Header().Set("Content-Type", gitUploadPackAdvertisement)
+		if err := ar.Encode(rw); err != nil {
+			return herror{errors.Wrap(err, "writing references"), http.StatusInternalServerError}
+		}
+		return nil
+	}
+	body := io.Reader(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return herror{errors.Wrap(err, "decompressing request"), http.StatusBadRequest}
+		}
+		defer gz.Close()
+		body = gz
+	}
+	req := packp.NewUploadPackRequest()
+	if err := req.Decode(bufio.NewReader(body)); err != nil {
+		return herror{errors.Wrap(err, "parsing upload-pack request"), http.StatusBadRequest}
+	}
+	// NOTE: Haves are not decoded so the full history, a single commit, is always sent.
+	resp, err := sess.UploadPack(r.Context(), req)
+	if err != nil {
+		return herror{errors.Wrap(err, "packing objects"), http.StatusBadRequest}
+	}
+	defer resp.Close()
+	rw.Header().Set("Content-Type", gitUploadPackResult)
+	if err := resp.Encode(rw); err != nil {
+		return herror{errors.Wrap(err, "writing pack"), http.StatusInternalServerError}
+	}
+	return nil
+}
+
+var errTooManyCrates = errors.Errorf("dependencies exceed %d crates", maxCargoIndexCrates)
+
+// createCargoIndexRepo creates a git repository with a single commit of the index files at indexCommit for the
+// provided crates and, transitively, all crates referenced by their dependencies.
+//
+// If the Handler has a Cache, the index files are stored there so the
+// repository need not be crawled again for each request of a fetch.
+func (h Handler) createCargoIndexRepo(indexCommit string, crates []string) (*memory.Storage, error) {
+	entries, err := h.cachedCargoIndexEntries(indexCommit, crates)
+	if err != nil {
+		return nil, err
+	}
+	wfs := memfs.New()
+	if err := util.WriteFile(wfs, "config.json", []byte(defaultCratesConfig), 0644); err != nil {
+		return nil, errors.Wrap(err, "writing config.json")
+	}
+	for name, content := range entries {
+		if err := writeCargoIndexEntry(wfs, name, content); err != nil {
+			return nil, errors.Wrapf(err, "adding %s to index", name)
+		}
+	}
+	storer := memory.NewStorage()
+	if _, err := commitGitRepo(storer, wfs, cargoGitCommitTime); err != nil {
+		return nil, err
+	}
+	return storer, nil
+}
+
+// cachedCargoIndexEntries returns the result of cargoIndexEntries, consulting the Handler's Cache if present.
+func (h Handler) cachedCargoIndexEntries(indexCommit string, crates []string) (map[string][]byte, error) {
+	if h.Cache == nil {
+		return h.cargoIndexEntries(indexCommit, crates)
+	}
+	var roots []string
+	for _, name := range crates {
+		roots = append(roots, strings.ToLower(name))
+	}
+	slices.Sort(roots)
+	key := strings.Join([]string{"cargogit-index", indexCommit, strings.Join(slices.Compact(roots), ",")}, "\n")
+	var entries map[string][]byte
+	if resp, err := h.Cache.Get(key); err == nil {
+		if err := json.Unmarshal(resp.Body, &entries); err != nil {
+			return nil, errors.Wrap(err, "parsing cached index")
+		}
+		return entries, nil
+	} else if !errors.Is(err, ErrCacheMiss) {
+		log.Printf("error: %+v", errors.Wrap(err, "reading cache"))
+	}
+	entries, err := h.cargoIndexEntries(indexCommit, crates)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing index")
+	}
+	if err := h.Cache.Put(key, &CachedResponse{StatusCode: http.StatusOK, Body: body}); err != nil {
+		log.Printf("error: %+v", errors.Wrap(err, "writing cache"))
+	}
+	return entries, nil
+}
+
+// cargoIndexEntries fetches the index files at indexCommit for the provided
+// crates and, transitively, all crates referenced by their dependencies.
+//
+// Dev-dependencies are only followed for the provided crates since cargo only resolves them for workspace members.
+func (h Handler) cargoIndexEntries(indexCommit string, crates []string) (map[string][]byte, error) {
+	entries := make(map[string][]byte)
+	seen := make(map[string]bool)
+	queue := make([]string, 0, len(crates))
+	for _, name := range crates {
+		if name = strings.ToLower(name); !seen[name] {
+			seen[name] = true
+			queue = append(queue, name)
+		}
+	}
+	roots := len(queue)
+	for i := 0; i < len(queue); i++ {
+		if i == maxCargoIndexCrates {
+			return nil, errTooManyCrates
+		}
+		name := queue[i]
+		content, err := h.fetchCargoIndexEntry(indexCommit, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetching %s", name)
+		}
+		if content == nil {
+			continue
+		}
+		entries[name] = content
+		isRoot := i < roots
+		for _, line := range bytes.Split(content, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var entry cargoIndexEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return nil, errors.Wrapf(err, "parsing index entry for %s", name)
+			}
+			for _, dep := range entry.Deps {
+				// NOTE: Dependencies on other registries are not served from this index.
+				if dep.Registry != nil || (dep.Kind == "dev" && !isRoot) {
+					continue
+				}
+				depName := dep.Name
+				if dep.Package != nil {
+					depName = *dep.Package
+				}
+				if depName = strings.ToLower(depName); !seen[depName] {
+					seen[depName] = true
+					queue = append(queue, depName)
+				}
+			}
+		}
+	}
+	return entries, nil
+}
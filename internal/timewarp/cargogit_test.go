@@ -0,0 +1,168 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package timewarp
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+)
+
+const testIndexCommit = "0123456789abcdef0123456789abcdef01234567"
+
+func TestCargoGit(t *testing.T) {
+	entries := map[string]string{
+		"foo": `{"name":"foo","vers":"1.0.0","deps":[{"name":"bar","req":"^1","kind":"normal"},{"name":"b","package":"baz","req":"^1","kind":"dev"}]}`,
+		"bar": `{"name":"bar","vers":"1.0.0","deps":[{"name":"qux","req":"^1","kind":"dev"}]}` + "\n" +
+			`{"name":"bar","vers":"1.1.0","deps":[{"name":"foo","req":"^1","kind":"build"}]}`,
+		"baz": `{"name":"baz","vers":"1.0.0","deps":[{"name":"other","req":"^1","kind":"normal","registry":"https://example.com/index"}]}`,
+	}
+	paths := map[string]string{"foo": "3/f/foo", "bar": "3/b/bar", "baz": "3/b/baz"}
+	var calls []httpxtest.Call
+	// NOTE: Both the ref advertisement and the upload-pack request build the repo.
+	for range 2 {
+		for _, name := range []string{"foo", "bar", "baz"} {
+			calls = append(calls, httpxtest.Call{
+				Method:   "GET",
+				URL:      "https://raw.githubusercontent.com/rust-lang/crates.io-index/" + testIndexCommit + "/" + paths[name],
+				Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(entries[name])},
+			})
+		}
+	}
+	client := &httpxtest.MockClient{Calls: calls, URLValidator: httpxtest.NewURLValidator(t)}
+	srv := httptest.NewServer(Handler{Client: client})
+	defer srv.Close()
+	wfs := memfs.New()
+	_, err := git.Clone(memory.NewStorage(), wfs, &git.CloneOptions{
+		URL: strings.Replace(srv.URL, "http://", "http://cargogit:"+testIndexCommit+"@", 1) + "/foo",
+	})
+	if err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	var got []string
+	err = util.Walk(wfs, "", func(path string, info fs.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			got = append(got, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"3/b/bar", "3/b/baz", "3/f/foo", "config.json"}, got); diff != "" {
+		t.Errorf("cloned files mismatch (-want +got):\n%s", diff)
+	}
+	for name, path := range paths {
+		content, err := util.ReadFile(wfs, path)
+		if err != nil {
+			t.Fatalf("ReadFile(%s) error = %v", path, err)
+		}
+		if diff := cmp.Diff(entries[name], string(content)); diff != "" {
+			t.Errorf("%s content mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+	if client.CallCount() != len(calls) {
+		t.Errorf("upstream calls = %d, want %d", client.CallCount(), len(calls))
+	}
+}
+
+func TestCargoGitCache(t *testing.T) {
+	calls := []httpxtest.Call{
+		{
+			Method:   "GET",
+			URL:      "https://raw.githubusercontent.com/rust-lang/crates.io-index/" + testIndexCommit + "/3/f/foo",
+			Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(`{"name":"foo","vers":"1.0.0","deps":[{"name":"bar","req":"^1","kind":"normal"}]}`)},
+		},
+		{
+			Method:   "GET",
+			URL:      "https://raw.githubusercontent.com/rust-lang/crates.io-index/" + testIndexCommit + "/3/b/bar",
+			Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(`{"name":"bar","vers":"1.0.0","deps":[]}`)},
+		},
+	}
+	client := &httpxtest.MockClient{Calls: calls, URLValidator: httpxtest.NewURLValidator(t)}
+	srv := httptest.NewServer(Handler{Client: client, Cache: &MemoryCache{}})
+	defer srv.Close()
+	// The index is crawled once for the first clone and the second is served entirely from the cache.
+	for range 2 {
+		wfs := memfs.New()
+		_, err := git.Clone(memory.NewStorage(), wfs, &git.CloneOptions{
+			URL: strings.Replace(srv.URL, "http://", "http://cargogit:"+testIndexCommit+"@", 1) + "/Foo",
+		})
+		if err != nil {
+			t.Fatalf("Clone() error = %v", err)
+		}
+		if _, err := wfs.Stat("3/b/bar"); err != nil {
+			t.Errorf("Stat(3/b/bar) error = %v", err)
+		}
+	}
+	if client.CallCount() != len(calls) {
+		t.Errorf("upstream calls = %d, want %d", client.CallCount(), len(calls))
+	}
+}
+
+func TestCargoGitUpstreamError(t *testing.T) {
+	fooURL := "https://raw.githubusercontent.com/rust-lang/crates.io-index/" + testIndexCommit + "/3/f/foo"
+	barURL := "https://raw.githubusercontent.com/rust-lang/crates.io-index/" + testIndexCommit + "/3/b/bar"
+	foo := `{"name":"foo","vers":"1.0.0","deps":[{"name":"bar","req":"^1","kind":"normal"}]}`
+	calls := []httpxtest.Call{
+		{Method: "GET", URL: fooURL, Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(foo)}},
+		{Method: "GET", URL: barURL, Response: &http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable", Body: httpxtest.Body("")}},
+		{Method: "GET", URL: fooURL, Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(foo)}},
+		{Method: "GET", URL: barURL, Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(`{"name":"bar","vers":"1.0.0","deps":[]}`)}},
+	}
+	client := &httpxtest.MockClient{Calls: calls, URLValidator: httpxtest.NewURLValidator(t)}
+	srv := httptest.NewServer(Handler{Client: client, Cache: &MemoryCache{}})
+	defer srv.Close()
+	url := strings.Replace(srv.URL, "http://", "http://cargogit:"+testIndexCommit+"@", 1) + "/foo"
+	if _, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: url}); err == nil {
+		t.Fatal("Clone() with failing upstream succeeded")
+	}
+	// The failed crawl must not be cached in place of the complete index.
+	wfs := memfs.New()
+	if _, err := git.Clone(memory.NewStorage(), wfs, &git.CloneOptions{URL: url}); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if _, err := wfs.Stat("3/b/bar"); err != nil {
+		t.Errorf("Stat(3/b/bar) error = %v", err)
+	}
+	if client.CallCount() != len(calls) {
+		t.Errorf("upstream calls = %d, want %d", client.CallCount(), len(calls))
+	}
+}
+
+func TestCargoGitErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		url    string
+		auth   string
+		want   int
+	}{
+		{"dumb protocol", http.MethodGet, "/foo/info/refs", testIndexCommit, http.StatusForbidden},
+		{"no crates", http.MethodGet, "/info/refs?service=git-upload-pack", testIndexCommit, http.StatusBadRequest},
+		{"invalid commit", http.MethodGet, "/foo/info/refs?service=git-upload-pack", "2022-01-01T00:00:00Z", http.StatusBadRequest},
+		{"receive-pack", http.MethodPost, "/foo/git-receive-pack", testIndexCommit, http.StatusNotFound},
+		{"upload-pack via GET", http.MethodGet, "/foo/git-upload-pack", testIndexCommit, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://localhost:8081"+tc.url, nil)
+			req.SetBasicAuth("cargogit", tc.auth)
+			rr := httptest.NewRecorder()
+			Handler{Client: &httpxtest.MockClient{URLValidator: httpxtest.NewURLValidator(t)}}.ServeHTTP(rr, req)
+			if rr.Code != tc.want {
+				t.Errorf("status = %d, want %d", rr.Code, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package timewarp
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/oss-rebuild/internal/httpx"
+	"github.com/pkg/errors"
+)
+
+var (
+	mavenMetadataPattern = regexp.MustCompile(`^maven-metadata\.xml(\.(md5|sha1|sha256|sha512))?$`)
+	// Matches the entries of a Maven Central directory listing.
+	// Example: <a href="1.0.0/" title="1.0.0/">1.0.0/</a>        2021-06-01 12:00         -
+	mavenListingPattern = regexp.MustCompile(`<a href="([^"/]+)/"[^>]*>[^<]*</a>\s+(\d{4}-\d{2}-\d{2} \d{2}:\d{2})`)
+	mavenChecksums      = map[string]func() hash.Hash{
+		".md5":    md5.New,
+		".sha1":   sha1.New,
+		".sha256": sha256.New,
+		".sha512": sha512.New,
+	}
+	errNoMavenVersions = errors.New("no versions before time warp")
+)
+
+const (
+	mavenListingTimeFormat     = "2006-01-02 15:04"
+	mavenLastUpdatedTimeFormat = "20060102150405"
+)
+
+// mavenMetadata is the subset of maven-metadata.xml used by Maven Central.
+type mavenMetadata struct {
+	XMLName      xml.Name         `xml:"metadata"`
+	ModelVersion string           `xml:"modelVersion,attr,omitempty"`
+	GroupID      string           `xml:"groupId,omitempty"`
+	ArtifactID   string           `xml:"artifactId,omitempty"`
+	Version      string           `xml:"version,omitempty"`
+	Versioning   *mavenVersioning `xml:"versioning"`
+}
+
+type mavenVersioning struct {
+	Latest      string   `xml:"latest,omitempty"`
+	Release     string   `xml:"release,omitempty"`
+	Versions    []string `xml:"versions>version"`
+	LastUpdated string   `xml:"lastUpdated,omitempty"`
+}
+
+// serveMavenMetadata serves the maven-metadata.xml file, or its checksum, as of "at".
+func (h Handler) serveMavenMetadata(rw http.ResponseWriter, r *http.Request, at time.Time) error {
+	metadataURL := *r.URL
+	newHash, isChecksum := mavenChecksums[path.Ext(metadataURL.Path)]
+	if isChecksum {
+		metadataURL.Path = strings.TrimSuffix(metadataURL.Path, path.Ext(metadataURL.Path))
+	}
+	req, _ := http.NewRequest(http.MethodGet, metadataURL.String(), nil)
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return herror{errors.Wrap(err, "fetching metadata"), http.StatusBadGateway}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		rw.WriteHeader(resp.StatusCode)
+		if _, err := io.Copy(rw, resp.Body); err != nil {
+			log.Printf("error: %+v", errors.Wrap(err, "transmitting non-ok response"))
+		}
+		return nil
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return herror{errors.Wrap(err, "reading metadata"), http.StatusBadGateway}
+	}
+	dirURL := metadataURL
+	dirURL.Path = path.Dir(metadataURL.Path) + "/"
+	content, err = timeWarpMavenMetadata(h.Client, content, dirURL.String(), at)
+	if errors.Is(err, errNoMavenVersions) {
+		return herror{err, http.StatusNotFound}
+	} else if err != nil {
+		return herror{errors.Wrap(err, "warping response"), http.StatusBadGateway}
+	}
+	// NOTE: Clients verify the metadata against its checksum so these must
+	// reflect the warped content rather than that of the upstream file.
+	if isChecksum {
+		hasher := newHash()
+		hasher.Write(content)
+		content = []byte(hex.EncodeToString(hasher.Sum(nil)))
+		rw.Header().Set("Content-Type", "text/plain")
+	} else {
+		rw.Header().Set("Content-Type", "text/xml")
+	}
+	if _, err := rw.Write(content); err != nil {
+		return herror{errors.Wrap(err, "writing response"), http.StatusInternalServerError}
+	}
+	return nil
+}
+
+// timeWarpMavenMetadata returns the provided maven-metadata.xml excluding all versions published after "at".
+//
+// Metadata without a versions list, such as that of a plugin group, is returned unchanged.
+func timeWarpMavenMetadata(client httpx.BasicClient, content []byte, dirURL string, at time.Time) ([]byte, error) {
+	var metadata mavenMetadata
+	if err := xml.Unmarshal(content, &metadata); err != nil {
+		return nil, errors.Wrap(err, "parsing metadata")
+	}
+	if metadata.Versioning == nil || len(metadata.Versioning.Versions) == 0 {
+		return content, nil
+	}
+	times, err := mavenVersionTimes(client, dirURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching version times")
+	}
+	var versions []string
+	var latestTime, releaseTime time.Time
+	v := metadata.Versioning
+	v.Latest, v.Release = "", ""
+	// NOTE: Versions are listed in the order they were published so, for those
+	// published in the same minute, the later entry is preferred.
+	for _, version := range v.Versions {
+		t, ok := times[version]
+		// NOTE: Publish times have minute resolution so include versions
+		// published in the minute containing "at".
+		if !ok || t.After(at) {
+			continue
+		}
+		versions = append(versions, version)
+		if !t.Before(latestTime) {
+			v.Latest, latestTime = version, t
+		}
+		if !strings.HasSuffix(version, "-SNAPSHOT") && !t.Before(releaseTime) {
+			v.Release, releaseTime = version, t
+		}
+	}
+	if len(versions) == 0 {
+		return nil, errNoMavenVersions
+	}
+	v.Versions = versions
+	v.LastUpdated = latestTime.Format(mavenLastUpdatedTimeFormat)
+	out, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing metadata")
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// mavenVersionTimes returns the publish times of the versions in the artifact directory listing at dirURL.
+func mavenVersionTimes(client httpx.BasicClient, dirURL string) (map[string]time.Time, error) {
+	req, err := http.NewRequest(http.MethodGet, dirURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	resp, err := client.Do(req)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = errors.New(resp.Status)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching listing")
+	}
+	defer resp.Body.Close()
+	listing, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading listing")
+	}
+	times := make(map[string]time.Time)
+	for _, m := range mavenListingPattern.FindAllSubmatch(listing, -1) {
+		t, err := time.Parse(mavenListingTimeFormat, string(m[2]))
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing time")
+		}
+		times[string(m[1])] = t
+	}
+	return times, nil
+}
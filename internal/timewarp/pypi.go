@@ -0,0 +1,273 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package timewarp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/oss-rebuild/internal/httpx"
+	"github.com/pkg/errors"
+)
+
+// Content types of the PyPI Simple API.
+// Reference: https://peps.python.org/pep-0691/#content-types
+const (
+	pypiSimpleJSON       = "application/vnd.pypi.simple.v1+json"
+	pypiSimpleHTML       = "application/vnd.pypi.simple.v1+html"
+	pypiSimpleLegacyHTML = "text/html"
+)
+
+// pypiSimpleProject is a project page of the PyPI Simple API in the JSON form.
+type pypiSimpleProject struct {
+	Meta     map[string]any   `json:"meta"`
+	Name     string           `json:"name"`
+	Files    []pypiSimpleFile `json:"files"`
+	Versions []string         `json:"versions,omitempty"`
+}
+
+// pypiSimpleFile is a file of a project page of the PyPI Simple API.
+type pypiSimpleFile struct {
+	Filename       string            `json:"filename"`
+	URL            string            `json:"url"`
+	Hashes         map[string]string `json:"hashes"`
+	RequiresPython *string           `json:"requires-python,omitempty"`
+	// CoreMetadata and DistInfoMetadata are either a bool or a map of hashes.
+	CoreMetadata     any   `json:"core-metadata,omitempty"`
+	DistInfoMetadata any   `json:"dist-info-metadata,omitempty"`
+	GPGSig           *bool `json:"gpg-sig,omitempty"`
+	// Yanked is either a bool or the reason for the yank.
+	Yanked     any    `json:"yanked,omitempty"`
+	Size       *int64 `json:"size,omitempty"`
+	UploadTime string `json:"upload-time,omitempty"`
+}
+
+// pypiSimpleFormat returns the content type of the Simple API to serve for the given Accept header.
+func pypiSimpleFormat(accept string) string {
+	best, bestQ := pypiSimpleLegacyHTML, 0.
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.
+		if qs, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qs, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case pypiSimpleJSON, pypiSimpleHTML, pypiSimpleLegacyHTML:
+			if q > bestQ {
+				best, bestQ = mediaType, q
+			}
+		}
+	}
+	return best
+}
+
+// servePyPISimpleProject serves the Simple API project page at the request's path as of "at".
+func (h Handler) servePyPISimpleProject(rw http.ResponseWriter, r *http.Request, at time.Time) error {
+	format := pypiSimpleFormat(r.Header.Get("Accept"))
+	// NOTE: Only the JSON form provides upload times so it's always requested
+	// and converted to HTML when that is requested by the client.
+	req, _ := http.NewRequest(http.MethodGet, r.URL.String(), nil)
+	req.Header.Set("Accept", pypiSimpleJSON)
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return herror{errors.Wrap(err, "fetching project"), http.StatusBadGateway}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		rw.WriteHeader(resp.StatusCode)
+		if _, err := io.Copy(rw, resp.Body); err != nil {
+			log.Printf("error: %+v", errors.Wrap(err, "transmitting non-ok response"))
+		}
+		return nil
+	}
+	var project pypiSimpleProject
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return herror{errors.Wrap(err, "parsing response"), http.StatusBadGateway}
+	}
+	if err := timeWarpPyPISimpleProject(h.Client, h.upstream("pypi"), &project, at); err != nil {
+		return herror{errors.Wrap(err, "warping response"), http.StatusBadGateway}
+	}
+	rw.Header().Set("Content-Type", format)
+	if format == pypiSimpleJSON {
+		if err := json.NewEncoder(rw).Encode(project); err != nil {
+			return herror{errors.Wrap(err, "serializing response"), http.StatusBadGateway}
+		}
+		return nil
+	}
+	if _, err := io.WriteString(rw, pypiSimpleProjectHTML(project)); err != nil {
+		return herror{errors.Wrap(err, "writing response"), http.StatusInternalServerError}
+	}
+	return nil
+}
+
+// timeWarpPyPISimpleProject modifies the provided project page to exclude all files uploaded after "at".
+func timeWarpPyPISimpleProject(client httpx.BasicClient, registry *url.URL, project *pypiSimpleProject, at time.Time) error {
+	var uploadTimes map[string]string
+	var files []pypiSimpleFile
+	for _, f := range project.Files {
+		uploaded := f.UploadTime
+		if uploaded == "" {
+			// NOTE: Upload times are optional in the Simple API (PEP 700) so, if
+			// absent, they are retrieved from the JSON API.
+			if uploadTimes == nil {
+				var err error
+				if uploadTimes, err = pypiUploadTimes(client, registry, project.Name); err != nil {
+					return errors.Wrap(err, "fetching upload times")
+				}
+			}
+			uploaded = uploadTimes[f.Filename]
+		}
+		if uploaded == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, uploaded)
+		if err != nil {
+			return errors.Wrap(err, "parsing time")
+		}
+		// NOTE: Ensure that if "at" and "t" are equal, we include the file.
+		if t.Before(at.Add(time.Second)) {
+			files = append(files, f)
+		}
+	}
+	project.Files = files
+	if project.Versions != nil {
+		var versions []string
+		for _, v := range project.Versions {
+			if slices.ContainsFunc(files, func(f pypiSimpleFile) bool { return pypiFileVersion(f.Filename) == v }) {
+				versions = append(versions, v)
+			}
+		}
+		project.Versions = versions
+	}
+	return nil
+}
+
+// pypiUploadTimes returns the upload time of each of the project's files from the JSON API.
+func pypiUploadTimes(client httpx.BasicClient, registry *url.URL, project string) (map[string]string, error) {
+	req, err := http.NewRequest(http.MethodGet, registry.JoinPath("pypi", project, "json").String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	resp, err := client.Do(req)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = errors.New(resp.Status)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching project")
+	}
+	defer resp.Body.Close()
+	var obj struct {
+		Releases map[string][]struct {
+			Filename   string `json:"filename"`
+			UploadTime string `json:"upload_time_iso_8601"`
+		} `json:"releases"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return nil, errors.Wrap(err, "decoding project")
+	}
+	times := make(map[string]string)
+	for _, files := range obj.Releases {
+		for _, f := range files {
+			times[f.Filename] = f.UploadTime
+		}
+	}
+	return times, nil
+}
+
+// pypiFileVersion returns the version component of a distribution filename.
+// NOTE: This relies on the version containing no hyphens which, while not
+// true for all legacy sdists, is for all normalized versions.
+func pypiFileVersion(filename string) string {
+	for _, ext := range []string{".whl", ".egg"} {
+		if stem, ok := strings.CutSuffix(filename, ext); ok {
+			if parts := strings.Split(stem, "-"); len(parts) > 1 {
+				return parts[1]
+			}
+			return ""
+		}
+	}
+	for _, ext := range []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tgz", ".zip"} {
+		if stem, ok := strings.CutSuffix(filename, ext); ok {
+			if i := strings.LastIndex(stem, "-"); i != -1 {
+				return stem[i+1:]
+			}
+		}
+	}
+	return ""
+}
+
+// pypiSimpleProjectHTML renders the project page in the HTML form of the Simple API.
+// Reference: https://packaging.python.org/en/latest/specifications/simple-repository-api/#base-html-api
+func pypiSimpleProjectHTML(project pypiSimpleProject) string {
+	var b strings.Builder
+	name := html.EscapeString(project.Name)
+	b.WriteString("<!DOCTYPE html>\n<html>\n  <head>\n")
+	if v, ok := project.Meta["api-version"].(string); ok {
+		fmt.Fprintf(&b, "    <meta name=\"pypi:repository-version\" content=\"%s\">\n", html.EscapeString(v))
+	}
+	fmt.Fprintf(&b, "    <title>Links for %s</title>\n  </head>\n  <body>\n    <h1>Links for %s</h1>\n", name, name)
+	for _, f := range project.Files {
+		href := f.URL
+		if digest, ok := f.Hashes["sha256"]; ok {
+			href += "#sha256=" + digest
+		} else if algs := slices.Sorted(maps.Keys(f.Hashes)); len(algs) > 0 {
+			href += "#" + algs[0] + "=" + f.Hashes[algs[0]]
+		}
+		fmt.Fprintf(&b, "    <a href=\"%s\"", html.EscapeString(href))
+		if f.RequiresPython != nil {
+			fmt.Fprintf(&b, " data-requires-python=\"%s\"", html.EscapeString(*f.RequiresPython))
+		}
+		if v, ok := pypiMetadataAttr(f.DistInfoMetadata); ok {
+			fmt.Fprintf(&b, " data-dist-info-metadata=\"%s\"", html.EscapeString(v))
+		}
+		if v, ok := pypiMetadataAttr(f.CoreMetadata); ok {
+			fmt.Fprintf(&b, " data-core-metadata=\"%s\"", html.EscapeString(v))
+		}
+		if f.GPGSig != nil {
+			fmt.Fprintf(&b, " data-gpg-sig=\"%t\"", *f.GPGSig)
+		}
+		switch y := f.Yanked.(type) {
+		case bool:
+			if y {
+				b.WriteString(" data-yanked=\"\"")
+			}
+		case string:
+			fmt.Fprintf(&b, " data-yanked=\"%s\"", html.EscapeString(y))
+		}
+		fmt.Fprintf(&b, ">%s</a><br />\n", html.EscapeString(f.Filename))
+	}
+	b.WriteString("  </body>\n</html>\n")
+	return b.String()
+}
+
+// pypiMetadataAttr returns the HTML attribute value for a metadata file field in the JSON form.
+func pypiMetadataAttr(v any) (string, bool) {
+	switch m := v.(type) {
+	case bool:
+		return "true", m
+	case map[string]any:
+		if digest, ok := m["sha256"].(string); ok {
+			return "sha256=" + digest, true
+		}
+		return "true", true
+	default:
+		return "", false
+	}
+}
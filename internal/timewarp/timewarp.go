@@ -34,6 +34,7 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/cache"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage"
 	"github.com/go-git/go-git/v5/storage/filesystem"
 	"github.com/google/oss-rebuild/internal/httpx"
 	"github.com/google/oss-rebuild/internal/urlx"
@@ -44,12 +45,16 @@ import (
 var (
 	npmRegistry         = urlx.MustParse("https://registry.npmjs.org/")
 	pypiRegistry        = urlx.MustParse("https://pypi.org/")
+	mavenRegistry       = urlx.MustParse("https://repo1.maven.org/maven2/")
 	cratesIndexURL      = urlx.MustParse("https://raw.githubusercontent.com/rust-lang/crates.io-index")
 	lowTimeBound        = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
 	commitHashRegex     = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
 	defaultCratesConfig = `{"dl": "https://static.crates.io/crates","api": "/"}`
 )
 
+// npmAbbreviatedContentType is the content type of npm's abbreviated package metadata.
+const npmAbbreviatedContentType = "application/vnd.npm.install-v1+json"
+
 func parseTime(ts string) (*time.Time, error) {
 	if ts == "" {
 		return nil, errors.New("no time set")
@@ -70,6 +75,28 @@ func parseTime(ts string) (*time.Time, error) {
 // Handler implements a registry-fronting HTTP service that filters returned content by time.
 type Handler struct {
 	Client httpx.BasicClient
+	// Cache, if provided, stores warped responses once they are immutable.
+	Cache Cache
+	// Upstreams, if provided, overrides the registry fronted for a platform
+	// e.g. "npm" to serve a mirror or private registry.
+	Upstreams map[string]*url.URL
+}
+
+// upstream returns the base URL of the registry fronted for platform.
+func (h Handler) upstream(platform string) *url.URL {
+	if u, ok := h.Upstreams[platform]; ok {
+		return u
+	}
+	switch platform {
+	case "npm":
+		return npmRegistry
+	case "pypi":
+		return pypiRegistry
+	case "maven":
+		return mavenRegistry
+	default:
+		return nil
+	}
 }
 
 var _ http.Handler = &Handler{}
@@ -80,6 +107,31 @@ type herror struct {
 }
 
 func (h Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if h.Cache == nil {
+		h.serve(rw, r)
+		return
+	}
+	key, ok := responseCacheKey(r)
+	if !ok {
+		h.serve(rw, r)
+		return
+	}
+	if resp, err := h.Cache.Get(key); err == nil {
+		writeCachedResponse(rw, resp)
+		return
+	} else if !errors.Is(err, ErrCacheMiss) {
+		log.Printf("error: %+v  [%s]", errors.Wrap(err, "reading cache"), r.URL.String())
+	}
+	rec := &responseRecorder{ResponseWriter: rw}
+	h.serve(rec, r)
+	if resp, ok := rec.response(); ok && resp.StatusCode == http.StatusOK {
+		if err := h.Cache.Put(key, resp); err != nil {
+			log.Printf("error: %+v  [%s]", errors.Wrap(err, "writing cache"), r.URL.String())
+		}
+	}
+}
+
+func (h Handler) serve(rw http.ResponseWriter, r *http.Request) {
 	if err := h.handleRequest(rw, r); err != nil {
 		status := http.StatusInternalServerError
 		if he, ok := err.(herror); ok {
@@ -103,15 +155,26 @@ func (h Handler) handleRequest(rw http.ResponseWriter, r *http.Request) error {
 	// http://<platform>:<RFC3339>@<hostname>/
 	// These populate the Authorization header with a "Basic" mode value and are
 	// accessible here via Request.BasicAuth.
-	platform, ts, _ := r.BasicAuth()
+	platform, ts, ok := r.BasicAuth()
+	if !ok {
+		// NOTE: Clients such as Maven, Gradle, and git only send credentials once challenged.
+		rw.Header().Set("WWW-Authenticate", `Basic realm="timewarp"`)
+		return herror{errors.New("no platform set"), http.StatusUnauthorized}
+	}
+	// reqPath is the path requested of timewarp, before any upstream base path is applied.
+	reqPath := r.URL.Path
 	switch platform {
-	case "npm":
-		r.URL.Host = npmRegistry.Host
-		r.URL.Scheme = npmRegistry.Scheme
-	case "pypi":
-		r.URL.Host = pypiRegistry.Host
-		r.URL.Scheme = pypiRegistry.Scheme
-	// TODO: We should add cargogit which serves the repo from a given set of packages. This is built into go-git v6.
+	case "npm", "pypi", "maven":
+		upstream := h.upstream(platform)
+		u := upstream.JoinPath(r.URL.EscapedPath())
+		u.RawQuery = r.URL.RawQuery
+		r.URL = u
+	case "cargogit":
+		// The "timestamp" is actually a commit hash for the index
+		if !commitHashRegex.MatchString(ts) {
+			return herror{errors.New("invalid commit hash format"), http.StatusBadRequest}
+		}
+		return h.serveCargoGit(rw, r, ts)
 	case "cargogitarchive":
 		// Hard-code the only available endpoint since we only serve the archive
 		if r.URL.Path != "/index.git.tar" {
@@ -182,22 +245,33 @@ func (h Handler) handleRequest(rw http.ResponseWriter, r *http.Request) error {
 	}
 	// Determine whether to reroute the request based on the path structure.
 	{
-		parts := strings.Split(strings.Trim(path.Clean(r.URL.Path), "/"), "/")
+		parts := strings.Split(strings.Trim(path.Clean(reqPath), "/"), "/")
 		switch {
 		// Reference: https://github.com/npm/registry/blob/master/docs/REGISTRY-API.md
 		case platform == "npm" && len(parts) == 1 && parts[0] != "": // /{pkg}
 		case platform == "npm" && len(parts) == 2 && strings.HasPrefix(parts[0], "@"): // /@{org}/{pkg}
 		// Reference: https://warehouse.pypa.io/api-reference/json.html
 		case platform == "pypi" && len(parts) == 3 && parts[0] == "pypi" && parts[2] == "json": // /pypi/{pkg}/json
+		// Reference: https://packaging.python.org/en/latest/specifications/simple-repository-api/
+		case platform == "pypi" && len(parts) == 2 && parts[0] == "simple": // /simple/{pkg}/
+		// Reference: https://maven.apache.org/repositories/metadata.html
+		case platform == "maven" && mavenMetadataPattern.MatchString(parts[len(parts)-1]): // /{group}/{artifact}/maven-metadata.xml[.{checksum}]
 		default:
 			http.Redirect(rw, r, r.URL.String(), http.StatusFound)
 			return nil
 		}
 	}
+	if platform == "maven" {
+		return h.serveMavenMetadata(rw, r, *t)
+	}
+	if platform == "pypi" && strings.HasPrefix(reqPath, "/simple/") {
+		return h.servePyPISimpleProject(rw, r, *t)
+	}
 	// Create a new request based on the provided method, path, and body but
 	// directed at the upstream registry.
 	nr, _ := http.NewRequest(r.Method, r.URL.String(), r.Body)
 	// Configure headers for upstream registry request.
+	var abbreviate bool
 	{
 		nr.Header = r.Header.Clone()
 		// Remove the basic auth header set with the timewarp params.
@@ -208,15 +282,10 @@ func (h Handler) handleRequest(rw http.ResponseWriter, r *http.Request) error {
 		// The application/vnd.npm.install-v1 content type indicates that this must
 		// be an NPM install request. However for NPM API requests, this install-v1
 		// data format does not contain the requisite fields to filter by time. For
-		// these cases, we attempt to downgrade to the more complete
-		// application/json content type if the client allows it.
-		if a := nr.Header.Get("Accept"); strings.Contains(a, "application/vnd.npm.install-v1+json") {
-			if !strings.Contains(a, "application/json") {
-				// TODO: We can support this case by adding a translation from the
-				// application/json response ourselves but current client behavior does
-				// not (yet) require it.
-				return herror{errors.Errorf("unsupported Accept header: %s", a), http.StatusBadGateway}
-			}
+		// these cases, we request the more complete application/json content type
+		// and, if the client doesn't allow it, abbreviate the warped response.
+		if a := nr.Header.Get("Accept"); strings.Contains(a, npmAbbreviatedContentType) {
+			abbreviate = !strings.Contains(a, "application/json")
 			nr.Header.Set("Accept", "application/json")
 		}
 	}
@@ -251,8 +320,12 @@ func (h Handler) handleRequest(rw http.ResponseWriter, r *http.Request) error {
 		if err := timeWarpNPMPackageRequest(obj, *t); err != nil {
 			return herror{errors.Wrap(err, "warping response"), http.StatusBadGateway}
 		}
+		if abbreviate {
+			obj = abbreviateNPMPackageRequest(obj)
+			rw.Header().Set("Content-Type", npmAbbreviatedContentType)
+		}
 	} else if platform == "pypi" && obj["releases"] != nil {
-		if err := timeWarpPyPIProjectRequest(h.Client, obj, *t); err != nil {
+		if err := timeWarpPyPIProjectRequest(h.Client, h.upstream(platform), obj, *t); err != nil {
 			return herror{errors.Wrap(err, "warping response"), http.StatusBadGateway}
 		}
 	}
@@ -334,8 +407,71 @@ func timeWarpNPMPackageRequest(obj map[string]any, at time.Time) error {
 	return nil
 }
 
+// npmAbbreviatedVersionFields are the version fields retained in abbreviated package metadata.
+// Reference: https://github.com/npm/registry/blob/main/docs/responses/package-metadata.md#abbreviated-version-object
+var npmAbbreviatedVersionFields = []string{
+	"name",
+	"version",
+	"deprecated",
+	"dependencies",
+	"optionalDependencies",
+	"devDependencies",
+	"bundleDependencies",
+	"peerDependencies",
+	"peerDependenciesMeta",
+	"bin",
+	"directories",
+	"dist",
+	"engines",
+	"_hasShrinkwrap",
+	"hasInstallScript",
+	"cpu",
+	"os",
+}
+
+// abbreviateNPMPackageRequest returns the abbreviated form of the provided JSON-like package metadata.
+func abbreviateNPMPackageRequest(obj map[string]any) map[string]any {
+	abbreviated := map[string]any{
+		"name":      obj["name"],
+		"dist-tags": obj["dist-tags"],
+	}
+	if times, ok := obj["time"].(map[string]any); ok {
+		abbreviated["modified"] = times["modified"]
+	}
+	versions := make(map[string]any)
+	if vs, ok := obj["versions"].(map[string]any); ok {
+		for v, val := range vs {
+			version, ok := val.(map[string]any)
+			if !ok {
+				continue
+			}
+			av := make(map[string]any)
+			for _, field := range npmAbbreviatedVersionFields {
+				if fv, ok := version[field]; ok {
+					av[field] = fv
+				}
+			}
+			// NOTE: The registry derives hasInstallScript from the install
+			// lifecycle scripts but it's not always present in the full document.
+			if _, ok := av["hasInstallScript"]; !ok {
+				if scripts, ok := version["scripts"].(map[string]any); ok {
+					for _, script := range []string{"preinstall", "install", "postinstall"} {
+						if _, ok := scripts[script]; ok {
+							av["hasInstallScript"] = true
+							break
+						}
+					}
+				}
+			}
+			versions[v] = av
+		}
+	}
+	abbreviated["versions"] = versions
+	return abbreviated
+}
+
 // timeWarpPyPIProjectRequest modifies the provided JSON-like map to exclude all content after "at".
-func timeWarpPyPIProjectRequest(client httpx.BasicClient, obj map[string]any, at time.Time) error {
+func timeWarpPyPIProjectRequest(client httpx.BasicClient, registry *url.URL, obj map[string]any, at time.Time) error {
 	var futureVersions []string
 	var latestVersion string
 	var latestVersionTime time.Time
@@ -393,7 +529,7 @@ func timeWarpPyPIProjectRequest(client httpx.BasicClient, obj map[string]any, at
 		// are actively used by package manager clients for dependency resolution
 		// so we need to make sure it's kept up to date.
 		project := obj["info"].(map[string]any)["name"].(string)
-		versionURL := pypiRegistry.JoinPath("pypi", project, latestVersion, "json")
+		versionURL := registry.JoinPath("pypi", project, latestVersion, "json")
 		req, err := http.NewRequest(http.MethodGet, versionURL.String(), nil)
 		if err != nil {
 			return errors.Wrap(err, "creating request")
@@ -451,56 +587,84 @@ func (h Handler) createCargoIndexArchive(indexCommit string, packageNames []stri
 
 // addPackageToIndex fetches the index file for a package and adds it to the filesystem.
 func (h Handler) addPackageToIndex(fs billy.Filesystem, indexCommit, packageName string) error {
-	indexPath := index.EntryPath(packageName)
-	indexURL := cratesIndexURL.JoinPath(indexCommit, indexPath).String()
+	content, err := h.fetchCargoIndexEntry(indexCommit, packageName)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		return nil // skip missing file
+	}
+	return writeCargoIndexEntry(fs, packageName, content)
+}
+
+// fetchCargoIndexEntry returns the index file for a package at the given commit or nil if it does not exist.
+//
+// Only a 404 response indicates the package does not exist. Other unsuccessful
+// responses are returned as errors so a transient failure is not mistaken for
+// an absent package.
+func (h Handler) fetchCargoIndexEntry(indexCommit, packageName string) ([]byte, error) {
+	indexURL := cratesIndexURL.JoinPath(indexCommit, index.EntryPath(packageName)).String()
 	req, err := http.NewRequest(http.MethodGet, indexURL, nil)
 	if err != nil {
-		return errors.Wrap(err, "creating request")
+		return nil, errors.Wrap(err, "creating request")
 	}
 	resp, err := h.Client.Do(req)
 	if err != nil {
-		return errors.Wrap(err, "fetching index file")
+		return nil, errors.Wrap(err, "fetching index file")
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Package %s not found in index at commit %s (status %d)", packageName, indexCommit, resp.StatusCode)
-		return nil // skip missing file
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		log.Printf("Package %s not found in index at commit %s", packageName, indexCommit)
+		return nil, nil
+	default:
+		return nil, errors.Wrap(errors.New(resp.Status), "fetching index file")
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading index file")
 	}
-	// Ensure directory exists
+	return content, nil
+}
+
+// writeCargoIndexEntry writes the index file for a package to the filesystem.
+func writeCargoIndexEntry(fs billy.Filesystem, packageName string, content []byte) error {
+	indexPath := index.EntryPath(packageName)
 	if err := fs.MkdirAll(path.Dir(indexPath), 0755); err != nil {
 		return errors.Wrap(err, "creating directory")
 	}
-	// Write index file
-	if indexFile, err := fs.Create(indexPath); err != nil {
-		return errors.Wrap(err, "creating index file")
-	} else {
-		defer indexFile.Close()
-		if _, err := io.Copy(indexFile, resp.Body); err != nil {
-			return errors.Wrap(err, "copying index content")
-		}
+	if err := util.WriteFile(fs, indexPath, content, 0644); err != nil {
+		return errors.Wrap(err, "writing index file")
 	}
 	return nil
 }
 
 func makeGitRepo(wfs billy.Filesystem, dotGit billy.Filesystem) error {
 	storer := filesystem.NewStorage(dotGit, cache.NewObjectLRUDefault())
+	_, err := commitGitRepo(storer, wfs, time.Now())
+	return err
+}
+
+// commitGitRepo initializes a repository in storer with a single commit of the contents of wfs.
+func commitGitRepo(storer storage.Storer, wfs billy.Filesystem, when time.Time) (*git.Repository, error) {
 	repo, err := git.Init(storer, wfs)
 	if err != nil {
-		return errors.Wrap(err, "initializing git repository")
+		return nil, errors.Wrap(err, "initializing git repository")
 	}
 	worktree, err := repo.Worktree()
 	if err != nil {
-		return errors.Wrap(err, "getting worktree")
+		return nil, errors.Wrap(err, "getting worktree")
 	}
 	if _, err := worktree.Add("."); err != nil {
-		return errors.Wrap(err, "adding files to git")
+		return nil, errors.Wrap(err, "adding files to git")
 	}
-	signature := &object.Signature{Name: "Timewarp", Email: "timewarp@localhost", When: time.Now()}
+	signature := &object.Signature{Name: "Timewarp", Email: "timewarp@localhost", When: when}
 	_, err = worktree.Commit("Initial cargo index", &git.CommitOptions{Author: signature})
 	if err != nil {
-		return errors.Wrap(err, "creating commit")
+		return nil, errors.Wrap(err, "creating commit")
 	}
-	return nil
+	return repo, nil
 }
 
 // addFSToTar write the fs to the tar archive.
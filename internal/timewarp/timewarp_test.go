@@ -9,12 +9,69 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/google/oss-rebuild/internal/urlx"
 )
 
+const mavenMetadataXML = `<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>com.example</groupId>
+  <artifactId>lib</artifactId>
+  <versioning>
+    <latest>2.0.0</latest>
+    <release>2.0.0</release>
+    <versions>
+      <version>1.0.0</version>
+      <version>1.1.0-SNAPSHOT</version>
+      <version>2.0.0</version>
+    </versions>
+    <lastUpdated>20220601120000</lastUpdated>
+  </versioning>
+</metadata>`
+
+const mavenListingHTML = `<html><body><pre><a href="../">../</a>
+<a href="1.0.0/" title="1.0.0/">1.0.0/</a>                                            2021-06-01 12:00         -
+<a href="1.1.0-SNAPSHOT/" title="1.1.0-SNAPSHOT/">1.1.0-SNAPSHOT/</a>                            2021-09-01 08:30         -
+<a href="2.0.0/" title="2.0.0/">2.0.0/</a>                                            2022-06-01 12:00         -
+<a href="maven-metadata.xml" title="maven-metadata.xml">maven-metadata.xml</a>                                2022-06-01 12:00       420
+</pre></body></html>`
+
+const pypiSimpleProjectJSON = `{
+	"meta": {"api-version": "1.1"},
+	"name": "some-package",
+	"files": [
+		{
+			"filename": "some_package-1.0.0-py3-none-any.whl",
+			"url": "https://files.pythonhosted.org/some_package-1.0.0-py3-none-any.whl",
+			"hashes": {"sha256": "aaaa"},
+			"requires-python": ">=3.7",
+			"core-metadata": {"sha256": "bbbb"},
+			"yanked": false,
+			"size": 100,
+			"upload-time": "2021-06-01T00:00:00.000000Z"
+		},
+		{
+			"filename": "some-package-1.0.0.tar.gz",
+			"url": "https://files.pythonhosted.org/some-package-1.0.0.tar.gz",
+			"hashes": {"md5": "cccc"},
+			"yanked": "broken",
+			"upload-time": "2021-06-01T00:00:01.000000Z"
+		},
+		{
+			"filename": "some_package-2.0.0-py3-none-any.whl",
+			"url": "https://files.pythonhosted.org/some_package-2.0.0-py3-none-any.whl",
+			"hashes": {"sha256": "dddd"},
+			"upload-time": "2022-06-01T00:00:00.000000Z"
+		}
+	],
+	"versions": ["1.0.0", "2.0.0"]
+}`
+
 func TestHandler_ServeHTTP(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -363,6 +420,322 @@ func TestHandler_ServeHTTP(t *testing.T) {
 `)),
 			},
 		},
+		{
+			name:      "maven metadata request - successful time warp",
+			url:       "http://localhost:8081/com/example/lib/maven-metadata.xml",
+			basicAuth: "maven:2022-01-01T00:00:00Z",
+			client: &httpxtest.MockClient{
+				Calls: []httpxtest.Call{
+					{
+						Method: "GET",
+						URL:    "https://repo1.maven.org/maven2/com/example/lib/maven-metadata.xml",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(mavenMetadataXML)),
+						},
+					},
+					{
+						Method: "GET",
+						URL:    "https://repo1.maven.org/maven2/com/example/lib/",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(mavenListingHTML)),
+						},
+					},
+				},
+				URLValidator: httpxtest.NewURLValidator(t),
+			},
+			want: &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type": []string{"text/xml"},
+				},
+				Body: io.NopCloser(bytes.NewBufferString(`<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>com.example</groupId>
+  <artifactId>lib</artifactId>
+  <versioning>
+    <latest>1.1.0-SNAPSHOT</latest>
+    <release>1.0.0</release>
+    <versions>
+      <version>1.0.0</version>
+      <version>1.1.0-SNAPSHOT</version>
+    </versions>
+    <lastUpdated>20210901083000</lastUpdated>
+  </versioning>
+</metadata>
+`)),
+			},
+		},
+		{
+			name:      "maven metadata checksum request - successful time warp",
+			url:       "http://localhost:8081/com/example/lib/maven-metadata.xml.sha1",
+			basicAuth: "maven:2022-01-01T00:00:00Z",
+			client: &httpxtest.MockClient{
+				Calls: []httpxtest.Call{
+					{
+						Method: "GET",
+						URL:    "https://repo1.maven.org/maven2/com/example/lib/maven-metadata.xml",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(mavenMetadataXML)),
+						},
+					},
+					{
+						Method: "GET",
+						URL:    "https://repo1.maven.org/maven2/com/example/lib/",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(mavenListingHTML)),
+						},
+					},
+				},
+				URLValidator: httpxtest.NewURLValidator(t),
+			},
+			want: &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type": []string{"text/plain"},
+				},
+				Body: io.NopCloser(bytes.NewBufferString("f6433a82f04e5f7a43b17451b19582708949a290")),
+			},
+		},
+		{
+			name:      "maven metadata request - created after time warp",
+			url:       "http://localhost:8081/com/example/lib/maven-metadata.xml",
+			basicAuth: "maven:2021-01-01T00:00:00Z",
+			client: &httpxtest.MockClient{
+				Calls: []httpxtest.Call{
+					{
+						Method: "GET",
+						URL:    "https://repo1.maven.org/maven2/com/example/lib/maven-metadata.xml",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(mavenMetadataXML)),
+						},
+					},
+					{
+						Method: "GET",
+						URL:    "https://repo1.maven.org/maven2/com/example/lib/",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(mavenListingHTML)),
+						},
+					},
+				},
+				URLValidator: httpxtest.NewURLValidator(t),
+			},
+			want: &http.Response{
+				StatusCode: http.StatusNotFound,
+				Body:       io.NopCloser(bytes.NewBufferString("no versions before time warp\n")),
+			},
+		},
+		{
+			name:      "maven artifact request - skipped time warp",
+			url:       "http://localhost:8081/com/example/lib/1.0.0/lib-1.0.0.jar",
+			basicAuth: "maven:2022-01-01T00:00:00Z",
+			client: &httpxtest.MockClient{
+				Calls:        []httpxtest.Call{},
+				URLValidator: httpxtest.NewURLValidator(t),
+			},
+			want: &http.Response{
+				StatusCode: http.StatusFound,
+				Body: io.NopCloser(bytes.NewBufferString(`<a href="https://repo1.maven.org/maven2/com/example/lib/1.0.0/lib-1.0.0.jar">Found</a>.
+
+`)),
+			},
+		},
+		{
+			name:   "missing credentials",
+			url:    "http://localhost:8081/com/example/lib/maven-metadata.xml",
+			client: &httpxtest.MockClient{},
+			want: &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Header:     http.Header{"Www-Authenticate": []string{`Basic realm="timewarp"`}},
+				Body:       io.NopCloser(bytes.NewBufferString("no platform set\n")),
+			},
+		},
+		{
+			name:   "missing credentials - artifact challenged",
+			url:    "http://localhost:8081/com/example/lib/1.0.0/lib-1.0.0.pom",
+			client: &httpxtest.MockClient{},
+			want: &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Header:     http.Header{"Www-Authenticate": []string{`Basic realm="timewarp"`}},
+				Body:       io.NopCloser(bytes.NewBufferString("no platform set\n")),
+			},
+		},
+		{
+			name:   "missing credentials - git challenged",
+			url:    "http://localhost:8081/info/refs?service=git-upload-pack",
+			client: &httpxtest.MockClient{},
+			want: &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Header:     http.Header{"Www-Authenticate": []string{`Basic realm="timewarp"`}},
+				Body:       io.NopCloser(bytes.NewBufferString("no platform set\n")),
+			},
+		},
+		{
+			name:      "pypi simple json request - successful time warp",
+			url:       "http://localhost:8081/simple/some-package/",
+			basicAuth: "pypi:2022-01-01T00:00:00Z",
+			headers: map[string]string{
+				"Accept": "application/vnd.pypi.simple.v1+json, application/vnd.pypi.simple.v1+html; q=0.1, text/html; q=0.01",
+			},
+			client: &httpxtest.MockClient{
+				Calls: []httpxtest.Call{
+					{
+						Method: "GET",
+						URL:    "https://pypi.org/simple/some-package/",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(pypiSimpleProjectJSON)),
+						},
+					},
+				},
+				URLValidator: httpxtest.NewURLValidator(t),
+			},
+			want: &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type": []string{"application/vnd.pypi.simple.v1+json"},
+				},
+				Body: io.NopCloser(bytes.NewBufferString(`{"meta":{"api-version":"1.1"},"name":"some-package","files":[` +
+					`{"filename":"some_package-1.0.0-py3-none-any.whl","url":"https://files.pythonhosted.org/some_package-1.0.0-py3-none-any.whl","hashes":{"sha256":"aaaa"},"requires-python":"\u003e=3.7","core-metadata":{"sha256":"bbbb"},"yanked":false,"size":100,"upload-time":"2021-06-01T00:00:00.000000Z"},` +
+					`{"filename":"some-package-1.0.0.tar.gz","url":"https://files.pythonhosted.org/some-package-1.0.0.tar.gz","hashes":{"md5":"cccc"},"yanked":"broken","upload-time":"2021-06-01T00:00:01.000000Z"}` +
+					`],"versions":["1.0.0"]}` + "\n")),
+			},
+		},
+		{
+			name:      "pypi simple html request - successful time warp",
+			url:       "http://localhost:8081/simple/some-package/",
+			basicAuth: "pypi:2022-01-01T00:00:00Z",
+			headers: map[string]string{
+				"Accept": "text/html",
+			},
+			client: &httpxtest.MockClient{
+				Calls: []httpxtest.Call{
+					{
+						Method: "GET",
+						URL:    "https://pypi.org/simple/some-package/",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       io.NopCloser(bytes.NewBufferString(strings.ReplaceAll(pypiSimpleProjectJSON, `"upload-time"`, `"other-time"`))),
+						},
+					},
+					{
+						Method: "GET",
+						URL:    "https://pypi.org/pypi/some-package/json",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body: io.NopCloser(bytes.NewBufferString(`{"releases": {
+								"1.0.0": [
+									{"filename": "some_package-1.0.0-py3-none-any.whl", "upload_time_iso_8601": "2021-06-01T00:00:00.000000Z"},
+									{"filename": "some-package-1.0.0.tar.gz", "upload_time_iso_8601": "2021-06-01T00:00:01.000000Z"}
+								],
+								"2.0.0": [
+									{"filename": "some_package-2.0.0-py3-none-any.whl", "upload_time_iso_8601": "2022-06-01T00:00:00.000000Z"}
+								]
+							}}`)),
+						},
+					},
+				},
+				URLValidator: httpxtest.NewURLValidator(t),
+			},
+			want: &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type": []string{"text/html"},
+				},
+				Body: io.NopCloser(bytes.NewBufferString(`<!DOCTYPE html>
+<html>
+  <head>
+    <meta name="pypi:repository-version" content="1.1">
+    <title>Links for some-package</title>
+  </head>
+  <body>
+    <h1>Links for some-package</h1>
+    <a href="https://files.pythonhosted.org/some_package-1.0.0-py3-none-any.whl#sha256=aaaa" data-requires-python="&gt;=3.7" data-core-metadata="sha256=bbbb">some_package-1.0.0-py3-none-any.whl</a><br />
+    <a href="https://files.pythonhosted.org/some-package-1.0.0.tar.gz#md5=cccc" data-yanked="broken">some-package-1.0.0.tar.gz</a><br />
+  </body>
+</html>
+`)),
+			},
+		},
+		{
+			name:      "npm abbreviated package request - successful time warp",
+			url:       "http://localhost:8081/some-package",
+			basicAuth: "npm:2022-01-01T00:00:00Z",
+			headers: map[string]string{
+				"Accept": "application/vnd.npm.install-v1+json",
+			},
+			client: &httpxtest.MockClient{
+				Calls: []httpxtest.Call{
+					{
+						Method: "GET",
+						URL:    "https://registry.npmjs.org/some-package",
+						Response: &http.Response{
+							StatusCode: http.StatusOK,
+							Header: http.Header{
+								"Content-Type": []string{"application/json"},
+							},
+							Body: io.NopCloser(bytes.NewBufferString(`{
+								"_id": "some-package",
+								"name": "some-package",
+								"readme": "long readme",
+								"time": {
+									"created": "2021-01-01T00:00:00Z",
+									"modified": "2023-01-01T00:00:00Z",
+									"1.0.0": "2021-06-01T00:00:00Z",
+									"2.0.0": "2022-06-01T00:00:00Z"
+								},
+								"versions": {
+									"1.0.0": {
+										"name": "some-package",
+										"version": "1.0.0",
+										"description": "v1 desc",
+										"repository": "repo1",
+										"dependencies": {"dep": "^1.0.0"},
+										"scripts": {"test": "test", "postinstall": "node install.js"},
+										"dist": {"tarball": "https://registry.npmjs.org/some-package/-/some-package-1.0.0.tgz", "integrity": "sha512-aaaa"},
+										"engines": {"node": ">=10"}
+									},
+									"2.0.0": {
+										"name": "some-package",
+										"version": "2.0.0",
+										"dist": {"tarball": "https://registry.npmjs.org/some-package/-/some-package-2.0.0.tgz", "integrity": "sha512-bbbb"}
+									}
+								}
+							}`)),
+						},
+					},
+				},
+				URLValidator: httpxtest.NewURLValidator(t),
+			},
+			want: &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type": []string{"application/vnd.npm.install-v1+json"},
+				},
+				Body: io.NopCloser(bytes.NewBufferString(`{
+					"name": "some-package",
+					"modified": "2021-06-01T00:00:00Z",
+					"dist-tags": {
+						"latest": "1.0.0"
+					},
+					"versions": {
+						"1.0.0": {
+							"name": "some-package",
+							"version": "1.0.0",
+							"dependencies": {"dep": "^1.0.0"},
+							"dist": {"tarball": "https://registry.npmjs.org/some-package/-/some-package-1.0.0.tgz", "integrity": "sha512-aaaa"},
+							"engines": {"node": ">=10"},
+							"hasInstallScript": true
+						}
+					}
+				}`)),
+			},
+		},
 		{
 			name:      "invalid platform",
 			url:       "http://localhost:8081/some-package",
@@ -576,7 +949,7 @@ func TestHandler_ServeHTTP(t *testing.T) {
 				}
 			}
 			// Compare responses
-			if got.Header.Get("Content-Type") == "application/json" {
+			if ct := got.Header.Get("Content-Type"); ct == "application/json" || strings.HasSuffix(ct, "+json") {
 				var gotJSON, wantJSON any
 				if err := json.Unmarshal(gotBody, &gotJSON); err != nil {
 					t.Fatalf("Failed to parse response body: %v", err)
@@ -608,3 +981,95 @@ func TestHandler_ServeHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerUpstreams(t *testing.T) {
+	upstreams := map[string]*url.URL{
+		"npm":  urlx.MustParse("https://artifactory.example.com/api/npm/npm-remote/"),
+		"pypi": urlx.MustParse("https://artifactory.example.com/api/pypi/pypi-remote"),
+	}
+	testCases := []struct {
+		name         string
+		url          string
+		basicAuth    []string
+		calls        []httpxtest.Call
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:      "npm scoped package",
+			url:       "http://localhost:8081/@scope%2fpkg",
+			basicAuth: []string{"npm", "2022-01-01T00:00:00Z"},
+			calls: []httpxtest.Call{
+				{
+					Method: "GET",
+					URL:    "https://artifactory.example.com/api/npm/npm-remote/@scope%2fpkg",
+					Response: &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": []string{"application/json"}},
+						Body:       httpxtest.Body(npmPackumentJSON),
+					},
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "npm tarball redirect",
+			url:          "http://localhost:8081/pkg/-/pkg-1.0.0.tgz",
+			basicAuth:    []string{"npm", "2022-01-01T00:00:00Z"},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://artifactory.example.com/api/npm/npm-remote/pkg/-/pkg-1.0.0.tgz",
+		},
+		{
+			name:      "pypi project",
+			url:       "http://localhost:8081/pypi/pkg/json",
+			basicAuth: []string{"pypi", "2022-01-01T00:00:00Z"},
+			calls: []httpxtest.Call{
+				{
+					Method: "GET",
+					URL:    "https://artifactory.example.com/api/pypi/pypi-remote/pypi/pkg/json",
+					Response: &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Content-Type": []string{"application/json"}},
+						Body: httpxtest.Body(`{"info": {"name": "pkg"}, "releases": {
+							"1.0.0": [{"upload_time_iso_8601": "2021-01-01T00:00:00Z"}],
+							"2.0.0": [{"upload_time_iso_8601": "2023-01-01T00:00:00Z"}]}}`),
+					},
+				},
+				{
+					Method: "GET",
+					URL:    "https://artifactory.example.com/api/pypi/pypi-remote/pypi/pkg/1.0.0/json",
+					Response: &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       httpxtest.Body(`{"info": {"name": "pkg", "version": "1.0.0"}}`),
+					},
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "maven default upstream",
+			url:          "http://localhost:8081/com/example/lib/1.0.0/lib-1.0.0.jar",
+			basicAuth:    []string{"maven", "2022-01-01T00:00:00Z"},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://repo1.maven.org/maven2/com/example/lib/1.0.0/lib-1.0.0.jar",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &httpxtest.MockClient{Calls: tc.calls, URLValidator: httpxtest.NewURLValidator(t)}
+			req := httptest.NewRequest("GET", tc.url, nil)
+			req.SetBasicAuth(tc.basicAuth[0], tc.basicAuth[1])
+			rr := httptest.NewRecorder()
+			Handler{Client: client, Upstreams: upstreams}.ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tc.wantStatus, rr.Body.String())
+			}
+			if got := rr.Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tc.wantLocation)
+			}
+			if client.CallCount() != len(tc.calls) {
+				t.Errorf("upstream calls = %d, want %d", client.CallCount(), len(tc.calls))
+			}
+		})
+	}
+}
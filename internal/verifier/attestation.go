@@ -20,7 +20,9 @@ import (
 )
 
 // CreateAttestations creates the SLSA attestations associated with a rebuild.
-func CreateAttestations(ctx context.Context, t rebuild.Target, defn *schema.BuildDefinition, strategy rebuild.Strategy, id string, rb, up ArtifactSummary, metadata rebuild.AssetStore, serviceLoc, prebuildLoc, buildDefLoc rebuild.Location, prebuildConfig rebuild.PrebuildConfig) (equivalence, build *in_toto.ProvenanceStatementSLSA1, err error) {
+//
+// The registry is the URL of the non-public registry serving t, if any.
+func CreateAttestations(ctx context.Context, t rebuild.Target, registry string, defn *schema.BuildDefinition, strategy rebuild.Strategy, id string, rb, up ArtifactSummary, metadata rebuild.AssetStore, serviceLoc, prebuildLoc, buildDefLoc rebuild.Location, prebuildConfig rebuild.PrebuildConfig) (equivalence, build *in_toto.ProvenanceStatementSLSA1, err error) {
 	var dockerfile []byte
 	{
 		r, err := metadata.Reader(ctx, rebuild.DockerfileAsset.For(t))
@@ -88,18 +90,9 @@ func CreateAttestations(ctx context.Context, t rebuild.Target, defn *schema.Buil
 		return nil, nil, err
 	}
 	var deps attestation.RebuildDeps
-	var loc rebuild.Location
-	{
-		// NOTE: Workaround the lack of a proper means of accessing Location on Strategy.
-		// A timewarp host value is required to not break TimewarpURLFromString calls.
-		inst, err := strategy.GenerateFor(t, rebuild.BuildEnv{TimewarpHost: "example.internal"})
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "retrieving repo")
-		}
-		loc = inst.Location
-	}
-	if loc.Ref != "" {
-		deps.Source = &slsa1.ResourceDescriptor{Name: "git+" + loc.Repo, Digest: GitDigestSet(loc)}
+	deps.Source, err = sourceDescriptor(t, strategy)
+	if err != nil {
+		return nil, nil, err
 	}
 	for n, s := range buildInfo.BuildImages {
 		if !strings.HasPrefix(s, "sha256:") {
@@ -126,6 +119,7 @@ func CreateAttestations(ctx context.Context, t rebuild.Target, defn *schema.Buil
 		Package:   t.Package,
 		Version:   t.Version,
 		Artifact:  t.Artifact,
+		Registry:  registry,
 	}
 	// Only add manual strategy field if it was used.
 	if defn != nil {
@@ -171,6 +165,88 @@ func CreateAttestations(ctx context.Context, t rebuild.Target, defn *schema.Buil
 	return eqStmt, stmt, nil
 }
 
+// sourceDescriptor returns the descriptor of the source repo used by strategy, if any.
+func sourceDescriptor(t rebuild.Target, strategy rebuild.Strategy) (*slsa1.ResourceDescriptor, error) {
+	// NOTE: Workaround the lack of a proper means of accessing Location on Strategy.
+	// A timewarp host value is required to not break TimewarpURLFromString calls.
+	inst, err := strategy.GenerateFor(t, rebuild.BuildEnv{TimewarpHost: "example.internal"})
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving repo")
+	}
+	loc := inst.Location
+	if loc.Ref == "" {
+		return nil, nil
+	}
+	return &slsa1.ResourceDescriptor{Name: "git+" + loc.Repo, Digest: GitDigestSet(loc)}, nil
+}
+
+// CreateFailureAttestation creates the attestation recording a failed rebuild.
+//
+// The registry is the URL of the non-public registry serving t, if any. The
+// strategy may be nil if the failure occurred before one was determined.
+func CreateFailureAttestation(t rebuild.Target, registry string, defn *schema.BuildDefinition, strategy rebuild.Strategy, id string, up ArtifactSummary, details attestation.RebuildFailureDetails, serviceLoc, prebuildLoc, buildDefLoc rebuild.Location, prebuildConfig rebuild.PrebuildConfig) (*attestation.RebuildFailureAttestation, error) {
+	externalParams := attestation.RebuildParams{
+		Ecosystem: string(t.Ecosystem),
+		Package:   t.Package,
+		Version:   t.Version,
+		Artifact:  t.Artifact,
+		Registry:  registry,
+	}
+	var deps attestation.RebuildDeps
+	var byproducts attestation.RebuildFailureByproducts
+	if strategy != nil {
+		var err error
+		deps.Source, err = sourceDescriptor(t, strategy)
+		if err != nil {
+			return nil, err
+		}
+		strategyBytes, err := json.Marshal(schema.NewStrategyOneOf(strategy))
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling Strategy")
+		}
+		byproducts.BuildStrategy = &slsa1.ResourceDescriptor{Name: attestation.ByproductBuildStrategy, Content: strategyBytes}
+	}
+	if defn != nil {
+		rawDefinition, err := json.Marshal(*defn)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling build definition")
+		}
+		deps.BuildFix = &slsa1.ResourceDescriptor{Name: attestation.DependencyBuildFix, Content: rawDefinition}
+		src := attestation.SourceLocationFromLocation(buildDefLoc)
+		externalParams.BuildConfigSource = &src
+	}
+	detailsBytes, err := json.Marshal(details)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling failure details")
+	}
+	byproducts.Failure = slsa1.ResourceDescriptor{Name: attestation.ByproductFailure, Content: detailsBytes}
+	return &attestation.RebuildFailureAttestation{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV1,
+			Subject:       []in_toto.Subject{{Name: t.Artifact, Digest: makeDigestSet(up.Hash...)}},
+			PredicateType: attestation.PredicateRebuildFailureV01,
+		},
+		Predicate: attestation.RebuildFailurePredicate{
+			BuildDefinition: attestation.RebuildFailureBuildDef{
+				BuildType:          attestation.BuildTypeRebuildFailureV01,
+				ExternalParameters: externalParams,
+				InternalParameters: attestation.ServiceInternalParams{
+					ServiceSource:  attestation.SourceLocationFromLocation(serviceLoc),
+					PrebuildSource: attestation.SourceLocationFromLocation(prebuildLoc),
+					PrebuildConfig: prebuildConfig,
+				},
+				ResolvedDependencies: deps,
+			},
+			RunDetails: attestation.RebuildFailureRunDetails{
+				// TODO: Make the host configurable.
+				Builder:       slsa1.Builder{ID: attestation.HostGoogle},
+				BuildMetadata: slsa1.BuildMetadata{InvocationID: id},
+				Byproducts:    byproducts,
+			},
+		},
+	}, nil
+}
+
 func checkClose(closer io.Closer) {
 	if err := closer.Close(); err != nil {
 		panic(errors.Wrap(err, "deferred close failed"))
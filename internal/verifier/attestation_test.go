@@ -14,6 +14,7 @@ import (
 	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/internal/hashext"
+	"github.com/google/oss-rebuild/pkg/attestation"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/google/oss-rebuild/pkg/rebuild/schema"
 	"google.golang.org/api/cloudbuild/v1"
@@ -70,7 +71,7 @@ func TestCreateAttestations(t *testing.T) {
 		serviceLoc := rebuild.Location{Repo: "https://github.com/google/oss-rebuild", Ref: "v0.0.0-202501010000-feeddeadbeef00"}
 		prebuildLoc := rebuild.Location{Repo: "https://github.com/google/oss-rebuild", Ref: "v0.0.0-202401010000-feeddeadbeef99"}
 		buildDefLoc := rebuild.Location{Repo: "https://github.com/google/oss-rebuild", Ref: "b33eec7134eff8a16cb902b80e434de58bf37e2c", Dir: "definitions/cratesio/bytes/1.0.0/bytes-1.0.0.crate/build.yaml"}
-		eqStmt, buildStmt, err := CreateAttestations(ctx, target, &defn, strategy, "test-id", rbSummary, upSummary, metadata, serviceLoc, prebuildLoc, buildDefLoc, rebuild.PrebuildConfig{Bucket: "test-bucket", Dir: "test-dir", Auth: true})
+		eqStmt, buildStmt, err := CreateAttestations(ctx, target, "", &defn, strategy, "test-id", rbSummary, upSummary, metadata, serviceLoc, prebuildLoc, buildDefLoc, rebuild.PrebuildConfig{Bucket: "test-bucket", Dir: "test-dir", Auth: true})
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
@@ -237,3 +238,50 @@ func TestCreateAttestations(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateFailureAttestation(t *testing.T) {
+	target := rebuild.Target{Ecosystem: rebuild.CratesIO, Package: "bytes", Version: "1.0.0", Artifact: "bytes-1.0.0.crate"}
+	upSummary := ArtifactSummary{
+		URI:            "https://up.stream/bytes-1.0.0.crate",
+		Hash:           hashext.NewMultiHash(crypto.SHA256),
+		StabilizedHash: hashext.NewMultiHash(crypto.SHA256),
+	}
+	serviceLoc := rebuild.Location{Repo: "https://github.com/google/oss-rebuild", Ref: "v0.0.0-202501010000-feeddeadbeef00"}
+	prebuildLoc := rebuild.Location{Repo: "https://github.com/google/oss-rebuild", Ref: "v0.0.0-202401010000-feeddeadbeef99"}
+	details := attestation.RebuildFailureDetails{Reason: attestation.FailureReasonBuild, Message: "executing rebuild: exit 1"}
+	for _, tc := range []struct {
+		name     string
+		strategy rebuild.Strategy
+	}{
+		{name: "WithStrategy", strategy: &rebuild.ManualStrategy{Location: rebuild.Location{Repo: "http://github.com/foo/bar", Ref: "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33"}, Deps: "echo deps", Build: "echo build", OutputPath: "foo/bar"}},
+		{name: "WithoutStrategy"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stmt, err := CreateFailureAttestation(target, "", nil, tc.strategy, "test-id", upSummary, details, serviceLoc, prebuildLoc, rebuild.Location{}, rebuild.PrebuildConfig{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if stmt.Subject[0].Name != target.Artifact {
+				t.Errorf("Subject name = %s, want %s", stmt.Subject[0].Name, target.Artifact)
+			}
+			if stmt.PredicateType != attestation.PredicateRebuildFailureV01 {
+				t.Errorf("PredicateType = %s, want %s", stmt.PredicateType, attestation.PredicateRebuildFailureV01)
+			}
+			if stmt.Predicate.BuildDefinition.BuildType != attestation.BuildTypeRebuildFailureV01 {
+				t.Errorf("BuildType = %s", stmt.Predicate.BuildDefinition.BuildType)
+			}
+			var fa attestation.RebuildFailureAttestation
+			orDie(json.Unmarshal(must(json.Marshal(stmt)), &fa))
+			got := must(fa.Predicate.RunDetails.Byproducts.Details())
+			if diff := cmp.Diff(&details, got); diff != "" {
+				t.Errorf("Details mismatch (-want +got):\n%s", diff)
+			}
+			if (fa.Predicate.RunDetails.Byproducts.BuildStrategy != nil) != (tc.strategy != nil) {
+				t.Errorf("BuildStrategy presence mismatch")
+			}
+			if (fa.Predicate.BuildDefinition.ResolvedDependencies.Source != nil) != (tc.strategy != nil) {
+				t.Errorf("Source presence mismatch")
+			}
+		})
+	}
+}
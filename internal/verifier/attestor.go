@@ -8,17 +8,39 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"time"
 
+	"github.com/google/oss-rebuild/pkg/attestation"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/pkg/errors"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
 )
 
+// VSAConfig configures the publication of a Verification Summary Attestation alongside a bundle.
+type VSAConfig struct {
+	// Verifier checks the signatures of the published bundle before it is summarized.
+	Verifier *dsse.EnvelopeVerifier
+	// VerifierID identifies the entity issuing the VSA.
+	VerifierID string
+	// VerifiedLevels are the levels asserted for a published bundle.
+	VerifiedLevels []string
+}
+
 // Attestor is a verifier that signs and publishes attestation bundles.
 type Attestor struct {
 	Store          rebuild.AssetStore
 	Signer         InTotoEnvelopeSigner
 	AllowOverwrite bool
+	// Verifier checks the signatures of stored revocations before they are
+	// trusted. It is required to act on a bundle with a revocation.
+	Verifier *dsse.EnvelopeVerifier
+	// VSA, if provided, enables publication of a VSA with each bundle.
+	VSA *VSAConfig
+	// Log, if provided, records each bundle in a transparency log.
+	Log TransparencyLog
+	// Index, if provided, records the digests of each bundle's subjects.
+	Index rebuild.DigestIndex
 }
 
 // BundleExists returns whether an existing attestation bundle exists.
@@ -35,11 +57,31 @@ func (a Attestor) BundleExists(ctx context.Context, t rebuild.Target) (bool, err
 }
 
 // PublishBundle signs and publishes an attestation bundle.
+//
+// A revoked bundle may always be replaced, in which case the revocation is
+// updated to link to the superseding bundle. Any failure attestation for the
+// target is deleted if the Store supports it. The index entries for subjects of
+// a replaced bundle that are absent from the new one are removed.
+//
+// The bundle itself is written last so that, if any step fails, no new bundle
+// is in place and the publication may be retried.
 func (a Attestor) PublishBundle(ctx context.Context, t rebuild.Target, stmts ...*in_toto.ProvenanceStatementSLSA1) error {
+	var revocation *attestation.Revocation
+	var replaced []byte
 	if exists, err := a.BundleExists(ctx, t); err != nil {
 		return errors.Wrap(err, "checking for existing bundle")
-	} else if exists && !a.AllowOverwrite {
-		return errors.New("bundle already exists")
+	} else if exists {
+		revocation, err = a.currentRevocation(ctx, t)
+		if err != nil {
+			return errors.Wrap(err, "checking for revocation")
+		}
+		if revocation == nil && !a.AllowOverwrite {
+			return errors.New("bundle already exists")
+		}
+		replaced, err = readAll(ctx, a.Store, rebuild.AttestationBundleAsset.For(t))
+		if err != nil {
+			return errors.Wrap(err, "reading existing bundle")
+		}
 	}
 	bundle := bytes.NewBuffer(nil)
 	e := json.NewEncoder(bundle)
@@ -52,15 +94,306 @@ func (a Attestor) PublishBundle(ctx context.Context, t rebuild.Target, stmts ...
 			return errors.Wrap(err, "marshalling DSSE")
 		}
 	}
-	w, err := a.Store.Writer(ctx, rebuild.AttestationBundleAsset.For(t))
+	bundleBytes := bundle.Bytes()
+	if a.Log != nil {
+		if err := a.logBundle(ctx, t, bundleBytes); err != nil {
+			return errors.Wrap(err, "logging bundle")
+		}
+	}
+	if a.VSA != nil {
+		// Publication implies the bundle satisfied the service's verification policy.
+		outcome := attestation.PolicyOutcome{Passed: true, VerifiedLevels: a.VSA.VerifiedLevels}
+		if err := a.publishVSA(ctx, t, bundleBytes, outcome); err != nil {
+			return errors.Wrap(err, "publishing VSA")
+		}
+	}
+	if a.Index != nil {
+		if err := a.indexBundle(ctx, t, stmts, replaced); err != nil {
+			return errors.Wrap(err, "indexing bundle")
+		}
+	}
+	if ds, ok := a.Store.(rebuild.DeletableAssetStore); ok {
+		// A failure recorded by an earlier attempt no longer describes the target.
+		if err := ds.Delete(ctx, rebuild.RebuildFailureAsset.For(t)); err != nil && !errors.Is(err, rebuild.ErrAssetNotFound) {
+			return errors.Wrap(err, "deleting failure attestation")
+		}
+	}
+	if revocation != nil {
+		// NOTE: The revocation continues to apply to the replaced bundle until
+		// the new one is written so a failed write may still be retried.
+		revocation.Supersede(bundleBytes, a.assetURI(rebuild.AttestationBundleAsset.For(t)))
+		if err := a.writeRevocation(ctx, t, revocation); err != nil {
+			return errors.Wrap(err, "linking superseding bundle")
+		}
+	}
+	if err := a.write(ctx, rebuild.AttestationBundleAsset.For(t), bundleBytes); err != nil {
+		return errors.Wrap(err, "writing bundle")
+	}
+	return nil
+}
+
+// PublishFailure signs and publishes an attestation of a failed rebuild.
+//
+// Failures are always overwritten by later attempts but never published
+// alongside an existing bundle.
+func (a Attestor) PublishFailure(ctx context.Context, t rebuild.Target, fa *attestation.RebuildFailureAttestation) error {
+	if exists, err := a.BundleExists(ctx, t); err != nil {
+		return errors.Wrap(err, "checking for existing bundle")
+	} else if exists {
+		return errors.New("bundle already exists")
+	}
+	stmt, err := fa.ToStatement()
+	if err != nil {
+		return errors.Wrap(err, "converting attestation")
+	}
+	envelope, err := a.Signer.SignGenericStatement(ctx, stmt)
+	if err != nil {
+		return errors.Wrap(err, "signing attestation")
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(envelope); err != nil {
+		return errors.Wrap(err, "marshalling DSSE")
+	}
+	return a.write(ctx, rebuild.RebuildFailureAsset.For(t), buf.Bytes())
+}
+
+// indexBundle records the sha256 digest of each subject in the bundle as
+// resolving to t and removes those of the replaced bundle, if any, that no
+// longer do.
+func (a Attestor) indexBundle(ctx context.Context, t rebuild.Target, stmts []*in_toto.ProvenanceStatementSLSA1, replaced []byte) error {
+	current := make(map[string]bool)
+	for _, stmt := range stmts {
+		for _, digest := range subjectDigests(stmt.StatementHeader) {
+			if current[digest] {
+				continue
+			}
+			current[digest] = true
+			if err := a.Index.Add(ctx, "sha256:"+digest, t); err != nil {
+				return errors.Wrapf(err, "adding sha256:%s", digest)
+			}
+		}
+	}
+	if replaced == nil {
+		return nil
+	}
+	d := json.NewDecoder(bytes.NewReader(replaced))
+	for d.More() {
+		var env dsse.Envelope
+		if err := d.Decode(&env); err != nil {
+			return errors.Wrap(err, "decoding replaced bundle")
+		}
+		payload, err := env.DecodeB64Payload()
+		if err != nil {
+			return errors.Wrap(err, "decoding replaced bundle payload")
+		}
+		var header in_toto.StatementHeader
+		if err := json.Unmarshal(payload, &header); err != nil {
+			return errors.Wrap(err, "decoding replaced bundle statement")
+		}
+		for _, digest := range subjectDigests(header) {
+			if current[digest] {
+				continue
+			}
+			// Mark as handled to avoid repeated removals.
+			current[digest] = true
+			if err := a.Index.Remove(ctx, "sha256:"+digest, t); err != nil {
+				return errors.Wrapf(err, "removing sha256:%s", digest)
+			}
+		}
+	}
+	return nil
+}
+
+// subjectDigests returns the sha256 digests of the statement's subjects.
+func subjectDigests(h in_toto.StatementHeader) []string {
+	var digests []string
+	for _, s := range h.Subject {
+		if digest, ok := s.Digest["sha256"]; ok {
+			digests = append(digests, digest)
+		}
+	}
+	return digests
+}
+
+// logBundle appends the bundle to the transparency log and stores the inclusion proof next to it.
+func (a Attestor) logBundle(ctx context.Context, t rebuild.Target, bundleBytes []byte) error {
+	proof, err := a.Log.Append(ctx, bundleBytes)
+	if err != nil {
+		return errors.Wrap(err, "appending to log")
+	}
+	if err := verifyEntryInclusion(proof, bundleBytes); err != nil {
+		return errors.Wrap(err, "verifying inclusion proof")
+	}
+	b, err := json.Marshal(proof)
+	if err != nil {
+		return errors.Wrap(err, "marshalling proof")
+	}
+	return a.write(ctx, rebuild.TransparencyLogProofAsset.For(t), b)
+}
+
+// publishVSA summarizes the bundle and its policy outcome as a VSA and writes it to the store.
+func (a Attestor) publishVSA(ctx context.Context, t rebuild.Target, bundleBytes []byte, outcome attestation.PolicyOutcome) error {
+	b, err := attestation.NewBundle(ctx, bundleBytes, a.VSA.Verifier)
+	if err != nil {
+		return errors.Wrap(err, "verifying bundle")
+	}
+	bundleURI := a.assetURI(rebuild.AttestationBundleAsset.For(t))
+	vsa, err := attestation.NewVSA(b, outcome, attestation.VSAOptions{
+		Verifier:  attestation.VSAVerifier{ID: a.VSA.VerifierID},
+		BundleURI: bundleURI,
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating VSA")
+	}
+	stmt, err := vsa.ToStatement()
+	if err != nil {
+		return errors.Wrap(err, "converting VSA")
+	}
+	envelope, err := a.Signer.SignGenericStatement(ctx, stmt)
+	if err != nil {
+		return errors.Wrap(err, "signing VSA")
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(envelope); err != nil {
+		return errors.Wrap(err, "marshalling DSSE")
+	}
+	return a.write(ctx, rebuild.VSAAsset.For(t), buf.Bytes())
+}
+
+// Revoke signs and publishes a revocation of the existing attestation bundle.
+//
+// The bundle's VSA is replaced with a failed one, or deleted if VSA publication
+// is not configured and the Store supports it, and the index entries for the
+// bundle's subjects are removed. These are updated before the revocation is
+// written so that a partially applied revocation may be retried.
+func (a Attestor) Revoke(ctx context.Context, t rebuild.Target, reason string) (*attestation.Revocation, error) {
+	bundleBytes, err := readAll(ctx, a.Store, rebuild.AttestationBundleAsset.For(t))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading bundle")
+	}
+	if existing, err := a.currentRevocation(ctx, t); err != nil {
+		return nil, errors.Wrap(err, "checking for revocation")
+	} else if existing != nil {
+		return nil, errors.New("bundle already revoked")
+	}
+	if a.VSA != nil {
+		if err := a.publishVSA(ctx, t, bundleBytes, attestation.PolicyOutcome{Passed: false}); err != nil {
+			return nil, errors.Wrap(err, "publishing failed VSA")
+		}
+	} else if ds, ok := a.Store.(rebuild.DeletableAssetStore); ok {
+		if err := ds.Delete(ctx, rebuild.VSAAsset.For(t)); err != nil && !errors.Is(err, rebuild.ErrAssetNotFound) {
+			return nil, errors.Wrap(err, "deleting VSA")
+		}
+	}
+	if a.Index != nil {
+		// Treat the revoked bundle as replaced by an empty one to remove all its entries.
+		if err := a.indexBundle(ctx, t, nil, bundleBytes); err != nil {
+			return nil, errors.Wrap(err, "removing index entries")
+		}
+	}
+	revocation := attestation.NewRevocation(bundleBytes, a.assetURI(rebuild.AttestationBundleAsset.For(t)), reason, time.Now())
+	if err := a.writeRevocation(ctx, t, revocation); err != nil {
+		return nil, err
+	}
+	return revocation, nil
+}
+
+// BundleRevoked returns whether the existing attestation bundle has been revoked.
+func (a Attestor) BundleRevoked(ctx context.Context, t rebuild.Target) (bool, error) {
+	revocation, err := a.currentRevocation(ctx, t)
+	return revocation != nil, err
+}
+
+// currentRevocation returns the stored revocation if it applies to the current bundle.
+//
+// The revocation's signature is checked with the Attestor's Verifier so a
+// record placed in the store by another party cannot unlock an overwrite.
+func (a Attestor) currentRevocation(ctx context.Context, t rebuild.Target) (*attestation.Revocation, error) {
+	data, err := readAll(ctx, a.Store, rebuild.RevocationAsset.For(t))
+	if errors.Is(err, rebuild.ErrAssetNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if a.Verifier == nil {
+		return nil, errors.New("no verifier configured to check revocation")
+	}
+	revocation, err := attestation.NewVerifiedRevocation(ctx, data, a.Verifier)
+	if err != nil {
+		return nil, errors.Wrap(err, "verifying revocation")
+	}
+	bundleBytes, err := readAll(ctx, a.Store, rebuild.AttestationBundleAsset.For(t))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading bundle")
+	}
+	if !revocation.Revokes(bundleBytes) {
+		return nil, nil
+	}
+	return revocation, nil
+}
+
+func (a Attestor) writeRevocation(ctx context.Context, t rebuild.Target, revocation *attestation.Revocation) error {
+	stmt, err := revocation.ToStatement()
+	if err != nil {
+		return errors.Wrap(err, "converting revocation")
+	}
+	envelope, err := a.Signer.SignGenericStatement(ctx, stmt)
+	if err != nil {
+		return errors.Wrap(err, "signing revocation")
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(envelope); err != nil {
+		return errors.Wrap(err, "marshalling DSSE")
+	}
+	return a.write(ctx, rebuild.RevocationAsset.For(t), buf.Bytes())
+}
+
+func (a Attestor) assetURI(asset rebuild.Asset) string {
+	if ls, ok := a.Store.(rebuild.LocatableAssetStore); ok {
+		if u := ls.URL(asset); u != nil {
+			return u.String()
+		}
+	}
+	return string(asset.Type)
+}
+
+// CheckRevocation returns the published revocation of the provided bundle contents, if any.
+func CheckRevocation(ctx context.Context, store rebuild.ReadOnlyAssetStore, t rebuild.Target, bundleBytes []byte, verifier *dsse.EnvelopeVerifier) (*attestation.Revocation, error) {
+	data, err := readAll(ctx, store, rebuild.RevocationAsset.For(t))
+	if errors.Is(err, rebuild.ErrAssetNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading revocation")
+	}
+	revocation, err := attestation.NewVerifiedRevocation(ctx, data, verifier)
+	if err != nil {
+		return nil, errors.Wrap(err, "verifying revocation")
+	}
+	if !revocation.Revokes(bundleBytes) {
+		return nil, nil
+	}
+	return revocation, nil
+}
+
+func readAll(ctx context.Context, store rebuild.ReadOnlyAssetStore, asset rebuild.Asset) ([]byte, error) {
+	r, err := store.Reader(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (a Attestor) write(ctx context.Context, asset rebuild.Asset, data []byte) error {
+	w, err := a.Store.Writer(ctx, asset)
 	if err != nil {
-		return errors.Wrap(err, "creating writer for bundle")
+		return errors.Wrap(err, "creating writer")
 	}
-	if _, err := io.Copy(w, bundle); err != nil {
-		return errors.Wrap(err, "uploading bundle")
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		return errors.Wrap(err, "uploading")
 	}
 	if err := w.Close(); err != nil {
-		return errors.Wrap(err, "closing bundle upload")
+		return errors.Wrap(err, "closing upload")
 	}
 	return nil
 }
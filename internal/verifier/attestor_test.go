@@ -0,0 +1,216 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package verifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/pkg/attestation"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+func TestAttestorRevoke(t *testing.T) {
+	ctx := context.Background()
+	target := rebuild.Target{Ecosystem: rebuild.NPM, Package: "pkg", Version: "1.0.0", Artifact: "pkg-1.0.0.tgz"}
+	sv := &ecdsaTestSigner{must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))}
+	store := rebuild.NewFilesystemAssetStore(memfs.New())
+	dsseVerifier := must(dsse.NewEnvelopeVerifier(sv))
+	idx := rebuild.NewFilesystemDigestIndex(memfs.New())
+	a := Attestor{
+		Store:    store,
+		Signer:   InTotoEnvelopeSigner{must(dsse.NewEnvelopeSigner(sv))},
+		Verifier: dsseVerifier,
+		VSA:      &VSAConfig{Verifier: dsseVerifier, VerifierID: "https://verifier.example"},
+		Index:    idx,
+	}
+	stmt := func(digest string) *in_toto.ProvenanceStatementSLSA1 {
+		return &in_toto.ProvenanceStatementSLSA1{
+			StatementHeader: in_toto.StatementHeader{
+				Type:          in_toto.StatementInTotoV1,
+				PredicateType: slsa1.PredicateSLSAProvenance,
+				Subject:       []in_toto.Subject{{Name: target.Artifact, Digest: common.DigestSet{"sha256": digest}}},
+			},
+			Predicate: slsa1.ProvenancePredicate{BuildDefinition: slsa1.ProvenanceBuildDefinition{BuildType: attestation.BuildTypeArtifactEquivalenceV01}},
+		}
+	}
+	vsaResult := func() string {
+		b := must(attestation.NewBundle(ctx, must(readAll(ctx, store, rebuild.VSAAsset.For(target))), dsseVerifier))
+		vsa := must(attestation.FilterForOne[attestation.VerificationSummaryAttestation](b, attestation.WithPredicateType(attestation.PredicateSLSAVerificationSummaryV1)))
+		return vsa.Predicate.VerificationResult
+	}
+	originalDigest, replacementDigest := strings.Repeat("ab", 32), strings.Repeat("cd", 32)
+	if _, err := a.Revoke(ctx, target, "bad strategy"); err == nil {
+		t.Fatal("Revoke() without bundle succeeded")
+	}
+	orDie(a.PublishBundle(ctx, target, stmt(originalDigest)))
+	if got := vsaResult(); got != attestation.VerificationResultPassed {
+		t.Fatalf("VSA result = %s, want %s", got, attestation.VerificationResultPassed)
+	}
+	original := must(readAll(ctx, store, rebuild.AttestationBundleAsset.For(target)))
+	if r, err := CheckRevocation(ctx, store, target, original, dsseVerifier); err != nil || r != nil {
+		t.Fatalf("CheckRevocation() = %v, %v; want nil, nil", r, err)
+	}
+	if err := a.PublishBundle(ctx, target, stmt(replacementDigest)); err == nil {
+		t.Fatal("PublishBundle() over unrevoked bundle succeeded")
+	}
+	if _, err := a.Revoke(ctx, target, "bad strategy"); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err := idx.Lookup(ctx, "sha256:"+originalDigest); !errors.Is(err, rebuild.ErrDigestNotFound) {
+		t.Errorf("Lookup(revoked) error = %v, want ErrDigestNotFound", err)
+	}
+	if got := vsaResult(); got != attestation.VerificationResultFailed {
+		t.Errorf("VSA result after Revoke() = %s, want %s", got, attestation.VerificationResultFailed)
+	}
+	if _, err := a.Revoke(ctx, target, "bad strategy"); err == nil {
+		t.Error("Revoke() of revoked bundle succeeded")
+	}
+	r, err := CheckRevocation(ctx, store, target, original, dsseVerifier)
+	if err != nil {
+		t.Fatalf("CheckRevocation() error = %v", err)
+	}
+	if r == nil || r.Predicate.Reason != "bad strategy" || r.Predicate.SupersededBy != nil {
+		t.Fatalf("CheckRevocation() = %+v", r)
+	}
+	// A revoked bundle may be superseded without AllowOverwrite.
+	orDie(a.PublishBundle(ctx, target, stmt(replacementDigest)))
+	if got := vsaResult(); got != attestation.VerificationResultPassed {
+		t.Errorf("VSA result after replacement = %s, want %s", got, attestation.VerificationResultPassed)
+	}
+	if _, err := idx.Lookup(ctx, "sha256:"+replacementDigest); err != nil {
+		t.Errorf("Lookup(replacement) error = %v", err)
+	}
+	replacement := must(readAll(ctx, store, rebuild.AttestationBundleAsset.For(target)))
+	if r, err := CheckRevocation(ctx, store, target, replacement, dsseVerifier); err != nil || r != nil {
+		t.Fatalf("CheckRevocation(replacement) = %v, %v; want nil, nil", r, err)
+	}
+	r, err = CheckRevocation(ctx, store, target, original, dsseVerifier)
+	if err != nil {
+		t.Fatalf("CheckRevocation(original) error = %v", err)
+	}
+	if r == nil || !r.IsSupersededBy(replacement) {
+		t.Errorf("CheckRevocation(original) = %+v, want superseded by replacement", r)
+	}
+}
+
+func TestAttestorIndex(t *testing.T) {
+	ctx := context.Background()
+	target := rebuild.Target{Ecosystem: rebuild.NPM, Package: "pkg", Version: "1.0.0", Artifact: "pkg-1.0.0.tgz"}
+	sv := &ecdsaTestSigner{must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))}
+	idx := rebuild.NewFilesystemDigestIndex(memfs.New())
+	a := Attestor{Store: rebuild.NewFilesystemAssetStore(memfs.New()), Signer: InTotoEnvelopeSigner{must(dsse.NewEnvelopeSigner(sv))}, Index: idx}
+	digest := strings.Repeat("ab", 32)
+	subject := []in_toto.Subject{{Name: target.Artifact, Digest: common.DigestSet{"sha256": digest}}}
+	stmts := []*in_toto.ProvenanceStatementSLSA1{
+		{StatementHeader: in_toto.StatementHeader{Type: in_toto.StatementInTotoV1, PredicateType: slsa1.PredicateSLSAProvenance, Subject: subject}},
+		{StatementHeader: in_toto.StatementHeader{Type: in_toto.StatementInTotoV1, PredicateType: slsa1.PredicateSLSAProvenance, Subject: subject}},
+	}
+	orDie(a.PublishBundle(ctx, target, stmts...))
+	got, err := idx.Lookup(ctx, "sha256:"+digest)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if diff := cmp.Diff([]rebuild.Target{target}, got); diff != "" {
+		t.Errorf("Lookup() mismatch (-want +got):\n%s", diff)
+	}
+	// Overwriting the bundle with one for a different artifact removes the stale entry.
+	a.AllowOverwrite = true
+	newDigest := strings.Repeat("cd", 32)
+	stmts[0].Subject = []in_toto.Subject{{Name: target.Artifact, Digest: common.DigestSet{"sha256": newDigest}}}
+	orDie(a.PublishBundle(ctx, target, stmts[0]))
+	if _, err := idx.Lookup(ctx, "sha256:"+digest); !errors.Is(err, rebuild.ErrDigestNotFound) {
+		t.Errorf("Lookup(replaced) error = %v, want ErrDigestNotFound", err)
+	}
+	got, err = idx.Lookup(ctx, "sha256:"+newDigest)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if diff := cmp.Diff([]rebuild.Target{target}, got); diff != "" {
+		t.Errorf("Lookup() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAttestorBundleReplacesFailure(t *testing.T) {
+	ctx := context.Background()
+	target := rebuild.Target{Ecosystem: rebuild.NPM, Package: "pkg", Version: "1.0.0", Artifact: "pkg-1.0.0.tgz"}
+	sv := &ecdsaTestSigner{must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))}
+	store := rebuild.NewFilesystemAssetStore(memfs.New())
+	a := Attestor{Store: store, Signer: InTotoEnvelopeSigner{must(dsse.NewEnvelopeSigner(sv))}}
+	stmt := &in_toto.ProvenanceStatementSLSA1{StatementHeader: in_toto.StatementHeader{Type: in_toto.StatementInTotoV1, PredicateType: slsa1.PredicateSLSAProvenance}}
+	fa := &attestation.RebuildFailureAttestation{StatementHeader: in_toto.StatementHeader{Type: in_toto.StatementInTotoV1, PredicateType: attestation.PredicateRebuildFailureV01}}
+	orDie(a.PublishFailure(ctx, target, fa))
+	if _, err := readAll(ctx, store, rebuild.RebuildFailureAsset.For(target)); err != nil {
+		t.Fatalf("reading failure error = %v", err)
+	}
+	orDie(a.PublishBundle(ctx, target, stmt))
+	if _, err := readAll(ctx, store, rebuild.RebuildFailureAsset.For(target)); !errors.Is(err, rebuild.ErrAssetNotFound) {
+		t.Errorf("reading failure after bundle error = %v, want ErrAssetNotFound", err)
+	}
+}
+
+type failingLog struct{}
+
+func (failingLog) Append(context.Context, []byte) (*TransparencyLogProof, error) {
+	return nil, errors.New("log unavailable")
+}
+
+func (failingLog) ConsistencyProof(context.Context, int64, int64) ([][]byte, error) {
+	return nil, errors.New("log unavailable")
+}
+
+func TestAttestorPublishBundleRetry(t *testing.T) {
+	ctx := context.Background()
+	target := rebuild.Target{Ecosystem: rebuild.NPM, Package: "pkg", Version: "1.0.0", Artifact: "pkg-1.0.0.tgz"}
+	sv := &ecdsaTestSigner{must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))}
+	store := rebuild.NewFilesystemAssetStore(memfs.New())
+	a := Attestor{Store: store, Signer: InTotoEnvelopeSigner{must(dsse.NewEnvelopeSigner(sv))}, Log: failingLog{}}
+	stmt := &in_toto.ProvenanceStatementSLSA1{StatementHeader: in_toto.StatementHeader{Type: in_toto.StatementInTotoV1, PredicateType: slsa1.PredicateSLSAProvenance}}
+	if err := a.PublishBundle(ctx, target, stmt); err == nil {
+		t.Fatal("PublishBundle() with failing log succeeded")
+	}
+	if exists := must(a.BundleExists(ctx, target)); exists {
+		t.Error("BundleExists() after failed publication = true, want false")
+	}
+	a.Log = nil
+	if err := a.PublishBundle(ctx, target, stmt); err != nil {
+		t.Errorf("PublishBundle() retry error = %v", err)
+	}
+}
+
+func TestAttestorRejectsForgedRevocation(t *testing.T) {
+	ctx := context.Background()
+	target := rebuild.Target{Ecosystem: rebuild.NPM, Package: "pkg", Version: "1.0.0", Artifact: "pkg-1.0.0.tgz"}
+	sv := &ecdsaTestSigner{must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))}
+	forger := &ecdsaTestSigner{must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))}
+	store := rebuild.NewFilesystemAssetStore(memfs.New())
+	a := Attestor{Store: store, Signer: InTotoEnvelopeSigner{must(dsse.NewEnvelopeSigner(sv))}, Verifier: must(dsse.NewEnvelopeVerifier(sv))}
+	stmt := &in_toto.ProvenanceStatementSLSA1{StatementHeader: in_toto.StatementHeader{Type: in_toto.StatementInTotoV1, PredicateType: slsa1.PredicateSLSAProvenance}}
+	orDie(a.PublishBundle(ctx, target, stmt))
+	// A revocation written to the store under another key must not be trusted.
+	forged := Attestor{Store: store, Signer: InTotoEnvelopeSigner{must(dsse.NewEnvelopeSigner(forger))}}
+	must(forged.Revoke(ctx, target, "forged"))
+	if _, err := a.BundleRevoked(ctx, target); err == nil {
+		t.Error("BundleRevoked() with forged revocation succeeded")
+	}
+	if err := a.PublishBundle(ctx, target, stmt); err == nil {
+		t.Error("PublishBundle() over bundle with forged revocation succeeded")
+	}
+	// Without a Verifier, a stored revocation cannot be acted upon.
+	a.Verifier = nil
+	if _, err := a.BundleRevoked(ctx, target); err == nil {
+		t.Error("BundleRevoked() without Verifier succeeded")
+	}
+}
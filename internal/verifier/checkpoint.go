@@ -0,0 +1,133 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package verifier
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Checkpoint is a log's commitment to the root hash of its tree at a given size.
+// See https://github.com/transparency-dev/formats/blob/main/log/README.md
+type Checkpoint struct {
+	// Origin uniquely identifies the log
+	Origin string
+	// TreeSize is the number of entries in the tree
+	TreeSize int64
+	// RootHash is the RFC 6962 root hash of the tree
+	RootHash []byte
+}
+
+func (c Checkpoint) body() string {
+	return c.Origin + "\n" + strconv.FormatInt(c.TreeSize, 10) + "\n" + base64.StdEncoding.EncodeToString(c.RootHash) + "\n"
+}
+
+// noteSigPrefix begins each signature line of a signed note.
+const noteSigPrefix = "— "
+
+// keyHint returns the identifier of key carried in note signatures.
+func keyHint(key crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return sum[:4], nil
+}
+
+// SignCheckpoint encodes c as a signed note with a signature from signer.
+func SignCheckpoint(c Checkpoint, signer crypto.Signer) (string, error) {
+	hint, err := keyHint(signer.Public())
+	if err != nil {
+		return "", errors.Wrap(err, "identifying key")
+	}
+	body := c.body()
+	var sig []byte
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		sig, err = signer.Sign(rand.Reader, []byte(body), crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256([]byte(body))
+		sig, err = signer.Sign(rand.Reader, sum[:], crypto.SHA256)
+	default:
+		return "", errors.Errorf("unsupported key type %T", signer.Public())
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "signing checkpoint")
+	}
+	return body + "\n" + noteSigPrefix + c.Origin + " " + base64.StdEncoding.EncodeToString(append(hint, sig...)) + "\n", nil
+}
+
+// VerifyCheckpoint checks that note is a checkpoint signed by key and returns its contents.
+func VerifyCheckpoint(note string, key crypto.PublicKey) (*Checkpoint, error) {
+	body, sigs, ok := strings.Cut(note, "\n\n")
+	if !ok {
+		return nil, errors.New("malformed note: missing signatures")
+	}
+	body += "\n"
+	hint, err := keyHint(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "identifying key")
+	}
+	var verified bool
+	for _, line := range strings.Split(strings.TrimSuffix(sigs, "\n"), "\n") {
+		rest, ok := strings.CutPrefix(line, noteSigPrefix)
+		if !ok {
+			return nil, errors.Errorf("malformed signature line: %q", line)
+		}
+		_, encoded, ok := strings.Cut(rest, " ")
+		if !ok {
+			return nil, errors.Errorf("malformed signature line: %q", line)
+		}
+		sig, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(sig) < len(hint) {
+			return nil, errors.Errorf("malformed signature line: %q", line)
+		}
+		if !bytes.Equal(sig[:len(hint)], hint) {
+			continue
+		}
+		if verifyNoteSignature(key, []byte(body), sig[len(hint):]) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("no valid signature from key")
+	}
+	lines := strings.SplitN(body, "\n", 4)
+	if len(lines) < 4 {
+		return nil, errors.New("malformed checkpoint")
+	}
+	size, err := strconv.ParseInt(lines[1], 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing tree size")
+	}
+	root, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing root hash")
+	}
+	return &Checkpoint{Origin: lines[0], TreeSize: size, RootHash: root}, nil
+}
+
+func verifyNoteSignature(key crypto.PublicKey, msg, sig []byte) bool {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, msg, sig)
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(msg)
+		return ecdsa.VerifyASN1(k, sum[:], sig)
+	default:
+		return false
+	}
+}
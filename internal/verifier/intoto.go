@@ -25,12 +25,21 @@ type InTotoEnvelopeSigner struct {
 
 // SignStatement produces a DSSE Envelope for the provided ProvenanceStatement.
 func (signer *InTotoEnvelopeSigner) SignStatement(ctx context.Context, s *in_toto.ProvenanceStatementSLSA1) (*dsse.Envelope, error) {
+	return signer.signInToto(ctx, s)
+}
+
+// SignGenericStatement produces a DSSE Envelope for an in-toto Statement with an arbitrary predicate.
+func (signer *InTotoEnvelopeSigner) SignGenericStatement(ctx context.Context, s *in_toto.Statement) (*dsse.Envelope, error) {
+	return signer.signInToto(ctx, s)
+}
+
+func (signer *InTotoEnvelopeSigner) signInToto(ctx context.Context, s any) (*dsse.Envelope, error) {
 	b, err := json.Marshal(s)
 	if err != nil {
 		return nil, errors.Wrap(err, "marshalling statement")
 	}
-	// Since this is signing only a slsa predicate wrapped in an in-toto statement,
-	// the payload type is fixed to the in-toto media type.
+	// Since this is signing only in-toto statements, the payload type is fixed
+	// to the in-toto media type.
 	envelope, err := signer.SignPayload(ctx, attestation.InTotoPayloadType, b)
 	if err != nil {
 		return nil, errors.Wrap(err, "signing payload")
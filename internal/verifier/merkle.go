@@ -0,0 +1,179 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package verifier
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// Merkle tree hashing and proofs as defined in RFC 6962 Section 2.1.
+// See https://www.rfc-editor.org/rfc/rfc6962#section-2.1
+
+const (
+	leafHashPrefix = 0x00
+	nodeHashPrefix = 0x01
+)
+
+// HashLeaf returns the RFC 6962 hash of a leaf's contents.
+func HashLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafHashPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashChildren(l, r []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodeHashPrefix})
+	h.Write(l)
+	h.Write(r)
+	return h.Sum(nil)
+}
+
+// splitPoint returns the largest power of two strictly less than n.
+func splitPoint(n int64) int64 {
+	return int64(1) << (bits.Len64(uint64(n-1)) - 1)
+}
+
+// merkleRoot computes the root hash of the tree with the provided leaf hashes.
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		return leaves[0]
+	}
+	k := splitPoint(int64(len(leaves)))
+	return hashChildren(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// inclusionPath computes the audit path for the leaf at index m.
+func inclusionPath(m int64, leaves [][]byte) [][]byte {
+	n := int64(len(leaves))
+	if n <= 1 {
+		return nil
+	}
+	k := splitPoint(n)
+	if m < k {
+		return append(inclusionPath(m, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(inclusionPath(m-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// consistencyPath computes the proof that the tree of size m is a prefix of leaves.
+func consistencyPath(m int64, leaves [][]byte) [][]byte {
+	return subproof(m, leaves, true)
+}
+
+func subproof(m int64, leaves [][]byte, complete bool) [][]byte {
+	n := int64(len(leaves))
+	if m == n {
+		if complete {
+			return nil
+		}
+		return [][]byte{merkleRoot(leaves)}
+	}
+	k := splitPoint(n)
+	if m <= k {
+		return append(subproof(m, leaves[:k], complete), merkleRoot(leaves[k:]))
+	}
+	return append(subproof(m-k, leaves[k:], false), merkleRoot(leaves[:k]))
+}
+
+// VerifyInclusion checks that leafHash is at index in the tree of size with the given root.
+// See https://www.rfc-editor.org/rfc/rfc9162#section-2.1.3.2
+func VerifyInclusion(index, size int64, leafHash []byte, proof [][]byte, root []byte) error {
+	if index < 0 || index >= size {
+		return errors.Errorf("index %d out of range for tree size %d", index, size)
+	}
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return errors.New("inclusion proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = hashChildren(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = hashChildren(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("inclusion proof too short")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.New("inclusion proof does not match root hash")
+	}
+	return nil
+}
+
+// VerifyConsistency checks that the tree of size first with root firstRoot is a prefix of the tree of size second with root secondRoot.
+// See https://www.rfc-editor.org/rfc/rfc9162#section-2.1.4.2
+func VerifyConsistency(first, second int64, firstRoot, secondRoot []byte, proof [][]byte) error {
+	switch {
+	case first < 0 || first > second:
+		return errors.Errorf("invalid tree sizes %d and %d", first, second)
+	case first == second:
+		if len(proof) != 0 {
+			return errors.New("non-empty consistency proof for equal tree sizes")
+		}
+		if !bytes.Equal(firstRoot, secondRoot) {
+			return errors.New("root hashes differ for equal tree sizes")
+		}
+		return nil
+	case first == 0:
+		// The empty tree is trivially a prefix of every tree.
+		if len(proof) != 0 {
+			return errors.New("non-empty consistency proof for empty tree")
+		}
+		return nil
+	}
+	if first&(first-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	if len(proof) == 0 {
+		return errors.New("empty consistency proof")
+	}
+	fn, sn := first-1, second-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return errors.New("consistency proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = hashChildren(c, fr)
+			sr = hashChildren(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = hashChildren(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("consistency proof too short")
+	}
+	if !bytes.Equal(fr, firstRoot) || !bytes.Equal(sr, secondRoot) {
+		return errors.New("consistency proof does not match root hashes")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/oss-rebuild/internal/httpx"
+	"github.com/pkg/errors"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// rekorEntry is the hashedrekord v0.0.1 entry format.
+// See https://github.com/sigstore/rekor/blob/main/pkg/types/hashedrekord/v0.0.1/hashedrekord_v0_0_1_schema.json
+type rekorEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+	} `json:"spec"`
+}
+
+type rekorLogEntry struct {
+	Body           []byte `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		InclusionProof struct {
+			Checkpoint string   `json:"checkpoint"`
+			Hashes     []string `json:"hashes"`
+			LogIndex   int64    `json:"logIndex"`
+			RootHash   string   `json:"rootHash"`
+			TreeSize   int64    `json:"treeSize"`
+		} `json:"inclusionProof"`
+	} `json:"verification"`
+}
+
+// RekorLog is a TransparencyLog client for a Rekor-compatible log.
+//
+// Entries are recorded as hashedrekord entries signed by Signer.
+type RekorLog struct {
+	Client httpx.BasicClient
+	URL    *url.URL
+	Signer dsse.SignerVerifier
+}
+
+var _ TransparencyLog = &RekorLog{}
+
+// Append records a signed hashedrekord entry for data.
+func (l *RekorLog) Append(ctx context.Context, data []byte) (*TransparencyLogProof, error) {
+	sig, err := l.Signer.Sign(ctx, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "signing data")
+	}
+	der, err := x509.MarshalPKIXPublicKey(l.Signer.Public())
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling public key")
+	}
+	sum := sha256.Sum256(data)
+	var e rekorEntry
+	e.APIVersion = "0.0.1"
+	e.Kind = "hashedrekord"
+	e.Spec.Signature.Content = sig
+	e.Spec.Signature.PublicKey.Content = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	e.Spec.Data.Hash.Algorithm = "sha256"
+	e.Spec.Data.Hash.Value = hex.EncodeToString(sum[:])
+	reqBody, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling entry")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.URL.JoinPath("api/v1/log/entries").String(), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, errors.Errorf("creating entry: %s", resp.Status)
+	}
+	var entries map[string]rekorLogEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, errors.Wrap(err, "decoding response")
+	}
+	if len(entries) != 1 {
+		return nil, errors.Errorf("expected 1 entry, got %d", len(entries))
+	}
+	var entry rekorLogEntry
+	for _, e := range entries {
+		entry = e
+	}
+	ip := entry.Verification.InclusionProof
+	root, err := hex.DecodeString(ip.RootHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding root hash")
+	}
+	hashes, err := decodeHexHashes(ip.Hashes)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding inclusion proof")
+	}
+	return &TransparencyLogProof{
+		Kind:           RekorLogKind,
+		LogID:          entry.LogID,
+		LogIndex:       ip.LogIndex,
+		IntegratedTime: time.Unix(entry.IntegratedTime, 0).UTC(),
+		Body:           entry.Body,
+		TreeSize:       ip.TreeSize,
+		RootHash:       root,
+		Hashes:         hashes,
+		Checkpoint:     ip.Checkpoint,
+	}, nil
+}
+
+// ConsistencyProof fetches the proof that the tree of size first is a prefix of the tree of size second.
+func (l *RekorLog) ConsistencyProof(ctx context.Context, first, second int64) ([][]byte, error) {
+	u := l.URL.JoinPath("api/v1/log/proof")
+	u.RawQuery = url.Values{
+		"firstSize": {strconv.FormatInt(first, 10)},
+		"lastSize":  {strconv.FormatInt(second, 10)},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "sending request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetching consistency proof: %s", resp.Status)
+	}
+	var proof struct {
+		Hashes []string `json:"hashes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&proof); err != nil {
+		return nil, errors.Wrap(err, "decoding response")
+	}
+	return decodeHexHashes(proof.Hashes)
+}
+
+func decodeHexHashes(hs []string) ([][]byte, error) {
+	var ret [][]byte
+	for _, h := range hs {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, b)
+	}
+	return ret, nil
+}
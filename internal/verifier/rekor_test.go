@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type ecdsaTestSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *ecdsaTestSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	h := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, s.key, h[:])
+}
+
+func (s *ecdsaTestSigner) Verify(ctx context.Context, data, sig []byte) error {
+	h := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(&s.key.PublicKey, h[:], sig) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (s *ecdsaTestSigner) KeyID() (string, error)   { return "test", nil }
+func (s *ecdsaTestSigner) Public() crypto.PublicKey { return s.key.Public() }
+
+func TestRekorLog(t *testing.T) {
+	ctx := context.Background()
+	key := must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	logKey := must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	// Pre-populate the fake log so the new entry lands mid-tree.
+	leaves := testLeaves(6)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/log/entries", func(w http.ResponseWriter, r *http.Request) {
+		var e rekorEntry
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := must(hex.DecodeString(e.Spec.Data.Hash.Value))
+		if !ecdsa.VerifyASN1(&key.PublicKey, digest, e.Spec.Signature.Content) {
+			http.Error(w, "bad signature", http.StatusBadRequest)
+			return
+		}
+		body := must(json.Marshal(e))
+		leaves = append(leaves, HashLeaf(body))
+		index := int64(len(leaves) - 1)
+		var hashes []string
+		for _, h := range inclusionPath(index, leaves) {
+			hashes = append(hashes, hex.EncodeToString(h))
+		}
+		var entry rekorLogEntry
+		entry.Body = body
+		entry.IntegratedTime = 1700000000
+		entry.LogID = "fake"
+		entry.LogIndex = index
+		entry.Verification.InclusionProof.Hashes = hashes
+		entry.Verification.InclusionProof.LogIndex = index
+		entry.Verification.InclusionProof.RootHash = hex.EncodeToString(merkleRoot(leaves))
+		entry.Verification.InclusionProof.TreeSize = int64(len(leaves))
+		entry.Verification.InclusionProof.Checkpoint = must(SignCheckpoint(Checkpoint{Origin: "fake", TreeSize: int64(len(leaves)), RootHash: merkleRoot(leaves)}, logKey))
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]rekorLogEntry{"uuid": entry})
+	})
+	mux.HandleFunc("GET /api/v1/log/proof", func(w http.ResponseWriter, r *http.Request) {
+		var hashes []string
+		for _, h := range consistencyPath(6, leaves) {
+			hashes = append(hashes, hex.EncodeToString(h))
+		}
+		json.NewEncoder(w).Encode(map[string]any{"hashes": hashes, "rootHash": hex.EncodeToString(merkleRoot(leaves))})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	l := &RekorLog{Client: http.DefaultClient, URL: must(url.Parse(srv.URL)), Signer: &ecdsaTestSigner{key}}
+	data := []byte("bundle")
+	proof, err := l.Append(ctx, data)
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if proof.Kind != RekorLogKind || proof.LogIndex != 6 || proof.TreeSize != 7 {
+		t.Errorf("Append() = %+v", proof)
+	}
+	if err := VerifyLogProof(proof, data, logKey.Public()); err != nil {
+		t.Errorf("VerifyLogProof() error = %v", err)
+	}
+	if err := VerifyLogProof(proof, []byte("other"), logKey.Public()); err == nil {
+		t.Error("VerifyLogProof() with other data succeeded")
+	}
+	if err := VerifyLogProof(proof, data, key.Public()); err == nil {
+		t.Error("VerifyLogProof() with entry signing key succeeded")
+	}
+	cp, err := l.ConsistencyProof(ctx, 6, 7)
+	if err != nil {
+		t.Fatalf("ConsistencyProof() error = %v", err)
+	}
+	if err := VerifyConsistency(6, 7, merkleRoot(leaves[:6]), proof.RootHash, cp); err != nil {
+		t.Errorf("VerifyConsistency() error = %v", err)
+	}
+}
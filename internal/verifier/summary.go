@@ -5,6 +5,7 @@
 package verifier
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"io"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/google/oss-rebuild/internal/hashext"
 	"github.com/google/oss-rebuild/pkg/archive"
+	"github.com/google/oss-rebuild/pkg/attestation"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/pkg/errors"
 )
@@ -56,3 +58,74 @@ func SummarizeArtifacts(ctx context.Context, metadata rebuild.LocatableAssetStor
 	}
 	return rb, up, nil
 }
+
+// SummarizeUpstream fetches and hashes the upstream artifact.
+func SummarizeUpstream(ctx context.Context, upstreamURI string, hashes []crypto.Hash) (ArtifactSummary, error) {
+	up := ArtifactSummary{Hash: hashext.NewMultiHash(hashes...), StabilizedHash: hashext.NewMultiHash(hashes...), URI: upstreamURI}
+	req, _ := http.NewRequest(http.MethodGet, up.URI, nil)
+	resp, err := rebuild.DoContext(ctx, req)
+	if err != nil {
+		return up, errors.Wrap(err, "fetching upstream artifact")
+	}
+	defer checkClose(resp.Body)
+	if resp.StatusCode != 200 {
+		return up, errors.Wrap(errors.New(resp.Status), "fetching upstream artifact")
+	}
+	if _, err := io.Copy(up.Hash, resp.Body); err != nil {
+		return up, errors.Wrap(err, "hashing upstream artifact")
+	}
+	return up, nil
+}
+
+// maxDiffPaths is the maximum number of paths of each kind recorded in a ContentDiffSummary.
+const maxDiffPaths = 100
+
+// SummarizeContentDiff compares the file contents of the stabilized rebuild and upstream artifacts.
+func SummarizeContentDiff(ctx context.Context, metadata rebuild.ReadOnlyAssetStore, t rebuild.Target, rb, up ArtifactSummary, stabilizers []archive.Stabilizer) (*attestation.ContentDiffSummary, error) {
+	summarize := func(r io.Reader) (*archive.ContentSummary, error) {
+		var stabilized bytes.Buffer
+		if err := archive.StabilizeWithOpts(&stabilized, r, t.ArchiveType(), archive.StabilizeOpts{Stabilizers: stabilizers}); err != nil {
+			return nil, errors.Wrap(err, "stabilizing")
+		}
+		return archive.NewContentSummary(&stabilized, t.ArchiveType())
+	}
+	r, err := metadata.Reader(ctx, rebuild.RebuildAsset.For(t))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading artifact")
+	}
+	csRB, err := summarize(r)
+	checkClose(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "summarizing rebuild")
+	}
+	req, _ := http.NewRequest(http.MethodGet, up.URI, nil)
+	resp, err := rebuild.DoContext(ctx, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching upstream artifact")
+	}
+	if resp.StatusCode != 200 {
+		checkClose(resp.Body)
+		return nil, errors.Wrap(errors.New(resp.Status), "fetching upstream artifact")
+	}
+	csUP, err := summarize(resp.Body)
+	checkClose(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "summarizing upstream")
+	}
+	rbOnly, diffs, upOnly := csRB.Diff(csUP)
+	summary := &attestation.ContentDiffSummary{
+		RebuildDigest:  makeDigestSet(rb.StabilizedHash...),
+		UpstreamDigest: makeDigestSet(up.StabilizedHash...),
+	}
+	truncate := func(paths []string) []string {
+		if len(paths) > maxDiffPaths {
+			summary.Truncated = true
+			return paths[:maxDiffPaths]
+		}
+		return paths
+	}
+	summary.RebuildOnly = truncate(rbOnly)
+	summary.UpstreamOnly = truncate(upOnly)
+	summary.Modified = truncate(diffs)
+	return summary, nil
+}
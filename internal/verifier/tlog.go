@@ -0,0 +1,101 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TransparencyLog is an append-only log in which published bundles are recorded.
+type TransparencyLog interface {
+	// Append records data in the log and returns proof of its inclusion.
+	Append(ctx context.Context, data []byte) (*TransparencyLogProof, error)
+	// ConsistencyProof returns the proof that the tree of size first is a prefix of the tree of size second.
+	ConsistencyProof(ctx context.Context, first, second int64) ([][]byte, error)
+}
+
+// LogKind identifies the format of a transparency log's leaf entries.
+type LogKind string
+
+// RekorLogKind is a hashedrekord entry produced by a Rekor log.
+const RekorLogKind LogKind = "rekor"
+
+// TransparencyLogProof is the proof that an entry was included in a transparency log.
+type TransparencyLogProof struct {
+	// Kind is the format of the log's entries
+	Kind LogKind `json:"kind"`
+	// LogID identifies the log to which the entry was appended
+	LogID string `json:"logID"`
+	// LogIndex is the index of the entry in the log
+	LogIndex int64 `json:"logIndex"`
+	// IntegratedTime is the time at which the entry was appended
+	IntegratedTime time.Time `json:"integratedTime"`
+	// Body is the leaf content recorded in the log
+	Body []byte `json:"body"`
+	// TreeSize is the size of the tree against which the inclusion proof was computed
+	TreeSize int64 `json:"treeSize"`
+	// RootHash is the root hash of the tree of TreeSize
+	RootHash []byte `json:"rootHash"`
+	// Hashes is the RFC 6962 audit path from the leaf to the root
+	Hashes [][]byte `json:"hashes"`
+	// Checkpoint is the log's signed note committing to RootHash at TreeSize
+	Checkpoint string `json:"checkpoint,omitempty"`
+}
+
+// VerifyLogProof checks that proof demonstrates inclusion of data in the log identified by logKey.
+//
+// The proof's root hash is only trusted once it is matched by a checkpoint
+// signed by logKey. Whether that root is the one the log presents to everyone
+// else must still be established by log monitors using consistency proofs.
+func VerifyLogProof(proof *TransparencyLogProof, data []byte, logKey crypto.PublicKey) error {
+	if proof.Checkpoint == "" {
+		return errors.New("proof has no signed checkpoint")
+	}
+	cp, err := VerifyCheckpoint(proof.Checkpoint, logKey)
+	if err != nil {
+		return errors.Wrap(err, "verifying checkpoint")
+	}
+	if cp.TreeSize != proof.TreeSize || !bytes.Equal(cp.RootHash, proof.RootHash) {
+		return errors.New("checkpoint does not match proof")
+	}
+	return verifyEntryInclusion(proof, data)
+}
+
+// verifyEntryInclusion checks that the proof's entry records data and is included under the proof's root hash.
+func verifyEntryInclusion(proof *TransparencyLogProof, data []byte) error {
+	digest, err := bodyDigest(proof.Kind, proof.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading log entry")
+	}
+	sum := sha256.Sum256(data)
+	if digest != hex.EncodeToString(sum[:]) {
+		return errors.New("log entry does not match data")
+	}
+	return VerifyInclusion(proof.LogIndex, proof.TreeSize, HashLeaf(proof.Body), proof.Hashes, proof.RootHash)
+}
+
+// bodyDigest returns the hex-encoded sha256 digest recorded in a log entry body.
+func bodyDigest(kind LogKind, body []byte) (string, error) {
+	switch kind {
+	case RekorLogKind:
+		var e rekorEntry
+		if err := json.Unmarshal(body, &e); err != nil {
+			return "", err
+		}
+		if e.Kind != "hashedrekord" || e.Spec.Data.Hash.Algorithm != "sha256" {
+			return "", errors.Errorf("unsupported rekor entry: %s", e.Kind)
+		}
+		return e.Spec.Data.Hash.Value, nil
+	default:
+		return "", errors.Errorf("unsupported log kind: %s", kind)
+	}
+}
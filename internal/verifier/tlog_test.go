@@ -0,0 +1,131 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package verifier
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func testLeaves(n int) [][]byte {
+	var leaves [][]byte
+	for i := range n {
+		leaves = append(leaves, HashLeaf([]byte(fmt.Sprintf("leaf-%d", i))))
+	}
+	return leaves
+}
+
+func TestMerkleInclusion(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		leaves := testLeaves(size)
+		root := merkleRoot(leaves)
+		for i := range size {
+			proof := inclusionPath(int64(i), leaves)
+			if err := VerifyInclusion(int64(i), int64(size), leaves[i], proof, root); err != nil {
+				t.Errorf("VerifyInclusion(%d, %d) error = %v", i, size, err)
+			}
+			if err := VerifyInclusion(int64(i), int64(size), HashLeaf([]byte("other")), proof, root); err == nil {
+				t.Errorf("VerifyInclusion(%d, %d) with wrong leaf succeeded", i, size)
+			}
+		}
+	}
+}
+
+func TestMerkleConsistency(t *testing.T) {
+	for second := 1; second <= 17; second++ {
+		leaves := testLeaves(second)
+		secondRoot := merkleRoot(leaves)
+		for first := 1; first <= second; first++ {
+			firstRoot := merkleRoot(leaves[:first])
+			proof := consistencyPath(int64(first), leaves)
+			if err := VerifyConsistency(int64(first), int64(second), firstRoot, secondRoot, proof); err != nil {
+				t.Errorf("VerifyConsistency(%d, %d) error = %v", first, second, err)
+			}
+			if first < second {
+				if err := VerifyConsistency(int64(first), int64(second), HashLeaf(nil), secondRoot, proof); err == nil {
+					t.Errorf("VerifyConsistency(%d, %d) with wrong root succeeded", first, second)
+				}
+			}
+		}
+	}
+}
+
+// testRekorBody returns a hashedrekord log entry body recording data.
+func testRekorBody(data []byte) []byte {
+	var e rekorEntry
+	e.Kind = "hashedrekord"
+	e.Spec.Data.Hash.Algorithm = "sha256"
+	sum := sha256.Sum256(data)
+	e.Spec.Data.Hash.Value = hex.EncodeToString(sum[:])
+	return must(json.Marshal(e))
+}
+
+func TestVerifyLogProofRejectsForgedRoot(t *testing.T) {
+	logKey := must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	data := []byte("bundle")
+	var leaves [][]byte
+	for _, d := range []string{"bundle", "next", "last"} {
+		leaves = append(leaves, HashLeaf(testRekorBody([]byte(d))))
+	}
+	// A self-consistent proof against a root the log never signed.
+	forged := TransparencyLogProof{
+		Kind:     RekorLogKind,
+		LogIndex: 0,
+		Body:     testRekorBody(data),
+		TreeSize: 1,
+		RootHash: leaves[0],
+	}
+	if err := verifyEntryInclusion(&forged, data); err != nil {
+		t.Fatalf("verifyEntryInclusion() error = %v", err)
+	}
+	forged.Checkpoint = must(SignCheckpoint(Checkpoint{Origin: "fake", TreeSize: int64(len(leaves)), RootHash: merkleRoot(leaves)}, logKey))
+	if err := VerifyLogProof(&forged, data, logKey.Public()); err == nil {
+		t.Error("VerifyLogProof() with mismatched checkpoint succeeded")
+	}
+	forged.Checkpoint = ""
+	if err := VerifyLogProof(&forged, data, logKey.Public()); err == nil {
+		t.Error("VerifyLogProof() without checkpoint succeeded")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	ecKey := must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Checkpoint{Origin: "example.com/log", TreeSize: 42, RootHash: HashLeaf([]byte("root"))}
+	for _, signer := range []crypto.Signer{ecKey, edKey} {
+		note, err := SignCheckpoint(want, signer)
+		if err != nil {
+			t.Fatalf("SignCheckpoint() error = %v", err)
+		}
+		got, err := VerifyCheckpoint(note, signer.Public())
+		if err != nil {
+			t.Fatalf("VerifyCheckpoint() error = %v", err)
+		}
+		if diff := cmp.Diff(&want, got); diff != "" {
+			t.Errorf("VerifyCheckpoint() mismatch (-want +got):\n%s", diff)
+		}
+		tampered := strings.Replace(note, "\n42\n", "\n43\n", 1)
+		if _, err := VerifyCheckpoint(tampered, signer.Public()); err == nil {
+			t.Error("VerifyCheckpoint() with tampered body succeeded")
+		}
+	}
+	note := must(SignCheckpoint(want, ecKey))
+	if _, err := VerifyCheckpoint(note, edKey.Public()); err == nil {
+		t.Error("VerifyCheckpoint() with other key succeeded")
+	}
+}
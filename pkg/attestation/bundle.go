@@ -53,6 +53,8 @@ func NewVerifiedEnvelope[T any](ctx context.Context, e *dsse.Envelope, verifier
 
 type Bundle struct {
 	envelopes []VerifiedEnvelope[in_toto.Statement]
+	// data is the serialized bundle from which the envelopes were decoded.
+	data []byte
 }
 
 func (b Bundle) Statements() []*in_toto.Statement {
@@ -80,5 +82,5 @@ func NewBundle(ctx context.Context, data []byte, verifier *dsse.EnvelopeVerifier
 		}
 		envelopes = append(envelopes, *ve)
 	}
-	return &Bundle{envelopes: envelopes}, nil
+	return &Bundle{envelopes: envelopes, data: data}, nil
 }
@@ -0,0 +1,102 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/pkg/errors"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// PredicateRevocationV01 is the predicate type for attestation bundle revocations.
+const PredicateRevocationV01 = "https://docs.oss-rebuild.dev/revocation/v0.1"
+
+// RevocationPredicate describes why and when a bundle was revoked.
+type RevocationPredicate struct {
+	// Reason is a human-readable explanation for the revocation
+	Reason string `json:"reason"`
+	// RevokedAt is the time at which the revocation was issued
+	RevokedAt time.Time `json:"revokedAt"`
+	// SupersededBy identifies the bundle published to replace the revoked one, if any
+	SupersededBy *slsa1.ResourceDescriptor `json:"supersededBy,omitempty"`
+}
+
+// Revocation is a statement that the bundle identified by its subject must no longer be trusted.
+type Revocation struct {
+	// StatementHeader contains the standard in-toto statement header
+	in_toto.StatementHeader `json:",inline"`
+	// Predicate contains the revocation details
+	Predicate RevocationPredicate `json:"predicate"`
+}
+
+// NewRevocation creates a Revocation of the bundle contents located at bundleURI.
+func NewRevocation(bundle []byte, bundleURI, reason string, revokedAt time.Time) *Revocation {
+	return &Revocation{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV1,
+			PredicateType: PredicateRevocationV01,
+			Subject:       []in_toto.Subject{{Name: bundleURI, Digest: bundleDigest(bundle)}},
+		},
+		Predicate: RevocationPredicate{
+			Reason:    reason,
+			RevokedAt: revokedAt.UTC(),
+		},
+	}
+}
+
+// Supersede records the bundle contents located at bundleURI as the replacement for the revoked bundle.
+func (r *Revocation) Supersede(bundle []byte, bundleURI string) {
+	r.Predicate.SupersededBy = &slsa1.ResourceDescriptor{Name: bundleURI, Digest: bundleDigest(bundle)}
+}
+
+// Revokes returns whether the revocation applies to the provided bundle contents.
+func (r *Revocation) Revokes(bundle []byte) bool {
+	want := bundleDigest(bundle)["sha256"]
+	for _, s := range r.Subject {
+		if s.Digest["sha256"] == want {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSupersededBy returns whether the provided bundle contents are the recorded replacement.
+func (r *Revocation) IsSupersededBy(bundle []byte) bool {
+	return r.Predicate.SupersededBy != nil && r.Predicate.SupersededBy.Digest["sha256"] == bundleDigest(bundle)["sha256"]
+}
+
+// ToStatement converts the Revocation to a generic in-toto statement.
+func (r *Revocation) ToStatement() (*in_toto.Statement, error) {
+	return reinterpretJSON[in_toto.Statement](r)
+}
+
+// NewVerifiedRevocation verifies and decodes a serialized revocation record.
+func NewVerifiedRevocation(ctx context.Context, data []byte, verifier *dsse.EnvelopeVerifier) (*Revocation, error) {
+	var env dsse.Envelope
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&env); err != nil {
+		return nil, errors.Wrap(err, "decoding envelope")
+	}
+	ve, err := NewVerifiedEnvelope[Revocation](ctx, &env, verifier)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding payload")
+	}
+	if ve.payload.PredicateType != PredicateRevocationV01 {
+		return nil, errors.Errorf("unexpected predicate type: %s", ve.payload.PredicateType)
+	}
+	return ve.payload, nil
+}
+
+func bundleDigest(bundle []byte) common.DigestSet {
+	sum := sha256.Sum256(bundle)
+	return common.DigestSet{"sha256": hex.EncodeToString(sum[:])}
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+func TestRevocation(t *testing.T) {
+	ctx := context.Background()
+	bundle := []byte("bundle\n")
+	replacement := []byte("replacement\n")
+	r := NewRevocation(bundle, "gs://bucket/rebuild.intoto.jsonl", "overly broad stabilizer", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if !r.Revokes(bundle) {
+		t.Error("Revokes(bundle) = false")
+	}
+	if r.Revokes(replacement) || r.IsSupersededBy(replacement) {
+		t.Error("unexpected match for replacement")
+	}
+	r.Supersede(replacement, "gs://bucket/rebuild.intoto.jsonl")
+	if !r.IsSupersededBy(replacement) {
+		t.Error("IsSupersededBy(replacement) = false")
+	}
+	stmt := must(r.ToStatement())
+	if stmt.PredicateType != PredicateRevocationV01 {
+		t.Errorf("PredicateType = %s", stmt.PredicateType)
+	}
+	payload := must(json.Marshal(stmt))
+	env := &dsse.Envelope{PayloadType: InTotoPayloadType, Payload: base64.StdEncoding.EncodeToString(payload), Signatures: []dsse.Signature{{KeyID: "test-key", Sig: "c2ln"}}}
+	data := must(json.Marshal(env))
+	t.Run("Verified", func(t *testing.T) {
+		got, err := NewVerifiedRevocation(ctx, data, must(dsse.NewEnvelopeVerifier(&successVerifier{})))
+		if err != nil {
+			t.Fatalf("NewVerifiedRevocation() error = %v", err)
+		}
+		if diff := cmp.Diff(r, got); diff != "" {
+			t.Errorf("NewVerifiedRevocation() mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("BadSignature", func(t *testing.T) {
+		if _, err := NewVerifiedRevocation(ctx, data, must(dsse.NewEnvelopeVerifier(&failingVerifier{err: errors.New("verification failed")}))); err == nil {
+			t.Error("NewVerifiedRevocation() with failing verifier succeeded")
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 )
 
@@ -18,6 +19,13 @@ const (
 	BuildTypeRebuildV01 = "https://docs.oss-rebuild.dev/builds/Rebuild@v0.1"
 	// BuildTypeArtifactEquivalenceV01 is the SLSA build type used for artifact equivalence attestations.
 	BuildTypeArtifactEquivalenceV01 = "https://docs.oss-rebuild.dev/builds/ArtifactEquivalence@v0.1"
+	// BuildTypeRebuildFailureV01 is the build type recorded in attestations of failed rebuilds.
+	BuildTypeRebuildFailureV01 = "https://docs.oss-rebuild.dev/builds/RebuildFailure@v0.1"
+	// PredicateRebuildFailureV01 is the predicate type for attestations of failed rebuilds.
+	//
+	// Failures are not SLSA Provenance: no artifact was produced and the subject
+	// is the upstream artifact for which the rebuild was attempted.
+	PredicateRebuildFailureV01 = "https://docs.oss-rebuild.dev/predicates/RebuildFailure@v0.1"
 
 	HostGoogle = "https://docs.oss-rebuild.dev/hosts/Google"
 
@@ -26,6 +34,7 @@ const (
 	ByproductBuildStrategy = "build.json"
 	ByproductBuildSteps    = "steps.json"
 	ByproductDockerfile    = "Dockerfile"
+	ByproductFailure       = "failure.json"
 )
 
 // SourceLocation describes a source code reference and optional path
@@ -71,6 +80,8 @@ type RebuildParams struct {
 	Package string `json:"package"`
 	// Version is the specific version of the package to rebuild
 	Version string `json:"version"`
+	// Registry is the URL of the package registry if not the ecosystem's public registry
+	Registry string `json:"registry,omitempty"`
 }
 
 // RebuildDeps represents the resolved dependencies for a rebuild operation.
@@ -319,3 +330,133 @@ func (ap *ArtifactEquivalenceAttestation) ToStatement() (*in_toto.ProvenanceStat
 	}
 	return &s, nil
 }
+
+// RebuildFailure attestation type definitions
+
+// FailureReason is the classified cause of a failed rebuild.
+type FailureReason string
+
+const (
+	// FailureReasonInference indicates no build strategy could be determined.
+	FailureReasonInference FailureReason = "INFERENCE"
+	// FailureReasonBuild indicates the build strategy failed to produce an artifact.
+	FailureReasonBuild FailureReason = "BUILD"
+	// FailureReasonContentMismatch indicates the rebuilt artifact did not match upstream.
+	FailureReasonContentMismatch FailureReason = "CONTENT_MISMATCH"
+)
+
+// ContentDiffSummary summarizes the differences between the stabilized rebuild and upstream artifacts.
+type ContentDiffSummary struct {
+	// RebuildOnly lists files present only in the rebuilt artifact
+	RebuildOnly []string `json:"rebuildOnly,omitempty"`
+	// UpstreamOnly lists files present only in the upstream artifact
+	UpstreamOnly []string `json:"upstreamOnly,omitempty"`
+	// Modified lists files present in both artifacts with differing content
+	Modified []string `json:"modified,omitempty"`
+	// Truncated indicates that the file lists were truncated
+	Truncated bool `json:"truncated,omitempty"`
+	// RebuildDigest is the digest of the stabilized rebuilt artifact
+	RebuildDigest common.DigestSet `json:"rebuildDigest,omitempty"`
+	// UpstreamDigest is the digest of the stabilized upstream artifact
+	UpstreamDigest common.DigestSet `json:"upstreamDigest,omitempty"`
+}
+
+// RebuildFailureDetails describes why a rebuild failed.
+type RebuildFailureDetails struct {
+	// Reason is the classified cause of the failure
+	Reason FailureReason `json:"reason"`
+	// Message is the error message produced by the rebuild
+	Message string `json:"message"`
+	// ContentDiff summarizes the content differences for content mismatches
+	ContentDiff *ContentDiffSummary `json:"contentDiff,omitempty"`
+}
+
+// RebuildFailureByproducts contains the byproducts of a failed rebuild operation.
+type RebuildFailureByproducts struct {
+	// BuildStrategy contains the serialized strategy used for the rebuild, if one was determined
+	BuildStrategy *slsa1.ResourceDescriptor
+	// Failure contains the serialized RebuildFailureDetails
+	Failure slsa1.ResourceDescriptor
+}
+
+// MarshalJSON flattens the byproducts into a ResourceDescriptors slice for compatibility with SLSA Provenance.
+func (d RebuildFailureByproducts) MarshalJSON() ([]byte, error) {
+	var rd []slsa1.ResourceDescriptor
+	if d.BuildStrategy != nil {
+		rd = append(rd, *d.BuildStrategy)
+	}
+	rd = append(rd, d.Failure)
+	return json.Marshal(rd)
+}
+
+// UnmarshalJSON extracts byproducts from a ResourceDescriptors slice for compatibility with SLSA Provenance.
+func (d *RebuildFailureByproducts) UnmarshalJSON(data []byte) error {
+	var descriptors []slsa1.ResourceDescriptor
+	if err := json.Unmarshal(data, &descriptors); err != nil {
+		return err
+	}
+	for _, desc := range descriptors {
+		switch desc.Name {
+		case ByproductBuildStrategy:
+			d.BuildStrategy = &desc
+		case ByproductFailure:
+			d.Failure = desc
+		}
+	}
+	if d.Failure.Name == "" {
+		return errors.New("missing failure descriptor")
+	}
+	return nil
+}
+
+// Details decodes the RebuildFailureDetails from the failure byproduct.
+func (d RebuildFailureByproducts) Details() (*RebuildFailureDetails, error) {
+	var details RebuildFailureDetails
+	if err := json.Unmarshal(d.Failure.Content, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// RebuildFailureBuildDef defines the build definition for a failed rebuild operation.
+type RebuildFailureBuildDef struct {
+	// BuildType is the RebuildFailure build type identifier
+	BuildType string `json:"buildType"`
+	// ExternalParameters contains user-provided rebuild parameters
+	ExternalParameters RebuildParams `json:"externalParameters"`
+	// InternalParameters contains service-internal configuration
+	InternalParameters ServiceInternalParams `json:"internalParameters"`
+	// ResolvedDependencies contains the dependencies resolved before the failure
+	ResolvedDependencies RebuildDeps `json:"resolvedDependencies"`
+}
+
+// RebuildFailureRunDetails contains the runtime details of a failed rebuild operation.
+type RebuildFailureRunDetails struct {
+	// Builder contains information about the build environment
+	Builder slsa1.Builder `json:"builder"`
+	// BuildMetadata contains metadata about the build execution
+	BuildMetadata slsa1.BuildMetadata `json:"metadata"`
+	// Byproducts contains the strategy and failure details
+	Byproducts RebuildFailureByproducts `json:"byproducts"`
+}
+
+// RebuildFailurePredicate represents the predicate portion of a rebuild failure attestation.
+type RebuildFailurePredicate struct {
+	// BuildDefinition defines what was attempted and how
+	BuildDefinition RebuildFailureBuildDef `json:"buildDefinition"`
+	// RunDetails contains the actual execution information
+	RunDetails RebuildFailureRunDetails `json:"runDetails"`
+}
+
+// RebuildFailureAttestation represents a complete rebuild failure statement.
+type RebuildFailureAttestation struct {
+	// StatementHeader contains the standard in-toto statement header
+	in_toto.StatementHeader `json:",inline"`
+	// Predicate contains the failure details
+	Predicate RebuildFailurePredicate `json:"predicate"`
+}
+
+// ToStatement converts the RebuildFailureAttestation to a generic in-toto statement.
+func (fa *RebuildFailureAttestation) ToStatement() (*in_toto.Statement, error) {
+	return reinterpretJSON[in_toto.Statement](fa)
+}
@@ -322,6 +322,64 @@ func TestArtifactEquivalenceByproducts_UnmarshalErrors(t *testing.T) {
 	}
 }
 
+func TestRebuildFailureByproducts_JSONRoundtrip(t *testing.T) {
+	details := RebuildFailureDetails{
+		Reason:  FailureReasonContentMismatch,
+		Message: "rebuild content mismatch",
+		ContentDiff: &ContentDiffSummary{
+			Modified:       []string{"package/index.js"},
+			RebuildDigest:  common.DigestSet{"sha256": "rebuild123456"},
+			UpstreamDigest: common.DigestSet{"sha256": "upstream123456"},
+		},
+	}
+	tests := []struct {
+		name  string
+		input RebuildFailureByproducts
+	}{
+		{
+			name: "with strategy",
+			input: RebuildFailureByproducts{
+				BuildStrategy: &slsa1.ResourceDescriptor{Name: ByproductBuildStrategy, Content: []byte(`{}`)},
+				Failure:       slsa1.ResourceDescriptor{Name: ByproductFailure, Content: must(json.Marshal(details))},
+			},
+		},
+		{
+			name: "without strategy",
+			input: RebuildFailureByproducts{
+				Failure: slsa1.ResourceDescriptor{Name: ByproductFailure, Content: must(json.Marshal(details))},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			var restored RebuildFailureByproducts
+			if err := json.Unmarshal(data, &restored); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.input, restored); diff != "" {
+				t.Errorf("JSON roundtrip mismatch (-want +got):\n%s", diff)
+			}
+			got, err := restored.Details()
+			if err != nil {
+				t.Fatalf("Details failed: %v", err)
+			}
+			if diff := cmp.Diff(&details, got); diff != "" {
+				t.Errorf("Details mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+	t.Run("missing failure", func(t *testing.T) {
+		var restored RebuildFailureByproducts
+		if err := json.Unmarshal([]byte(`[{"name": "build.json"}]`), &restored); err == nil {
+			t.Error("Expected error but got none")
+		}
+	})
+}
+
 func TestRebuildAttestation_SLSACompatibility(t *testing.T) {
 	tests := []struct {
 		name        string
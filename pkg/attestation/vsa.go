@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"time"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PredicateSLSAVerificationSummaryV1 is the predicate type for SLSA Verification Summary Attestations.
+	PredicateSLSAVerificationSummaryV1 = "https://slsa.dev/verification_summary/v1"
+
+	// VerificationResultPassed indicates the resource satisfied the policy.
+	VerificationResultPassed = "PASSED"
+	// VerificationResultFailed indicates the resource did not satisfy the policy.
+	VerificationResultFailed = "FAILED"
+
+	// VerifierOSSRebuild is the default verifier ID for VSAs produced from OSS Rebuild bundles.
+	VerifierOSSRebuild = "https://docs.oss-rebuild.dev/verifiers/OSSRebuild"
+
+	// LevelRebuildV01 is the verified level asserted for artifacts reproduced by OSS Rebuild.
+	LevelRebuildV01 = "OSS_REBUILD_REPRODUCED_v0.1"
+)
+
+// VSAVerifier identifies the entity that performed the verification.
+type VSAVerifier struct {
+	// ID is the URI of the verifier
+	ID string `json:"id"`
+	// Version maps components of the verifier to their versions
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// VSAPolicy identifies the policy against which the resource was verified.
+type VSAPolicy struct {
+	// URI is the location of the policy
+	URI string `json:"uri,omitempty"`
+	// Digest is the digest of the policy contents
+	Digest common.DigestSet `json:"digest,omitempty"`
+}
+
+// VSAPredicate is the predicate of a SLSA Verification Summary Attestation.
+// See https://slsa.dev/spec/v1.0/verification_summary
+type VSAPredicate struct {
+	// Verifier identifies the entity that performed the verification
+	Verifier VSAVerifier `json:"verifier"`
+	// TimeVerified is the time at which the verification occurred
+	TimeVerified time.Time `json:"timeVerified"`
+	// ResourceURI is the URI of the resource to which the verification applies
+	ResourceURI string `json:"resourceUri"`
+	// Policy describes the policy the resource was verified against
+	Policy VSAPolicy `json:"policy"`
+	// InputAttestations are the attestations used to make the verification decision
+	InputAttestations []slsa1.ResourceDescriptor `json:"inputAttestations,omitempty"`
+	// VerificationResult is either PASSED or FAILED
+	VerificationResult string `json:"verificationResult"`
+	// VerifiedLevels are the levels the resource was verified to meet
+	VerifiedLevels []string `json:"verifiedLevels"`
+	// SLSAVersion is the version of the SLSA spec used for verification
+	SLSAVersion string `json:"slsaVersion,omitempty"`
+}
+
+// VerificationSummaryAttestation represents a complete SLSA VSA statement.
+type VerificationSummaryAttestation struct {
+	// StatementHeader contains the standard in-toto statement header
+	in_toto.StatementHeader `json:",inline"`
+	// Predicate contains the verification summary
+	Predicate VSAPredicate `json:"predicate"`
+}
+
+// ToStatement converts the VerificationSummaryAttestation to a generic in-toto statement.
+func (v *VerificationSummaryAttestation) ToStatement() (*in_toto.Statement, error) {
+	return reinterpretJSON[in_toto.Statement](v)
+}
+
+// PolicyOutcome is the result of evaluating a Bundle against a verification policy.
+type PolicyOutcome struct {
+	// Passed is whether the bundle satisfied the policy
+	Passed bool
+	// VerifiedLevels are the levels asserted by the policy when satisfied
+	VerifiedLevels []string
+	// Policy identifies the policy that was evaluated
+	Policy VSAPolicy
+}
+
+// VSAOptions configures the generation of a VSA.
+type VSAOptions struct {
+	// Verifier identifies the entity issuing the VSA
+	Verifier VSAVerifier
+	// BundleURI is the location of the bundle, recorded on the input attestation
+	BundleURI string
+	// ResourceURI overrides the resource URI derived from the bundle
+	ResourceURI string
+	// TimeVerified overrides the verification time, defaulting to now
+	TimeVerified time.Time
+}
+
+// NewVSA summarizes a verified rebuild Bundle and the policy outcome applied to it as a VSA.
+//
+// The subject and resource URI are taken from the bundle's artifact equivalence
+// attestation as it describes the upstream artifact that consumers will admit.
+func NewVSA(b *Bundle, outcome PolicyOutcome, opts VSAOptions) (*VerificationSummaryAttestation, error) {
+	eq, err := FilterForOne[ArtifactEquivalenceAttestation](b, WithBuildType(BuildTypeArtifactEquivalenceV01))
+	if err != nil {
+		return nil, errors.Wrap(err, "finding artifact equivalence attestation")
+	}
+	if opts.Verifier.ID == "" {
+		return nil, errors.New("verifier ID required")
+	}
+	resourceURI := opts.ResourceURI
+	if resourceURI == "" {
+		resourceURI = eq.Predicate.BuildDefinition.ExternalParameters.Target
+	}
+	if b.data == nil {
+		return nil, errors.New("bundle contents unavailable")
+	}
+	// NOTE: The digest must be of the bundle exactly as stored at BundleURI so
+	// consumers can match it against the bytes they fetch.
+	inputs := []slsa1.ResourceDescriptor{{URI: opts.BundleURI, Digest: bundleDigest(b.data)}}
+	verified := opts.TimeVerified
+	if verified.IsZero() {
+		verified = time.Now().UTC()
+	}
+	result := VerificationResultFailed
+	levels := []string{}
+	if outcome.Passed {
+		result = VerificationResultPassed
+		levels = append(levels, outcome.VerifiedLevels...)
+	}
+	return &VerificationSummaryAttestation{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV1,
+			Subject:       eq.Subject,
+			PredicateType: PredicateSLSAVerificationSummaryV1,
+		},
+		Predicate: VSAPredicate{
+			Verifier:           opts.Verifier,
+			TimeVerified:       verified,
+			ResourceURI:        resourceURI,
+			Policy:             outcome.Policy,
+			InputAttestations:  inputs,
+			VerificationResult: result,
+			VerifiedLevels:     levels,
+			SLSAVersion:        "1.0",
+		},
+	}, nil
+}
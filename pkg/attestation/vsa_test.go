@@ -0,0 +1,126 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+func TestNewVSA(t *testing.T) {
+	ctx := context.Background()
+	subject := []in_toto.Subject{{Name: "foo-1.0.0.tgz", Digest: common.DigestSet{"sha256": "abcd"}}}
+	eq := &in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV1,
+			PredicateType: slsa1.PredicateSLSAProvenance,
+			Subject:       subject,
+		},
+		Predicate: map[string]any{
+			"buildDefinition": map[string]any{
+				"buildType":          BuildTypeArtifactEquivalenceV01,
+				"externalParameters": map[string]any{"candidate": "rebuild/foo-1.0.0.tgz", "target": "https://registry.npmjs.org/foo/-/foo-1.0.0.tgz"},
+			},
+		},
+	}
+	rb := &in_toto.Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          in_toto.StatementInTotoV1,
+			PredicateType: slsa1.PredicateSLSAProvenance,
+			Subject:       []in_toto.Subject{{Name: "rebuild/foo-1.0.0.tgz", Digest: common.DigestSet{"sha256": "abcd"}}},
+		},
+		Predicate: map[string]any{
+			"buildDefinition": map[string]any{"buildType": BuildTypeRebuildV01},
+		},
+	}
+	var data bytes.Buffer
+	for _, stmt := range []*in_toto.Statement{eq, rb} {
+		orDie(json.NewEncoder(&data).Encode(createTestEnvelope(t, stmt)))
+	}
+	sum := sha256.Sum256(data.Bytes())
+	bundleDigest := hex.EncodeToString(sum[:])
+	bundle := must(NewBundle(ctx, data.Bytes(), must(dsse.NewEnvelopeVerifier(&successVerifier{}))))
+	verified := must(time.Parse(time.RFC3339, "2025-01-01T00:00:00Z"))
+	opts := VSAOptions{
+		Verifier:     VSAVerifier{ID: "https://example.com/verifier"},
+		BundleURI:    "gs://bucket/bundle",
+		TimeVerified: verified,
+	}
+	policy := VSAPolicy{URI: "https://example.com/policy"}
+
+	t.Run("Passed", func(t *testing.T) {
+		vsa, err := NewVSA(bundle, PolicyOutcome{Passed: true, VerifiedLevels: []string{LevelRebuildV01}, Policy: policy}, opts)
+		if err != nil {
+			t.Fatalf("NewVSA() error = %v", err)
+		}
+		want := &VerificationSummaryAttestation{
+			StatementHeader: in_toto.StatementHeader{
+				Type:          in_toto.StatementInTotoV1,
+				Subject:       subject,
+				PredicateType: PredicateSLSAVerificationSummaryV1,
+			},
+			Predicate: VSAPredicate{
+				Verifier:     opts.Verifier,
+				TimeVerified: verified,
+				ResourceURI:  "https://registry.npmjs.org/foo/-/foo-1.0.0.tgz",
+				Policy:       policy,
+				InputAttestations: []slsa1.ResourceDescriptor{
+					{URI: "gs://bucket/bundle", Digest: common.DigestSet{"sha256": bundleDigest}},
+				},
+				VerificationResult: VerificationResultPassed,
+				VerifiedLevels:     []string{LevelRebuildV01},
+				SLSAVersion:        "1.0",
+			},
+		}
+		if diff := cmp.Diff(want, vsa); diff != "" {
+			t.Errorf("NewVSA() mismatch (-want +got):\n%s", diff)
+		}
+		stmt, err := vsa.ToStatement()
+		if err != nil {
+			t.Fatalf("ToStatement() error = %v", err)
+		}
+		if stmt.PredicateType != PredicateSLSAVerificationSummaryV1 {
+			t.Errorf("ToStatement() PredicateType = %s", stmt.PredicateType)
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		vsa, err := NewVSA(bundle, PolicyOutcome{Passed: false, VerifiedLevels: []string{LevelRebuildV01}}, opts)
+		if err != nil {
+			t.Fatalf("NewVSA() error = %v", err)
+		}
+		if vsa.Predicate.VerificationResult != VerificationResultFailed {
+			t.Errorf("VerificationResult = %s, want %s", vsa.Predicate.VerificationResult, VerificationResultFailed)
+		}
+		if len(vsa.Predicate.VerifiedLevels) != 0 {
+			t.Errorf("VerifiedLevels = %v, want empty", vsa.Predicate.VerifiedLevels)
+		}
+	})
+
+	t.Run("MissingVerifier", func(t *testing.T) {
+		if _, err := NewVSA(bundle, PolicyOutcome{Passed: true}, VSAOptions{}); err == nil {
+			t.Error("NewVSA() expected error")
+		}
+	})
+
+	t.Run("MissingEquivalence", func(t *testing.T) {
+		var data bytes.Buffer
+		orDie(json.NewEncoder(&data).Encode(createTestEnvelope(t, rb)))
+		b := must(NewBundle(ctx, data.Bytes(), must(dsse.NewEnvelopeVerifier(&successVerifier{}))))
+		if _, err := NewVSA(b, PolicyOutcome{Passed: true}, opts); err == nil {
+			t.Error("NewVSA() expected error")
+		}
+	})
+}
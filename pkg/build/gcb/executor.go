@@ -95,6 +95,7 @@ func (e *Executor) Start(ctx context.Context, input rebuild.Input, opts build.Op
 	planOpts := build.PlanOptions{
 		UseTimewarp:            opts.UseTimewarp,
 		UseNetworkProxy:        opts.UseNetworkProxy,
+		NetworkAuditPolicy:     opts.NetworkAuditPolicy,
 		UseSyscallMonitor:      opts.UseSyscallMonitor,
 		PreferPreciseToolchain: true,
 		Resources:              opts.Resources,
@@ -257,10 +257,15 @@ var gcbProxyBuildTpl = template.Must(
 			{{- end}}
 			curl -O {{if .ProxyAuth}}-H @/tmp/auth_header {{end -}} {{.ProxyURL}}
 			chmod +x proxy
+			{{- if .AuditPolicy}}
+			cat <<'EOP' > /workspace/proxy-policy.json
+			{{.AuditPolicy}}
+			EOP
+			{{- end}}
 			docker network create proxynet
 			useradd --system {{.User}}
 			uid=$(id -u {{.User}})
-			docker run --detach --name=proxy --network=proxynet --privileged -v=/workspace/proxy:/workspace/proxy -v=/var/run/docker.sock:/var/run/docker.sock --entrypoint /bin/sh gcr.io/cloud-builders/docker -euxc '
+			docker run --detach --name=proxy --network=proxynet --privileged -v=/workspace/proxy:/workspace/proxy {{if .AuditPolicy}}-v=/workspace/proxy-policy.json:/workspace/proxy-policy.json {{end}}-v=/var/run/docker.sock:/var/run/docker.sock --entrypoint /bin/sh gcr.io/cloud-builders/docker -euxc '
 				useradd --system --non-unique --uid '$uid' {{.User}}
 				chown {{.User}} /workspace/proxy
 				chown {{.User}} /var/run/docker.sock
@@ -273,6 +278,10 @@ var gcbProxyBuildTpl = template.Must(
 					-docker_socket=/var/run/docker.sock \
 					-docker_truststore_env_vars={{join "," .CertEnvVars}} \
 					-docker_network=container:build \
+					{{- if .AuditPolicy}}
+					-policy_mode=audit \
+					-policy_file=/workspace/proxy-policy.json \
+					{{- end}}
 					-docker_java_truststore=true"
 			'
 			proxyIP=$(docker inspect -f '{{printf "%s" "{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}"}}' proxy)
@@ -313,6 +322,9 @@ var gcbProxyBuildTpl = template.Must(
 			docker kill tetragon
 			{{- end}}
 			curl http://proxy:{{.CtrlPort}}/summary > /workspace/netlog.json
+			{{- if .AuditPolicy}}
+			curl http://proxy:{{.CtrlPort}}/violations > /workspace/proxy-violations.json
+			{{- end}}
 			`)[1:], // remove leading newline
 	))
 
@@ -507,6 +519,9 @@ func (p *Planner) generateProxyBuildScript(target rebuild.Target, dockerfile str
 	if err != nil {
 		return "", errors.Wrap(err, "failed to process timewarp URL")
 	}
+	if opts.NetworkAuditPolicy != "" && !json.Valid([]byte(opts.NetworkAuditPolicy)) {
+		return "", errors.New("network audit policy is not valid JSON")
+	}
 	var buf bytes.Buffer
 	if err := gcbProxyBuildTpl.Execute(&buf, map[string]any{
 		"TargetStr":           fmt.Sprintf("%+v", target),
@@ -523,6 +538,7 @@ func (p *Planner) generateProxyBuildScript(target rebuild.Target, dockerfile str
 		"CtrlPort":            "3127",
 		"DockerPort":          "3130",
 		"CertEnvVars":         []string{"PIP_CERT", "CURL_CA_BUNDLE", "NODE_EXTRA_CA_CERTS", "CLOUDSDK_CORE_CUSTOM_CA_CERTS_FILE", "NIX_SSL_CERT_FILE"},
+		"AuditPolicy":         opts.NetworkAuditPolicy,
 	}); err != nil {
 		return "", errors.Wrap(err, "failed to execute proxy build template")
 	}
@@ -543,6 +559,9 @@ func (p *Planner) generateAssetUploadScript(target rebuild.Target, opts build.Pl
 		}
 		if opts.UseNetworkProxy {
 			assetTypes = append(assetTypes, rebuild.ProxyNetlogAsset)
+			if opts.NetworkAuditPolicy != "" {
+				assetTypes = append(assetTypes, rebuild.ProxyViolationsAsset)
+			}
 		}
 		for _, assetType := range assetTypes {
 			url := opts.Resources.AssetStore.URL(assetType.For(target))
@@ -570,6 +589,11 @@ func (p *Planner) generateAssetUploadScript(target rebuild.Target, opts build.Pl
 					From: "/workspace/netlog.json",
 					To:   url.String(),
 				})
+			case rebuild.ProxyViolationsAsset:
+				uploads = append(uploads, upload{
+					From: "/workspace/proxy-violations.json",
+					To:   url.String(),
+				})
 			}
 		}
 	}
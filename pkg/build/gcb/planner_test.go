@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-git/go-billy/v5/memfs"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/pkg/build"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
@@ -283,3 +284,57 @@ func TestGCBPlannerGeneratePlan(t *testing.T) {
 		t.Error("Expected upload step not found")
 	}
 }
+
+func TestGCBPlannerNetworkAuditPolicy(t *testing.T) {
+	planner := NewPlanner(PlannerConfig{
+		Project:        "test-project",
+		ServiceAccount: "test@test.iam.gserviceaccount.com",
+	})
+	input := rebuild.Input{
+		Target: rebuild.Target{Ecosystem: rebuild.NPM, Package: "pkg", Version: "version", Artifact: "pkg-version.tgz"},
+		Strategy: &rebuild.ManualStrategy{
+			Location:   rebuild.Location{Repo: "github.com/example", Ref: "main", Dir: "/src"},
+			SystemDeps: []string{"git", "make"},
+			Deps:       "make deps ...",
+			Build:      "make build ...",
+			OutputPath: "output/foo.tgz",
+		},
+	}
+	policy := `{"Policy": {"AnyOf": [{"ruleType": "MethodRule", "methods": ["GET"]}]}}`
+	opts := build.PlanOptions{
+		UseNetworkProxy:    true,
+		NetworkAuditPolicy: policy,
+		Resources: build.Resources{
+			AssetStore: rebuild.NewFilesystemAssetStore(memfs.New()),
+			ToolURLs: map[build.ToolType]string{
+				build.ProxyTool:    "https://test-bootstrap.storage.googleapis.com/proxy",
+				build.GSUtilTool:   "https://test-bootstrap.storage.googleapis.com/gsutil_writeonly",
+				build.TimewarpTool: "https://test-bootstrap.storage.googleapis.com/timewarp",
+			},
+			BaseImageConfig: build.DefaultBaseImageConfig(),
+		},
+	}
+	plan, err := planner.GeneratePlan(context.Background(), input, opts)
+	if err != nil {
+		t.Fatalf("GeneratePlan failed: %v", err)
+	}
+	var scripts strings.Builder
+	for _, step := range plan.Steps {
+		scripts.WriteString(step.Script)
+	}
+	for _, want := range []string{
+		"cat <<'EOP' > /workspace/proxy-policy.json\n" + policy + "\nEOP\n",
+		"-policy_mode=audit",
+		"-policy_file=/workspace/proxy-policy.json",
+		"curl http://proxy:3127/violations > /workspace/proxy-violations.json",
+		"cp /workspace/proxy-violations.json file:///npm/pkg/version/pkg-version.tgz/proxy-violations.json",
+	} {
+		if !strings.Contains(scripts.String(), want) {
+			t.Errorf("plan missing %q", want)
+		}
+	}
+	opts.NetworkAuditPolicy = "{not json"
+	if _, err := planner.GeneratePlan(context.Background(), input, opts); err == nil {
+		t.Error("GeneratePlan with invalid policy succeeded")
+	}
+}
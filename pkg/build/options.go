@@ -34,6 +34,9 @@ type Options struct {
 	UseTimewarp bool
 	// UseNetworkProxy enables network proxy functionality
 	UseNetworkProxy bool
+	// NetworkAuditPolicy is a JSON-encoded proxy policy to evaluate in audit mode.
+	// Violations are uploaded as the ProxyViolationsAsset. Requires UseNetworkProxy.
+	NetworkAuditPolicy string
 	// UseSyscallMonitor enables syscall monitoring
 	UseSyscallMonitor bool
 	// Resources configures URLs and authentication for build resources
@@ -46,6 +49,9 @@ type PlanOptions struct {
 	UseTimewarp bool
 	// UseNetworkProxy enables network proxy functionality
 	UseNetworkProxy bool
+	// NetworkAuditPolicy is a JSON-encoded proxy policy to evaluate in audit mode.
+	// Violations are uploaded as the ProxyViolationsAsset. Requires UseNetworkProxy.
+	NetworkAuditPolicy string
 	// UseSyscallMonitor enables syscall monitoring
 	UseSyscallMonitor bool
 	// PreferPreciseToolchain indicates whether to use precise toolchain versions
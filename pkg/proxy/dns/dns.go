@@ -0,0 +1,280 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dns defines a DNS forwarder that records and filters the queries it relays.
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// maxMessageSize is the largest DNS message that can be carried over UDP or TCP.
+const maxMessageSize = 65535
+
+// Forwarder relays DNS queries received over UDP or TCP to an upstream resolver.
+//
+// Queries are relayed upstream over the transport on which they were received.
+// Responses too large for UDP are relayed with the truncation bit set, as
+// received from upstream, so clients can retry the query over TCP.
+type Forwarder struct {
+	// Upstream is the address of the resolver to which queries are relayed, e.g. 8.8.8.8:53.
+	Upstream string
+	// Allow, if provided, reports whether queries for a name may be resolved.
+	// Queries for names that are not allowed are answered with REFUSED.
+	Allow func(name string) bool
+	// Log, if provided, is called with the outcome of each query.
+	Log func(netlog.DNSQueryLog)
+	// Timeout bounds each exchange with the upstream resolver and the time a
+	// TCP connection may remain idle.
+	Timeout time.Duration
+}
+
+func (f *Forwarder) timeout() time.Duration {
+	if f.Timeout == 0 {
+		return 5 * time.Second
+	}
+	return f.Timeout
+}
+
+// Serve answers the queries received on pc until it is closed.
+func (f *Forwarder) Serve(pc net.PacketConn) error {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		query := slices.Clone(buf[:n])
+		go func() {
+			resp := f.Handle(query)
+			if resp == nil {
+				return
+			}
+			if _, err := pc.WriteTo(resp, addr); err != nil {
+				log.Printf("Error writing DNS response to %s: %v", addr, err)
+			}
+		}()
+	}
+}
+
+// ServeTCP answers the queries received on connections accepted from l until it is closed.
+func (f *Forwarder) ServeTCP(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go f.serveConn(c)
+	}
+}
+
+// serveConn answers the queries received on c until it is closed, idle, or sends a malformed query.
+func (f *Forwarder) serveConn(c net.Conn) {
+	defer c.Close()
+	for {
+		if err := c.SetReadDeadline(time.Now().Add(f.timeout())); err != nil {
+			return
+		}
+		query, err := readTCPMessage(c)
+		if err != nil {
+			return
+		}
+		resp := f.handle(query, "tcp")
+		if resp == nil {
+			return
+		}
+		if err := writeTCPMessage(c, resp); err != nil {
+			log.Printf("Error writing DNS response to %s: %v", c.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// Handle returns the packed response to the packed DNS query received over UDP.
+// Returns nil if the query is malformed and should be dropped.
+func (f *Forwarder) Handle(query []byte) []byte {
+	return f.handle(query, "udp")
+}
+
+// handle returns the packed response to the packed DNS query, relaying it
+// upstream over network.
+func (f *Forwarder) handle(query []byte, network string) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil
+	}
+	entry := netlog.DNSQueryLog{
+		Name:      strings.TrimSuffix(q.Name.String(), "."),
+		Type:      strings.TrimPrefix(q.Type.String(), "Type"),
+		StartTime: time.Now().UTC(),
+	}
+	var resp []byte
+	var rcode dnsmessage.RCode
+	switch {
+	case f.Allow != nil && !f.Allow(entry.Name):
+		log.Printf("DNS query for %s blocked by network policy", entry.Name)
+		entry.Blocked = true
+		rcode = dnsmessage.RCodeRefused
+		resp, err = reply(h, q, rcode)
+	default:
+		if resp, err = f.exchange(query, network); err != nil {
+			log.Printf("Error resolving %s: %v", entry.Name, err)
+			rcode = dnsmessage.RCodeServerFailure
+			resp, err = reply(h, q, rcode)
+			break
+		}
+		if rcode, entry.Answers, err = answers(resp); err != nil {
+			// Relay the upstream response, even if unparseable, and log what we know.
+			log.Printf("Error parsing DNS response for %s: %v", entry.Name, err)
+			err = nil
+		}
+	}
+	if err != nil {
+		log.Printf("Error building DNS response for %s: %v", entry.Name, err)
+		return nil
+	}
+	entry.RCode = strings.TrimPrefix(rcode.String(), "RCode")
+	entry.Duration = time.Since(entry.StartTime)
+	if f.Log != nil {
+		f.Log(entry)
+	}
+	return resp
+}
+
+// exchange sends the query to the upstream resolver over network and returns its response.
+func (f *Forwarder) exchange(query []byte, network string) ([]byte, error) {
+	timeout := f.timeout()
+	conn, err := net.DialTimeout(network, f.Upstream, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxMessageSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// readTCPMessage reads a single length-prefixed DNS message from r.
+// See https://www.rfc-editor.org/rfc/rfc1035#section-4.2.2
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var prefix [2]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(prefix[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeTCPMessage writes msg to w with its length prefix.
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	if len(msg) > maxMessageSize {
+		return errors.New("message too large")
+	}
+	b := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(msg)), uint16(len(msg)))
+	_, err := w.Write(append(b, msg...))
+	return err
+}
+
+// reply builds a response to q carrying only the given response code.
+func reply(h dnsmessage.Header, q dnsmessage.Question, rcode dnsmessage.RCode) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 h.ID,
+		Response:           true,
+		OpCode:             h.OpCode,
+		RecursionDesired:   h.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// answers returns the response code along with the addresses and canonical names answered in resp.
+func answers(resp []byte) (dnsmessage.RCode, []string, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(resp)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return h.RCode, nil, err
+	}
+	var values []string
+	for {
+		ah, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			return h.RCode, values, nil
+		} else if err != nil {
+			return h.RCode, values, err
+		}
+		switch ah.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return h.RCode, values, err
+			}
+			values = append(values, netip.AddrFrom4(r.A).String())
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return h.RCode, values, err
+			}
+			values = append(values, netip.AddrFrom16(r.AAAA).String())
+		case dnsmessage.TypeCNAME:
+			r, err := p.CNAMEResource()
+			if err != nil {
+				return h.RCode, values, err
+			}
+			values = append(values, strings.TrimSuffix(r.CNAME.String(), "."))
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return h.RCode, values, err
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package dns
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func must[T any](t T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// fakeResolver answers A queries for example.com via a CNAME and everything else with NXDOMAIN.
+// It serves UDP and TCP on the same address and answers queries for large.example.com only over
+// TCP, setting the truncation bit over UDP.
+func fakeResolver(t *testing.T) (addr string, queries func(network string) int) {
+	t.Helper()
+	pc := must(net.ListenPacket("udp", "127.0.0.1:0"))
+	t.Cleanup(func() { pc.Close() })
+	l := must(net.Listen("tcp", pc.LocalAddr().String()))
+	t.Cleanup(func() { l.Close() })
+	var mx sync.Mutex
+	count := make(map[string]int)
+	answer := func(query []byte, network string) []byte {
+		mx.Lock()
+		count[network]++
+		mx.Unlock()
+		var p dnsmessage.Parser
+		h := must(p.Start(query))
+		q := must(p.Question())
+		rh := dnsmessage.Header{ID: h.ID, Response: true, RCode: dnsmessage.RCodeNameError}
+		switch q.Name.String() {
+		case "example.com.", "large.example.com.":
+			rh.RCode = dnsmessage.RCodeSuccess
+		}
+		truncated := q.Name.String() == "large.example.com." && network == "udp"
+		rh.Truncated = truncated
+		b := dnsmessage.NewBuilder(nil, rh)
+		b.StartQuestions()
+		b.Question(q)
+		switch {
+		case truncated:
+		case q.Name.String() == "large.example.com.":
+			b.StartAnswers()
+			b.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET}, dnsmessage.AResource{A: [4]byte{192, 0, 2, 2}})
+		case rh.RCode == dnsmessage.RCodeSuccess:
+			b.StartAnswers()
+			target := dnsmessage.MustNewName("www.example.com.")
+			b.CNAMEResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET}, dnsmessage.CNAMEResource{CNAME: target})
+			b.AResource(dnsmessage.ResourceHeader{Name: target, Class: dnsmessage.ClassINET}, dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}})
+		}
+		return must(b.Finish())
+	}
+	go func() {
+		buf := make([]byte, maxMessageSize)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(answer(buf[:n], "udp"), addr)
+		}
+	}()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				for {
+					msg, err := readTCPMessage(c)
+					if err != nil {
+						return
+					}
+					writeTCPMessage(c, answer(msg, "tcp"))
+				}
+			}()
+		}
+	}()
+	return pc.LocalAddr().String(), func(network string) int {
+		mx.Lock()
+		defer mx.Unlock()
+		return count[network]
+	}
+}
+
+func query(name string) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	b.StartQuestions()
+	b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET})
+	return must(b.Finish())
+}
+
+func TestForwarder(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantRCode   dnsmessage.RCode
+		wantLog     netlog.DNSQueryLog
+		wantQueries int
+	}{
+		{
+			name:        "allowed name is resolved",
+			query:       "example.com.",
+			wantRCode:   dnsmessage.RCodeSuccess,
+			wantLog:     netlog.DNSQueryLog{Name: "example.com", Type: "A", Answers: []string{"www.example.com", "192.0.2.1"}, RCode: "Success"},
+			wantQueries: 1,
+		},
+		{
+			name:        "upstream error is relayed",
+			query:       "missing.example.com.",
+			wantRCode:   dnsmessage.RCodeNameError,
+			wantLog:     netlog.DNSQueryLog{Name: "missing.example.com", Type: "A", RCode: "NameError"},
+			wantQueries: 1,
+		},
+		{
+			name:        "disallowed name is refused",
+			query:       "evil.com.",
+			wantRCode:   dnsmessage.RCodeRefused,
+			wantLog:     netlog.DNSQueryLog{Name: "evil.com", Type: "A", RCode: "Refused", Blocked: true},
+			wantQueries: 0,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream, queries := fakeResolver(t)
+			var logs []netlog.DNSQueryLog
+			f := &Forwarder{
+				Upstream: upstream,
+				Allow:    func(name string) bool { return name != "evil.com" },
+				Log:      func(e netlog.DNSQueryLog) { logs = append(logs, e) },
+			}
+			resp := f.Handle(query(tc.query))
+			if resp == nil {
+				t.Fatal("Handle() returned no response")
+			}
+			var p dnsmessage.Parser
+			h := must(p.Start(resp))
+			if h.ID != 42 || !h.Response {
+				t.Errorf("response header = %+v, want ID 42 response", h)
+			}
+			if h.RCode != tc.wantRCode {
+				t.Errorf("RCode = %v, want %v", h.RCode, tc.wantRCode)
+			}
+			if got := queries("udp"); got != tc.wantQueries {
+				t.Errorf("upstream queries = %d, want %d", got, tc.wantQueries)
+			}
+			if diff := cmp.Diff([]netlog.DNSQueryLog{tc.wantLog}, logs, cmpopts.IgnoreFields(netlog.DNSQueryLog{}, "StartTime", "Duration")); diff != "" {
+				t.Errorf("logs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestForwarderUpstreamFailure(t *testing.T) {
+	// Reserve and release a port so nothing answers on it.
+	pc := must(net.ListenPacket("udp", "127.0.0.1:0"))
+	upstream := pc.LocalAddr().String()
+	pc.Close()
+	var logs []netlog.DNSQueryLog
+	f := &Forwarder{Upstream: upstream, Timeout: 100 * time.Millisecond, Log: func(e netlog.DNSQueryLog) { logs = append(logs, e) }}
+	resp := f.Handle(query("example.com."))
+	var p dnsmessage.Parser
+	if h := must(p.Start(resp)); h.RCode != dnsmessage.RCodeServerFailure {
+		t.Errorf("RCode = %v, want %v", h.RCode, dnsmessage.RCodeServerFailure)
+	}
+	if len(logs) != 1 || logs[0].RCode != "ServerFailure" {
+		t.Errorf("logs = %+v, want one ServerFailure entry", logs)
+	}
+}
+
+func TestForwarderMalformedQuery(t *testing.T) {
+	f := &Forwarder{Upstream: "127.0.0.1:1"}
+	if resp := f.Handle([]byte{0x01}); resp != nil {
+		t.Errorf("Handle() = %v, want nil", resp)
+	}
+}
+
+func TestForwarderTCP(t *testing.T) {
+	upstream, queries := fakeResolver(t)
+	var mx sync.Mutex
+	var logs []netlog.DNSQueryLog
+	f := &Forwarder{
+		Upstream: upstream,
+		Log: func(e netlog.DNSQueryLog) {
+			mx.Lock()
+			defer mx.Unlock()
+			logs = append(logs, e)
+		},
+	}
+	// A response too large for UDP is relayed truncated.
+	var p dnsmessage.Parser
+	if h := must(p.Start(f.Handle(query("large.example.com.")))); !h.Truncated {
+		t.Fatalf("UDP response header = %+v, want truncated", h)
+	}
+	// The client then retries over TCP, sending multiple queries on one connection.
+	l := must(net.Listen("tcp", "127.0.0.1:0"))
+	defer l.Close()
+	go f.ServeTCP(l)
+	c := must(net.Dial("tcp", l.Addr().String()))
+	defer c.Close()
+	for _, name := range []string{"large.example.com.", "example.com."} {
+		if err := writeTCPMessage(c, query(name)); err != nil {
+			t.Fatal(err)
+		}
+		resp := must(readTCPMessage(c))
+		h := must(p.Start(resp))
+		if h.ID != 42 || !h.Response || h.Truncated || h.RCode != dnsmessage.RCodeSuccess {
+			t.Errorf("TCP response header for %s = %+v, want complete successful response", name, h)
+		}
+	}
+	if got := queries("tcp"); got != 2 {
+		t.Errorf("upstream TCP queries = %d, want 2", got)
+	}
+	mx.Lock()
+	defer mx.Unlock()
+	want := []netlog.DNSQueryLog{
+		{Name: "large.example.com", Type: "A", RCode: "Success"},
+		{Name: "large.example.com", Type: "A", Answers: []string{"192.0.2.2"}, RCode: "Success"},
+		{Name: "example.com", Type: "A", Answers: []string{"www.example.com", "192.0.2.1"}, RCode: "Success"},
+	}
+	if diff := cmp.Diff(want, logs, cmpopts.IgnoreFields(netlog.DNSQueryLog{}, "StartTime", "Duration")); diff != "" {
+		t.Errorf("logs mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,482 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	iofs "io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	re "regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/oss-rebuild/internal/proxy/dockerfs"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// gRPC methods of the containerd API that are intercepted.
+const (
+	containerdCreateMethod = "/containerd.services.containers.v1.Containers/Create"
+	containerdDeleteMethod = "/containerd.services.containers.v1.Containers/Delete"
+	containerdStartMethod  = "/containerd.services.tasks.v1.Tasks/Start"
+	containerdPauseMethod  = "/containerd.services.tasks.v1.Tasks/Pause"
+	containerdResumeMethod = "/containerd.services.tasks.v1.Tasks/Resume"
+	// gRPC metadata identifying the namespace of a request.
+	containerdNamespaceHeader = "Containerd-Namespace"
+	// Type of the Any message containing the OCI runtime spec of a container.
+	ociSpecTypeURL = "types.containerd.io/opencontainers/runtime-spec/1/Spec"
+)
+
+// Valid containerd namespaces and container IDs.
+var containerdIdentifierPattern = re.MustCompile(`^[A-Za-z0-9]+(?:[._-][A-Za-z0-9]+)*$`)
+
+// ContainerdRuntime is the containerd gRPC API, as used by nerdctl.
+//
+// Unlike the Docker API, containerd provides no access to container files so
+// root filesystems are patched directly on the host. The proxy must therefore
+// share a mount namespace with containerd and have access to its state.
+//
+// Containers are patched on task start and the patches are reverted while the
+// task is paused, as nerdctl does to commit a container.
+type ContainerdRuntime struct {
+	// StateDir is containerd's state directory. Defaults to /run/containerd.
+	StateDir string
+	// VolumeDir is the host directory in which volumes bound into containers
+	// are created. Defaults to the system temporary directory. A container's
+	// volumes are removed when it is deleted.
+	VolumeDir string
+}
+
+var _ Runtime = ContainerdRuntime{}
+
+// DefaultSocket returns the path of the containerd daemon's socket.
+func (ContainerdRuntime) DefaultSocket() string {
+	return "/run/containerd/containerd.sock"
+}
+
+func (ContainerdRuntime) socketEnvVars(path string) []string {
+	return []string{containerdEnvVar + "=" + path}
+}
+
+func (r ContainerdRuntime) serve(d *ContainerTruststorePatcher, c net.Conn, socket string) {
+	defer c.Close()
+	tr := &http2.Transport{
+		// NOTE: gRPC over UDS uses HTTP/2 without TLS.
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}
+	defer tr.CloseIdleConnections()
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = "http"
+			pr.Out.URL.Host = "containerd"
+		},
+		Transport: tr,
+		// Relay streamed responses without delay.
+		FlushInterval: -1,
+	}
+	srv := &http2.Server{}
+	srv.ServeConn(c, &http2.ServeConnOpts{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			done, err := r.intercept(d, req)
+			if err != nil {
+				log.Printf("Failed to intercept %s: %s", req.URL.Path, err)
+				w.Header().Set("Content-Type", "application/grpc")
+				w.Header().Set("Grpc-Status", "13") // INTERNAL
+				w.Header().Set("Grpc-Message", err.Error())
+				return
+			}
+			rp.ServeHTTP(w, req)
+			if done != nil {
+				done(grpcSucceeded(w.Header()))
+			}
+		}),
+	})
+}
+
+// intercept patches containers according to the containerd method invoked by req.
+// If non-nil, done must be called with whether the upstream call succeeded.
+func (r ContainerdRuntime) intercept(d *ContainerTruststorePatcher, req *http.Request) (done func(ok bool), err error) {
+	switch req.URL.Path {
+	case containerdCreateMethod, containerdDeleteMethod, containerdStartMethod, containerdPauseMethod, containerdResumeMethod:
+	default:
+		return nil, nil
+	}
+	log.Printf("Intercepting request: %s", req.URL.Path)
+	msg, err := readGRPCMessage(req.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading request")
+	}
+	req.Body.Close()
+	// NOTE: Restore the body, as modified, for the upstream request.
+	defer func() {
+		b := grpcMessage(msg)
+		req.ContentLength = int64(len(b))
+		req.Body = io.NopCloser(bytes.NewReader(b))
+	}()
+	ns := req.Header.Get(containerdNamespaceHeader)
+	if req.URL.Path == containerdCreateMethod {
+		// CreateContainerRequest.container.spec.value
+		var volumes []string
+		msg, err = rewriteProtoField(msg, 1, func(container []byte) ([]byte, error) {
+			id, err := protoField(container, 1)
+			if err != nil {
+				return nil, err
+			}
+			return rewriteProtoField(container, 5, func(spec []byte) ([]byte, error) {
+				if typeURL, err := protoField(spec, 1); err != nil || string(typeURL) != ociSpecTypeURL {
+					return spec, err
+				}
+				return rewriteProtoField(spec, 2, func(value []byte) ([]byte, error) {
+					dir, err := r.containerVolumeDir(ns, string(id))
+					if err != nil {
+						return nil, err
+					}
+					s, err := newOCISpec(value, dir)
+					if err != nil {
+						return nil, err
+					}
+					if err := d.patchSpec(s); err != nil {
+						// NOTE: As with the Docker API, failing to patch would allow the
+						// container to run unobserved so it's better we crash.
+						log.Fatalf("Failed to patch spec for request %s: %s", req.URL.Path, err)
+					}
+					volumes = s.volumes
+					return s.Bytes()
+				})
+			})
+		})
+		if err != nil || len(volumes) == 0 {
+			return nil, err
+		}
+		// Volumes of a container that failed to be created would never be removed.
+		// NOTE: Only this request's volumes are removed since the failure may be
+		// due to a container of the same ID already existing.
+		return func(ok bool) {
+			if !ok {
+				for _, v := range volumes {
+					removeVolume(v)
+				}
+			}
+		}, nil
+	}
+	id, err := protoField(msg, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing request")
+	}
+	if req.URL.Path == containerdDeleteMethod {
+		dir, err := r.containerVolumeDir(ns, string(id))
+		if err != nil {
+			return nil, err
+		}
+		return func(ok bool) {
+			if ok {
+				removeVolume(dir)
+			}
+		}, nil
+	}
+	if req.URL.Path == containerdStartMethod {
+		// Exec processes are started in a running container which is already patched.
+		if execID, err := protoField(msg, 2); err != nil || len(execID) > 0 {
+			return nil, err
+		}
+	}
+	dfs, err := r.containerFS(ns, string(id))
+	if err != nil {
+		log.Printf("Unable to access container %s: %s", id, err)
+		return nil, nil
+	}
+	key := ns + "/" + string(id)
+	switch req.URL.Path {
+	case containerdStartMethod:
+		d.patchContainer(dfs, key)
+	case containerdPauseMethod:
+		d.suspendPatches(dfs, key)
+	case containerdResumeMethod:
+		d.resumePatches(dfs, key)
+	}
+	return nil, nil
+}
+
+func (r ContainerdRuntime) stateDir() string {
+	if r.StateDir == "" {
+		return "/run/containerd"
+	}
+	return r.StateDir
+}
+
+func (r ContainerdRuntime) volumeDir() string {
+	if r.VolumeDir == "" {
+		return os.TempDir()
+	}
+	return r.VolumeDir
+}
+
+// containerVolumeDir returns the directory holding the volumes created for a container.
+func (r ContainerdRuntime) containerVolumeDir(ns, id string) (string, error) {
+	if !containerdIdentifierPattern.MatchString(ns) || !containerdIdentifierPattern.MatchString(id) {
+		return "", errors.Errorf("invalid container identifier: %s/%s", ns, id)
+	}
+	return filepath.Join(r.volumeDir(), "containerd-volumes", ns, id), nil
+}
+
+// removeVolume removes dir and its contents, logging any failure.
+func removeVolume(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("Failed to remove volume %s: %s", dir, err)
+	}
+}
+
+// grpcSucceeded returns whether the headers, including trailers, of a gRPC response report success.
+func grpcSucceeded(h http.Header) bool {
+	status := h.Get("Grpc-Status")
+	if status == "" {
+		status = h.Get(http.TrailerPrefix + "Grpc-Status")
+	}
+	return status == "0"
+}
+
+// containerFS returns the filesystem of a container whose task has been created.
+func (r ContainerdRuntime) containerFS(ns, id string) (dockerfs.FS, error) {
+	if !containerdIdentifierPattern.MatchString(ns) || !containerdIdentifierPattern.MatchString(id) {
+		return nil, errors.Errorf("invalid container identifier: %s/%s", ns, id)
+	}
+	bundle := filepath.Join(r.stateDir(), "io.containerd.runtime.v2.task", ns, id)
+	b, err := os.ReadFile(filepath.Join(bundle, "config.json"))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading bundle config")
+	}
+	var spec struct {
+		Root struct {
+			Path string `json:"path"`
+		} `json:"root"`
+		Mounts []struct {
+			Destination string   `json:"destination"`
+			Type        string   `json:"type"`
+			Source      string   `json:"source"`
+			Options     []string `json:"options"`
+		} `json:"mounts"`
+	}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return nil, errors.Wrap(err, "parsing bundle config")
+	}
+	root := spec.Root.Path
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(bundle, root)
+	}
+	mounts := make(map[string]string)
+	for _, m := range spec.Mounts {
+		if m.Type != "bind" && !slices.Contains(m.Options, "bind") && !slices.Contains(m.Options, "rbind") {
+			continue
+		}
+		// NOTE: Only directories are supported by RootFS.
+		if fi, err := os.Stat(m.Source); err != nil || !fi.IsDir() {
+			continue
+		}
+		mounts[m.Destination] = m.Source
+	}
+	return dockerfs.RootFS{Root: root, Mounts: mounts}, nil
+}
+
+// ociSpec is an OCI runtime spec, as provided to containerd.
+// See https://github.com/opencontainers/runtime-spec/blob/main/config.md
+type ociSpec struct {
+	spec      map[string]any
+	volumeDir string
+	// volumes are the directories created by AddBind.
+	volumes []string
+}
+
+var _ containerSpec = &ociSpec{}
+
+func newOCISpec(body []byte, volumeDir string) (*ociSpec, error) {
+	spec := make(map[string]any)
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&spec); err != nil {
+		return nil, errors.Errorf("failed to unmarshal json: %s\nBody: %s", err, string(body))
+	}
+	return &ociSpec{spec: spec, volumeDir: volumeDir}, nil
+}
+
+func (s *ociSpec) process() (map[string]any, error) {
+	raw, ok := s.spec["process"]
+	if !ok || raw == nil {
+		process := make(map[string]any)
+		s.spec["process"] = process
+		return process, nil
+	}
+	process, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.New("unexpected type of process")
+	}
+	return process, nil
+}
+
+func (s *ociSpec) Env(name string) (string, error) {
+	process, err := s.process()
+	if err != nil {
+		return "", err
+	}
+	env, _ := process["env"].([]any)
+	// NOTE: Last one wins!
+	val, found := "", false
+	for _, e := range env {
+		if e, ok := e.(string); ok && strings.HasPrefix(e, name+"=") {
+			val, found = strings.TrimPrefix(e, name+"="), true
+		}
+	}
+	if !found {
+		return "", iofs.ErrNotExist
+	}
+	return val, nil
+}
+
+func (s *ociSpec) AddEnv(vars []string) error {
+	process, err := s.process()
+	if err != nil {
+		return err
+	}
+	env, _ := process["env"].([]any)
+	if process["env"] != nil && env == nil {
+		return errors.New("unexpected type of process.env")
+	}
+	for _, v := range vars {
+		env = append(env, v)
+	}
+	process["env"] = env
+	return nil
+}
+
+// AddBind adds a bind mount of from to the container.
+// Named volumes are created as new directories within volumeDir.
+func (s *ociSpec) AddBind(from, to, mode string) error {
+	if !filepath.IsAbs(from) {
+		if err := os.MkdirAll(s.volumeDir, 0755); err != nil {
+			return errors.Wrap(err, "creating volume directory")
+		}
+		dir, err := os.MkdirTemp(s.volumeDir, from+"-*")
+		if err != nil {
+			return errors.Wrap(err, "creating volume")
+		}
+		// NOTE: MkdirTemp restricts access to the owner but the cert files
+		// within must be readable by any user in the container.
+		s.volumes = append(s.volumes, dir)
+		if err := os.Chmod(dir, 0755); err != nil {
+			return errors.Wrap(err, "setting volume mode")
+		}
+		from = dir
+	}
+	raw, ok := s.spec["mounts"]
+	var mounts []any
+	if ok && raw != nil {
+		if mounts, ok = raw.([]any); !ok {
+			return errors.New("unexpected type of mounts")
+		}
+	}
+	s.spec["mounts"] = append(mounts, map[string]any{
+		"destination": to,
+		"type":        "bind",
+		"source":      from,
+		"options":     []any{"rbind", mode},
+	})
+	return nil
+}
+
+// Network is unsupported since nerdctl configures networks outside of the spec.
+func (s *ociSpec) Network() (string, error) {
+	return "", errors.New("network configuration is unsupported for containerd")
+}
+
+// SetNetwork is unsupported since nerdctl configures networks outside of the spec.
+func (s *ociSpec) SetNetwork(network string) error {
+	return errors.New("network configuration is unsupported for containerd")
+}
+
+func (s *ociSpec) Bytes() ([]byte, error) {
+	b, err := json.Marshal(s.spec)
+	if err != nil {
+		return nil, errors.Errorf("failed to re-marshal json: %s\nStruct: %s", err, s.spec)
+	}
+	return b, nil
+}
+
+// readGRPCMessage reads a single length-prefixed gRPC message from r.
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("compressed messages are unsupported")
+	}
+	msg := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// grpcMessage returns msg with the gRPC length prefix.
+func grpcMessage(msg []byte) []byte {
+	b := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	return append(b, msg...)
+}
+
+// protoField returns the last value of the length-delimited field num in the protobuf message msg.
+func protoField(msg []byte, num protowire.Number) ([]byte, error) {
+	var val []byte
+	_, err := rewriteProtoField(msg, num, func(b []byte) ([]byte, error) {
+		val = b
+		return b, nil
+	})
+	return val, err
+}
+
+// rewriteProtoField replaces each value of the length-delimited field num in the protobuf message msg with the result of f.
+func rewriteProtoField(msg []byte, num protowire.Number, f func([]byte) ([]byte, error)) ([]byte, error) {
+	var out []byte
+	for len(msg) > 0 {
+		n, typ, tagLen := protowire.ConsumeTag(msg)
+		if tagLen < 0 {
+			return nil, protowire.ParseError(tagLen)
+		}
+		if n != num || typ != protowire.BytesType {
+			valLen := protowire.ConsumeFieldValue(n, typ, msg[tagLen:])
+			if valLen < 0 {
+				return nil, protowire.ParseError(valLen)
+			}
+			out = append(out, msg[:tagLen+valLen]...)
+			msg = msg[tagLen+valLen:]
+			continue
+		}
+		val, valLen := protowire.ConsumeBytes(msg[tagLen:])
+		if valLen < 0 {
+			return nil, protowire.ParseError(valLen)
+		}
+		newVal, err := f(val)
+		if err != nil {
+			return nil, err
+		}
+		out = protowire.AppendTag(out, n, typ)
+		out = protowire.AppendBytes(out, newVal)
+		msg = msg[tagLen+valLen:]
+	}
+	return out, nil
+}
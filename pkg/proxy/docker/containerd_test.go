@@ -0,0 +1,285 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/pkg/proxy/cert"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type grpcCall struct {
+	Method    string
+	Namespace string
+	Message   []byte
+}
+
+// fakeContainerd records unary gRPC calls made to a UDS and responds with an empty message and the given status.
+func fakeContainerd(t *testing.T, sock, status string) func() []grpcCall {
+	t.Helper()
+	l, err := net.Listen("unix", sock)
+	orFail(t, err)
+	t.Cleanup(func() { l.Close() })
+	var mu sync.Mutex
+	var calls []grpcCall
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		msg, err := readGRPCMessage(req.Body)
+		if err != nil {
+			t.Errorf("reading %s: %v", req.URL.Path, err)
+		}
+		mu.Lock()
+		calls = append(calls, grpcCall{req.URL.Path, req.Header.Get(containerdNamespaceHeader), msg})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write(grpcMessage(nil))
+		w.Header().Set("Grpc-Status", status)
+	})
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(c, &http2.ServeConnOpts{Handler: h})
+		}
+	}()
+	return func() []grpcCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+// serveContainerd serves runtime on a UDS proxying to upstream and returns a client of it.
+func serveContainerd(t *testing.T, runtime ContainerdRuntime, ctp *ContainerTruststorePatcher, upstream string) *http.Client {
+	t.Helper()
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "proxy.sock"))
+	orFail(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go runtime.serve(ctp, c, upstream)
+		}
+	}()
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", l.Addr().String())
+		},
+	}}
+}
+
+func callContainerd(t *testing.T, client *http.Client, method string, msg []byte) {
+	t.Helper()
+	if got := callContainerdStatus(t, client, method, msg); got != "0" {
+		t.Fatalf("%s: Grpc-Status = %q, want %q", method, got, "0")
+	}
+}
+
+// callContainerdStatus makes a unary gRPC call and returns its status.
+func callContainerdStatus(t *testing.T, client *http.Client, method string, msg []byte) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "http://containerd"+method, bytes.NewReader(grpcMessage(msg)))
+	orFail(t, err)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	req.Header.Set(containerdNamespaceHeader, "default")
+	resp, err := client.Do(req)
+	orFail(t, err)
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	// NOTE: Trailers-only responses carry the status in the headers.
+	if status := resp.Trailer.Get("Grpc-Status"); status != "" {
+		return status
+	}
+	return resp.Header.Get("Grpc-Status")
+}
+
+func protoMessage(fields ...any) []byte {
+	var b []byte
+	for i := 0; i < len(fields); i += 2 {
+		b = protowire.AppendTag(b, protowire.Number(fields[i].(int)), protowire.BytesType)
+		switch v := fields[i+1].(type) {
+		case string:
+			b = protowire.AppendString(b, v)
+		case []byte:
+			b = protowire.AppendBytes(b, v)
+		}
+	}
+	return b
+}
+
+func TestContainerdRuntime(t *testing.T) {
+	dir := t.TempDir()
+	upstream := filepath.Join(dir, "containerd.sock")
+	calls := fakeContainerd(t, upstream, "0")
+	runtime := ContainerdRuntime{StateDir: filepath.Join(dir, "state"), VolumeDir: t.TempDir()}
+	ctp, err := NewContainerTruststorePatcher(CERT, ContainerTruststorePatcherOpts{Runtime: runtime, TruststoreEnvVars: []string{"SSL_CERT_FILE"}})
+	orFail(t, err)
+	client := serveContainerd(t, runtime, ctp, upstream)
+
+	// Create container.
+	spec := `{"ociVersion":"1.1.0","process":{"env":["PATH=/bin"],"user":{"uid":4294967294}},"root":{"path":"rootfs"},"mounts":[{"destination":"/proc","type":"proc","source":"proc"}]}`
+	container := protoMessage(1, "abc", 3, "docker.io/library/alpine:latest", 5, protoMessage(1, ociSpecTypeURL, 2, spec))
+	callContainerd(t, client, containerdCreateMethod, protoMessage(1, container))
+	got := calls()
+	if len(got) != 1 || got[0].Method != containerdCreateMethod || got[0].Namespace != "default" {
+		t.Fatalf("Unexpected calls: %+v", got)
+	}
+	gotContainer, err := protoField(got[0].Message, 1)
+	orFail(t, err)
+	if id, _ := protoField(gotContainer, 1); string(id) != "abc" {
+		t.Errorf("Unexpected container ID: want=abc got=%s", id)
+	}
+	if image, _ := protoField(gotContainer, 3); string(image) != "docker.io/library/alpine:latest" {
+		t.Errorf("Unexpected container image: got=%s", image)
+	}
+	gotAny, err := protoField(gotContainer, 5)
+	orFail(t, err)
+	gotSpecBytes, err := protoField(gotAny, 2)
+	orFail(t, err)
+	var gotSpec struct {
+		Process struct {
+			Env  []string
+			User struct{ UID json.Number }
+		}
+		Mounts []struct {
+			Destination string
+			Type        string
+			Source      string
+			Options     []string
+		}
+	}
+	orFail(t, json.Unmarshal(gotSpecBytes, &gotSpec))
+	if diff := cmp.Diff([]string{"PATH=/bin", "SSL_CERT_FILE=/var/cache/proxy.crt"}, gotSpec.Process.Env); diff != "" {
+		t.Errorf("Unexpected env (-want +got):\n%s", diff)
+	}
+	if gotSpec.Process.User.UID != "4294967294" {
+		t.Errorf("Unexpected uid: want=4294967294 got=%s", gotSpec.Process.User.UID)
+	}
+	if len(gotSpec.Mounts) != 2 || gotSpec.Mounts[1].Destination != "/var/cache" || !strings.HasPrefix(gotSpec.Mounts[1].Source, filepath.Join(runtime.VolumeDir, "containerd-volumes", "default", "abc")) {
+		t.Fatalf("Unexpected mounts: %+v", gotSpec.Mounts)
+	}
+	vol := gotSpec.Mounts[1].Source
+
+	// Create the task bundle as containerd would.
+	bundle := filepath.Join(runtime.StateDir, "io.containerd.runtime.v2.task", "default", "abc")
+	orFail(t, os.MkdirAll(filepath.Join(bundle, "rootfs", "etc", "ssl"), 0755))
+	orFail(t, os.WriteFile(filepath.Join(bundle, "config.json"), gotSpecBytes, 0644))
+	orFail(t, os.WriteFile(filepath.Join(bundle, "rootfs", "etc", "os-release"), []byte("NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.16.0\n"), 0644))
+	certPath := filepath.Join(bundle, "rootfs", "etc", "ssl", "cert.pem")
+	orFail(t, os.WriteFile(certPath, []byte("EXISTING\n"), 0644))
+	readCert := func() string {
+		b, err := os.ReadFile(certPath)
+		orFail(t, err)
+		return string(b)
+	}
+	proxyCert := string(cert.ToPEM(&CERT))
+
+	// Exec processes don't trigger patching.
+	callContainerd(t, client, containerdStartMethod, protoMessage(1, "abc", 2, "exec1"))
+	if got := readCert(); got != "EXISTING\n" {
+		t.Fatalf("Unexpected truststore after exec: %q", got)
+	}
+	// Start container.
+	callContainerd(t, client, containerdStartMethod, protoMessage(1, "abc"))
+	if got := readCert(); got != "EXISTING\n"+proxyCert {
+		t.Fatalf("Unexpected truststore after start: %q", got)
+	}
+	b, err := os.ReadFile(filepath.Join(vol, "proxy.crt"))
+	orFail(t, err)
+	if string(b) != proxyCert {
+		t.Fatalf("Unexpected proxy cert: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(bundle, "rootfs", "var", "cache", "proxy.crt")); !os.IsNotExist(err) {
+		t.Fatalf("Proxy cert written to rootfs: %v", err)
+	}
+	// Pause container.
+	callContainerd(t, client, containerdPauseMethod, protoMessage(1, "abc"))
+	if got := readCert(); got != "EXISTING\n" {
+		t.Fatalf("Unexpected truststore after pause: %q", got)
+	}
+	// Resume container.
+	callContainerd(t, client, containerdResumeMethod, protoMessage(1, "abc"))
+	if got := readCert(); got != "EXISTING\n"+proxyCert {
+		t.Fatalf("Unexpected truststore after resume: %q", got)
+	}
+
+	// Delete container.
+	callContainerd(t, client, containerdDeleteMethod, protoMessage(1, "abc"))
+	if _, err := os.Stat(vol); !os.IsNotExist(err) {
+		t.Fatalf("Volume not removed after delete: %v", err)
+	}
+
+	var methods []string
+	for _, c := range calls() {
+		methods = append(methods, c.Method)
+	}
+	want := []string{containerdCreateMethod, containerdStartMethod, containerdStartMethod, containerdPauseMethod, containerdResumeMethod, containerdDeleteMethod}
+	if diff := cmp.Diff(want, methods); diff != "" {
+		t.Errorf("Unexpected calls (-want +got):\n%s", diff)
+	}
+}
+
+func TestContainerdRuntimeFailedCreate(t *testing.T) {
+	upstream := filepath.Join(t.TempDir(), "containerd.sock")
+	fakeContainerd(t, upstream, "6") // ALREADY_EXISTS
+	runtime := ContainerdRuntime{StateDir: t.TempDir(), VolumeDir: t.TempDir()}
+	ctp, err := NewContainerTruststorePatcher(CERT, ContainerTruststorePatcherOpts{Runtime: runtime, TruststoreEnvVars: []string{"SSL_CERT_FILE"}})
+	orFail(t, err)
+	client := serveContainerd(t, runtime, ctp, upstream)
+	// A volume of the existing container must survive the failed create.
+	existing := filepath.Join(runtime.VolumeDir, "containerd-volumes", "default", "abc", "proxy-vol1-existing")
+	orFail(t, os.MkdirAll(existing, 0755))
+	spec := `{"ociVersion":"1.1.0","process":{"env":["PATH=/bin"]},"root":{"path":"rootfs"}}`
+	container := protoMessage(1, "abc", 5, protoMessage(1, ociSpecTypeURL, 2, spec))
+	if got := callContainerdStatus(t, client, containerdCreateMethod, protoMessage(1, container)); got != "6" {
+		t.Fatalf("Grpc-Status = %q, want %q", got, "6")
+	}
+	entries, err := os.ReadDir(filepath.Dir(existing))
+	orFail(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if diff := cmp.Diff([]string{"proxy-vol1-existing"}, names); diff != "" {
+		t.Errorf("Unexpected volumes after failed create (-want +got):\n%s", diff)
+	}
+}
+
+func TestContainerdRuntimeRejectsNetworkOverride(t *testing.T) {
+	if _, err := NewContainerTruststorePatcher(CERT, ContainerTruststorePatcherOpts{Runtime: ContainerdRuntime{}, NetworkOverride: "host"}); err == nil {
+		t.Fatal("NewContainerTruststorePatcher() succeeded, want error")
+	}
+}
+
+func TestContainerdFSRejectsInvalidIdentifiers(t *testing.T) {
+	runtime := ContainerdRuntime{StateDir: t.TempDir()}
+	for _, id := range []string{"", "..", "../abc", "a/b"} {
+		if _, err := runtime.containerFS("default", id); err == nil {
+			t.Errorf("containerFS(default, %q) succeeded, want error", id)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 // Copyright 2025 Google LLC
 // SPDX-License-Identifier: Apache-2.0
 
-// Package docker defines a proxy for the Docker API.
+// Package docker defines a proxy for container engine APIs.
+//
+// The Docker API is supported along with the Podman-compatible API and the
+// containerd API used by nerdctl. See Runtime.
 //
 // Summary: Change the internal container state transparently while providing
 // an otherwise unmodified view from the external API.
@@ -39,6 +42,7 @@ import (
 	"os"
 	"path/filepath"
 	re "regexp"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -64,6 +68,10 @@ const (
 	bazelSystemRCPath = "/etc/bazel.bazelrc"
 	// Env var to which docker requests will be sent by the docker CLI.
 	dockerEnvVar = "DOCKER_HOST"
+	// Env var to which podman requests will be sent by the podman remote CLI.
+	podmanEnvVar = "CONTAINER_HOST"
+	// Env var to which containerd requests will be sent by nerdctl and ctr.
+	containerdEnvVar = "CONTAINERD_ADDRESS"
 	// The path to the docker proxy that can be bound within a container to make docker calls.
 	proxySocketPath = "/var/cache/proxy.sock"
 )
@@ -116,17 +124,15 @@ func resolveContainerID(c *UDSHTTPClient, id string) (string, error) {
 	}
 }
 
-func truststoreCertPatch(fs dockerfs.Filesystem, cert []byte) (*patch, error) {
-	truststore, err := locateTruststore(&fs)
+func truststoreCertPatch(fs dockerfs.FS, cert []byte) (*patch, error) {
+	truststore, err := locateTruststore(fs)
 	if err != nil {
 		return nil, errors.Wrap(err, "locating truststore")
 	}
-	old := *truststore
-	truststore.Contents = append(truststore.Contents[:], cert...)
-	return newPatch(&old, truststore)
+	return appendCertPatch(truststore, cert)
 }
 
-func createFile(fs dockerfs.Filesystem, content []byte, path string) error {
+func createFile(fs dockerfs.FS, content []byte, path string) error {
 	_, err := fs.Stat(path)
 	if !errors.Is(err, iofs.ErrNotExist) {
 		return iofs.ErrExist
@@ -374,12 +380,12 @@ func newPatch(before, after *dockerfs.File) (*patch, error) {
 }
 
 // Apply writes the After file to the target container.
-func (p patch) Apply(fs *dockerfs.Filesystem) error {
+func (p patch) Apply(fs dockerfs.FS) error {
 	return fs.WriteFile(p.After)
 }
 
 // Revert writes the Before file to the target container, validating After is the current state.
-func (p patch) Revert(fs *dockerfs.Filesystem) error {
+func (p patch) Revert(fs dockerfs.FS) error {
 	f, err := fs.Open(*p.Path())
 	if err != nil {
 		return err
@@ -405,13 +411,43 @@ func (p patch) Path() *string {
 type patchSet struct {
 	*sync.Mutex
 	Patches []patch
+	// Whether Patches have been reverted by suspendPatches.
+	suspended bool
+}
+
+// reapply applies the patches in the set, reverting all of them if any fail.
+func (ps *patchSet) reapply(fs dockerfs.FS, id string) {
+	var applied []int
+	for i, p := range ps.Patches {
+		if err := p.Apply(fs); err != nil {
+			log.Printf("Failed to re-apply patches for %s: %s", id, err)
+		} else {
+			applied = append(applied, i)
+		}
+	}
+	if len(applied) == 0 {
+		ps.Patches = ps.Patches[:0]
+	} else if len(applied) != len(ps.Patches) {
+		log.Printf("Attempting to recover from patch application failure for %s", id)
+		for _, idx := range applied {
+			p := ps.Patches[idx]
+			if err := p.Revert(fs); err != nil {
+				// Rollback failed. We're in an inconsistent state so crashing
+				// is the safest option.
+				log.Fatalf("Failed to recover from repatch failure for %s: %s", id, err)
+			}
+		}
+		ps.Patches = ps.Patches[:0]
+	}
 }
 
-// ContainerTruststorePatcher provides a Docker API proxy that patches the container truststore while running.
+// ContainerTruststorePatcher provides a container engine API proxy that patches the container truststore while running.
 type ContainerTruststorePatcher struct {
+	runtime              Runtime
 	cert                 x509.Certificate
 	envVars              []string
 	truststoreEnvVars    []string
+	truststores          []truststoreProvider
 	javaTruststoreEnvVar bool
 	bazelTruststore      bool
 	networkOverride      string // TODO: Not a good fit for this abstraction
@@ -423,16 +459,36 @@ type ContainerTruststorePatcher struct {
 
 // ContainerTruststorePatcherOpts defines the optional parameters for creating a ContainerTruststorePatcher.
 type ContainerTruststorePatcherOpts struct {
+	// Runtime is the container engine whose API is proxied. Defaults to DockerRuntime.
+	Runtime              Runtime
 	EnvVars              []string
 	TruststoreEnvVars    []string
 	JavaTruststoreEnvVar bool
 	BazelTruststore      bool
 	RecursiveProxy       bool
 	NetworkOverride      string
+	// Truststores names the optional truststore providers with which to patch
+	// containers, in addition to the system truststore. See TruststoreProviders.
+	Truststores []string
 }
 
 // NewContainerTruststorePatcher creates a new ContainerTruststorePatcher with the provided certificate and options.
 func NewContainerTruststorePatcher(cert x509.Certificate, opts ContainerTruststorePatcherOpts) (*ContainerTruststorePatcher, error) {
+	runtime := opts.Runtime
+	if runtime == nil {
+		runtime = DockerRuntime{}
+	}
+	if _, ok := runtime.(ContainerdRuntime); ok && opts.NetworkOverride != "" {
+		return nil, errors.New("network override is unsupported for containerd")
+	}
+	truststores := []truststoreProvider{systemTruststore{}}
+	for _, name := range opts.Truststores {
+		p, ok := truststoreProviders[name]
+		if !ok {
+			return nil, errors.Errorf("unknown truststore provider: %s", name)
+		}
+		truststores = append(truststores, p)
+	}
 	var sockName string
 	if opts.RecursiveProxy {
 		file, err := os.CreateTemp("/tmp", "proxy-*.sock")
@@ -446,9 +502,11 @@ func NewContainerTruststorePatcher(cert x509.Certificate, opts ContainerTruststo
 	}
 
 	return &ContainerTruststorePatcher{
+		runtime:              runtime,
 		cert:                 cert,
 		envVars:              opts.EnvVars,
 		truststoreEnvVars:    opts.TruststoreEnvVars,
+		truststores:          truststores,
 		javaTruststoreEnvVar: opts.JavaTruststoreEnvVar,
 		bazelTruststore:      opts.BazelTruststore,
 		networkOverride:      opts.NetworkOverride,
@@ -473,8 +531,12 @@ func (d *ContainerTruststorePatcher) leasePatchSet(container string) *patchSet {
 	return p
 }
 
-// Proxy serves the Docker API while patching the container truststore.
-func (d *ContainerTruststorePatcher) Proxy(srvAddr, dockerAddr string) {
+// Proxy serves the container engine API at socket while patching the container truststore.
+// If socket is empty, the runtime's default socket is used.
+func (d *ContainerTruststorePatcher) Proxy(srvAddr, socket string) {
+	if socket == "" {
+		socket = d.runtime.DefaultSocket()
+	}
 	proxyChan := make(chan net.Conn, 1)
 	udsChan := make(chan net.Conn, 1)
 
@@ -525,13 +587,166 @@ func (d *ContainerTruststorePatcher) Proxy(srvAddr, dockerAddr string) {
 		case c = <-proxyChan:
 		case c = <-udsChan:
 		}
-		s, err := net.Dial("unix", dockerAddr)
+		go d.runtime.serve(d, c, socket)
+	}
+}
+
+// patchSpec modifies the configuration of a container being created to trust the proxy.
+func (d *ContainerTruststorePatcher) patchSpec(spec containerSpec) error {
+	// NOTE: This binding overlaps with the cert file we write at start time.
+	// Due to Docker's fs layering behavior, this allows us to ensure export
+	// and commit operations on the container won't pick up any new files or
+	// directories written to the dir during its execution.
+	volName := fmt.Sprintf("proxy-vol%d", d.created.Add(1))
+	if err := spec.AddBind(volName, filepath.Dir(proxyCertPath), "rw"); err != nil {
+		return errors.Wrap(err, "adding volume")
+	}
+	var vars []string
+	for _, v := range d.envVars {
+		vars = append(vars, v)
+	}
+	for _, v := range d.truststoreEnvVars {
+		vars = append(vars, v+"="+proxyCertPath)
+	}
+	for _, t := range d.truststores {
+		vars = append(vars, t.envVars()...)
+	}
+	if d.javaTruststoreEnvVar {
+		// NOTE: Since other user-provided values can be set in JAVA_TOOL_OPTIONS,
+		// we merge the proxy-specific arg into the existing value, if present.
+		val, err := spec.Env(javaTruststoreEnvVar)
+		if err != nil && !errors.Is(err, iofs.ErrNotExist) {
+			return errors.Wrap(err, "getting env var")
+		}
+		newVal := val
+		if val != "" {
+			newVal = trimQuotes(val) + " "
+		}
+		newVal += "-Djavax.net.ssl.trustStore=" + proxyCertJKSPath
+		vars = append(vars, javaTruststoreEnvVar+"="+newVal)
+		log.Printf("Updated %s [old=%s, new=%s]", javaTruststoreEnvVar, val, newVal)
+	}
+	if d.proxySocket != "" {
+		if err := spec.AddBind(d.proxySocket, proxySocketPath, "rw"); err != nil {
+			return errors.Wrap(err, "adding proxy socket binding")
+		}
+		vars = append(vars, d.runtime.socketEnvVars(proxySocketPath)...)
+		log.Printf("Bound %s to %s", d.proxySocket, proxySocketPath)
+	}
+	if err := spec.AddEnv(vars); err != nil {
+		return errors.Wrap(err, "adding env vars")
+	}
+	if d.networkOverride != "" {
+		network, err := spec.Network()
+		if err != nil {
+			return errors.Wrap(err, "getting network")
+		}
+		log.Printf("Modifying network from %s to %s", network, d.networkOverride)
+		if err := spec.SetNetwork(d.networkOverride); err != nil {
+			return errors.Wrap(err, "setting network")
+		}
+	}
+	return nil
+}
+
+// patchContainer writes the proxy cert files to the container and patches its truststore.
+func (d *ContainerTruststorePatcher) patchContainer(dfs dockerfs.FS, id string) {
+	certBytes := cert.ToPEM(&d.cert)
+	// NOTE: This doesn't need to be cleaned up due to the enclosing volume
+	// binding made at creation time.
+	if err := createFile(dfs, certBytes, proxyCertPath); err != nil {
+		log.Printf("Creating proxy cert: %v", err)
+		return
+	}
+	if d.javaTruststoreEnvVar {
+		jks, err := cert.ToJKS(&d.cert)
+		if err != nil {
+			log.Printf("Generating java proxy cert: %v", err)
+			return
+		}
+		if err := createFile(dfs, jks, proxyCertJKSPath); err != nil {
+			log.Printf("Creating java proxy cert: %v", err)
+			return
+		}
+	}
+	if d.bazelTruststore {
+		bazelRCContents := fmt.Sprintf("startup --host_jvm_args=-Djavax.net.ssl.trustStore=%s", proxyCertJKSPath)
+		bazelRCBytes := []byte(bazelRCContents)
+		f, err := dfs.OpenAndResolve(bazelSystemRCPath)
+		if err != nil {
+			if errors.Is(err, iofs.ErrNotExist) {
+				if err := createFile(dfs, bazelRCBytes, bazelSystemRCPath); err != nil {
+					log.Printf("Creating bazelrc file: %v", err)
+					return
+				}
+			} else {
+				log.Printf("Reading bazelrc file: %v", err)
+				return
+			}
+		} else {
+			f.Contents = append(f.Contents[:], bazelRCBytes...)
+			err = dfs.WriteFile(f)
+			if err != nil {
+				log.Printf("Writing to bazelrc file: %v", err)
+				return
+			}
+		}
+	}
+	patchset := d.leasePatchSet(id)
+	defer patchset.Unlock()
+	if len(patchset.Patches) > 0 {
+		log.Printf("Active patches applied for %s", id)
+		return
+	}
+	var patches []*patch
+	for _, t := range d.truststores {
+		ps, err := t.patches(dfs, certBytes)
 		if err != nil {
-			log.Printf("Failed to establish connection: %s", err)
+			log.Printf("patching certstore for %s: %v", id, err)
+			continue
+		}
+		for _, p := range ps {
+			// NOTE: Truststores may be links to the same file, e.g. certifi's on Debian.
+			if !slices.ContainsFunc(patches, func(o *patch) bool { return *o.Path() == *p.Path() }) {
+				patches = append(patches, p)
+			}
+		}
+	}
+	for _, p := range patches {
+		if err := p.Apply(dfs); err != nil {
+			log.Printf("Unable to apply patch for %s: %v", id, err)
 			continue
 		}
-		go d.proxyRequest(c, s)
+		patchset.Patches = append(patchset.Patches, *p)
+	}
+}
+
+// suspendPatches reverts the patches applied to a container until resumePatches is called.
+func (d *ContainerTruststorePatcher) suspendPatches(dfs dockerfs.FS, id string) {
+	patchset := d.leasePatchSet(id)
+	defer patchset.Unlock()
+	if patchset.suspended {
+		return
+	}
+	for _, p := range patchset.Patches {
+		if err := p.Revert(dfs); err != nil {
+			// XXX: As when unpatching for export, it's better we crash than
+			// allow the corrupted state to go silently unreported.
+			log.Fatalf("Failed to revert patches for %s: %s", id, err)
+		}
+	}
+	patchset.suspended = true
+}
+
+// resumePatches re-applies the patches reverted by suspendPatches.
+func (d *ContainerTruststorePatcher) resumePatches(dfs dockerfs.FS, id string) {
+	patchset := d.leasePatchSet(id)
+	defer patchset.Unlock()
+	if !patchset.suspended {
+		return
 	}
+	patchset.suspended = false
+	patchset.reapply(dfs, id)
 }
 
 func (d *ContainerTruststorePatcher) proxyRequest(clientConn, serverConn net.Conn) {
@@ -552,60 +767,19 @@ func (d *ContainerTruststorePatcher) proxyRequest(clientConn, serverConn net.Con
 		if err != nil {
 			log.Fatalf("Failed to read body for request %s: %s", req.URL.Path, err)
 		}
-		newBody := body
-		// NOTE: This binding overlaps with the cert file we write at start time.
-		// Due to Docker's fs layering behavior, this allows us to ensure export
-		// and commit operations on the container won't pick up any new files or
-		// directories written to the dir during its execution.
-		volName := fmt.Sprintf("proxy-vol%d", d.created.Add(1))
-		newBody, err = addBinding(newBody, volName, filepath.Dir(proxyCertPath), "rw")
-		if err != nil {
-			log.Fatalf("Failed to add volume for request %s: %s", req.URL.Path, err)
-		}
-		var vars []string
-		for _, v := range d.envVars {
-			vars = append(vars, v)
-		}
-		for _, v := range d.truststoreEnvVars {
-			vars = append(vars, v+"="+proxyCertPath)
-		}
-		if d.javaTruststoreEnvVar {
-			// NOTE: Since other user-provided values can be set in JAVA_TOOL_OPTIONS,
-			// we merge the proxy-specific arg into the existing value, if present.
-			val, err := getEnvVar(newBody, javaTruststoreEnvVar)
-			if err != nil && !errors.Is(err, iofs.ErrNotExist) {
-				log.Fatalf("Failed to get env var for request %s: %s", req.URL.Path, err)
-			}
-			newVal := val
-			if val != "" {
-				newVal = trimQuotes(val) + " "
-			}
-			newVal += "-Djavax.net.ssl.trustStore=" + proxyCertJKSPath
-			vars = append(vars, javaTruststoreEnvVar+"="+newVal)
-			log.Printf("Updated %s [old=%s, new=%s]", javaTruststoreEnvVar, val, newVal)
-		}
-		if d.proxySocket != "" {
-			newBody, err = addBinding(newBody, d.proxySocket, proxySocketPath, "rw")
+		var spec containerSpec = &dockerSpec{body}
+		if libpodPattern.MatchString(req.URL.Path) {
+			spec, err = newLibpodSpec(body)
 			if err != nil {
-				log.Fatalf("failed to add docker bindings for request %s: %s", req.URL.Path, err)
+				log.Fatalf("Failed to parse spec for request %s: %s", req.URL.Path, err)
 			}
-			vars = append(vars, dockerEnvVar+"=unix://"+proxySocketPath)
-			log.Printf("Bound %s to %s", d.proxySocket, proxySocketPath)
 		}
-		newBody, err = addEnvVars(newBody, vars)
-		if err != nil {
-			log.Fatalf("Failed to add env vars for request %s: %s", req.URL.Path, err)
+		if err := d.patchSpec(spec); err != nil {
+			log.Fatalf("Failed to patch spec for request %s: %s", req.URL.Path, err)
 		}
-		if d.networkOverride != "" {
-			network, err := getNetwork(newBody)
-			if err != nil {
-				log.Fatalf("Failed to get network for request %s: %s", req.URL.Path, err)
-			}
-			log.Printf("Modifying network from %s to %s", network, d.networkOverride)
-			newBody, err = setNetwork(newBody, d.networkOverride)
-			if err != nil {
-				log.Fatalf("Failed to set network for request %s: %s", req.URL.Path, err)
-			}
+		newBody, err := spec.Bytes()
+		if err != nil {
+			log.Fatalf("Failed to serialize spec for request %s: %s", req.URL.Path, err)
 		}
 		req.ContentLength = int64(len(newBody))
 		req.Body = io.NopCloser(bytes.NewReader(newBody))
@@ -621,67 +795,7 @@ func (d *ContainerTruststorePatcher) proxyRequest(clientConn, serverConn net.Con
 			}
 			return
 		}
-		dfs := dockerfs.Filesystem{Client: serverClient, Container: id}
-		certBytes := cert.ToPEM(&d.cert)
-		// NOTE: This doesn't need to be cleaned up due to the enclosing volume
-		// binding made at creation time.
-		if err := createFile(dfs, certBytes, proxyCertPath); err != nil {
-			log.Printf("Creating proxy cert: %v", err)
-			break
-		}
-		if d.javaTruststoreEnvVar {
-			jks, err := cert.ToJKS(&d.cert)
-			if err != nil {
-				log.Printf("Generating java proxy cert: %v", err)
-				break
-			}
-			if err := createFile(dfs, jks, proxyCertJKSPath); err != nil {
-				log.Printf("Creating java proxy cert: %v", err)
-				break
-			}
-		}
-		if d.bazelTruststore {
-			bazelRCContents := fmt.Sprintf("startup --host_jvm_args=-Djavax.net.ssl.trustStore=%s", proxyCertJKSPath)
-			bazelRCBytes := []byte(bazelRCContents)
-			f, err := dfs.OpenAndResolve(bazelSystemRCPath)
-			if err != nil {
-				if err == iofs.ErrNotExist {
-					if err := createFile(dfs, bazelRCBytes, bazelSystemRCPath); err != nil {
-						log.Printf("Creating bazelrc file: %v", err)
-						break
-					}
-				} else {
-					log.Printf("Reading bazelrc file: %v", err)
-					break
-				}
-			} else {
-				f.Contents = append(f.Contents[:], bazelRCBytes...)
-				err = dfs.WriteFile(f)
-				if err != nil {
-					log.Printf("Writing to bazelrc file: %v", err)
-					break
-				}
-			}
-		}
-		patchset := d.leasePatchSet(id)
-		if len(patchset.Patches) > 0 {
-			log.Printf("Active patches applied for %s", id)
-			patchset.Unlock()
-			break
-		}
-		truststorePatch, err := truststoreCertPatch(dfs, certBytes)
-		if err != nil {
-			log.Printf("patching certstore for %s: %v", id, err)
-			patchset.Unlock()
-			break
-		}
-		if err := truststorePatch.Apply(&dfs); err != nil {
-			log.Printf("Unable to apply patch for %s: %v", id, err)
-			patchset.Unlock()
-			break
-		}
-		patchset.Patches = append(patchset.Patches, *truststorePatch)
-		patchset.Unlock()
+		d.patchContainer(dockerfs.Filesystem{Client: serverClient, Container: id}, id)
 	case unpatchTruststoreAndEnvVarsDuring:
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
@@ -692,20 +806,25 @@ func (d *ContainerTruststorePatcher) proxyRequest(clientConn, serverConn net.Con
 			otherVars = append(otherVars, javaTruststoreEnvVar)
 		}
 		if d.proxySocket != "" {
-			otherVars = append(otherVars, dockerEnvVar)
+			otherVars = append(otherVars, envVarNames(d.runtime.socketEnvVars(proxySocketPath))...)
+		}
+		for _, t := range d.truststores {
+			otherVars = append(otherVars, envVarNames(t.envVars())...)
 		}
 		allVars := append(otherVars, d.envVars...)
 		allVars = append(otherVars, d.truststoreEnvVars...)
 		var newBody []byte
-		if !bytes.Equal(body, nullJSONBody) {
+		// NOTE: The libpod API accepts no body for commit.
+		if len(body) > 0 && !bytes.Equal(body, nullJSONBody) {
 			newBody, err = removeEnvVars(body, allVars)
 			if err != nil {
 				log.Fatalf("failed to remove env vars for request %s: %s", req.URL.Path, err)
 			}
 		} else {
-			// With a null body, the docker daemon will access the container
+			// With a null or empty body, the daemon will access the container
 			// specifications internally. As such, we need to substitute this out for
 			// out own specification.
+			newBody = body
 			origID := id
 			resp, err := serverClient.Get("/containers/" + id + "/json")
 			if err != nil {
@@ -754,7 +873,6 @@ func (d *ContainerTruststorePatcher) proxyRequest(clientConn, serverConn net.Con
 			resp.Body.Close()
 			newID := container.ID
 			req.URL.RawQuery = strings.Replace(req.URL.RawQuery, "container="+origID, "container="+newID, -1)
-			newBody = nullJSONBody
 		}
 		req.ContentLength = int64(len(newBody))
 		req.Body = io.NopCloser(bytes.NewReader(newBody))
@@ -779,7 +897,7 @@ func (d *ContainerTruststorePatcher) proxyRequest(clientConn, serverConn net.Con
 		}
 		// TODO: /pause the container here to ensure container operations don't see unpatched changes.
 		for _, p := range patchset.Patches {
-			if err := p.Revert(&dfs); err != nil {
+			if err := p.Revert(dfs); err != nil {
 				// XXX: This is a really bad situation. If we can't revert the
 				// patches applied, it's better we crash and try to ensure the
 				// corrupted state doesn't go silently unreported.
@@ -787,30 +905,7 @@ func (d *ContainerTruststorePatcher) proxyRequest(clientConn, serverConn net.Con
 			}
 		}
 		// Re-apply the patches once the command completes.
-		defer func() {
-			var applied []int
-			for i, p := range patchset.Patches {
-				if err := p.Apply(&dfs); err != nil {
-					log.Printf("Failed to re-apply patches for %s: %s", id, err)
-				} else {
-					applied = append(applied, i)
-				}
-			}
-			if len(applied) == 0 {
-				patchset.Patches = patchset.Patches[:0]
-			} else if len(applied) != len(patchset.Patches) {
-				log.Printf("Attempting to recover from patch application failure for %s", id)
-				for _, idx := range applied {
-					p := patchset.Patches[idx]
-					if err := p.Revert(&dfs); err != nil {
-						// Rollback failed. We're in an inconsistent state so crashing
-						// is the safest option.
-						log.Fatalf("Failed to recover from repatch failure for %s: %s", id, err)
-					}
-				}
-				patchset.Patches = patchset.Patches[:0]
-			}
-		}()
+		defer patchset.reapply(dfs, id)
 	}
 	// Round-trip request to API server and complete request with client..
 	// TODO: Use uds HTTPClient once Upgrade works.
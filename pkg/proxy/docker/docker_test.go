@@ -292,6 +292,13 @@ func TestTryPatchOnStartUnknownOS(t *testing.T) {
 		{"/containers/def/archive?path=/kaniko", http.Response{StatusCode: http.StatusNotFound}},
 		{"/containers/def/archive?path=/etc/os-release", http.Response{StatusCode: http.StatusOK, Header: osHeader}},
 		{"/containers/def/archive?path=/etc/os-release", http.Response{StatusCode: http.StatusOK, Body: osTar}},
+		// Probe well-known truststores.
+		{"/containers/def/archive?path=/etc/ssl/certs/ca-certificates.crt", http.Response{StatusCode: http.StatusNotFound}},
+		{"/containers/def/archive?path=/etc/pki/tls/certs/ca-bundle.crt", http.Response{StatusCode: http.StatusNotFound}},
+		{"/containers/def/archive?path=/etc/ssl/ca-bundle.pem", http.Response{StatusCode: http.StatusNotFound}},
+		{"/containers/def/archive?path=/etc/pki/tls/cacert.pem", http.Response{StatusCode: http.StatusNotFound}},
+		{"/containers/def/archive?path=/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", http.Response{StatusCode: http.StatusNotFound}},
+		{"/containers/def/archive?path=/etc/ssl/cert.pem", http.Response{StatusCode: http.StatusNotFound}},
 		{"/containers/abc/start", http.Response{StatusCode: http.StatusOK, Body: http.NoBody}},
 	}
 	outcome := make(chan error, 1)
@@ -328,6 +335,9 @@ func TestTryPatchOnStartUnknownOS(t *testing.T) {
 	orFail(t, <-outcome) // PUT /containers/def/archive?path=/var/cache
 	orFail(t, <-outcome) // HEAD /containers/def/archive?path=/etc/os-release
 	orFail(t, <-outcome) // GET /containers/def/archive?path=/etc/os-release
+	for range wellKnownTruststores {
+		orFail(t, <-outcome) // HEAD /containers/def/archive?path=<truststore>
+	}
 	orFail(t, <-outcome) // POST /containers/abc/start
 	resp, err = http.ReadResponse(bufio.NewReader(clientIn), req)
 	orFail(t, err)
@@ -4,65 +4,110 @@
 package docker
 
 import (
+	iofs "io/fs"
 	re "regexp"
+	"strings"
 
 	"github.com/google/oss-rebuild/internal/proxy/dockerfs"
 	"github.com/pkg/errors"
 )
 
-var distroPattern = re.MustCompile(`\bID=["'']?([^\r\n]+?)["'']?[\r\n]`)
+var (
+	distroPattern     = re.MustCompile(`\bID=["'']?([^\r\n]+?)["'']?[\r\n]`)
+	distroLikePattern = re.MustCompile(`\bID_LIKE=["'']?([^\r\n]+?)["'']?[\r\n]`)
+)
 
-// distro returns the given container's distribution identifier.
-func distro(dfs *dockerfs.Filesystem) (string, error) {
+// distro returns the given container's distribution identifier followed by
+// those of the distributions from which it is derived, if any.
+func distro(dfs dockerfs.FS) ([]string, error) {
 	// Kaniko images are built from scratch and do not have the /etc/os-release file.
 	// The /kaniko directory is present in all Kaniko images and also contains its own trust store.
 	if _, err := dfs.Stat("/kaniko"); err == nil {
-		return "kaniko", nil
+		return []string{"kaniko"}, nil
 	}
 	f, err := dfs.OpenAndResolve("/etc/os-release")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	matches := distroPattern.FindSubmatch(f.Contents)
 	if matches == nil {
-		return "", errors.New("distro identifier not found")
+		return nil, errors.New("distro identifier not found")
 	}
-	return string(matches[1]), nil
+	ids := []string{string(matches[1])}
+	if matches := distroLikePattern.FindSubmatch(f.Contents); matches != nil {
+		ids = append(ids, strings.Fields(string(matches[1]))...)
+	}
+	return ids, nil
 }
 
-// locateTruststore returns the truststore file for the given OS distribution.
-func locateTruststore(dfs *dockerfs.Filesystem) (*dockerfs.File, error) {
-	d, err := distro(dfs)
-	if err != nil {
-		return nil, err
-	}
-	switch d {
+// distroTruststore returns the path of the truststore file for the given OS distribution.
+func distroTruststore(id string) (string, bool) {
+	switch id {
 	case "alpine", "arch", "openwrt":
 		// Expected Cert File: /etc/ssl/cert.pem
 		// Expected Cert Dir:  /etc/ssl/certs/
-		return dfs.OpenAndResolve("/etc/ssl/cert.pem")
-	case "rhel", "fedora", "centos":
+		return "/etc/ssl/cert.pem", true
+	case "rhel", "fedora", "centos", "rocky", "almalinux", "ol", "amzn":
 		// Expected Cert File: /etc/pki/tls/cert.pem
 		// Expected Cert Dir:  /etc/pki/tls/certs
-		return dfs.OpenAndResolve("/etc/pki/tls/cert.pem")
-	case "debian", "ubuntu", "gentoo", "linuxmint":
+		// NOTE: Usually a link to /etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem
+		// which is regenerated by update-ca-trust.
+		return "/etc/pki/tls/cert.pem", true
+	case "debian", "ubuntu", "gentoo", "linuxmint", "wolfi":
 		// Expected Cert File: /etc/ssl/certs/ca-certificates.crt
 		// Expected Cert Dir:  /etc/ssl/certs/
 		// NOTE: Only expected to be present if ca-certificates installed or is distroless
 		// NOTE: To survive regeneration, cert needs to be added to /usr/share/ca-certificates/
 		// and the new relpath added to a new line in /etc/ca-certificates.conf.
-		return dfs.OpenAndResolve("/etc/ssl/certs/ca-certificates.crt")
-	case "opensuse-leap", "opensuse-tumbleweed":
+		return "/etc/ssl/certs/ca-certificates.crt", true
+	case "opensuse-leap", "opensuse-tumbleweed", "sles":
 		// Expected Cert File: /var/lib/ca-certificates/ca-bundle.pem
 		// Expected Cert Dir:  /var/lib/ca-certificates/{openssl,pem}/
 		// NOTE: JKS file also needs to be regenerated at /var/lib/ca-certificates/java-cacerts.
-		return dfs.OpenAndResolve("/var/lib/ca-certificates/ca-bundle.pem")
+		return "/var/lib/ca-certificates/ca-bundle.pem", true
 	case "kaniko":
 		// Expected Cert File: /kaniko/ssl/certs/ca-certificates.crt
 		// Expected Cert Dir: /kaniko/ssl/certs
 		// https://github.com/GoogleContainerTools/kaniko/blob/e328007bc1fa0d8c2eacf1918bebbabc923abafa/deploy/Dockerfile#L69
-		return dfs.OpenAndResolve("/kaniko/ssl/certs/ca-certificates.crt")
+		return "/kaniko/ssl/certs/ca-certificates.crt", true
 	default:
-		return nil, errors.Errorf("unsupported distro: %s", d)
+		return "", false
+	}
+}
+
+// wellKnownTruststores are the truststore files probed in containers of
+// unknown distributions, e.g. those built from scratch.
+// NOTE: These follow the search order of Go's crypto/x509.
+var wellKnownTruststores = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux
+}
+
+// locateTruststore returns the system truststore file of the given container.
+func locateTruststore(dfs dockerfs.FS) (*dockerfs.File, error) {
+	ids, err := distro(dfs)
+	if err != nil && !errors.Is(err, iofs.ErrNotExist) {
+		return nil, err
+	}
+	for _, id := range ids {
+		if path, ok := distroTruststore(id); ok {
+			return dfs.OpenAndResolve(path)
+		}
+	}
+	for _, path := range wellKnownTruststores {
+		f, err := dfs.OpenAndResolve(path)
+		if err == nil {
+			return f, nil
+		} else if !errors.Is(err, iofs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("no truststore found")
 	}
+	return nil, errors.Errorf("unsupported distro: %s", ids[0])
 }
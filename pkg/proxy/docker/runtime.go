@@ -0,0 +1,261 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	iofs "io/fs"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	re "regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Runtime is a container engine whose API can be proxied by a ContainerTruststorePatcher.
+type Runtime interface {
+	// DefaultSocket returns the path of the engine's API socket.
+	DefaultSocket() string
+	// socketEnvVars returns the env vars directing clients to the API socket at path.
+	socketEnvVars(path string) []string
+	// serve proxies the API calls made on c to the engine at socket.
+	serve(d *ContainerTruststorePatcher, c net.Conn, socket string)
+}
+
+// DockerRuntime is the Docker Engine API.
+type DockerRuntime struct{}
+
+var _ Runtime = DockerRuntime{}
+
+// DefaultSocket returns the path of the Docker daemon's socket.
+func (DockerRuntime) DefaultSocket() string {
+	return "/var/run/docker.sock"
+}
+
+func (DockerRuntime) socketEnvVars(path string) []string {
+	return []string{dockerEnvVar + "=unix://" + path}
+}
+
+func (DockerRuntime) serve(d *ContainerTruststorePatcher, c net.Conn, socket string) {
+	serveHTTP(d, c, socket)
+}
+
+// PodmanRuntime is the Podman API service, including both its Docker-compatible and libpod endpoints.
+type PodmanRuntime struct{}
+
+var _ Runtime = PodmanRuntime{}
+
+// DefaultSocket returns the path of the Podman service's socket.
+// For rootless Podman, this is the socket in the user's runtime directory.
+func (PodmanRuntime) DefaultSocket() string {
+	if os.Geteuid() == 0 {
+		return "/run/podman/podman.sock"
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = fmt.Sprintf("/run/user/%d", os.Getuid())
+	}
+	return filepath.Join(dir, "podman", "podman.sock")
+}
+
+func (PodmanRuntime) socketEnvVars(path string) []string {
+	// NOTE: Both the docker CLI and podman remote clients may be used with the Podman API.
+	return []string{dockerEnvVar + "=unix://" + path, podmanEnvVar + "=unix://" + path}
+}
+
+func (PodmanRuntime) serve(d *ContainerTruststorePatcher, c net.Conn, socket string) {
+	serveHTTP(d, c, socket)
+}
+
+// serveHTTP proxies a request for an HTTP API served at socket.
+func serveHTTP(d *ContainerTruststorePatcher, c net.Conn, socket string) {
+	s, err := net.Dial("unix", socket)
+	if err != nil {
+		log.Printf("Failed to establish connection: %s", err)
+		c.Close()
+		return
+	}
+	d.proxyRequest(c, s)
+}
+
+// containerSpec is the configuration with which a container is created.
+type containerSpec interface {
+	// Env returns the value of an env var or iofs.ErrNotExist if it is unset.
+	Env(name string) (string, error)
+	// AddEnv sets the given "KEY=VALUE" env vars.
+	AddEnv(vars []string) error
+	// AddBind mounts from at to with the given mode.
+	// Relative values of from refer to named volumes.
+	AddBind(from, to, mode string) error
+	Network() (string, error)
+	SetNetwork(network string) error
+	Bytes() ([]byte, error)
+}
+
+// dockerSpec is a container configuration for the Docker Engine API.
+type dockerSpec struct {
+	body []byte
+}
+
+var _ containerSpec = &dockerSpec{}
+
+func (s *dockerSpec) Env(name string) (string, error) {
+	return getEnvVar(s.body, name)
+}
+
+func (s *dockerSpec) AddEnv(vars []string) (err error) {
+	s.body, err = addEnvVars(s.body, vars)
+	return err
+}
+
+func (s *dockerSpec) AddBind(from, to, mode string) (err error) {
+	s.body, err = addBinding(s.body, from, to, mode)
+	return err
+}
+
+func (s *dockerSpec) Network() (string, error) {
+	return getNetwork(s.body)
+}
+
+func (s *dockerSpec) SetNetwork(network string) (err error) {
+	s.body, err = setNetwork(s.body, network)
+	return err
+}
+
+func (s *dockerSpec) Bytes() ([]byte, error) {
+	return s.body, nil
+}
+
+// libpodPattern matches paths of the Podman-specific libpod API.
+var libpodPattern = re.MustCompile(`^(/v[^/]+)?/libpod/`)
+
+// libpodSpec is a container configuration for the libpod API.
+// See https://docs.podman.io/en/latest/_static/api.html#tag/containers/operation/ContainerCreateLibpod
+type libpodSpec struct {
+	spec map[string]any
+}
+
+var _ containerSpec = &libpodSpec{}
+
+func newLibpodSpec(body []byte) (*libpodSpec, error) {
+	spec := make(map[string]any)
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&spec); err != nil {
+		return nil, errors.Errorf("failed to unmarshal json: %s\nBody: %s", err, string(body))
+	}
+	return &libpodSpec{spec}, nil
+}
+
+func (s *libpodSpec) env() (map[string]any, error) {
+	raw, ok := s.spec["env"]
+	if !ok || raw == nil {
+		return make(map[string]any), nil
+	}
+	env, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.New("unexpected type of env")
+	}
+	return env, nil
+}
+
+func (s *libpodSpec) Env(name string) (string, error) {
+	env, err := s.env()
+	if err != nil {
+		return "", err
+	}
+	raw, ok := env[name]
+	if !ok {
+		return "", iofs.ErrNotExist
+	}
+	val, ok := raw.(string)
+	if !ok {
+		return "", errors.Errorf("unexpected type of env %s", name)
+	}
+	return val, nil
+}
+
+func (s *libpodSpec) AddEnv(vars []string) error {
+	env, err := s.env()
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		key, val, _ := strings.Cut(v, "=")
+		env[key] = val
+	}
+	s.spec["env"] = env
+	return nil
+}
+
+// appendTo appends v to the list at key.
+func (s *libpodSpec) appendTo(key string, v any) error {
+	raw, ok := s.spec[key]
+	if !ok || raw == nil {
+		s.spec[key] = []any{v}
+		return nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return errors.Errorf("unexpected type of %s", key)
+	}
+	s.spec[key] = append(list, v)
+	return nil
+}
+
+func (s *libpodSpec) AddBind(from, to, mode string) error {
+	if filepath.IsAbs(from) {
+		return s.appendTo("mounts", map[string]any{
+			"destination": to,
+			"type":        "bind",
+			"source":      from,
+			"options":     []any{"rbind", mode},
+		})
+	}
+	return s.appendTo("volumes", map[string]any{
+		"Name":    from,
+		"Dest":    to,
+		"Options": []any{mode},
+	})
+}
+
+// Network returns the network mode or, if connected to named networks, the name of one of them.
+func (s *libpodSpec) Network() (string, error) {
+	netns, _ := s.spec["netns"].(map[string]any)
+	mode, _ := netns["nsmode"].(string)
+	if networks, ok := s.spec["Networks"].(map[string]any); ok {
+		for name := range networks {
+			return name, nil
+		}
+	}
+	return mode, nil
+}
+
+// SetNetwork sets the network mode using the Docker API's NetworkMode syntax.
+func (s *libpodSpec) SetNetwork(network string) error {
+	delete(s.spec, "Networks")
+	switch {
+	case network == "host", network == "none", network == "private", network == "bridge", network == "slirp4netns", network == "pasta":
+		s.spec["netns"] = map[string]any{"nsmode": network}
+	case strings.HasPrefix(network, "container:"):
+		s.spec["netns"] = map[string]any{"nsmode": "container", "value": strings.TrimPrefix(network, "container:")}
+	default:
+		s.spec["netns"] = map[string]any{"nsmode": "bridge"}
+		s.spec["Networks"] = map[string]any{network: map[string]any{}}
+	}
+	return nil
+}
+
+func (s *libpodSpec) Bytes() ([]byte, error) {
+	b, err := json.Marshal(s.spec)
+	if err != nil {
+		return nil, errors.Errorf("failed to re-marshal json: %s\nStruct: %s", err, s.spec)
+	}
+	return b, nil
+}
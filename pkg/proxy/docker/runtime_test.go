@@ -0,0 +1,109 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package docker
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPatchSpecLibpod(t *testing.T) {
+	testCases := []struct {
+		name    string
+		network string
+		body    string
+		want    string
+	}{
+		{
+			name: "empty spec",
+			body: `{"image":"alpine"}`,
+			want: `{
+				"image": "alpine",
+				"env": {"SSL_CERT_FILE": "/var/cache/proxy.crt", "JAVA_TOOL_OPTIONS": "-Djavax.net.ssl.trustStore=/var/cache/proxy.crt.jks", "DOCKER_HOST": "unix:///var/cache/proxy.sock", "CONTAINER_HOST": "unix:///var/cache/proxy.sock"},
+				"volumes": [{"Name": "proxy-vol1", "Dest": "/var/cache", "Options": ["rw"]}],
+				"mounts": [{"destination": "/var/cache/proxy.sock", "type": "bind", "source": "/tmp/proxy.sock", "options": ["rbind", "rw"]}]
+			}`,
+		},
+		{
+			name:    "existing config with named network",
+			network: "isolated",
+			body: `{
+				"image": "alpine",
+				"env": {"JAVA_TOOL_OPTIONS": "'-Xmx1g'"},
+				"volumes": [{"Name": "data", "Dest": "/data", "Options": []}],
+				"netns": {"nsmode": "host"}
+			}`,
+			want: `{
+				"image": "alpine",
+				"env": {"SSL_CERT_FILE": "/var/cache/proxy.crt", "JAVA_TOOL_OPTIONS": "-Xmx1g -Djavax.net.ssl.trustStore=/var/cache/proxy.crt.jks", "DOCKER_HOST": "unix:///var/cache/proxy.sock", "CONTAINER_HOST": "unix:///var/cache/proxy.sock"},
+				"volumes": [{"Name": "data", "Dest": "/data", "Options": []}, {"Name": "proxy-vol1", "Dest": "/var/cache", "Options": ["rw"]}],
+				"mounts": [{"destination": "/var/cache/proxy.sock", "type": "bind", "source": "/tmp/proxy.sock", "options": ["rbind", "rw"]}],
+				"netns": {"nsmode": "bridge"},
+				"Networks": {"isolated": {}}
+			}`,
+		},
+		{
+			name:    "host network",
+			network: "host",
+			body:    `{"image":"alpine","netns":{"nsmode":"bridge"},"Networks":{"podman":{}}}`,
+			want: `{
+				"image": "alpine",
+				"env": {"SSL_CERT_FILE": "/var/cache/proxy.crt", "JAVA_TOOL_OPTIONS": "-Djavax.net.ssl.trustStore=/var/cache/proxy.crt.jks", "DOCKER_HOST": "unix:///var/cache/proxy.sock", "CONTAINER_HOST": "unix:///var/cache/proxy.sock"},
+				"volumes": [{"Name": "proxy-vol1", "Dest": "/var/cache", "Options": ["rw"]}],
+				"mounts": [{"destination": "/var/cache/proxy.sock", "type": "bind", "source": "/tmp/proxy.sock", "options": ["rbind", "rw"]}],
+				"netns": {"nsmode": "host"}
+			}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctp, err := NewContainerTruststorePatcher(CERT, ContainerTruststorePatcherOpts{
+				Runtime:              PodmanRuntime{},
+				TruststoreEnvVars:    []string{"SSL_CERT_FILE"},
+				JavaTruststoreEnvVar: true,
+				NetworkOverride:      tc.network,
+			})
+			orFail(t, err)
+			ctp.proxySocket = "/tmp/proxy.sock"
+			spec, err := newLibpodSpec([]byte(tc.body))
+			orFail(t, err)
+			orFail(t, ctp.patchSpec(spec))
+			b, err := spec.Bytes()
+			orFail(t, err)
+			var got, want any
+			orFail(t, json.Unmarshal(b, &got))
+			orFail(t, json.Unmarshal([]byte(tc.want), &want))
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("patchSpec() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLibpodPattern(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/v4.0.0/libpod/containers/create": true,
+		"/libpod/commit":                   true,
+		"/v1.41/containers/create":         false,
+		"/containers/libpod/start":         false,
+	} {
+		if got := libpodPattern.MatchString(path); got != want {
+			t.Errorf("libpodPattern.MatchString(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestPodmanDefaultSocket(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	want := "/run/user/1000/podman/podman.sock"
+	if os.Geteuid() == 0 {
+		want = "/run/podman/podman.sock"
+	}
+	if got := (PodmanRuntime{}).DefaultSocket(); got != want {
+		t.Errorf("DefaultSocket() = %s, want %s", got, want)
+	}
+}
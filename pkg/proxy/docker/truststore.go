@@ -0,0 +1,162 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package docker
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/google/oss-rebuild/internal/proxy/dockerfs"
+	"github.com/pkg/errors"
+)
+
+// Path at which a bundle of the system truststore and the proxy cert is created.
+const proxyBundlePath = "/var/cache/proxy-bundle.crt"
+
+// truststoreProvider configures a class of TLS clients in a container to trust the proxy.
+type truststoreProvider interface {
+	// envVars returns the "KEY=VALUE" env vars with which containers are created.
+	envVars() []string
+	// patches detects the provider's truststores in the container and returns
+	// the patches that add cert to them. Patches are applied by the caller
+	// and reverted during export and commit.
+	patches(fs dockerfs.FS, cert []byte) ([]*patch, error)
+}
+
+// truststoreProviders are the optional truststore providers, by name.
+var truststoreProviders = map[string]truststoreProvider{
+	"certifi":       certifiTruststore{},
+	"node":          nodeTruststore{},
+	"ssl_cert_file": sslCertFileTruststore{},
+}
+
+// TruststoreProviders returns the names of the optional truststore providers.
+func TruststoreProviders() []string {
+	var names []string
+	for name := range truststoreProviders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// systemTruststore is the system CA bundle of the container's distribution.
+// It is always patched.
+type systemTruststore struct{}
+
+func (systemTruststore) envVars() []string { return nil }
+
+func (systemTruststore) patches(fs dockerfs.FS, cert []byte) ([]*patch, error) {
+	p, err := truststoreCertPatch(fs, cert)
+	if err != nil {
+		return nil, err
+	}
+	return []*patch{p}, nil
+}
+
+// certifiTruststore is the CA bundle vendored by Python's certifi package,
+// used by requests and pip in place of the system truststore.
+type certifiTruststore struct{}
+
+func (certifiTruststore) envVars() []string { return nil }
+
+// pythonLibDirs returns the library directories of the Python installations in the container.
+func pythonLibDirs(fs dockerfs.FS) ([]string, error) {
+	// NOTE: Distro packages for any Python version are installed here on Debian.
+	dirs := []string{"/usr/lib/python3/dist-packages"}
+	// NOTE: Directory listing is unsupported so we probe for recent versions.
+	for minor := 6; minor <= 14; minor++ {
+		for _, prefix := range []string{"/usr/local/lib", "/usr/lib", "/usr/lib64", "/opt/conda/lib"} {
+			dir := fmt.Sprintf("%s/python3.%d", prefix, minor)
+			if _, err := fs.Stat(dir); errors.Is(err, iofs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, dir+"/site-packages", dir+"/dist-packages")
+		}
+	}
+	return dirs, nil
+}
+
+func (certifiTruststore) patches(fs dockerfs.FS, cert []byte) ([]*patch, error) {
+	dirs, err := pythonLibDirs(fs)
+	if err != nil {
+		return nil, err
+	}
+	var patches []*patch
+	for _, dir := range dirs {
+		for _, pkg := range []string{"certifi", "pip/_vendor/certifi"} {
+			f, err := fs.OpenAndResolve(filepath.Join(dir, pkg, "cacert.pem"))
+			if errors.Is(err, iofs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			p, err := appendCertPatch(f, cert)
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, p)
+		}
+	}
+	return patches, nil
+}
+
+// nodeTruststore is Node.js's truststore which is extended with the proxy cert.
+// NOTE: Node.js otherwise uses its compiled-in roots, not the system truststore.
+type nodeTruststore struct{}
+
+func (nodeTruststore) envVars() []string {
+	return []string{"NODE_EXTRA_CA_CERTS=" + proxyCertPath}
+}
+
+func (nodeTruststore) patches(dockerfs.FS, []byte) ([]*patch, error) { return nil, nil }
+
+// sslCertFileTruststore is the truststore file named by SSL_CERT_FILE.
+//
+// SSL_CERT_FILE is honored by Go, OpenSSL, and rustls-native-certs so this
+// covers clients in images with no system truststore, such as Go binaries
+// built from scratch. Clients using compiled-in roots, such as
+// rustls with webpki-roots, cannot be patched.
+type sslCertFileTruststore struct{}
+
+func (sslCertFileTruststore) envVars() []string {
+	return []string{"SSL_CERT_FILE=" + proxyBundlePath}
+}
+
+func (sslCertFileTruststore) patches(fs dockerfs.FS, cert []byte) ([]*patch, error) {
+	var bundle []byte
+	if f, err := locateTruststore(fs); err == nil {
+		bundle = append(bundle, f.Contents...)
+		if len(bundle) > 0 && bundle[len(bundle)-1] != '\n' {
+			bundle = append(bundle, '\n')
+		}
+	}
+	// NOTE: This doesn't need to be cleaned up due to the enclosing volume
+	// binding made at creation time.
+	if err := createFile(fs, append(bundle, cert...), proxyBundlePath); err != nil && !errors.Is(err, iofs.ErrExist) {
+		return nil, errors.Wrap(err, "creating proxy bundle")
+	}
+	return nil, nil
+}
+
+// appendCertPatch returns a patch appending cert to the truststore file f.
+func appendCertPatch(f *dockerfs.File, cert []byte) (*patch, error) {
+	old := *f
+	f.Contents = append(slices.Clip(f.Contents), cert...)
+	return newPatch(&old, f)
+}
+
+// envVarNames returns the names of the given "KEY=VALUE" env vars.
+func envVarNames(vars []string) []string {
+	var names []string
+	for _, v := range vars {
+		names = append(names, strings.SplitN(v, "=", 2)[0])
+	}
+	return names
+}
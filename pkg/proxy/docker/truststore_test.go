@@ -0,0 +1,278 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package docker
+
+import (
+	"archive/tar"
+	iofs "io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/internal/proxy/dockerfs"
+	"github.com/google/oss-rebuild/pkg/proxy/cert"
+)
+
+// fakeFS is an in-memory dockerfs.FS in which directories exist implicitly.
+type fakeFS map[string]*dockerfs.File
+
+var _ dockerfs.FS = fakeFS{}
+
+func (f fakeFS) file(path, content string) fakeFS {
+	f[path] = &dockerfs.File{Path: path, Metadata: tar.Header{Typeflag: tar.TypeReg, Name: filepath.Base(path), Mode: 0644, Size: int64(len(content))}, Contents: []byte(content)}
+	return f
+}
+
+func (f fakeFS) link(path, target string) fakeFS {
+	f[path] = &dockerfs.File{Path: path, Metadata: tar.Header{Typeflag: tar.TypeSymlink, Name: filepath.Base(path), Mode: 0777, Linkname: target}}
+	return f
+}
+
+func (f fakeFS) contents(path string) string {
+	if file, ok := f[path]; ok {
+		return string(file.Contents)
+	}
+	return ""
+}
+
+func (f fakeFS) Open(path string) (*dockerfs.File, error) {
+	file, ok := f[path]
+	if !ok {
+		return nil, iofs.ErrNotExist
+	}
+	cpy := *file
+	cpy.Contents = append([]byte(nil), file.Contents...)
+	return &cpy, nil
+}
+
+func (f fakeFS) Stat(path string) (*dockerfs.FileInfo, error) {
+	if file, ok := f[path]; ok {
+		fi := dockerfs.NewFileInfo(file.Metadata.Name, int64(len(file.Contents)), file.Metadata.FileInfo().Mode(), time.Time{}, file.Metadata.Linkname)
+		return &fi, nil
+	}
+	for p := range f {
+		if strings.HasPrefix(p, path+"/") {
+			fi := dockerfs.NewFileInfo(filepath.Base(path), 0, iofs.ModeDir|0755, time.Time{}, "")
+			return &fi, nil
+		}
+	}
+	return nil, iofs.ErrNotExist
+}
+
+func (f fakeFS) OpenAndResolve(path string) (*dockerfs.File, error) {
+	for range 255 {
+		file, ok := f[path]
+		if !ok {
+			return nil, iofs.ErrNotExist
+		}
+		if file.Metadata.Typeflag != tar.TypeSymlink {
+			return f.Open(path)
+		}
+		target := file.Metadata.Linkname
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return nil, iofs.ErrInvalid
+}
+
+func (f fakeFS) WriteFile(file *dockerfs.File) error {
+	cpy := *file
+	f[file.Path] = &cpy
+	return nil
+}
+
+func TestLocateTruststore(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fs      fakeFS
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "alpine",
+			fs:   fakeFS{}.file("/etc/os-release", "ID=alpine\n").file("/etc/ssl/cert.pem", "alpine"),
+			want: "/etc/ssl/cert.pem",
+		},
+		{
+			name: "fedora",
+			fs: fakeFS{}.file("/etc/os-release", "NAME=\"Fedora Linux\"\nID=fedora\n").
+				link("/etc/pki/tls/cert.pem", "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem").
+				file("/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", "fedora"),
+			want: "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+		},
+		{
+			name: "derivative identified by ID_LIKE",
+			fs: fakeFS{}.file("/etc/os-release", "ID=\"custom\"\nID_LIKE=\"rhel centos fedora\"\n").
+				file("/etc/pki/tls/cert.pem", "custom"),
+			want: "/etc/pki/tls/cert.pem",
+		},
+		{
+			name: "distroless without os-release",
+			fs:   fakeFS{}.file("/etc/ssl/certs/ca-certificates.crt", "distroless"),
+			want: "/etc/ssl/certs/ca-certificates.crt",
+		},
+		{
+			name: "unknown distro with well-known truststore",
+			fs:   fakeFS{}.file("/etc/os-release", "ID=foo\n").file("/etc/ssl/cert.pem", "foo"),
+			want: "/etc/ssl/cert.pem",
+		},
+		{
+			name: "kaniko",
+			fs:   fakeFS{}.file("/kaniko/executor", "").file("/kaniko/ssl/certs/ca-certificates.crt", "kaniko"),
+			want: "/kaniko/ssl/certs/ca-certificates.crt",
+		},
+		{
+			name:    "unknown distro without truststore",
+			fs:      fakeFS{}.file("/etc/os-release", "ID=foo\n"),
+			wantErr: true,
+		},
+		{
+			name:    "known distro without truststore",
+			fs:      fakeFS{}.file("/etc/os-release", "ID=debian\n").file("/etc/ssl/cert.pem", "foo"),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := locateTruststore(tc.fs)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("locateTruststore() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && got.Path != tc.want {
+				t.Errorf("locateTruststore() = %s, want %s", got.Path, tc.want)
+			}
+		})
+	}
+}
+
+func TestTruststoreProviders(t *testing.T) {
+	certBytes := []byte("CERT\n")
+	testCases := []struct {
+		name        string
+		provider    truststoreProvider
+		fs          fakeFS
+		wantEnv     []string
+		wantPatched []string
+		wantFiles   map[string]string
+	}{
+		{
+			name:     "certifi in python images",
+			provider: certifiTruststore{},
+			fs: fakeFS{}.
+				file("/usr/local/lib/python3.12/site-packages/certifi/cacert.pem", "certifi").
+				file("/usr/local/lib/python3.12/site-packages/pip/_vendor/certifi/cacert.pem", "pip").
+				file("/usr/lib/python3.9/site-packages/certifi/cacert.pem", "system"),
+			wantPatched: []string{
+				"/usr/lib/python3.9/site-packages/certifi/cacert.pem",
+				"/usr/local/lib/python3.12/site-packages/certifi/cacert.pem",
+				"/usr/local/lib/python3.12/site-packages/pip/_vendor/certifi/cacert.pem",
+			},
+		},
+		{
+			name:     "certifi linked to system truststore",
+			provider: certifiTruststore{},
+			fs: fakeFS{}.
+				link("/usr/lib/python3/dist-packages/certifi/cacert.pem", "/etc/ssl/certs/ca-certificates.crt").
+				file("/etc/ssl/certs/ca-certificates.crt", "system"),
+			wantPatched: []string{"/etc/ssl/certs/ca-certificates.crt"},
+		},
+		{
+			name:     "certifi absent",
+			provider: certifiTruststore{},
+			fs:       fakeFS{}.file("/usr/local/lib/python3.12/os.py", ""),
+		},
+		{
+			name:     "node",
+			provider: nodeTruststore{},
+			fs:       fakeFS{},
+			wantEnv:  []string{"NODE_EXTRA_CA_CERTS=/var/cache/proxy.crt"},
+		},
+		{
+			name:      "ssl_cert_file with system truststore",
+			provider:  sslCertFileTruststore{},
+			fs:        fakeFS{}.file("/etc/os-release", "ID=alpine\n").file("/etc/ssl/cert.pem", "ROOTS"),
+			wantEnv:   []string{"SSL_CERT_FILE=/var/cache/proxy-bundle.crt"},
+			wantFiles: map[string]string{"/var/cache/proxy-bundle.crt": "ROOTS\nCERT\n"},
+		},
+		{
+			name:      "ssl_cert_file from scratch",
+			provider:  sslCertFileTruststore{},
+			fs:        fakeFS{},
+			wantEnv:   []string{"SSL_CERT_FILE=/var/cache/proxy-bundle.crt"},
+			wantFiles: map[string]string{"/var/cache/proxy-bundle.crt": "CERT\n"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.wantEnv, tc.provider.envVars()); diff != "" {
+				t.Errorf("envVars() mismatch (-want +got):\n%s", diff)
+			}
+			patches, err := tc.provider.patches(tc.fs, certBytes)
+			if err != nil {
+				t.Fatalf("patches() error = %v", err)
+			}
+			var patched []string
+			for _, p := range patches {
+				patched = append(patched, *p.Path())
+				if want := string(p.Before.Contents) + string(certBytes); string(p.After.Contents) != want {
+					t.Errorf("patch of %s = %q, want %q", *p.Path(), p.After.Contents, want)
+				}
+			}
+			if diff := cmp.Diff(tc.wantPatched, patched); diff != "" {
+				t.Errorf("patches() mismatch (-want +got):\n%s", diff)
+			}
+			for path, want := range tc.wantFiles {
+				if got := tc.fs.contents(path); got != want {
+					t.Errorf("contents of %s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchContainerWithTruststores(t *testing.T) {
+	ctp, err := NewContainerTruststorePatcher(CERT, ContainerTruststorePatcherOpts{Truststores: []string{"certifi", "node", "ssl_cert_file"}})
+	orFail(t, err)
+	fs := fakeFS{}.
+		file("/etc/os-release", "ID=debian\n").
+		file("/etc/ssl/certs/ca-certificates.crt", "ROOTS\n").
+		link("/usr/lib/python3/dist-packages/certifi/cacert.pem", "/etc/ssl/certs/ca-certificates.crt").
+		file("/usr/local/lib/python3.11/site-packages/certifi/cacert.pem", "CERTIFI\n")
+	ctp.patchContainer(fs, "abc")
+	proxyCert := string(cert.ToPEM(&CERT))
+	for path, want := range map[string]string{
+		proxyCertPath:                        proxyCert,
+		proxyBundlePath:                      "ROOTS\n" + proxyCert,
+		"/etc/ssl/certs/ca-certificates.crt": "ROOTS\n" + proxyCert,
+		"/usr/local/lib/python3.11/site-packages/certifi/cacert.pem": "CERTIFI\n" + proxyCert,
+	} {
+		if got := fs.contents(path); got != want {
+			t.Errorf("contents of %s = %q, want %q", path, got, want)
+		}
+	}
+	if got := len(ctp.patchMap["abc"].Patches); got != 2 {
+		t.Errorf("len(Patches) = %d, want 2", got)
+	}
+	spec := &dockerSpec{[]byte(`{"HostConfig":{}}`)}
+	orFail(t, ctp.patchSpec(spec))
+	var env []string
+	for _, name := range []string{"NODE_EXTRA_CA_CERTS", "SSL_CERT_FILE"} {
+		val, err := spec.Env(name)
+		orFail(t, err)
+		env = append(env, name+"="+val)
+	}
+	if diff := cmp.Diff([]string{"NODE_EXTRA_CA_CERTS=/var/cache/proxy.crt", "SSL_CERT_FILE=/var/cache/proxy-bundle.crt"}, env); diff != "" {
+		t.Errorf("env mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnknownTruststoreProvider(t *testing.T) {
+	if _, err := NewContainerTruststorePatcher(CERT, ContainerTruststorePatcherOpts{Truststores: []string{"bogus"}}); err == nil {
+		t.Fatal("NewContainerTruststorePatcher() succeeded, want error")
+	}
+}
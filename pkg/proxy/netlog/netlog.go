@@ -4,9 +4,14 @@
 package netlog
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
 	"net"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/elazarl/goproxy"
 )
@@ -16,14 +21,77 @@ type HTTPRequestLog struct {
 	Scheme string
 	Host   string
 	Path   string
+	// Proto is the protocol of the client request, e.g. HTTP/1.1 or HTTP/2.0.
+	Proto string
+	// StatusCode is the status of the response, or zero if none was received.
+	StatusCode int
+	// ContentLength is the number of response body bytes delivered to the client.
+	ContentLength int64
+	// ContentType is the Content-Type header of the response.
+	ContentType string
+	// Digest is the sha256 digest of the response body in the form
+	// sha256:<hex>, populated only when the body was read in its entirety.
+	Digest string
+	// StartTime is when the proxy received the request.
+	StartTime time.Time
+	// Duration is the time from receiving the request to delivering the full response.
+	Duration time.Duration
+}
+
+// DNSQueryLog is a DNS query relayed by the proxy's DNS forwarder.
+type DNSQueryLog struct {
+	// Name is the queried domain name without the trailing dot.
+	Name string
+	// Type is the queried record type, e.g. A or AAAA.
+	Type string
+	// Answers contains the addresses and canonical names in the answer section.
+	Answers []string
+	// RCode is the response code returned to the client, e.g. Success or NameError.
+	RCode string
+	// Blocked is whether the query was refused by the network policy.
+	Blocked bool
+	// StartTime is when the proxy received the query.
+	StartTime time.Time
+	// Duration is the time from receiving the query to sending the response.
+	Duration time.Duration
+}
+
+// TCPConnectionLog is a TCP connection relayed by the proxy's connection logger.
+type TCPConnectionLog struct {
+	// Destination is the address to which the client originally connected.
+	Destination string
+	// ServerName is the SNI of the TLS ClientHello, if the connection began with one.
+	ServerName string
+	// BytesSent is the number of bytes relayed from the client to the destination.
+	BytesSent int64
+	// BytesReceived is the number of bytes relayed from the destination to the client.
+	BytesReceived int64
+	// Blocked is whether the connection was closed without being relayed due to the network policy.
+	Blocked bool
+	// StartTime is when the proxy accepted the connection.
+	StartTime time.Time
+	// Duration is the time from accepting the connection to closing it.
+	Duration time.Duration
 }
 
 type NetworkActivityLog struct {
-	HTTPRequests []HTTPRequestLog
+	HTTPRequests   []HTTPRequestLog
+	DNSQueries     []DNSQueryLog
+	TCPConnections []TCPConnectionLog
 }
 
+// CaptureActivityLog registers handlers on t that record each request and its response.
+//
+// Response bodies are hashed as they are streamed to the client so the log
+// entry for a request is completed only once its body is consumed.
 func CaptureActivityLog(t *goproxy.ProxyHttpServer, mx *sync.Mutex) *NetworkActivityLog {
-	httpReqs := make(chan HTTPRequestLog, 10)
+	netlog := new(NetworkActivityLog)
+	// Initialize slice to avoid serializing as null.
+	netlog.HTTPRequests = []HTTPRequestLog{}
+	netlog.DNSQueries = []DNSQueryLog{}
+	netlog.TCPConnections = []TCPConnectionLog{}
+	// Each request is handled with a distinct ProxyCtx so it identifies the log entry.
+	var pending sync.Map // *goproxy.ProxyCtx -> int
 	t.OnRequest().DoFunc(func(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
 		// Schema-less requests will be raw HTTP requests with relative URLs.
 		if req.URL.Scheme == "" {
@@ -35,26 +103,95 @@ func CaptureActivityLog(t *goproxy.ProxyHttpServer, mx *sync.Mutex) *NetworkActi
 		if err != nil || !((port == "80" && req.URL.Scheme == "http") || (port == "443" && req.URL.Scheme == "https")) {
 			host = req.URL.Host
 		}
-		httpReqs <- HTTPRequestLog{
-			Method: req.Method,
-			Scheme: req.URL.Scheme,
-			Host:   host,
-			Path:   req.URL.Path,
-		}
+		mx.Lock()
+		netlog.HTTPRequests = append(netlog.HTTPRequests, HTTPRequestLog{
+			Method:    req.Method,
+			Scheme:    req.URL.Scheme,
+			Host:      host,
+			Path:      req.URL.Path,
+			Proto:     req.Proto,
+			StartTime: time.Now().UTC(),
+		})
+		pending.Store(ctx, len(netlog.HTTPRequests)-1)
+		mx.Unlock()
 		return req, nil
 	})
-	netlog := new(NetworkActivityLog)
-	// Initialize slice to avoid serializing as null.
-	netlog.HTTPRequests = []HTTPRequestLog{}
-	go func() {
-		for {
-			select {
-			case httpReq := <-httpReqs:
-				mx.Lock()
-				netlog.HTTPRequests = append(netlog.HTTPRequests, httpReq)
-				mx.Unlock()
-			}
+	t.OnResponse().DoFunc(func(resp *http.Response, ctx *goproxy.ProxyCtx) *http.Response {
+		v, ok := pending.LoadAndDelete(ctx)
+		if !ok {
+			return resp
 		}
-	}()
+		idx := v.(int)
+		update := func(f func(*HTTPRequestLog)) {
+			mx.Lock()
+			defer mx.Unlock()
+			e := &netlog.HTTPRequests[idx]
+			f(e)
+			e.Duration = time.Since(e.StartTime)
+		}
+		if resp == nil {
+			update(func(*HTTPRequestLog) {})
+			return resp
+		}
+		update(func(e *HTTPRequestLog) {
+			e.StatusCode = resp.StatusCode
+			e.ContentType = resp.Header.Get("Content-Type")
+		})
+		// Bodyless responses are left unwrapped to preserve their framing headers.
+		if resp.Body == nil || resp.Body == http.NoBody || resp.ContentLength == 0 || ctx.Req.Method == http.MethodHead {
+			// HEAD responses deliver no body, so their advertised length is not
+			// recorded and the digest of the unseen resource is left unset.
+			update(func(e *HTTPRequestLog) {
+				if ctx.Req.Method != http.MethodHead {
+					e.Digest = emptyDigest
+				}
+			})
+			return resp
+		}
+		resp.Body = &hashingBody{ReadCloser: resp.Body, hash: sha256.New(), update: update}
+		return resp
+	})
 	return netlog
 }
+
+var emptyDigest = func() string {
+	sum := sha256.Sum256(nil)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}()
+
+// hashingBody computes the digest of a response body as it is read.
+type hashingBody struct {
+	io.ReadCloser
+	hash   hash.Hash
+	n      int64
+	update func(func(*HTTPRequestLog))
+	done   bool
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.hash.Write(p[:n])
+		b.n += int64(n)
+	}
+	if err == io.EOF && !b.done {
+		b.done = true
+		digest := "sha256:" + hex.EncodeToString(b.hash.Sum(nil))
+		b.update(func(e *HTTPRequestLog) {
+			e.ContentLength = b.n
+			e.Digest = digest
+		})
+	}
+	return n, err
+}
+
+func (b *hashingBody) Close() error {
+	if !b.done {
+		// The body was not fully read so only the delivered length is known.
+		b.done = true
+		b.update(func(e *HTTPRequestLog) {
+			e.ContentLength = b.n
+		})
+	}
+	return b.ReadCloser.Close()
+}
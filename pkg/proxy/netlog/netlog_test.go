@@ -0,0 +1,73 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package netlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elazarl/goproxy"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func sha256Digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestCaptureActivityLog(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"ok":true}`)
+		}
+	}))
+	defer upstream.Close()
+	p := goproxy.NewProxyHttpServer()
+	mx := new(sync.Mutex)
+	netlog := CaptureActivityLog(p, mx)
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	c := &http.Client{Transport: &http.Transport{Proxy: func(*http.Request) (*url.URL, error) { return url.Parse(srv.URL) }}}
+	for _, path := range []string{"/data.json", "/missing"} {
+		resp, err := c.Get(upstream.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	resp, err := c.Head(upstream.URL + "/data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	want := []HTTPRequestLog{
+		{Method: "GET", Scheme: "http", Host: host, Path: "/data.json", Proto: "HTTP/1.1", StatusCode: 200, ContentLength: 11, ContentType: "application/json", Digest: sha256Digest(`{"ok":true}`)},
+		{Method: "GET", Scheme: "http", Host: host, Path: "/missing", Proto: "HTTP/1.1", StatusCode: 404, ContentLength: 19, ContentType: "text/plain; charset=utf-8", Digest: sha256Digest("404 page not found\n")},
+		{Method: "HEAD", Scheme: "http", Host: host, Path: "/data.json", Proto: "HTTP/1.1", StatusCode: 200, ContentType: "application/json"},
+	}
+	mx.Lock()
+	defer mx.Unlock()
+	if diff := cmp.Diff(want, netlog.HTTPRequests, cmpopts.IgnoreFields(HTTPRequestLog{}, "StartTime", "Duration")); diff != "" {
+		t.Errorf("HTTPRequests mismatch (-want +got):\n%s", diff)
+	}
+	for i, e := range netlog.HTTPRequests {
+		if e.StartTime.IsZero() || e.Duration <= 0 {
+			t.Errorf("HTTPRequests[%d] missing timing: %+v", i, e)
+		}
+	}
+}
@@ -5,10 +5,17 @@
 package policy
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/elazarl/goproxy"
@@ -62,6 +69,56 @@ func (p *Policy) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface for the Policy class.
+// Produces the format expected by UnmarshalJSON, naming each rule's type in ruleType.
+func (p Policy) MarshalJSON() ([]byte, error) {
+	var policywrapper struct {
+		Policy struct {
+			AnyOf []json.RawMessage `json:"anyOf"`
+			AllOf []json.RawMessage `json:"allOf"`
+		}
+	}
+	var err error
+	if policywrapper.Policy.AnyOf, err = marshalRules(p.AnyOf); err != nil {
+		return nil, err
+	}
+	if policywrapper.Policy.AllOf, err = marshalRules(p.AllOf); err != nil {
+		return nil, err
+	}
+	return json.Marshal(policywrapper)
+}
+
+func marshalRules(rules []Rule) ([]json.RawMessage, error) {
+	// Initialize slice to avoid serializing as null.
+	msgs := []json.RawMessage{}
+	for _, rule := range rules {
+		msg, err := marshalRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// marshalRule serializes rule along with its registered ruleType.
+func marshalRule(rule Rule) (json.RawMessage, error) {
+	ruleType := ruleTypeName(rule)
+	if ruleType == "" {
+		return nil, fmt.Errorf("rule type not registered: %T", rule)
+	}
+	b, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	fields["ruleType"], _ = json.Marshal(ruleType)
+	return json.Marshal(fields)
+}
+
 func newRuleFromJson(rule json.RawMessage) (Rule, error) {
 	var tmpmap map[string]any
 	if err := json.Unmarshal(rule, &tmpmap); err != nil {
@@ -83,29 +140,264 @@ func newRuleFromJson(rule json.RawMessage) (Rule, error) {
 	}
 }
 
-// Apply enforces the policy on the request. Returns http.StatusForbidden if the
-// request does not satisfy the policy rules.
-func (p Policy) Apply(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
+// Violation describes the part of a policy that a request did not satisfy.
+type Violation struct {
+	// Clause is the policy clause that was not satisfied, either "allOf" or "anyOf".
+	Clause string `json:"clause"`
+	// RuleType is the registered name of the AllOf rule that disallowed the request.
+	// Unset for AnyOf violations since no single rule is responsible.
+	RuleType string `json:"ruleType,omitempty"`
+	// Rule is the AllOf rule that disallowed the request.
+	Rule Rule `json:"rule,omitempty"`
+	// Digest is the digest of the response body, if the violation was found in the response.
+	Digest string `json:"digest,omitempty"`
+}
+
+// Evaluate checks the request against the policy. Returns nil if the
+// request satisfies the policy rules.
+//
+// Before the response is available, rules that only constrain the response
+// neither allow nor deny the request. AnyOf must therefore be satisfied by a
+// rule that constrains the request itself, so that a request is only sent
+// upstream if the policy could allow it for some response.
+func (p Policy) Evaluate(req *http.Request) *Violation {
+	digest, _ := responseDigest(req)
+	check := func(r Rule) verdict { return requestVerdict(r, req) }
 	for _, rule := range p.AllOf {
-		if !rule.Allows(req) {
-			return blockedResponse(req)
+		if check(rule) == denied {
+			return &Violation{Clause: "allOf", RuleType: ruleTypeName(rule), Rule: rule, Digest: digest}
 		}
 	}
 	if len(p.AllOf) != 0 && len(p.AnyOf) == 0 {
-		return req, nil
+		return nil
 	}
-	for _, rule := range p.AnyOf {
-		if rule.Allows(req) {
-			return req, nil
+	if anyOfVerdict(p.AnyOf, check) == allowed {
+		return nil
+	}
+	return &Violation{Clause: "anyOf", Digest: digest}
+}
+
+// verdict is the outcome of checking a rule before the response is available.
+type verdict int
+
+const (
+	denied verdict = iota
+	allowed
+	// deferred is the verdict of rules that only constrain the response
+	// which neither allow nor deny until the response digest is known.
+	deferred
+)
+
+func verdictOf(ok bool) verdict {
+	if ok {
+		return allowed
+	}
+	return denied
+}
+
+// requestVerdict checks rule against req, deferring the parts of rule that depend on an unknown response.
+func requestVerdict(rule Rule, req *http.Request) verdict {
+	if r, ok := rule.(interface{ requestVerdict(*http.Request) verdict }); ok {
+		return r.requestVerdict(req)
+	}
+	return verdictOf(rule.Allows(req))
+}
+
+// hostVerdict checks whether rule could allow a request to host.
+// Rules that do not constrain the host are assumed to allow it.
+func hostVerdict(rule Rule, host string) verdict {
+	switch r := rule.(type) {
+	case interface{ hostVerdict(string) verdict }:
+		return r.hostVerdict(host)
+	case interface{ allowsHost(string) bool }:
+		return verdictOf(r.allowsHost(host))
+	default:
+		return allowed
+	}
+}
+
+// anyOfVerdict combines the verdicts of rules of which at least one must allow.
+func anyOfVerdict(rules []Rule, check func(Rule) verdict) verdict {
+	v := denied
+	for _, r := range rules {
+		switch check(r) {
+		case allowed:
+			return allowed
+		case deferred:
+			v = deferred
 		}
 	}
-	return blockedResponse(req)
+	return v
+}
+
+// allOfVerdict combines the verdicts of rules of which none may deny.
+// Rules that are all deferred are deferred since none of them allows the request.
+func allOfVerdict(rules []Rule, check func(Rule) verdict) verdict {
+	v := deferred
+	if len(rules) == 0 {
+		v = allowed
+	}
+	for _, r := range rules {
+		switch check(r) {
+		case denied:
+			return denied
+		case allowed:
+			v = allowed
+		}
+	}
+	return v
+}
+
+// ruleTypeName returns the name under which the type of rule is registered, if any.
+func ruleTypeName(rule Rule) string {
+	t := reflect.TypeOf(rule)
+	for name, constructor := range ruleRegistry {
+		if rt := reflect.TypeOf(constructor()); rt == t || (rt.Kind() == reflect.Pointer && rt.Elem() == t) {
+			return name
+		}
+	}
+	return ""
+}
+
+// AllowsHost reports whether the policy could allow any request to host.
+//
+// This supports enforcing the policy on traffic, such as DNS queries, for
+// which only the hostname is known. Rules that do not constrain the host are
+// assumed to allow it so a host is only disallowed if no request to it could
+// satisfy the policy. As in Evaluate, rules that only constrain the response
+// do not satisfy AnyOf.
+func (p Policy) AllowsHost(host string) bool {
+	check := func(r Rule) verdict { return hostVerdict(r, host) }
+	for _, rule := range p.AllOf {
+		if check(rule) == denied {
+			return false
+		}
+	}
+	if len(p.AllOf) != 0 && len(p.AnyOf) == 0 {
+		return true
+	}
+	return anyOfVerdict(p.AnyOf, check) == allowed
 }
 
+// Apply enforces the policy on the request. Returns http.StatusForbidden if the
+// request does not satisfy the policy rules.
+func (p Policy) Apply(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
+	if p.Evaluate(req) != nil {
+		return blockedResponse(req)
+	}
+	return req, nil
+}
+
+// EvaluateResponse checks the response body against the policy. Returns a nil
+// Violation if the body satisfies the policy rules.
+//
+// Bodies are only inspected when the policy contains rules that require it.
+// Inspected bodies are spooled to a temporary file, rather than held in
+// memory, until their digest has been checked. The returned response must be
+// used in place of resp.
+func (p Policy) EvaluateResponse(resp *http.Response, ctx *goproxy.ProxyCtx) (*http.Response, *Violation, error) {
+	if resp == nil || ctx == nil || ctx.Req == nil || !p.inspectsResponse() {
+		return resp, nil, nil
+	}
+	body, digest, err := spoolBody(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Body = body
+	return resp, p.Evaluate(withResponseDigest(ctx.Req, digest)), nil
+}
+
+// ApplyResponse enforces the policy on the response body. Returns
+// http.StatusForbidden if the body does not satisfy the policy rules.
+func (p Policy) ApplyResponse(resp *http.Response, ctx *goproxy.ProxyCtx) *http.Response {
+	resp, v, err := p.EvaluateResponse(resp, ctx)
+	if err != nil {
+		log.Printf("Response from %s blocked: unable to read body: %v", ctx.Req.URL.String(), err)
+		_, blocked := blockedResponse(ctx.Req)
+		return blocked
+	}
+	if v != nil {
+		resp.Body.Close()
+		log.Printf("Response from %s with digest %s blocked by network policy", ctx.Req.URL.String(), v.Digest)
+		_, blocked := blockedResponse(ctx.Req)
+		return blocked
+	}
+	return resp
+}
+
+func (p Policy) inspectsResponse() bool {
+	return slices.ContainsFunc(p.AnyOf, inspectsResponse) || slices.ContainsFunc(p.AllOf, inspectsResponse)
+}
+
+// inspectsResponse reports whether rule, or any rule nested within it, must be evaluated against the response.
+func inspectsResponse(rule Rule) bool {
+	switch r := rule.(type) {
+	case interface{ inspectsResponse() bool }:
+		return r.inspectsResponse()
+	case interface{ children() []Rule }:
+		return slices.ContainsFunc(r.children(), inspectsResponse)
+	default:
+		return false
+	}
+}
+
+type responseDigestKey struct{}
+
+// withResponseDigest returns a copy of req carrying the digest of its response body.
+func withResponseDigest(req *http.Request, digest string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), responseDigestKey{}, digest))
+}
+
+// responseDigest returns the digest of the response body to req, if known.
+func responseDigest(req *http.Request) (string, bool) {
+	digest, ok := req.Context().Value(responseDigestKey{}).(string)
+	return digest, ok
+}
+
+// spoolBody copies body to a temporary file and returns a reader over the copy along with its digest.
+func spoolBody(body io.ReadCloser) (io.ReadCloser, string, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	defer body.Close()
+	f, err := os.CreateTemp("", "proxy-policy-")
+	if err != nil {
+		return nil, "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	return &tempFileBody{f}, "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// tempFileBody removes the backing file once the body is closed.
+type tempFileBody struct {
+	*os.File
+}
+
+func (b *tempFileBody) Close() error {
+	err := b.File.Close()
+	os.Remove(b.File.Name())
+	return err
+}
+
+// BlockedHeader is set on the responses generated for requests blocked by the policy.
+const BlockedHeader = "X-Proxy-Policy-Blocked"
+
 func blockedResponse(req *http.Request) (*http.Request, *http.Response) {
 	log.Printf("Request to %s blocked by network policy", req.URL.String())
 	errorMessage := fmt.Sprintf("Access to %s is blocked by the proxy's network policy", req.URL.String())
-	return req, goproxy.NewResponse(req, goproxy.ContentTypeText, http.StatusForbidden, errorMessage)
+	resp := goproxy.NewResponse(req, goproxy.ContentTypeText, http.StatusForbidden, errorMessage)
+	resp.Header.Set(BlockedHeader, "true")
+	return req, resp
 }
 
 // Rule interface with method to check compliance of incoming http(s) requests.
@@ -137,6 +429,20 @@ type URLMatchRule struct {
 // The empty string matches any domain.
 func (rule URLMatchRule) Allows(req *http.Request) bool {
 	url := req.URL
+	if !rule.allowsHost(url.Hostname()) {
+		return false
+	}
+	switch rule.PathMatch {
+	case PrefixMatch:
+		return strings.HasPrefix(url.Path, rule.Path)
+	case FullMatch:
+		return url.Path == rule.Path
+	default:
+		return false
+	}
+}
+
+func (rule URLMatchRule) allowsHost(hostname string) bool {
 	switch rule.HostMatch {
 	case SuffixMatch:
 		// Special case: match any.
@@ -145,7 +451,7 @@ func (rule URLMatchRule) Allows(req *http.Request) bool {
 		}
 
 		// Check for an exact match first (see below).
-		if url.Hostname() == rule.Host {
+		if hostname == rule.Host {
 			return true
 		}
 
@@ -155,22 +461,9 @@ func (rule URLMatchRule) Allows(req *http.Request) bool {
 		if !strings.HasPrefix(host, ".") {
 			host = "." + host
 		}
-		if !strings.HasSuffix(url.Hostname(), host) {
-			return false
-		}
+		return strings.HasSuffix(hostname, host)
 	case FullMatch:
-		if url.Hostname() != rule.Host {
-			return false
-		}
-	default:
-		return false
-	}
-
-	switch rule.PathMatch {
-	case PrefixMatch:
-		return strings.HasPrefix(url.Path, rule.Path)
-	case FullMatch:
-		return url.Path == rule.Path
+		return hostname == rule.Host
 	default:
 		return false
 	}
@@ -4,9 +4,17 @@
 package policy
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/elazarl/goproxy"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestApplyOnURLMatchRule(t *testing.T) {
@@ -308,3 +316,295 @@ func TestApplyOnURLMatchRule(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyOnBuiltinRules(t *testing.T) {
+	RegisterBuiltinRules()
+	var p Policy
+	err := json.Unmarshal([]byte(`{
+		"Policy": {
+			"AllOf": [
+				{"ruleType": "MethodRule", "methods": ["GET", "HEAD"]},
+				{"ruleType": "NotRule", "rule": {"ruleType": "HeaderRule", "header": "Authorization"}}
+			],
+			"AnyOf": [
+				{"ruleType": "PackageRule", "packages": ["pkg:npm/left-pad@1.3.0", "pkg:npm/@scope/pkg"]},
+				{
+					"ruleType": "AllOfRule",
+					"rules": [
+						{"ruleType": "URLMatchRule", "host": "registry.npmjs.org", "matchHostBy": "full", "path": "", "matchPathBy": "prefix"},
+						{"ruleType": "NotRule", "rule": {"ruleType": "PathRegexRule", "pattern": ".*\\.tgz"}}
+					]
+				},
+				{
+					"ruleType": "AnyOfRule",
+					"rules": [
+						{"ruleType": "PathRegexRule", "host": "example.com", "pattern": "/v[0-9]+/.*"}
+					]
+				}
+			]
+		}
+	}`), &p)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		header   http.Header
+		wantResp int
+	}{
+		{"package allowed", http.MethodGet, "https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz", nil, http.StatusOK},
+		{"package version not allowed", http.MethodGet, "https://registry.npmjs.org/left-pad/-/left-pad-1.2.0.tgz", nil, http.StatusForbidden},
+		{"unversioned scoped package allowed", http.MethodGet, "https://registry.npmjs.org/@scope/pkg/-/pkg-2.0.0.tgz", nil, http.StatusOK},
+		{"metadata allowed", http.MethodGet, "https://registry.npmjs.org/left-pad", nil, http.StatusOK},
+		{"regex path allowed", http.MethodHead, "https://example.com/v2/thing", nil, http.StatusOK},
+		{"regex path must fully match", http.MethodGet, "https://example.com/api/v2/thing", nil, http.StatusForbidden},
+		{"regex path host mismatch", http.MethodGet, "https://other.com/v2/thing", nil, http.StatusForbidden},
+		{"method not allowed", http.MethodPost, "https://registry.npmjs.org/left-pad", nil, http.StatusForbidden},
+		{"header not allowed", http.MethodGet, "https://registry.npmjs.org/left-pad", http.Header{"Authorization": {"Bearer x"}}, http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+			_, gotResp := p.Apply(req, nil)
+			gotCode := http.StatusOK
+			if gotResp != nil {
+				gotCode = gotResp.StatusCode
+			}
+			if gotCode != tc.wantResp {
+				t.Errorf("Apply returned an unexpected response code %v, want %v", gotCode, tc.wantResp)
+			}
+		})
+	}
+}
+
+func TestUnmarshalInvalidRules(t *testing.T) {
+	RegisterBuiltinRules()
+	for _, policy := range []string{
+		`{"Policy": {"AnyOf": [{"ruleType": "PathRegexRule", "pattern": "("}]}}`,
+		`{"Policy": {"AnyOf": [{"ruleType": "NotRule"}]}}`,
+		`{"Policy": {"AnyOf": [{"ruleType": "AnyOfRule", "rules": [{"ruleType": "NoSuchRule"}]}]}}`,
+	} {
+		var p Policy
+		if err := json.Unmarshal([]byte(policy), &p); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", policy)
+		}
+	}
+}
+
+func TestApplyResponse(t *testing.T) {
+	sum := sha256.Sum256([]byte("pinned"))
+	pinned := "sha256:" + hex.EncodeToString(sum[:])
+	host := URLMatchRule{Host: "host.com", HostMatch: FullMatch, PathMatch: PrefixMatch}
+	tests := []struct {
+		name     string
+		policy   Policy
+		body     string
+		wantResp int
+	}{
+		{
+			name:     "policy without response rules passes body through",
+			policy:   Policy{AnyOf: []Rule{URLMatchRule{HostMatch: SuffixMatch, PathMatch: PrefixMatch}}},
+			body:     "anything",
+			wantResp: http.StatusOK,
+		},
+		{
+			name:     "pinned digest allowed",
+			policy:   Policy{AnyOf: []Rule{AllOfRule{Rules: []Rule{host, DigestRule{Digests: []string{pinned}}}}}},
+			body:     "pinned",
+			wantResp: http.StatusOK,
+		},
+		{
+			name:     "unpinned digest blocked",
+			policy:   Policy{AnyOf: []Rule{AllOfRule{Rules: []Rule{host, DigestRule{Digests: []string{pinned}}}}}},
+			body:     "tampered",
+			wantResp: http.StatusForbidden,
+		},
+		{
+			name:     "negated digest blocked",
+			policy:   Policy{AllOf: []Rule{NotRule{Rule: AnyOfRule{Rules: []Rule{DigestRule{Digests: []string{pinned}}}}}}},
+			body:     "pinned",
+			wantResp: http.StatusForbidden,
+		},
+		{
+			name:     "negated digest allows others",
+			policy:   Policy{AllOf: []Rule{NotRule{Rule: DigestRule{Digests: []string{pinned}}}}},
+			body:     "other",
+			wantResp: http.StatusOK,
+		},
+		{
+			name:     "pinned digest in AllOf constrains AnyOf",
+			policy:   Policy{AllOf: []Rule{DigestRule{Digests: []string{pinned}}}, AnyOf: []Rule{host}},
+			body:     "tampered",
+			wantResp: http.StatusForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://host.com/file", nil)
+			if _, resp := tc.policy.Apply(req, nil); resp != nil {
+				t.Fatalf("Apply blocked request with response code %v", resp.StatusCode)
+			}
+			resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(tc.body)), Request: req}
+			got := tc.policy.ApplyResponse(resp, &goproxy.ProxyCtx{Req: req})
+			if got.StatusCode != tc.wantResp {
+				t.Fatalf("ApplyResponse returned an unexpected response code %v, want %v", got.StatusCode, tc.wantResp)
+			}
+			if tc.wantResp == http.StatusOK {
+				b, err := io.ReadAll(got.Body)
+				got.Body.Close()
+				if err != nil || string(b) != tc.body {
+					t.Errorf("ApplyResponse body = %q, %v; want %q", b, err, tc.body)
+				}
+			}
+		})
+	}
+}
+
+func TestDigestRuleDoesNotAllowRequests(t *testing.T) {
+	digest := DigestRule{Digests: []string{"sha256:abcd"}}
+	host := URLMatchRule{Host: "host.com", HostMatch: FullMatch, PathMatch: PrefixMatch}
+	tests := []struct {
+		name    string
+		policy  Policy
+		url     string
+		wantErr bool
+	}{
+		{
+			name:    "digest rule alone",
+			policy:  Policy{AnyOf: []Rule{digest}},
+			url:     "https://evil.com/upload",
+			wantErr: true,
+		},
+		{
+			name:    "nested digest rules",
+			policy:  Policy{AnyOf: []Rule{AnyOfRule{Rules: []Rule{AllOfRule{Rules: []Rule{digest}}}}}},
+			url:     "https://evil.com/upload",
+			wantErr: true,
+		},
+		{
+			name:    "negated digest rule",
+			policy:  Policy{AnyOf: []Rule{NotRule{Rule: digest}}},
+			url:     "https://evil.com/upload",
+			wantErr: true,
+		},
+		{
+			name:    "digest rule with other host",
+			policy:  Policy{AnyOf: []Rule{AllOfRule{Rules: []Rule{host, digest}}}},
+			url:     "https://evil.com/upload",
+			wantErr: true,
+		},
+		{
+			name:   "digest rule with host",
+			policy: Policy{AnyOf: []Rule{AllOfRule{Rules: []Rule{host, digest}}}},
+			url:    "https://host.com/file",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.url, nil)
+			if v := tc.policy.Evaluate(req); (v != nil) != tc.wantErr {
+				t.Errorf("Evaluate() = %+v, want violation: %v", v, tc.wantErr)
+			}
+			if got := tc.policy.AllowsHost(req.URL.Hostname()); got == tc.wantErr {
+				t.Errorf("AllowsHost(%s) = %v, want %v", req.URL.Hostname(), got, !tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyJSONRoundTrip(t *testing.T) {
+	RegisterBuiltinRules()
+	want := Policy{
+		AllOf: []Rule{&MethodRule{Methods: []string{"GET"}}},
+		AnyOf: []Rule{
+			&PackageRule{Packages: []string{"pkg:npm/left-pad@1.3.0"}},
+			&AllOfRule{Rules: []Rule{
+				&URLMatchRule{Host: "registry.npmjs.org", HostMatch: FullMatch, PathMatch: PrefixMatch},
+				&NotRule{Rule: &DigestRule{Digests: []string{"sha256:abcd"}}},
+			}},
+		},
+	}
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got Policy
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", b, err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAllowsHost(t *testing.T) {
+	registry := URLMatchRule{Host: "registry.npmjs.org", HostMatch: FullMatch, Path: "/left-pad", PathMatch: FullMatch}
+	github := URLMatchRule{Host: "github.com", HostMatch: SuffixMatch, Path: "", PathMatch: PrefixMatch}
+	tests := []struct {
+		name   string
+		policy Policy
+		host   string
+		want   bool
+	}{
+		{
+			name:   "empty policy disallows host",
+			policy: Policy{},
+			host:   "registry.npmjs.org",
+			want:   false,
+		},
+		{
+			name:   "path-specific rule allows host",
+			policy: Policy{AnyOf: []Rule{registry}},
+			host:   "registry.npmjs.org",
+			want:   true,
+		},
+		{
+			name:   "suffix rule allows subdomain",
+			policy: Policy{AnyOf: []Rule{registry, github}},
+			host:   "codeload.github.com",
+			want:   true,
+		},
+		{
+			name:   "unmatched host is disallowed",
+			policy: Policy{AnyOf: []Rule{registry, github}},
+			host:   "example.com",
+			want:   false,
+		},
+		{
+			name:   "host-independent AllOf rule allows host",
+			policy: Policy{AllOf: []Rule{MethodRule{Methods: []string{"GET"}}}},
+			host:   "example.com",
+			want:   true,
+		},
+		{
+			name:   "AllOf host rule disallows host",
+			policy: Policy{AllOf: []Rule{registry}, AnyOf: []Rule{github}},
+			host:   "github.com",
+			want:   false,
+		},
+		{
+			name:   "nested rules are checked",
+			policy: Policy{AnyOf: []Rule{AllOfRule{Rules: []Rule{MethodRule{Methods: []string{"GET"}}, &PathRegexRule{Host: "pypi.org", Pattern: "/simple/.*"}}}}},
+			host:   "files.pythonhosted.org",
+			want:   false,
+		},
+		{
+			name:   "negated rules do not constrain host",
+			policy: Policy{AnyOf: []Rule{NotRule{Rule: registry}}},
+			host:   "registry.npmjs.org",
+			want:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.policy.AllowsHost(tc.host); got != tc.want {
+				t.Errorf("AllowsHost(%q) = %v, want %v", tc.host, got, tc.want)
+			}
+		})
+	}
+}
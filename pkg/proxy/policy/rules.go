@@ -0,0 +1,324 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/oss-rebuild/internal/netclassify"
+)
+
+// RegisterBuiltinRules adds all rules defined in this package to the rule registry.
+func RegisterBuiltinRules() {
+	RegisterRule("URLMatchRule", func() Rule { return &URLMatchRule{} })
+	RegisterRule("MethodRule", func() Rule { return &MethodRule{} })
+	RegisterRule("HeaderRule", func() Rule { return &HeaderRule{} })
+	RegisterRule("PathRegexRule", func() Rule { return &PathRegexRule{} })
+	RegisterRule("PackageRule", func() Rule { return &PackageRule{} })
+	RegisterRule("DigestRule", func() Rule { return &DigestRule{} })
+	RegisterRule("AnyOfRule", func() Rule { return &AnyOfRule{} })
+	RegisterRule("AllOfRule", func() Rule { return &AllOfRule{} })
+	RegisterRule("NotRule", func() Rule { return &NotRule{} })
+}
+
+// Implements the Rule interface. Matches the request method against an allowlist.
+type MethodRule struct {
+	Methods []string `json:"methods"`
+}
+
+// Allows validates the rule against the method of req.
+func (rule MethodRule) Allows(req *http.Request) bool {
+	return slices.ContainsFunc(rule.Methods, func(m string) bool { return strings.EqualFold(m, req.Method) })
+}
+
+// Implements the Rule interface. Matches a request header against an allowlist of values.
+type HeaderRule struct {
+	Header string `json:"header"`
+	// Values is the set of permitted values. If empty, the header need only be present.
+	Values []string `json:"values"`
+}
+
+// Allows validates the rule against the headers of req.
+func (rule HeaderRule) Allows(req *http.Request) bool {
+	values := req.Header.Values(rule.Header)
+	if len(values) == 0 {
+		return false
+	}
+	if len(rule.Values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if !slices.Contains(rule.Values, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Implements the Rule interface. Matches the request path against a regular expression.
+type PathRegexRule struct {
+	// Host optionally restricts the rule to requests for a single host.
+	Host string `json:"host"`
+	// Pattern is an RE2 expression that must match the full request path.
+	Pattern string `json:"pattern"`
+
+	re *regexp.Regexp
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, validating the pattern.
+func (rule *PathRegexRule) UnmarshalJSON(data []byte) error {
+	type plain PathRegexRule
+	if err := json.Unmarshal(data, (*plain)(rule)); err != nil {
+		return err
+	}
+	re, err := compileFullMatch(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", rule.Pattern, err)
+	}
+	rule.re = re
+	return nil
+}
+
+func compileFullMatch(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+// Allows validates the rule against the URL in req.
+func (rule *PathRegexRule) Allows(req *http.Request) bool {
+	if rule.Host != "" && req.URL.Hostname() != rule.Host {
+		return false
+	}
+	re := rule.re
+	if re == nil {
+		var err error
+		if re, err = compileFullMatch(rule.Pattern); err != nil {
+			return false
+		}
+	}
+	return re.MatchString(req.URL.Path)
+}
+
+func (rule PathRegexRule) allowsHost(host string) bool {
+	return rule.Host == "" || rule.Host == host
+}
+
+// Implements the Rule interface. Matches requests for specific ecosystem packages.
+//
+// Packages are identified by the pURL assigned to the request URL by
+// netclassify. An entry without a version matches all versions of a package
+// and an entry without qualifiers matches all qualified variants of a version.
+// Requests netclassify cannot attribute to a package are not matched so
+// metadata endpoints must be allowed separately.
+type PackageRule struct {
+	Packages []string `json:"packages"`
+}
+
+// Allows validates the rule against the URL in req.
+func (rule PackageRule) Allows(req *http.Request) bool {
+	u := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: req.URL.Path}
+	c, err := netclassify.ClassifyURL(u.String())
+	if err != nil {
+		return false
+	}
+	unversioned := fmt.Sprintf("pkg:%s/%s", c.Ecosystem, c.Name)
+	versioned := unversioned + "@" + c.Version
+	for _, p := range rule.Packages {
+		if p == c.PURL || p == unversioned || (c.Version != "" && p == versioned) {
+			return true
+		}
+	}
+	return false
+}
+
+// Implements the Rule interface. Matches responses whose body digest is on an allowlist.
+//
+// The digest is unknown when the request is evaluated so the rule is enforced
+// only once the response body is available. Since it does not constrain the
+// request, it cannot by itself allow a request to be sent upstream and must be
+// combined with request rules, e.g. in an AllOfRule with a URLMatchRule.
+type DigestRule struct {
+	// Digests contains the permitted body digests in the form sha256:<hex>.
+	Digests []string `json:"digests"`
+}
+
+// Allows validates the rule against the response digest recorded on req, if any.
+func (rule DigestRule) Allows(req *http.Request) bool {
+	digest, ok := responseDigest(req)
+	if !ok {
+		return true
+	}
+	return slices.ContainsFunc(rule.Digests, func(d string) bool { return strings.EqualFold(d, digest) })
+}
+
+func (DigestRule) inspectsResponse() bool { return true }
+
+func (rule DigestRule) requestVerdict(req *http.Request) verdict {
+	if _, ok := responseDigest(req); !ok {
+		return deferred
+	}
+	return verdictOf(rule.Allows(req))
+}
+
+func (DigestRule) hostVerdict(string) verdict { return deferred }
+
+// Implements the Rule interface. Matches if any of the nested rules match.
+type AnyOfRule struct {
+	Rules []Rule `json:"rules"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, resolving nested rules from the registry.
+func (rule *AnyOfRule) UnmarshalJSON(data []byte) error {
+	rules, err := unmarshalNestedRules(data)
+	rule.Rules = rules
+	return err
+}
+
+// Allows validates the nested rules against req.
+func (rule AnyOfRule) Allows(req *http.Request) bool {
+	return slices.ContainsFunc(rule.Rules, func(r Rule) bool { return r.Allows(req) })
+}
+
+func (rule AnyOfRule) children() []Rule { return rule.Rules }
+
+func (rule AnyOfRule) requestVerdict(req *http.Request) verdict {
+	return anyOfVerdict(rule.Rules, func(r Rule) verdict { return requestVerdict(r, req) })
+}
+
+func (rule AnyOfRule) hostVerdict(host string) verdict {
+	return anyOfVerdict(rule.Rules, func(r Rule) verdict { return hostVerdict(r, host) })
+}
+
+// MarshalJSON implements the json.Marshaler interface, naming the type of each nested rule.
+func (rule AnyOfRule) MarshalJSON() ([]byte, error) {
+	rules, err := marshalRules(rule.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{"rules": rules})
+}
+
+// Implements the Rule interface. Matches if all of the nested rules match.
+type AllOfRule struct {
+	Rules []Rule `json:"rules"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, resolving nested rules from the registry.
+func (rule *AllOfRule) UnmarshalJSON(data []byte) error {
+	rules, err := unmarshalNestedRules(data)
+	rule.Rules = rules
+	return err
+}
+
+// Allows validates the nested rules against req.
+func (rule AllOfRule) Allows(req *http.Request) bool {
+	for _, r := range rule.Rules {
+		if !r.Allows(req) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rule AllOfRule) children() []Rule { return rule.Rules }
+
+func (rule AllOfRule) requestVerdict(req *http.Request) verdict {
+	return allOfVerdict(rule.Rules, func(r Rule) verdict { return requestVerdict(r, req) })
+}
+
+func (rule AllOfRule) hostVerdict(host string) verdict {
+	return allOfVerdict(rule.Rules, func(r Rule) verdict { return hostVerdict(r, host) })
+}
+
+// MarshalJSON implements the json.Marshaler interface, naming the type of each nested rule.
+func (rule AllOfRule) MarshalJSON() ([]byte, error) {
+	rules, err := marshalRules(rule.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{"rules": rules})
+}
+
+// Implements the Rule interface. Matches if the nested rule does not.
+type NotRule struct {
+	Rule Rule `json:"rule"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, resolving the nested rule from the registry.
+func (rule *NotRule) UnmarshalJSON(data []byte) error {
+	var wrapper struct {
+		Rule json.RawMessage `json:"rule"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return err
+	}
+	if wrapper.Rule == nil {
+		return fmt.Errorf("rule not specified in NotRule: %v", string(data))
+	}
+	r, err := newRuleFromJson(wrapper.Rule)
+	if err != nil {
+		return err
+	}
+	rule.Rule = r
+	return nil
+}
+
+// Allows validates the negation of the nested rule against req.
+func (rule NotRule) Allows(req *http.Request) bool {
+	// Defer negated response rules until the response is available.
+	if _, ok := responseDigest(req); !ok && inspectsResponse(rule.Rule) {
+		return true
+	}
+	return !rule.Rule.Allows(req)
+}
+
+func (rule NotRule) children() []Rule { return []Rule{rule.Rule} }
+
+func (rule NotRule) requestVerdict(req *http.Request) verdict {
+	// Defer negated response rules until the response is available.
+	if _, ok := responseDigest(req); !ok && inspectsResponse(rule.Rule) {
+		return deferred
+	}
+	return verdictOf(!rule.Rule.Allows(req))
+}
+
+// hostVerdict does not constrain the host since negating a host rule allows other hosts.
+func (rule NotRule) hostVerdict(string) verdict {
+	if inspectsResponse(rule.Rule) {
+		return deferred
+	}
+	return allowed
+}
+
+// MarshalJSON implements the json.Marshaler interface, naming the type of the nested rule.
+func (rule NotRule) MarshalJSON() ([]byte, error) {
+	r, err := marshalRule(rule.Rule)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{"rule": r})
+}
+
+func unmarshalNestedRules(data []byte) ([]Rule, error) {
+	var wrapper struct {
+		Rules []json.RawMessage `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	for _, r := range wrapper.Rules {
+		rule, err := newRuleFromJson(r)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
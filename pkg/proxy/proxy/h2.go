@@ -0,0 +1,126 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/elazarl/goproxy"
+	"golang.org/x/net/http2"
+)
+
+// serveHTTP2 terminates TLS for host on c and proxies each HTTP/2 stream.
+//
+// goproxy relays HTTP/2 sessions as opaque frames so, rather than using its
+// MITM, streams are served here and passed to the proxy as individual
+// requests. Each is then logged and subject to the policy like any other.
+func (t *TransparentProxyService) serveHTTP2(c net.Conn, host string) {
+	defer c.Close()
+	cfg, err := goproxy.TLSConfigFromCA(t.Ca)(host, &goproxy.ProxyCtx{Proxy: t.Proxy})
+	if err != nil {
+		log.Printf("Error creating TLS config for %s - %v", host, err)
+		return
+	}
+	cfg.NextProtos = []string{http2.NextProtoTLS}
+	conn := tls.Server(c, cfg)
+	if err := conn.Handshake(); err != nil {
+		log.Printf("Error completing TLS handshake for %s - %v", host, err)
+		return
+	}
+	if proto := conn.ConnectionState().NegotiatedProtocol; proto != http2.NextProtoTLS {
+		log.Printf("Unexpected protocol negotiated for %s: %q", host, proto)
+		return
+	}
+	srv := &http2.Server{}
+	srv.ServeConn(conn, &http2.ServeConnOpts{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Host == "" {
+				req.Host = host
+			}
+			req.URL.Scheme = "https"
+			req.URL.Host = req.Host
+			ctx := context.WithValue(req.Context(), trailerWriterKey{}, w)
+			t.Proxy.ServeHTTP(flushWriter{w}, req.WithContext(ctx))
+		}),
+	})
+}
+
+// flushWriter flushes each write so streamed responses are relayed without delay.
+type flushWriter struct {
+	http.ResponseWriter
+}
+
+func (w flushWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	if err == nil {
+		err = http.NewResponseController(w.ResponseWriter).Flush()
+	}
+	return n, err
+}
+
+// useHTTP2Upstream returns a handler that forwards requests received on
+// intercepted HTTP/2 streams over HTTP/2, as required to relay gRPC.
+//
+// All other requests continue to use the proxy's HTTP/1.1 transport.
+func useHTTP2Upstream(p *goproxy.ProxyHttpServer) goproxy.FuncReqHandler {
+	var once sync.Once
+	var tr *http.Transport
+	return func(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
+		if _, ok := req.Context().Value(trailerWriterKey{}).(http.ResponseWriter); !ok {
+			return req, nil
+		}
+		// NOTE: Cloned lazily so that any later customization of p.Tr is inherited.
+		once.Do(func() {
+			tr = p.Tr.Clone()
+			tr.ForceAttemptHTTP2 = true
+		})
+		ctx.RoundTripper = goproxy.RoundTripperFunc(func(req *http.Request, _ *goproxy.ProxyCtx) (*http.Response, error) {
+			return tr.RoundTrip(req)
+		})
+		return req, nil
+	}
+}
+
+// trailerWriterKey identifies the ResponseWriter to which a request's response trailers are relayed.
+type trailerWriterKey struct{}
+
+// relayTrailers arranges for the trailers of resp, such as the gRPC status,
+// to be sent to the client once the body has been read.
+//
+// goproxy only copies the response headers and body so trailers are written
+// using the TrailerPrefix convention which the HTTP/2 server sends after the body.
+func relayTrailers(resp *http.Response, ctx *goproxy.ProxyCtx) *http.Response {
+	if resp == nil || resp.Body == nil || ctx.Req == nil {
+		return resp
+	}
+	w, ok := ctx.Req.Context().Value(trailerWriterKey{}).(http.ResponseWriter)
+	if !ok {
+		return resp
+	}
+	resp.Body = &trailerBody{ReadCloser: resp.Body, resp: resp, header: w.Header()}
+	return resp
+}
+
+// trailerBody copies the response trailers to header once the body is consumed.
+type trailerBody struct {
+	io.ReadCloser
+	resp   *http.Response
+	header http.Header
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		for k, vs := range b.resp.Trailer {
+			b.header[http.TrailerPrefix+k] = vs
+		}
+	}
+	return n, err
+}
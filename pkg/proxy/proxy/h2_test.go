@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elazarl/goproxy"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/oss-rebuild/pkg/proxy/cert"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+	"github.com/google/oss-rebuild/pkg/proxy/policy"
+	"golang.org/x/net/http2"
+)
+
+func TestServeHTTP2(t *testing.T) {
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			http.Error(w, "expected HTTP/2", http.StatusHTTPVersionNotSupported)
+			return
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("Content-Type", "application/grpc")
+		io.WriteString(w, "hello")
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	upstream.EnableHTTP2 = true
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	p := NewTransparentProxyServer(false)
+	// Route all upstream connections to the test server, which is valid for example.com.
+	p.Tr.TLSClientConfig = upstream.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+	p.Tr.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, upstream.Listener.Addr().String())
+	}
+	ca := cert.GenerateCA()
+	policy.RegisterBuiltinRules()
+	pl := policy.Policy{AnyOf: []policy.Rule{
+		policy.URLMatchRule{Host: "example.com", HostMatch: policy.FullMatch, Path: "/allowed", PathMatch: policy.FullMatch},
+	}}
+	proxyService := NewTransparentProxyService(p, ca, EnforcementMode, TransparentProxyServiceOpts{Policy: &pl, HTTP2: true})
+	p.OnRequest().DoFunc(proxyService.ApplyNetworkPolicy)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go proxyService.serveHTTP2(c, "example.com")
+		}
+	}()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	client := &http.Client{Transport: &http2.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+		DialTLSContext: func(ctx context.Context, network, _ string, cfg *tls.Config) (net.Conn, error) {
+			c, err := (&net.Dialer{}).DialContext(ctx, network, ln.Addr().String())
+			if err != nil {
+				return nil, err
+			}
+			return tls.Client(c, cfg), nil
+		},
+	}}
+
+	resp, err := client.Get("https://example.com/allowed")
+	if err != nil {
+		t.Fatalf("Get(allowed) = %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("Get(allowed) = %d %q, want 200 %q", resp.StatusCode, body, "hello")
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Get(allowed) proto = %s, want HTTP/2.0", resp.Proto)
+	}
+	if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("Get(allowed) Grpc-Status trailer = %q, want %q", got, "0")
+	}
+
+	resp, err = client.Get("https://example.com/blocked")
+	if err != nil {
+		t.Fatalf("Get(blocked) = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Get(blocked) = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+
+	proxyService.mx.Lock()
+	defer proxyService.mx.Unlock()
+	blocked := "Access to https://example.com/blocked is blocked by the proxy's network policy"
+	want := []netlog.HTTPRequestLog{
+		{Method: "GET", Scheme: "https", Host: "example.com", Path: "/allowed", Proto: "HTTP/2.0", StatusCode: 200, ContentLength: 5, ContentType: "application/grpc", Digest: sha256Digest("hello")},
+		{Method: "GET", Scheme: "https", Host: "example.com", Path: "/blocked", Proto: "HTTP/2.0", StatusCode: 403, ContentLength: int64(len(blocked)), ContentType: "text/plain", Digest: sha256Digest(blocked)},
+	}
+	if diff := cmp.Diff(want, proxyService.networkLog.HTTPRequests, cmpopts.IgnoreFields(netlog.HTTPRequestLog{}, "StartTime", "Duration")); diff != "" {
+		t.Errorf("HTTPRequests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func sha256Digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestUseHTTP2Upstream(t *testing.T) {
+	p := NewTransparentProxyServer(false)
+	if p.Tr.ForceAttemptHTTP2 {
+		t.Error("proxy transport attempts HTTP/2 for all requests")
+	}
+	h := useHTTP2Upstream(p)
+	ctx := &goproxy.ProxyCtx{}
+	h(httptest.NewRequest(http.MethodGet, "https://example.com/", nil), ctx)
+	if ctx.RoundTripper != nil {
+		t.Error("HTTP/1.1 request given an HTTP/2 upstream")
+	}
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), trailerWriterKey{}, httptest.NewRecorder()))
+	h(req, ctx)
+	if ctx.RoundTripper == nil {
+		t.Error("HTTP/2 stream not given an HTTP/2 upstream")
+	}
+}
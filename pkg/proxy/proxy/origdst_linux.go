@@ -0,0 +1,66 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"syscall"
+)
+
+// soOriginalDst is the netfilter socket option that reports a redirected connection's original destination.
+// It shares its value with IP6T_SO_ORIGINAL_DST which reports the same for IPv6 connections.
+const soOriginalDst = 80
+
+// originalDestination returns the address to which c was addressed before being redirected to the proxy.
+func originalDestination(c net.Conn) (string, error) {
+	tc, ok := c.(*net.TCPConn)
+	if !ok {
+		return "", errors.New("not a TCP connection")
+	}
+	raw, err := tc.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+	// NOTE: IPv4 connections accepted on dual-stack sockets have IPv4-mapped
+	// addresses and are tracked by the IPv4 netfilter.
+	ipv6 := true
+	if la, ok := tc.LocalAddr().(*net.TCPAddr); ok && la.IP.To4() != nil {
+		ipv6 = false
+	}
+	var ip netip.Addr
+	var port uint16
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		if ipv6 {
+			// NOTE: The sockaddr_in6 result is the first member of the
+			// ip6_mtuinfo struct which is the only getsockopt wrapper in syscall
+			// that returns a buffer large enough to hold it.
+			var info *syscall.IPv6MTUInfo
+			if info, sockErr = syscall.GetsockoptIPv6MTUInfo(int(fd), syscall.IPPROTO_IPV6, soOriginalDst); sockErr == nil {
+				ip = netip.AddrFrom16(info.Addr.Addr)
+				// The port is in network byte order.
+				port = binary.BigEndian.Uint16(binary.NativeEndian.AppendUint16(nil, info.Addr.Port))
+			}
+			return
+		}
+		// NOTE: The sockaddr_in result fits in the IPv6Mreq struct which is the
+		// only getsockopt wrapper in syscall that returns a buffer of that size.
+		var mreq *syscall.IPv6Mreq
+		if mreq, sockErr = syscall.GetsockoptIPv6Mreq(int(fd), syscall.IPPROTO_IP, soOriginalDst); sockErr == nil {
+			sa := mreq.Multiaddr
+			ip = netip.AddrFrom4([4]byte{sa[4], sa[5], sa[6], sa[7]})
+			port = binary.BigEndian.Uint16(sa[2:4])
+		}
+	}); err != nil {
+		return "", err
+	}
+	if sockErr != nil {
+		return "", sockErr
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(int(port))), nil
+}
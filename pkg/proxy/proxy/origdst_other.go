@@ -0,0 +1,16 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package proxy
+
+import (
+	"errors"
+	"net"
+)
+
+// originalDestination returns the address to which c was addressed before being redirected to the proxy.
+func originalDestination(c net.Conn) (string, error) {
+	return "", errors.New("original destination is only supported on linux")
+}
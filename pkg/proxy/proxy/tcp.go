@@ -0,0 +1,192 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/oss-rebuild/internal/proxy/handshake"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+)
+
+// Resolves the original destination of redirected connections.
+// Used to enable customization during testing.
+var lookupOriginalDestination = originalDestination
+
+// How long to wait for a client to send the first bytes of a connection.
+// Protocols in which the server speaks first (e.g. SSH) never send them.
+const clientHelloTimeout = 500 * time.Millisecond
+
+// TLS record framing used to identify a ClientHello.
+const (
+	tlsRecordTypeHandshake = 0x16
+	tlsRecordHeaderLen     = 5
+	tlsMaxRecordLen        = 1 << 14
+)
+
+// ProxyTCP serves an endpoint that relays redirected TCP connections to their original destination.
+//
+// Each connection is recorded in the network activity log along with its
+// byte counts and, for TLS connections, the requested server name. In
+// EnforcementMode, connections to hosts to which the policy cannot allow any
+// request are closed without being relayed.
+func (t *TransparentProxyService) ProxyTCP(addr string) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Error listening for tcp connections - %v", err)
+	}
+	var inflight sync.WaitGroup
+	t.mx.Lock()
+	t.shutdownFuncs = append(t.shutdownFuncs, func(ctx context.Context) error {
+		if err := ln.Close(); err != nil {
+			return err
+		}
+		done := make(chan struct{})
+		go func() {
+			inflight.Wait()
+			close(done)
+		}()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+			return nil
+		}
+	})
+	t.mx.Unlock()
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Error accepting new connection - %v", err)
+			continue
+		}
+		inflight.Add(1)
+		go func() {
+			defer inflight.Done()
+			t.relayTCP(c)
+		}()
+	}
+}
+
+// relayTCP copies data between c and its original destination until both directions are closed.
+func (t *TransparentProxyService) relayTCP(c net.Conn) {
+	defer c.Close()
+	entry := netlog.TCPConnectionLog{StartTime: time.Now().UTC()}
+	dst, err := lookupOriginalDestination(c)
+	if err != nil {
+		log.Printf("Error resolving destination of connection from %s - %v", c.RemoteAddr(), err)
+		return
+	}
+	entry.Destination = dst
+	c, entry.ServerName = peekServerName(c)
+	defer func() {
+		entry.Duration = time.Since(entry.StartTime)
+		t.mx.Lock()
+		defer t.mx.Unlock()
+		t.networkLog.TCPConnections = append(t.networkLog.TCPConnections, entry)
+	}()
+	// NOTE: The server name is preferred as policies rarely list addresses.
+	host := entry.ServerName
+	if host == "" {
+		host, _, _ = net.SplitHostPort(dst)
+	}
+	if !t.permitsConnection(host) {
+		log.Printf("Connection to %s (%s) blocked by network policy", host, dst)
+		entry.Blocked = true
+		return
+	}
+	upstream, err := net.DialTimeout("tcp", dst, 30*time.Second)
+	if err != nil {
+		log.Printf("Error connecting to %s - %v", dst, err)
+		return
+	}
+	defer upstream.Close()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		entry.BytesSent, _ = io.Copy(upstream, c)
+		closeWrite(upstream)
+	}()
+	entry.BytesReceived, _ = io.Copy(c, upstream)
+	closeWrite(c)
+	wg.Wait()
+}
+
+// permitsConnection reports whether the policy permits relaying a connection to host.
+// In AuditMode, connections are always permitted but violations are logged.
+func (t *TransparentProxyService) permitsConnection(host string) bool {
+	t.mx.Lock()
+	mode := t.Mode
+	allowed := mode == DisabledMode || t.Policy.AllowsHost(host)
+	t.mx.Unlock()
+	if !allowed && mode == AuditMode {
+		log.Printf("Connection to %s violates network policy (audit)", host)
+		return true
+	}
+	return allowed
+}
+
+// peekServerName returns the SNI of c if it begins with a TLS ClientHello
+// along with a connection from which the peeked bytes can be re-read.
+func peekServerName(c net.Conn) (net.Conn, string) {
+	br := bufio.NewReaderSize(c, tlsRecordHeaderLen+tlsMaxRecordLen)
+	peeked := bufferedConn{Conn: c, r: br}
+	c.SetReadDeadline(time.Now().Add(clientHelloTimeout))
+	defer c.SetReadDeadline(time.Time{})
+	header, err := br.Peek(tlsRecordHeaderLen)
+	if err != nil || header[0] != tlsRecordTypeHandshake {
+		return peeked, ""
+	}
+	n := int(header[3])<<8 | int(header[4])
+	if n > tlsMaxRecordLen {
+		return peeked, ""
+	}
+	record, err := br.Peek(tlsRecordHeaderLen + n)
+	if err != nil {
+		return peeked, ""
+	}
+	// Parse a copy of the record so the peeked bytes remain to be relayed.
+	_, hello, err := handshake.PeekClientHello(bufferedConn{Conn: c, r: bytes.NewReader(record)})
+	if err != nil {
+		return peeked, ""
+	}
+	return peeked, hello.ServerName
+}
+
+// bufferedConn is a net.Conn whose reads are served from r.
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (b bufferedConn) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+// closeWrite signals the end of the stream to the peer of c, if supported.
+func closeWrite(c net.Conn) {
+	for {
+		switch v := c.(type) {
+		case interface{ CloseWrite() error }:
+			v.CloseWrite()
+			return
+		case bufferedConn:
+			c = v.Conn
+		default:
+			return
+		}
+	}
+}
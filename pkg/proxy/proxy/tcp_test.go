@@ -0,0 +1,206 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+	"github.com/google/oss-rebuild/pkg/proxy/policy"
+)
+
+// relayThrough returns a client connection whose peer is relayed to dst by proxyService.
+// The returned channel is closed once the relay completes.
+func relayThrough(t *testing.T, proxyService *TransparentProxyService, dst string) (net.Conn, <-chan struct{}) {
+	t.Helper()
+	orig := lookupOriginalDestination
+	t.Cleanup(func() { lookupOriginalDestination = orig })
+	lookupOriginalDestination = func(net.Conn) (string, error) { return dst, nil }
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		proxyService.relayTCP(c)
+	}()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, done
+}
+
+func TestRelayTCP(t *testing.T) {
+	ignoreTiming := cmpopts.IgnoreFields(netlog.TCPConnectionLog{}, "StartTime", "Duration")
+	t.Run("plaintext", func(t *testing.T) {
+		echo, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer echo.Close()
+		go func() {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			io.Copy(c, c)
+		}()
+		proxyService := NewTransparentProxyService(NewTransparentProxyServer(false), nil, DisabledMode, TransparentProxyServiceOpts{SkipLogging: true})
+		client, done := relayThrough(t, &proxyService, echo.Addr().String())
+		if _, err := client.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		client.(*net.TCPConn).CloseWrite()
+		got, err := io.ReadAll(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "hello" {
+			t.Errorf("echo = %q, want %q", got, "hello")
+		}
+		<-done
+		want := []netlog.TCPConnectionLog{{Destination: echo.Addr().String(), BytesSent: 5, BytesReceived: 5}}
+		if diff := cmp.Diff(want, proxyService.networkLog.TCPConnections, ignoreTiming); diff != "" {
+			t.Errorf("TCPConnections mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("tls", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		dst := server.Listener.Addr().String()
+		proxyService := NewTransparentProxyService(NewTransparentProxyServer(false), nil, DisabledMode, TransparentProxyServiceOpts{SkipLogging: true})
+		client, done := relayThrough(t, &proxyService, dst)
+		conn := tls.Client(client, &tls.Config{ServerName: "example.com", InsecureSkipVerify: true})
+		if err := conn.Handshake(); err != nil {
+			t.Fatalf("Handshake() = %v", err)
+		}
+		conn.Close()
+		<-done
+		got := proxyService.networkLog.TCPConnections
+		if len(got) != 1 {
+			t.Fatalf("TCPConnections = %+v, want one entry", got)
+		}
+		if got[0].Destination != dst || got[0].ServerName != "example.com" {
+			t.Errorf("TCPConnections[0] = %+v, want destination %s and server name example.com", got[0], dst)
+		}
+		if got[0].BytesSent == 0 || got[0].BytesReceived == 0 {
+			t.Errorf("TCPConnections[0] = %+v, want non-zero byte counts", got[0])
+		}
+	})
+	t.Run("unreachable destination", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := ln.Addr().String()
+		ln.Close()
+		proxyService := NewTransparentProxyService(NewTransparentProxyServer(false), nil, DisabledMode, TransparentProxyServiceOpts{SkipLogging: true})
+		client, done := relayThrough(t, &proxyService, dst)
+		client.Write([]byte("hello"))
+		<-done
+		if _, err := client.Read(make([]byte, 1)); err == nil {
+			t.Error("Read() succeeded, want closed connection")
+		}
+		want := []netlog.TCPConnectionLog{{Destination: dst}}
+		if diff := cmp.Diff(want, proxyService.networkLog.TCPConnections, ignoreTiming); diff != "" {
+			t.Errorf("TCPConnections mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestRelayTCPPolicy(t *testing.T) {
+	ignoreTiming := cmpopts.IgnoreFields(netlog.TCPConnectionLog{}, "StartTime", "Duration")
+	allowHost := func(host string) *policy.Policy {
+		return &policy.Policy{AnyOf: []policy.Rule{policy.URLMatchRule{Host: host, HostMatch: policy.FullMatch}}}
+	}
+	for _, tc := range []struct {
+		name        string
+		mode        PolicyMode
+		policy      *policy.Policy
+		wantBlocked bool
+	}{
+		{
+			name:   "EnforcementMode relays allowed destination",
+			mode:   EnforcementMode,
+			policy: allowHost("127.0.0.1"),
+		},
+		{
+			name:        "EnforcementMode blocks disallowed destination",
+			mode:        EnforcementMode,
+			policy:      allowHost("example.com"),
+			wantBlocked: true,
+		},
+		{
+			name:   "AuditMode relays disallowed destination",
+			mode:   AuditMode,
+			policy: allowHost("example.com"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			echo, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer echo.Close()
+			go func() {
+				c, err := echo.Accept()
+				if err != nil {
+					return
+				}
+				defer c.Close()
+				io.Copy(c, c)
+			}()
+			dst := echo.Addr().String()
+			proxyService := NewTransparentProxyService(NewTransparentProxyServer(false), nil, tc.mode, TransparentProxyServiceOpts{Policy: tc.policy, SkipLogging: true})
+			client, done := relayThrough(t, &proxyService, dst)
+			client.Write([]byte("hello"))
+			client.(*net.TCPConn).CloseWrite()
+			got, _ := io.ReadAll(client)
+			<-done
+			want := netlog.TCPConnectionLog{Destination: dst, Blocked: true}
+			if !tc.wantBlocked {
+				if string(got) != "hello" {
+					t.Errorf("echo = %q, want %q", got, "hello")
+				}
+				want = netlog.TCPConnectionLog{Destination: dst, BytesSent: 5, BytesReceived: 5}
+			} else if len(got) != 0 {
+				t.Errorf("echo = %q, want closed connection", got)
+			}
+			if diff := cmp.Diff([]netlog.TCPConnectionLog{want}, proxyService.networkLog.TCPConnections, ignoreTiming); diff != "" {
+				t.Errorf("TCPConnections mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+	t.Run("EnforcementMode relays allowed server name", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		proxyService := NewTransparentProxyService(NewTransparentProxyServer(false), nil, EnforcementMode, TransparentProxyServiceOpts{Policy: allowHost("example.com"), SkipLogging: true})
+		client, done := relayThrough(t, &proxyService, server.Listener.Addr().String())
+		conn := tls.Client(client, &tls.Config{ServerName: "example.com", InsecureSkipVerify: true})
+		if err := conn.Handshake(); err != nil {
+			t.Fatalf("Handshake() = %v", err)
+		}
+		conn.Close()
+		<-done
+		if got := proxyService.networkLog.TCPConnections; len(got) != 1 || got[0].Blocked {
+			t.Errorf("TCPConnections = %+v, want one relayed entry", got)
+		}
+	})
+}
@@ -17,13 +17,18 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/elazarl/goproxy"
 	"github.com/google/oss-rebuild/internal/proxy/handshake"
 	"github.com/google/oss-rebuild/pkg/proxy/cert"
+	"github.com/google/oss-rebuild/pkg/proxy/dns"
 	"github.com/google/oss-rebuild/pkg/proxy/netlog"
 	"github.com/google/oss-rebuild/pkg/proxy/policy"
+	"github.com/google/oss-rebuild/pkg/proxy/replay"
+	"golang.org/x/net/http2"
 )
 
 // TLS port to which proxied TLS traffic should be redirected.
@@ -52,6 +57,8 @@ func NewTransparentProxyServer(verbose bool) *goproxy.ProxyHttpServer {
 		// support this set of use-cases.
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
 	}
+	t.OnRequest().DoFunc(useHTTP2Upstream(t))
+	t.OnResponse().DoFunc(relayTrailers)
 
 	t.NonproxyHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		log.Printf("Nonproxy handler: %s", req.Host)
@@ -79,11 +86,13 @@ type PolicyMode string
 const (
 	DisabledMode    PolicyMode = "disabled"
 	EnforcementMode PolicyMode = "enforce"
+	// AuditMode allows all traffic but records requests that violate the policy.
+	AuditMode PolicyMode = "audit"
 )
 
 func (m PolicyMode) IsValid() bool {
 	switch m {
-	case DisabledMode, EnforcementMode:
+	case DisabledMode, EnforcementMode, AuditMode:
 		return true
 	default:
 		return false
@@ -96,16 +105,51 @@ type TransparentProxyService struct {
 	Ca     *tls.Certificate
 	Policy *policy.Policy
 	Mode   PolicyMode
+	Cache  *replay.Cache
+	// HTTP2 is whether clients offering HTTP/2 via ALPN have their streams intercepted.
+	HTTP2 bool
 
 	mx            *sync.Mutex
 	networkLog    *netlog.NetworkActivityLog
+	violations    *[]PolicyViolation
 	shutdownFuncs []func(context.Context) error
 }
 
+// PolicyPhase is the stage of a request at which the policy was evaluated.
+type PolicyPhase string
+
+const (
+	// RequestPhase evaluates the policy against the outgoing request.
+	RequestPhase PolicyPhase = "request"
+	// ResponsePhase evaluates the policy against the request and its response body.
+	ResponsePhase PolicyPhase = "response"
+)
+
+// PolicyViolation is a request that did not satisfy the policy while in AuditMode.
+//
+// A request may violate the policy in both phases, in which case one
+// violation is recorded for each.
+type PolicyViolation struct {
+	// Time is when the violation was observed
+	Time time.Time `json:"time"`
+	// Method is the HTTP method of the request
+	Method string `json:"method"`
+	// URL is the full URL of the request
+	URL string `json:"url"`
+	// Phase is the stage of the request at which the violation was observed
+	Phase PolicyPhase `json:"phase"`
+	policy.Violation
+}
+
 // TransparentProxyServiceOpts defines the optional parameters for creating a TransparentProxyService.
 type TransparentProxyServiceOpts struct {
 	Policy      *policy.Policy
 	SkipLogging bool
+	// Cache, if provided, is exposed on the admin endpoint.
+	Cache *replay.Cache
+	// HTTP2 intercepts the streams of clients offering HTTP/2 via ALPN, such
+	// as gRPC clients. Otherwise, all TLS clients are served HTTP/1.1.
+	HTTP2 bool
 }
 
 // NewTransparentProxyService creates a new TransparentProxyService.
@@ -117,18 +161,27 @@ func NewTransparentProxyService(p *goproxy.ProxyHttpServer, ca *tls.Certificate,
 	if mode != DisabledMode && opts.Policy == nil {
 		log.Fatalf("Invalid policy: %v", opts.Policy)
 	}
-	networkLog := &netlog.NetworkActivityLog{}
-	if !opts.SkipLogging {
-		networkLog = netlog.CaptureActivityLog(p, m)
+	if opts.Policy == nil {
+		opts.Policy = &policy.Policy{}
 	}
-	return TransparentProxyService{
+	t := TransparentProxyService{
 		Proxy:      p,
 		Ca:         ca,
 		Mode:       mode,
 		Policy:     opts.Policy,
+		Cache:      opts.Cache,
+		HTTP2:      opts.HTTP2,
 		mx:         m,
-		networkLog: networkLog,
+		networkLog: &netlog.NetworkActivityLog{},
+		violations: &[]PolicyViolation{},
 	}
+	// NOTE: The response policy precedes the activity log and any cache handlers
+	// so blocked responses are logged with their blocked status and never recorded.
+	p.OnResponse().DoFunc(t.ApplyNetworkPolicyResponse)
+	if !opts.SkipLogging {
+		t.networkLog = netlog.CaptureActivityLog(p, m)
+	}
+	return t
 }
 
 // Shutdown attempts to gracefully shutdown all active servers.
@@ -215,6 +268,11 @@ func (t *TransparentProxyService) ProxyTLS(addr string) {
 				c.Close()
 				return
 			}
+			if t.HTTP2 && slices.Contains(hello.ALPNProtocols, http2.NextProtoTLS) {
+				log.Printf("Connecting to %s over HTTP/2", host)
+				t.serveHTTP2(conn, host)
+				return
+			}
 			log.Printf("Connecting to %s", host)
 			connectReq := &http.Request{
 				Method: "CONNECT",
@@ -232,6 +290,50 @@ func (t *TransparentProxyService) ProxyTLS(addr string) {
 	}
 }
 
+// ProxyDNS serves UDP and TCP endpoints that forward DNS queries to the upstream resolver.
+// In EnforcementMode, queries for hosts to which the policy cannot allow any request are refused.
+func (t *TransparentProxyService) ProxyDNS(addr, upstream string) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		log.Fatalf("Error listening for dns queries - %v", err)
+	}
+	// NOTE: Clients retry queries over TCP when the UDP response is truncated.
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		log.Fatalf("Error listening for dns queries over tcp - %v", err)
+	}
+	t.mx.Lock()
+	t.shutdownFuncs = append(t.shutdownFuncs, func(ctx context.Context) error { return pc.Close() }, func(ctx context.Context) error { return l.Close() })
+	t.mx.Unlock()
+	f := &dns.Forwarder{
+		Upstream: upstream,
+		Allow:    t.allowsHost,
+		Log: func(e netlog.DNSQueryLog) {
+			t.mx.Lock()
+			defer t.mx.Unlock()
+			t.networkLog.DNSQueries = append(t.networkLog.DNSQueries, e)
+		},
+	}
+	go func() {
+		if err := f.ServeTCP(l); err != nil {
+			log.Println(err)
+		}
+	}()
+	if err := f.Serve(pc); err != nil {
+		log.Println(err)
+	}
+}
+
+// allowsHost reports whether the policy permits resolving host.
+func (t *TransparentProxyService) allowsHost(host string) bool {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.Mode != EnforcementMode {
+		return true
+	}
+	return t.Policy.AllowsHost(host)
+}
+
 func (t *TransparentProxyService) ServeAdmin(addr string) {
 	pemBytes := cert.ToPEM(t.Ca.Leaf)
 	jksBytes, err := cert.ToJKS(t.Ca.Leaf)
@@ -262,6 +364,8 @@ func (t *TransparentProxyService) ServeAdmin(addr string) {
 		}
 	})
 	mux.HandleFunc("/policy", t.policyHandler)
+	mux.HandleFunc("/violations", t.violationsHandler)
+	mux.HandleFunc("/cache", t.cacheHandler)
 	server := &http.Server{
 		Addr:    addr,
 		Handler: mux,
@@ -301,19 +405,103 @@ func (t *TransparentProxyService) policyHandler(w http.ResponseWriter, r *http.R
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error unmarshaling request body: %v", err), http.StatusBadRequest)
 		}
-		t.Policy = &p
+		// Updated in place so the handlers registered at construction observe the new policy.
+		*t.Policy = p
 	default:
 		log.Printf("Invalid method type received in request: %v", r.Method)
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// cacheHandler handles requests to the /cache endpoint, listing the recorded responses.
+func (t *TransparentProxyService) cacheHandler(w http.ResponseWriter, r *http.Request) {
+	if t.Cache == nil {
+		http.Error(w, "No cache configured", http.StatusNotFound)
+		return
+	}
+	entries, err := t.Cache.Entries()
+	if err != nil {
+		log.Printf("Failed to read cache entries: %v", err)
+		http.Error(w, "Internal Error", http.StatusInternalServerError)
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(entries); err != nil {
+		log.Printf("Failed to marshal cache entries: %v", err)
+		http.Error(w, "Internal Error", http.StatusInternalServerError)
+	}
+}
+
+// violationsHandler handles requests to the /violations endpoint, listing the policy violations observed in AuditMode.
+func (t *TransparentProxyService) violationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t.violations); err != nil {
+		log.Printf("Failed to marshal violations: %v", err)
+		http.Error(w, "Internal Error", http.StatusInternalServerError)
+	}
+}
+
+// Violations returns the policy violations observed in AuditMode.
+func (t *TransparentProxyService) Violations() []PolicyViolation {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return slices.Clone(*t.violations)
+}
+
+func (proxy TransparentProxyService) recordViolation(req *http.Request, phase PolicyPhase, v *policy.Violation) {
+	log.Printf("Request to %s violates network policy in %s phase (audit)", req.URL.String(), phase)
+	proxy.mx.Lock()
+	defer proxy.mx.Unlock()
+	*proxy.violations = append(*proxy.violations, PolicyViolation{
+		Time:      time.Now().UTC(),
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Phase:     phase,
+		Violation: *v,
+	})
+}
+
 // Check that the requested url is allowed by the network policy.
 func (proxy TransparentProxyService) ApplyNetworkPolicy(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
-	if proxy.Mode == DisabledMode {
+	switch proxy.Mode {
+	case DisabledMode:
+		return req, nil
+	case AuditMode:
+		if v := proxy.Policy.Evaluate(req); v != nil {
+			proxy.recordViolation(req, RequestPhase, v)
+		}
 		return req, nil
+	default:
+		return proxy.Policy.Apply(req, ctx)
+	}
+}
+
+// Check that the response body is allowed by the network policy.
+func (proxy TransparentProxyService) ApplyNetworkPolicyResponse(resp *http.Response, ctx *goproxy.ProxyCtx) *http.Response {
+	switch proxy.Mode {
+	case DisabledMode:
+		return resp
+	case AuditMode:
+		resp, v, err := proxy.Policy.EvaluateResponse(resp, ctx)
+		if err != nil {
+			log.Printf("Unable to read response body from %s: %v", ctx.Req.URL.String(), err)
+			return goproxy.NewResponse(ctx.Req, goproxy.ContentTypeText, http.StatusBadGateway, "Unable to read upstream response")
+		}
+		if v != nil {
+			proxy.recordViolation(ctx.Req, ResponsePhase, v)
+		}
+		return resp
+	default:
+		return proxy.Policy.ApplyResponse(resp, ctx)
 	}
-	return proxy.Policy.Apply(req, ctx)
 }
 
 // eatConnectResponseWriter drops the goproxy response to the HTTP CONNECT tunnel creation.
@@ -5,12 +5,24 @@ package proxy
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/elazarl/goproxy"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/pkg/proxy/policy"
+	"github.com/google/oss-rebuild/pkg/proxy/replay"
 )
 
 func TestApplyNetworkPolicy(t *testing.T) {
@@ -254,3 +266,194 @@ func TestPolicyEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestAuditMode(t *testing.T) {
+	pl := policy.Policy{
+		AllOf: []policy.Rule{policy.MethodRule{Methods: []string{http.MethodGet}}},
+		AnyOf: []policy.Rule{
+			policy.URLMatchRule{
+				Host:      "host.com",
+				HostMatch: policy.FullMatch,
+				Path:      "/path",
+				PathMatch: policy.PrefixMatch,
+			},
+		},
+	}
+	policy.RegisterBuiltinRules()
+	proxyService := NewTransparentProxyService(NewTransparentProxyServer(false), nil, AuditMode, TransparentProxyServiceOpts{
+		Policy:      &pl,
+		SkipLogging: true,
+	})
+	for _, tc := range []struct{ method, url string }{
+		{http.MethodGet, "https://host.com/path/with/compliance"},
+		{http.MethodGet, "https://host.com/non/compliant/path"},
+		{http.MethodPost, "https://host.com/path"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.url, nil)
+		if _, resp := proxyService.ApplyNetworkPolicy(req, nil); resp != nil {
+			t.Errorf("ApplyNetworkPolicy(%s %s) returned response code %v in AuditMode", tc.method, tc.url, resp.StatusCode)
+		}
+	}
+	got := proxyService.Violations()
+	for i := range got {
+		got[i].Time = time.Time{}
+	}
+	want := []PolicyViolation{
+		{Method: http.MethodGet, URL: "https://host.com/non/compliant/path", Phase: RequestPhase, Violation: policy.Violation{Clause: "anyOf"}},
+		{Method: http.MethodPost, URL: "https://host.com/path", Phase: RequestPhase, Violation: policy.Violation{Clause: "allOf", RuleType: "MethodRule", Rule: pl.AllOf[0]}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Violations() mismatch (-want +got):\n%s", diff)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/violations", proxyService.violationsHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/violations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var served []map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&served); err != nil {
+		t.Fatalf("decoding /violations: %v", err)
+	}
+	if len(served) != 2 || served[1]["ruleType"] != "MethodRule" || served[1]["phase"] != "request" {
+		t.Errorf("/violations = %v", served)
+	}
+}
+
+func TestAuditModeResponsePhase(t *testing.T) {
+	pinned := sha256Digest("pinned")
+	pl := policy.Policy{
+		AllOf: []policy.Rule{policy.DigestRule{Digests: []string{pinned}}},
+		AnyOf: []policy.Rule{policy.URLMatchRule{Host: "host.com", HostMatch: policy.FullMatch, PathMatch: policy.PrefixMatch}},
+	}
+	proxyService := NewTransparentProxyService(NewTransparentProxyServer(false), nil, AuditMode, TransparentProxyServiceOpts{
+		Policy:      &pl,
+		SkipLogging: true,
+	})
+	for _, tc := range []struct{ url, body string }{
+		{"https://host.com/pinned", "pinned"},
+		{"https://host.com/tampered", "tampered"},
+		{"https://other.com/tampered", "tampered"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		if _, resp := proxyService.ApplyNetworkPolicy(req, nil); resp != nil {
+			t.Errorf("ApplyNetworkPolicy(%s) returned response code %v in AuditMode", tc.url, resp.StatusCode)
+		}
+		upstream := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(tc.body))}
+		resp := proxyService.ApplyNetworkPolicyResponse(upstream, &goproxy.ProxyCtx{Req: req})
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("ApplyNetworkPolicyResponse(%s) returned response code %v in AuditMode", tc.url, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+	got := proxyService.Violations()
+	for i := range got {
+		got[i].Time = time.Time{}
+	}
+	tampered := sha256Digest("tampered")
+	want := []PolicyViolation{
+		{Method: http.MethodGet, URL: "https://host.com/tampered", Phase: ResponsePhase, Violation: policy.Violation{Clause: "allOf", RuleType: "DigestRule", Rule: pl.AllOf[0], Digest: tampered}},
+		{Method: http.MethodGet, URL: "https://other.com/tampered", Phase: RequestPhase, Violation: policy.Violation{Clause: "anyOf"}},
+		{Method: http.MethodGet, URL: "https://other.com/tampered", Phase: ResponsePhase, Violation: policy.Violation{Clause: "allOf", RuleType: "DigestRule", Rule: pl.AllOf[0], Digest: tampered}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Violations() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEnforceModeWithRecord(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer upstream.Close()
+	sum := sha256.Sum256([]byte("pinned"))
+	pl := policy.Policy{AnyOf: []policy.Rule{policy.AllOfRule{Rules: []policy.Rule{
+		policy.URLMatchRule{Host: "127.0.0.1", HostMatch: policy.FullMatch, PathMatch: policy.PrefixMatch},
+		policy.DigestRule{Digests: []string{"sha256:" + hex.EncodeToString(sum[:])}},
+	}}}}
+	cache := replay.NewCache(memfs.New())
+	p := NewTransparentProxyServer(false)
+	proxyService := NewTransparentProxyService(p, nil, EnforcementMode, TransparentProxyServiceOpts{Policy: &pl, Cache: cache})
+	p.OnRequest().DoFunc(proxyService.ApplyNetworkPolicy)
+	cache.Record(p)
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	proxyURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	for path, wantStatus := range map[string]int{"/pinned": http.StatusOK, "/tampered": http.StatusForbidden} {
+		resp, err := client.Get(upstream.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, wantStatus)
+		}
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	var recorded []string
+	for _, e := range entries {
+		recorded = append(recorded, e.URL)
+	}
+	if diff := cmp.Diff([]string{upstream.URL + "/pinned"}, recorded); diff != "" {
+		t.Errorf("recorded URLs mismatch (-want +got):\n%s", diff)
+	}
+	logged := make(map[string]int)
+	for _, r := range proxyService.networkLog.HTTPRequests {
+		logged[r.Path] = r.StatusCode
+	}
+	if diff := cmp.Diff(map[string]int{"/pinned": http.StatusOK, "/tampered": http.StatusForbidden}, logged); diff != "" {
+		t.Errorf("logged statuses mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEnforceModeBlocksRequestsBeforeUpstream(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		io.WriteString(w, "pinned")
+	}))
+	defer upstream.Close()
+	sum := sha256.Sum256([]byte("pinned"))
+	digest := policy.DigestRule{Digests: []string{"sha256:" + hex.EncodeToString(sum[:])}}
+	// The upstream's host is only reachable via a rule that constrains just the response.
+	pl := policy.Policy{AnyOf: []policy.Rule{
+		digest,
+		policy.AllOfRule{Rules: []policy.Rule{
+			policy.URLMatchRule{Host: "registry.example.com", HostMatch: policy.FullMatch, PathMatch: policy.PrefixMatch},
+			digest,
+		}},
+	}}
+	p := NewTransparentProxyServer(false)
+	proxyService := NewTransparentProxyService(p, nil, EnforcementMode, TransparentProxyServiceOpts{Policy: &pl})
+	p.OnRequest().DoFunc(proxyService.ApplyNetworkPolicy)
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	proxyURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Post(upstream.URL+"/upload", "text/plain", strings.NewReader("secret"))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Errorf("upstream received %d requests, want 0", got)
+	}
+}
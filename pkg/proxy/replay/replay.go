@@ -0,0 +1,375 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package replay provides a content-addressed cache of proxied HTTP exchanges
+// that can be recorded from live traffic and later served without network access.
+package replay
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/elazarl/goproxy"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/oss-rebuild/pkg/proxy/policy"
+	"github.com/pkg/errors"
+)
+
+// Mode describes how the proxy uses the cache.
+type Mode string
+
+const (
+	// DisabledMode neither records nor replays traffic.
+	DisabledMode Mode = "disabled"
+	// RecordMode forwards traffic upstream and records each response in the cache.
+	RecordMode Mode = "record"
+	// ReplayMode serves traffic exclusively from the cache and fails closed on misses.
+	ReplayMode Mode = "replay"
+)
+
+func (m Mode) IsValid() bool {
+	switch m {
+	case DisabledMode, RecordMode, ReplayMode:
+		return true
+	default:
+		return false
+	}
+}
+
+// ReplayMissHeader is set on the responses generated for requests absent from the cache.
+const ReplayMissHeader = "X-Proxy-Replay-Miss"
+
+// Entry is the recorded response to a single request.
+type Entry struct {
+	// Method is the HTTP method of the request
+	Method string `json:"method"`
+	// URL is the full URL of the request
+	URL string `json:"url"`
+	// RequestDigest is the digest of the request body, if one was sent
+	RequestDigest string `json:"requestDigest,omitempty"`
+	// Accept is the Accept header of the request, if one was sent
+	Accept string `json:"accept,omitempty"`
+	// StatusCode is the status code of the response
+	StatusCode int `json:"statusCode"`
+	// Header is the header of the response
+	Header http.Header `json:"header"`
+	// Digest is the sha256 digest of the response body in the form sha256:<hex>
+	Digest string `json:"digest"`
+	// Size is the length of the response body
+	Size int64 `json:"size"`
+}
+
+func (e Entry) key() string {
+	return requestKey(e.Method, e.URL, e.RequestDigest, e.Accept)
+}
+
+// requestKey identifies a request by its method, URL, body and Accept header.
+//
+// NOTE: Accept is included since registries commonly serve different
+// representations of a URL by it e.g. npm's abbreviated and full packuments.
+func requestKey(method, url, requestDigest, accept string) string {
+	k := method + " " + url + " " + requestDigest
+	if accept != "" {
+		k += " " + accept
+	}
+	h := sha256.Sum256([]byte(k))
+	return hex.EncodeToString(h[:])
+}
+
+// Cache is a content-addressed store of HTTP responses.
+//
+// Response bodies are stored once per digest under blobs/sha256/ and the
+// metadata for each request under entries/, keyed by a digest of the request.
+type Cache struct {
+	fs billy.Filesystem
+	mu sync.Mutex
+}
+
+// NewCache creates a Cache stored in fs.
+func NewCache(fs billy.Filesystem) *Cache {
+	return &Cache{fs: fs}
+}
+
+func blobPath(digest string) string {
+	return path.Join("blobs", "sha256", digest[len("sha256:"):])
+}
+
+func entryPath(key string) string {
+	return path.Join("entries", key+".json")
+}
+
+// Lookup returns the entry for the request, if one was recorded.
+func (c *Cache) Lookup(method, url, requestDigest, accept string) (*Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := util.ReadFile(c.fs, entryPath(requestKey(method, url, requestDigest, accept)))
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, errors.Wrap(err, "decoding entry")
+	}
+	return &e, nil
+}
+
+// Open returns the body of the recorded entry.
+func (c *Cache) Open(e *Entry) (io.ReadCloser, error) {
+	return c.fs.Open(blobPath(e.Digest))
+}
+
+// Entries returns all recorded entries sorted by URL and method.
+func (c *Cache) Entries() ([]Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	infos, err := c.fs.ReadDir("entries")
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Entry{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	for _, info := range infos {
+		b, err := util.ReadFile(c.fs, path.Join("entries", info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, errors.Wrapf(err, "decoding entry %s", info.Name())
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].Method < entries[j].Method
+	})
+	return entries, nil
+}
+
+// commit moves the recorded body into place and writes the entry.
+func (c *Cache) commit(tmp string, e Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.fs.MkdirAll(path.Dir(blobPath(e.Digest)), 0755); err != nil {
+		return err
+	}
+	if _, err := c.fs.Stat(blobPath(e.Digest)); errors.Is(err, fs.ErrNotExist) {
+		if err := c.fs.Rename(tmp, blobPath(e.Digest)); err != nil {
+			return errors.Wrap(err, "storing body")
+		}
+	} else {
+		c.fs.Remove(tmp)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "encoding entry")
+	}
+	return util.WriteFile(c.fs, entryPath(e.key()), b, 0644)
+}
+
+// requestState is the per-request data shared between request and response handlers.
+type requestState struct {
+	url           string
+	requestDigest string
+	accept        string
+}
+
+// prepareRequest computes the cache key components for req, preserving its body.
+func prepareRequest(req *http.Request) (*requestState, error) {
+	u := *req.URL
+	// Schema-less requests will be raw HTTP requests with relative URLs.
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		u.Host = req.Host
+	}
+	// Omit standard port numbers so keys match regardless of how the client addressed the host.
+	if host, port, err := net.SplitHostPort(u.Host); err == nil && ((port == "80" && u.Scheme == "http") || (port == "443" && u.Scheme == "https")) {
+		u.Host = host
+	}
+	s := &requestState{url: u.String(), accept: req.Header.Get("Accept")}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "reading request body")
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if len(body) > 0 {
+			sum := sha256.Sum256(body)
+			s.requestDigest = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return s, nil
+}
+
+// Record registers handlers on p that store each upstream response in the cache.
+//
+// Bodies are recorded as they are streamed to the client and only committed
+// once fully read, so aborted transfers are not cached.
+func (c *Cache) Record(p *goproxy.ProxyHttpServer) {
+	p.OnRequest().DoFunc(func(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
+		s, err := prepareRequest(req)
+		if err != nil {
+			log.Printf("replay: not recording %s: %v", req.URL, err)
+			return req, nil
+		}
+		ctx.UserData = s
+		return req, nil
+	})
+	p.OnResponse().DoFunc(func(resp *http.Response, ctx *goproxy.ProxyCtx) *http.Response {
+		s, ok := ctx.UserData.(*requestState)
+		if resp == nil || !ok {
+			return resp
+		}
+		// Responses blocked by the network policy never came from upstream.
+		if resp.Header.Get(policy.BlockedHeader) != "" {
+			return resp
+		}
+		if err := c.fs.MkdirAll("tmp", 0755); err != nil {
+			log.Printf("replay: not recording %s: %v", s.url, err)
+			return resp
+		}
+		tmpName := path.Join("tmp", rand.Text())
+		tmp, err := c.fs.Create(tmpName)
+		if err != nil {
+			log.Printf("replay: not recording %s: %v", s.url, err)
+			return resp
+		}
+		rb := &recordingBody{
+			ReadCloser: resp.Body,
+			cache:      c,
+			tmp:        tmp,
+			tmpName:    tmpName,
+			hash:       sha256.New(),
+			entry: Entry{
+				Method:        ctx.Req.Method,
+				URL:           s.url,
+				RequestDigest: s.requestDigest,
+				Accept:        s.accept,
+				StatusCode:    resp.StatusCode,
+				Header:        resp.Header.Clone(),
+			},
+		}
+		// Bodyless responses may never be read so are committed immediately.
+		if resp.ContentLength == 0 || ctx.Req.Method == http.MethodHead {
+			rb.done = true
+			rb.finish()
+		}
+		resp.Body = rb
+		return resp
+	})
+}
+
+// Replay registers a handler on p that serves all requests from the cache.
+//
+// Requests without a recorded response receive a 502 rather than being forwarded.
+func (c *Cache) Replay(p *goproxy.ProxyHttpServer) {
+	p.OnRequest().DoFunc(func(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
+		s, err := prepareRequest(req)
+		if err != nil {
+			return req, missResponse(req, err.Error())
+		}
+		e, err := c.Lookup(req.Method, s.url, s.requestDigest, s.accept)
+		if errors.Is(err, fs.ErrNotExist) {
+			log.Printf("replay: miss for %s %s", req.Method, s.url)
+			return req, missResponse(req, "no recorded response for "+req.Method+" "+s.url)
+		} else if err != nil {
+			log.Printf("replay: error for %s %s: %v", req.Method, s.url, err)
+			return req, missResponse(req, err.Error())
+		}
+		body, err := c.Open(e)
+		if err != nil {
+			log.Printf("replay: missing body for %s %s: %v", req.Method, s.url, err)
+			return req, missResponse(req, "recorded body unavailable")
+		}
+		return req, &http.Response{
+			Request:       req,
+			StatusCode:    e.StatusCode,
+			Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        e.Header.Clone(),
+			Body:          body,
+			ContentLength: e.Size,
+		}
+	})
+}
+
+func missResponse(req *http.Request, msg string) *http.Response {
+	resp := goproxy.NewResponse(req, goproxy.ContentTypeText, http.StatusBadGateway, "proxy replay: "+msg+"\n")
+	resp.Header.Set(ReplayMissHeader, "true")
+	return resp
+}
+
+// recordingBody tees a response body into the cache.
+type recordingBody struct {
+	io.ReadCloser
+	cache   *Cache
+	tmp     billy.File
+	tmpName string
+	hash    hash.Hash
+	entry   Entry
+	done    bool
+	err     error
+}
+
+func (r *recordingBody) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 && r.err == nil {
+		r.hash.Write(p[:n])
+		r.entry.Size += int64(n)
+		if _, werr := r.tmp.Write(p[:n]); werr != nil {
+			r.err = werr
+		}
+	}
+	if err == io.EOF && !r.done {
+		r.done = true
+		r.finish()
+	}
+	return n, err
+}
+
+func (r *recordingBody) finish() {
+	name := r.tmpName
+	if err := r.tmp.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.err != nil {
+		log.Printf("replay: not recording %s: %v", r.entry.URL, r.err)
+		r.cache.fs.Remove(name)
+		return
+	}
+	r.entry.Digest = "sha256:" + hex.EncodeToString(r.hash.Sum(nil))
+	if err := r.cache.commit(name, r.entry); err != nil {
+		log.Printf("replay: not recording %s: %v", r.entry.URL, err)
+		r.cache.fs.Remove(name)
+	}
+}
+
+func (r *recordingBody) Close() error {
+	if !r.done {
+		// The body was not fully read so the recording is incomplete.
+		r.done = true
+		r.tmp.Close()
+		r.cache.fs.Remove(r.tmpName)
+	}
+	return r.ReadCloser.Close()
+}
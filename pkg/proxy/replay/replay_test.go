@@ -0,0 +1,215 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package replay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/elazarl/goproxy"
+	"github.com/go-git/go-billy/v5/memfs"
+)
+
+func proxiedClient(t *testing.T, p *goproxy.ProxyHttpServer) *http.Client {
+	t.Helper()
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(u)}}
+}
+
+func get(t *testing.T, c *http.Client, method, u, body string) (*http.Response, string) {
+	t.Helper()
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, u, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestRecordReplay(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Upstream", "true")
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+string(b))
+		}
+	}))
+	cache := NewCache(memfs.New())
+	{
+		p := goproxy.NewProxyHttpServer()
+		cache.Record(p)
+		c := proxiedClient(t, p)
+		for _, tc := range []struct{ method, path, body string }{
+			{http.MethodGet, "/a?x=1", ""},
+			{http.MethodGet, "/missing", ""},
+			{http.MethodPost, "/a?x=1", "payload"},
+			{http.MethodHead, "/a?x=1", ""},
+		} {
+			get(t, c, tc.method, upstream.URL+tc.path, tc.body)
+		}
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Entries() = %d entries, want 4: %+v", len(entries), entries)
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Digest, "sha256:") {
+			t.Errorf("entry %s %s has digest %q", e.Method, e.URL, e.Digest)
+		}
+	}
+	// Replay without access to upstream.
+	upstream.Close()
+	p := goproxy.NewProxyHttpServer()
+	cache.Replay(p)
+	c := proxiedClient(t, p)
+	for _, tc := range []struct {
+		name, method, path, body string
+		wantCode                 int
+		wantBody                 string
+	}{
+		{"Hit", http.MethodGet, "/a?x=1", "", http.StatusOK, "GET /a?x=1 "},
+		{"HitWithStatus", http.MethodGet, "/missing", "", http.StatusNotFound, "404 page not found\n"},
+		{"HitWithBody", http.MethodPost, "/a?x=1", "payload", http.StatusOK, "POST /a?x=1 payload"},
+		{"HitHead", http.MethodHead, "/a?x=1", "", http.StatusOK, ""},
+		{"MissOnQuery", http.MethodGet, "/a?x=2", "", http.StatusBadGateway, ""},
+		{"MissOnBody", http.MethodPost, "/a?x=1", "other", http.StatusBadGateway, ""},
+		{"MissOnMethod", http.MethodPut, "/a?x=1", "", http.StatusBadGateway, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := get(t, c, tc.method, upstream.URL+tc.path, tc.body)
+			if resp.StatusCode != tc.wantCode {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tc.wantCode)
+			}
+			if tc.wantCode == http.StatusBadGateway {
+				if resp.Header.Get(ReplayMissHeader) != "true" {
+					t.Errorf("missing %s header", ReplayMissHeader)
+				}
+				return
+			}
+			if body != tc.wantBody {
+				t.Errorf("body = %q, want %q", body, tc.wantBody)
+			}
+			if resp.Header.Get("X-Upstream") != "true" {
+				t.Error("recorded header not replayed")
+			}
+		})
+	}
+}
+
+func TestRecordReplayAccept(t *testing.T) {
+	const abbreviated = "application/vnd.npm.install-v1+json"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == abbreviated {
+			io.WriteString(w, "abbreviated")
+		} else {
+			io.WriteString(w, "full")
+		}
+	}))
+	do := func(c *http.Client, accept string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, upstream.URL+"/some-package", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("GET error = %v", err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(b)
+	}
+	cache := NewCache(memfs.New())
+	{
+		p := goproxy.NewProxyHttpServer()
+		cache.Record(p)
+		c := proxiedClient(t, p)
+		do(c, "")
+		do(c, abbreviated)
+	}
+	upstream.Close()
+	p := goproxy.NewProxyHttpServer()
+	cache.Replay(p)
+	c := proxiedClient(t, p)
+	for _, tc := range []struct {
+		name, accept string
+		wantCode     int
+		wantBody     string
+	}{
+		{"Full", "", http.StatusOK, "full"},
+		{"Abbreviated", abbreviated, http.StatusOK, "abbreviated"},
+		{"MissOnAccept", "application/json", http.StatusBadGateway, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := do(c, tc.accept)
+			if resp.StatusCode != tc.wantCode {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tc.wantCode)
+			}
+			if tc.wantCode == http.StatusOK && body != tc.wantBody {
+				t.Errorf("body = %q, want %q", body, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestRecordIncompleteBody(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("x", 1<<20))
+	}))
+	defer upstream.Close()
+	cache := NewCache(memfs.New())
+	p := goproxy.NewProxyHttpServer()
+	cache.Record(p)
+	c := proxiedClient(t, p)
+	resp, err := c.Get(upstream.URL + "/big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Abandon the body before it is fully read.
+	resp.Body.Close()
+	get(t, c, http.MethodGet, upstream.URL+"/small", "")
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.URL, "/big") && e.Size != 1<<20 {
+			t.Errorf("incomplete body recorded: %+v", e)
+		}
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	reg "github.com/google/oss-rebuild/pkg/registry/cratesio"
 	"github.com/google/oss-rebuild/pkg/registry/cratesio/cargolock"
+	"github.com/google/oss-rebuild/pkg/vcs/gitscan"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/pkg/errors"
 )
@@ -167,6 +168,12 @@ func inferRefAndDir(t rebuild.Target, vmeta *reg.CrateVersion, crateBytes []byte
 		log.Printf("ref heuristic git log not found in repo")
 		fallthrough
 	default:
+		if newRef, newPath, err := findSourceMatch(rcfg.Repository, t, dir, crateBytes); err != nil {
+			log.Printf("source match heuristic failed: %v", err)
+		} else {
+			log.Printf("using source match heuristic ref: %s", newRef[:9])
+			return newRef, filepath.Dir(newPath), nil
+		}
 		if cargoVCSGuess == "" && tagGuess == "" && cargoTOMLGuess == "" {
 			return "", "", errors.Errorf("no git ref")
 		}
@@ -174,6 +181,31 @@ func inferRefAndDir(t rebuild.Target, vmeta *reg.CrateVersion, crateBytes []byte
 	}
 }
 
+// findSourceMatch finds the commit whose tree contains the most of the files in
+// the crate and a Cargo.toml with the expected name and version.
+func findSourceMatch(repo *git.Repository, t rebuild.Target, guess string, crateBytes []byte) (ref, pkgPath string, err error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(crateBytes))
+	if err != nil {
+		return "", "", errors.Wrap(err, "initializing gzip reader")
+	}
+	defer gzr.Close()
+	hashes, err := gitscan.BlobHashesFromTar(tar.NewReader(gzr))
+	if err != nil {
+		return "", "", errors.Wrap(err, "hashing crate contents")
+	}
+	m, err := gitscan.FindSourceMatch(repo, hashes, func(c *object.Commit) error {
+		p, err := findAndValidateCargoTOML(repo, c, t.Package, t.Version, guess)
+		if err == nil {
+			pkgPath = p
+		}
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return m.Commit, pkgPath, nil
+}
+
 func (Rebuilder) InferStrategy(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux, rcfg *rebuild.RepoConfig, hint rebuild.Strategy) (rebuild.Strategy, error) {
 	name, version := t.Package, t.Version
 	vmeta, err := mux.CratesIO.Version(ctx, name, version)
@@ -361,23 +393,24 @@ func findCargoTOML(repo *git.Repository, c *object.Commit, pkg string) (*reg.Car
 	} else {
 		log.Printf("Searching repo after ./Cargo.toml name mismatch")
 	}
-	grs, err := repo.Grep(&git.GrepOptions{
-		CommitHash: c.Hash,
-		PathSpecs:  []*regexp.Regexp{regexp.MustCompile(".*/Cargo.toml$")},
-		Patterns:   []*regexp.Regexp{regexp.MustCompile(fmt.Sprintf(`name\s*=\s*"%s"`, pkg))},
-	})
+	// NOTE: Unlike git.Repository.Grep, only the candidate files are loaded.
+	files, err := gitx.Files(repo, c, regexp.MustCompile(".*/Cargo.toml$").MatchString)
 	if err != nil {
 		return nil, "", err
 	}
+	pattern := regexp.MustCompile(fmt.Sprintf(`name\s*=\s*"%s"`, pkg))
 	var names []string
 	var cargoTOMLs []*reg.CargoTOML
-	for _, gr := range grs {
-		ct, err := getCargoTOML(t, gr.FileName)
+	for _, f := range files {
+		if contents, err := f.Contents(); err != nil || !pattern.MatchString(contents) {
+			continue
+		}
+		ct, err := getCargoTOML(t, f.Name)
 		if err != nil {
 			continue
 		}
 		if pkg == ct.Name {
-			names = append(names, gr.FileName)
+			names = append(names, f.Name)
 			cargoTOMLs = append(cargoTOMLs, &ct)
 		}
 	}
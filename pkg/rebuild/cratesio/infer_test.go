@@ -67,6 +67,46 @@ func TestInferStrategy(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "ref from source match",
+			repo: `commits:
+  - id: initial-commit
+    files:
+      Cargo.toml: |
+        [package]
+        name = "serde"
+        version = "1.0.0"
+      src/lib.rs: "pub fn f() {}"
+  - id: release
+    parent: initial-commit
+    files:
+      Cargo.toml: |
+        [package]
+        name = "serde"
+        version = "1.0.150"
+      src/lib.rs: "pub fn g() {}"
+  - id: docs
+    parent: release
+    files:
+      README.md: "docs"
+`,
+			metadata: `{"version":{"num":"1.0.150","dl_path":"/api/v1/crates/serde/1.0.150/download","rust_version": "1.35.0"}}`,
+			files: []archive.TarEntry{
+				{Header: &tar.Header{Name: "serde-1.0.150/Cargo.toml"}, Body: []byte("# Normalized\n[package]\nname = \"serde\"\nversion = \"1.0.150\"\n")},
+				{Header: &tar.Header{Name: "serde-1.0.150/Cargo.toml.orig"}, Body: []byte("[package]\nname = \"serde\"\nversion = \"1.0.150\"\n")},
+				{Header: &tar.Header{Name: "serde-1.0.150/src/lib.rs"}, Body: []byte("pub fn g() {}")},
+			},
+			wantFn: func(repo *gitxtest.Repository) rebuild.Strategy {
+				return &CratesIOCargoPackage{
+					Location: rebuild.Location{
+						Repo: "https://github.com/serde-rs/serde",
+						Ref:  repo.Commits["release"].String(),
+						Dir:  ".",
+					},
+					RustVersion: "1.35.0",
+				}
+			},
+		},
 		{
 			name: "rust_version from updated_at",
 			repo: `commits:
@@ -13,6 +13,7 @@ import (
 	"math"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -82,12 +83,14 @@ func (Rebuilder) InferStrategy(ctx context.Context, t rebuild.Target, mux rebuil
 		if err != nil {
 			return nil, errors.Wrapf(err, "inferring Maven strategy")
 		}
+		mavenStrategy.(*MavenBuild).RegistryTime = inferRegistryTime(ctx, t, mux)
 		return mavenStrategy, nil
 	case gradleBuildTool:
 		gradleStrategy, err := GradleInfer(ctx, t, mux, repoConfig)
 		if err != nil {
 			return nil, errors.Wrapf(err, "inferring Gradle strategy")
 		}
+		gradleStrategy.(*GradleBuild).RegistryTime = inferRegistryTime(ctx, t, mux)
 		return gradleStrategy, nil
 	case sbtBuildTool, antBuildTool, ivyBuildTool, leiningenBuildTool, npmBuildTool, millBuildTool:
 		return nil, errors.Errorf("build tool %s is recognized but not yet supported", buildTool)
@@ -96,6 +99,21 @@ func (Rebuilder) InferStrategy(ctx context.Context, t rebuild.Target, mux rebuil
 	}
 }
 
+// inferRegistryTime returns the publish time of the target as of which dependencies should be resolved.
+// If it cannot be determined, the zero time is returned and dependencies are resolved at the current time.
+func inferRegistryTime(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux) time.Time {
+	v, err := mux.Maven.PackageVersion(ctx, t.Package, t.Version)
+	if err != nil {
+		log.Printf("publish time not found, registry time not set: %v", err)
+		return time.Time{}
+	}
+	if v.Published.IsZero() {
+		log.Printf("publish time not reported by registry, registry time not set")
+		return time.Time{}
+	}
+	return v.Published.UTC()
+}
+
 // inferBuildTool scans the repository for build tool indicators and returns the most probable build tool.
 // It checks for the presence of "pom.xml" (Maven) and "gradlew" (Gradle) files, prioritizing the tool found in the shallowest directory.
 // The build tool located in the directory closest to the repository root is chosen, as it likely represents the primary build system.
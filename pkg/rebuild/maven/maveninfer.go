@@ -15,6 +15,7 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/oss-rebuild/internal/gitx"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/pkg/errors"
 )
@@ -24,7 +25,7 @@ func MavenInfer(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux,
 	var pomXMLGuess string
 	head, _ := repoConfig.Repository.Head()
 	commitObject, _ := repoConfig.Repository.CommitObject(head.Hash())
-	_, pkgPath, err := findPomXML(commitObject, t.Package)
+	_, pkgPath, err := findPomXML(repoConfig.Repository, commitObject, t.Package)
 	if err != nil {
 		log.Printf("cannot build ref map manifest heuristic: %s", err)
 	} else {
@@ -59,7 +60,7 @@ func MavenInfer(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux,
 		commit, err = repoConfig.Repository.CommitObject(plumbing.NewHash(tagGuess))
 		if err == nil {
 			// First, find the POM file by package name.
-			pomXML, foundPkgPath, err := findPomXML(commit, t.Package)
+			pomXML, foundPkgPath, err := findPomXML(repoConfig.Repository, commit, t.Package)
 			if err != nil {
 				// No POM with package name, continue to next heuristic.
 				log.Printf("tag heuristic failed: could not find a pom.xml for the package")
@@ -85,7 +86,7 @@ func MavenInfer(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux,
 	case pomXMLGuess != "":
 		commit, err = repoConfig.Repository.CommitObject(plumbing.NewHash(pomXMLGuess))
 		if err == nil {
-			pomXML, foundPkgPath, err := findPomXML(commit, t.Package)
+			pomXML, foundPkgPath, err := findPomXML(repoConfig.Repository, commit, t.Package)
 			if err != nil {
 				log.Printf("git log heuristic failed: could not find a pom.xml for the package")
 			} else {
@@ -108,7 +109,7 @@ func MavenInfer(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux,
 	case sourceJarGuess != nil:
 		commit = sourceJarGuess
 		if err == nil {
-			pomXML, foundPkgPath, err := findPomXML(commit, t.Package)
+			pomXML, foundPkgPath, err := findPomXML(repoConfig.Repository, commit, t.Package)
 			if err != nil {
 				log.Printf("source jar heuristic failed: could not find a pom.xml for the package")
 			} else {
@@ -143,28 +144,30 @@ func MavenInfer(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux,
 	}, nil
 }
 
-func findPomXML(commit *object.Commit, pkg string) (*PomXML, string, error) {
+func findPomXML(repo *git.Repository, commit *object.Commit, pkg string) (*PomXML, string, error) {
 	commitTree, _ := commit.Tree()
+	// Per Maven conventions, skip non-"pom.xml" files and those inside a `src` directory (unlikely to contain metadata).
+	// Reference: https://maven.apache.org/guides/introduction/introduction-to-the-standard-directory-layout.html
+	// Note: This will miss pom files, with name other than "pom.xml", whose paths are explicitly passed during build via "-f".
+	files, err := gitx.Files(repo, commit, func(name string) bool {
+		return path.Base(name) == "pom.xml" && !strings.HasPrefix(name, "src/") && !strings.Contains(name, "/src/")
+	})
+	if err != nil {
+		return nil, "", err
+	}
 	var names []string
 	var pomXMLs []PomXML
-	commitTree.Files().ForEach(func(f *object.File) error {
-		// Per Maven conventions, skip non-"pom.xml" files and those inside a `src` directory (unlikely to contain metadata).
-		// Reference: https://maven.apache.org/guides/introduction/introduction-to-the-standard-directory-layout.html
-		// Note: This will miss pom files, with name other than "pom.xml", whose paths are explicitly passed during build via "-f".
-		if path.Base(f.Name) != "pom.xml" || strings.HasPrefix(f.Name, "src/") || strings.Contains(f.Name, "/src/") {
-			return nil
-		}
+	for _, f := range files {
 		pomXML, err := getPomXML(commitTree, f.Name)
 		if err != nil {
 			// XXX: ignore parse errors
-			return nil
+			continue
 		}
 		if pkg == pomXML.Name() {
 			names = append(names, f.Name)
 			pomXMLs = append(pomXMLs, pomXML)
 		}
-		return nil
-	})
+	}
 	if len(names) > 0 {
 		if len(names) > 1 {
 			log.Printf("Multiple pom.xml file candidates [pkg=%s,ref=%s,matches=%v]\n", pkg, commit.Hash.String(), names)
@@ -88,7 +88,7 @@ func TestFindPomXMLHeuristic(t *testing.T) {
 				},
 			})
 			commit, _ := repo.CommitObject(commitHash)
-			_, name, err := findPomXML(commit, "group:artifact")
+			_, name, err := findPomXML(repo, commit, "group:artifact")
 			if err != nil {
 				t.Fatalf("findPomXML() error = %v", err)
 			}
@@ -17,7 +17,14 @@ type Rebuilder struct{}
 var _ rebuild.Rebuilder = Rebuilder{}
 
 func (r Rebuilder) UsesTimewarp(input rebuild.Input) bool {
-	return false
+	switch s := input.Strategy.(type) {
+	case *MavenBuild:
+		return !s.RegistryTime.IsZero()
+	case *GradleBuild:
+		return !s.RegistryTime.IsZero()
+	default:
+		return false
+	}
 }
 
 func (Rebuilder) Rebuild(ctx context.Context, t rebuild.Target, inst rebuild.Instructions, fs billy.Filesystem) error {
@@ -6,6 +6,7 @@ package maven
 import (
 	"path"
 	"strings"
+	"time"
 
 	"github.com/google/oss-rebuild/internal/textwrap"
 	"github.com/google/oss-rebuild/pkg/rebuild/flow"
@@ -18,10 +19,20 @@ type MavenBuild struct {
 
 	// JDKVersion is the version of the JDK to use for the build.
 	JDKVersion string `json:"jdk_version" yaml:"jdk_version"`
+	// RegistryTime, if set, is the time as of which Maven Central is resolved.
+	RegistryTime time.Time `json:"registry_time,omitzero" yaml:"registry_time,omitempty"`
 }
 
 var _ rebuild.Strategy = &MavenBuild{}
 
+// registryTimeString returns the RFC 3339 representation of t or, if unset, the empty string.
+func registryTimeString(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 func (b *MavenBuild) ToWorkflow() (*rebuild.WorkflowStrategy, error) {
 	jdkVersionURL, exists := JDKDownloadURLs[b.JDKVersion]
 	if !exists {
@@ -44,6 +55,14 @@ func (b *MavenBuild) ToWorkflow() (*rebuild.WorkflowStrategy, error) {
 			Uses: "maven/regen-cacerts",
 		})
 	}
+	if !b.RegistryTime.IsZero() {
+		deps = append(deps, flow.Step{
+			Uses: "maven/setup-registry",
+			With: map[string]string{
+				"registryTime": registryTimeString(b.RegistryTime),
+			},
+		})
+	}
 	return &rebuild.WorkflowStrategy{
 		Location: b.Location,
 		Source: []flow.Step{{
@@ -103,6 +122,14 @@ func (b *GradleBuild) ToWorkflow() (*rebuild.WorkflowStrategy, error) {
 			Uses: "maven/regen-cacerts",
 		})
 	}
+	if !b.RegistryTime.IsZero() {
+		deps = append(deps, flow.Step{
+			Uses: "maven/setup-gradle-registry",
+			With: map[string]string{
+				"registryTime": registryTimeString(b.RegistryTime),
+			},
+		})
+	}
 	build := []flow.Step{{
 		Uses: "maven/export-java",
 	}}
@@ -135,6 +162,8 @@ type GradleBuild struct {
 	JDKVersion string `json:"jdk_version" yaml:"jdk_version"`
 	// SystemGradle indicates the version of Gradle to install instead of using the Gradle wrapper (gradlew).
 	SystemGradle string `json:"system_gradle" yaml:"system_gradle"`
+	// RegistryTime, if set, is the time as of which Maven Central is resolved.
+	RegistryTime time.Time `json:"registry_time,omitzero" yaml:"registry_time,omitempty"`
 }
 
 var _ rebuild.Strategy = &GradleBuild{}
@@ -209,6 +238,72 @@ var toolkit = []*flow.Tool{
 			},
 		},
 	},
+	{
+		// NOTE: Maven doesn't support credentials in the mirror URL so the
+		// timewarp parameters are provided as those of the mirror's server.
+		Name: "maven/setup-registry",
+		Steps: []flow.Step{
+			{
+				Runs: textwrap.Dedent(`
+					mkdir -p $HOME/.m2
+					cat <<'EOF' > $HOME/.m2/settings.xml
+					<settings>
+					  <mirrors>
+					    <mirror>
+					      <id>timewarp</id>
+					      <mirrorOf>central</mirrorOf>
+					      <url>http://{{.BuildEnv.TimewarpHost}}/</url>
+					    </mirror>
+					  </mirrors>
+					  <servers>
+					    <server>
+					      <id>timewarp</id>
+					      <username>maven</username>
+					      <password>{{.With.registryTime}}</password>
+					    </server>
+					  </servers>
+					</settings>
+					EOF`[1:]),
+			},
+		},
+	},
+	{
+		// NOTE: Gradle has no equivalent to Maven's mirrors so an init script
+		// redirects each Maven Central repository to timewarp.
+		Name: "maven/setup-gradle-registry",
+		Steps: []flow.Step{
+			{
+				Runs: textwrap.Dedent(`
+					mkdir -p $HOME/.gradle/init.d
+					cat <<'EOF' > $HOME/.gradle/init.d/timewarp.gradle
+					def timewarp = { repos ->
+					  repos.withType(MavenArtifactRepository).all { repo ->
+					    if (repo.url.host in ['repo.maven.apache.org', 'repo1.maven.org']) {
+					      repo.url = 'http://{{.BuildEnv.TimewarpHost}}/'
+					      if (repo.hasProperty('allowInsecureProtocol')) {
+					        repo.allowInsecureProtocol = true
+					      }
+					      repo.credentials {
+					        username = 'maven'
+					        password = '{{.With.registryTime}}'
+					      }
+					    }
+					  }
+					}
+					settingsEvaluated { settings ->
+					  timewarp(settings.pluginManagement.repositories)
+					  if (settings.hasProperty('dependencyResolutionManagement')) {
+					    timewarp(settings.dependencyResolutionManagement.repositories)
+					  }
+					}
+					allprojects {
+					  timewarp(buildscript.repositories)
+					  timewarp(repositories)
+					}
+					EOF`[1:]),
+			},
+		},
+	},
 	{
 		Name: "maven/regen-cacerts",
 		Steps: []flow.Step{
@@ -5,6 +5,7 @@ package maven
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/internal/textwrap"
@@ -194,6 +195,110 @@ func TestStrategies(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "maven build instructions with registry time",
+			strategy: &MavenBuild{
+				Location: rebuild.Location{
+					Repo: "https://foo.bar",
+					Ref:  "ref",
+					Dir:  "dir",
+				},
+				JDKVersion:   "11.0.1",
+				RegistryTime: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: rebuild.Instructions{
+				Location: rebuild.Location{
+					Repo: "https://foo.bar",
+					Ref:  "ref",
+					Dir:  "dir",
+				},
+				SystemDeps: []string{"git", "wget", "maven"},
+				Source:     "git clone https://foo.bar .\ngit checkout --force 'ref'",
+				Deps: textwrap.Dedent(`
+					mkdir -p /opt/jdk
+					wget -q -O - "https://download.java.net/java/GA/jdk11/13/GPL/openjdk-11.0.1_linux-x64_bin.tar.gz" | tar -xzf - --strip-components=1 -C /opt/jdk
+					mkdir -p $HOME/.m2
+					cat <<'EOF' > $HOME/.m2/settings.xml
+					<settings>
+					  <mirrors>
+					    <mirror>
+					      <id>timewarp</id>
+					      <mirrorOf>central</mirrorOf>
+					      <url>http://localhost:8081/</url>
+					    </mirror>
+					  </mirrors>
+					  <servers>
+					    <server>
+					      <id>timewarp</id>
+					      <username>maven</username>
+					      <password>2022-01-01T00:00:00Z</password>
+					    </server>
+					  </servers>
+					</settings>
+					EOF`)[1:],
+				Build: textwrap.Dedent(`
+					export JAVA_HOME=/opt/jdk
+					export PATH=$JAVA_HOME/bin:$PATH
+					mvn clean package -DskipTests --batch-mode -f dir -Dmaven.javadoc.skip=true`[1:]),
+				OutputPath: "dir/target/ldapchai-0.8.6.jar",
+			},
+		},
+		{
+			name: "gradle build instructions with registry time",
+			strategy: &GradleBuild{
+				Location: rebuild.Location{
+					Repo: "https://foo.bar",
+					Ref:  "ref",
+					Dir:  "dir",
+				},
+				JDKVersion:   "11.0.1",
+				RegistryTime: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			want: rebuild.Instructions{
+				Location: rebuild.Location{
+					Repo: "https://foo.bar",
+					Ref:  "ref",
+					Dir:  "dir",
+				},
+				SystemDeps: []string{"git", "wget"},
+				Source:     "git clone https://foo.bar .\ngit checkout --force 'ref'",
+				Deps: textwrap.Dedent(`
+					mkdir -p /opt/jdk
+					wget -q -O - "https://download.java.net/java/GA/jdk11/13/GPL/openjdk-11.0.1_linux-x64_bin.tar.gz" | tar -xzf - --strip-components=1 -C /opt/jdk
+					mkdir -p $HOME/.gradle/init.d
+					cat <<'EOF' > $HOME/.gradle/init.d/timewarp.gradle
+					def timewarp = { repos ->
+					  repos.withType(MavenArtifactRepository).all { repo ->
+					    if (repo.url.host in ['repo.maven.apache.org', 'repo1.maven.org']) {
+					      repo.url = 'http://localhost:8081/'
+					      if (repo.hasProperty('allowInsecureProtocol')) {
+					        repo.allowInsecureProtocol = true
+					      }
+					      repo.credentials {
+					        username = 'maven'
+					        password = '2022-01-01T00:00:00Z'
+					      }
+					    }
+					  }
+					}
+					settingsEvaluated { settings ->
+					  timewarp(settings.pluginManagement.repositories)
+					  if (settings.hasProperty('dependencyResolutionManagement')) {
+					    timewarp(settings.dependencyResolutionManagement.repositories)
+					  }
+					}
+					allprojects {
+					  timewarp(buildscript.repositories)
+					  timewarp(repositories)
+					}
+					EOF`)[1:],
+				Build: textwrap.Dedent(`
+					export JAVA_HOME=/opt/jdk
+					export PATH=$JAVA_HOME/bin:$PATH
+					./gradlew assemble --no-daemon --console=plain -Pversion=0.8.6`[1:]),
+				OutputPath: "dir/build/libs/ldapchai-0.8.6.jar",
+			},
+		},
 		{
 			"throw an error if JDK installation candidate is not found",
 			&MavenBuild{
@@ -210,7 +315,7 @@ func TestStrategies(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			inst, err := tc.strategy.GenerateFor(rebuild.Target{Ecosystem: rebuild.Maven, Package: "com.github.ldapchai:ldapchai", Version: "0.8.6", Artifact: "ldapchai-0.8.6.jar"}, rebuild.BuildEnv{})
+			inst, err := tc.strategy.GenerateFor(rebuild.Target{Ecosystem: rebuild.Maven, Package: "com.github.ldapchai:ldapchai", Version: "0.8.6", Artifact: "ldapchai-0.8.6.jar"}, rebuild.BuildEnv{TimewarpHost: "localhost:8081"})
 			if err != nil && !tc.wantErr {
 				t.Fatalf("%s: Strategy%v.GenerateFor() failed unexpectedly: %v", tc.name, tc.strategy, err)
 			}
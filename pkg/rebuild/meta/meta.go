@@ -4,6 +4,7 @@
 package meta
 
 import (
+	"github.com/go-git/go-billy/v5"
 	"github.com/google/oss-rebuild/internal/httpx"
 	cratesrb "github.com/google/oss-rebuild/pkg/rebuild/cratesio"
 	debianrb "github.com/google/oss-rebuild/pkg/rebuild/debian"
@@ -28,6 +29,43 @@ func NewRegistryMux(c httpx.BasicClient) rebuild.RegistryMux {
 	}
 }
 
+// DirRegistryMux subdirectories for each ecosystem's DirRegistry.
+var dirRegistryRoots = map[rebuild.Ecosystem]string{
+	rebuild.NPM:      "npm",
+	rebuild.PyPI:     "pypi",
+	rebuild.CratesIO: "cratesio",
+	rebuild.Maven:    "maven",
+	rebuild.Debian:   "debian",
+}
+
+// DirRegistryRoot returns the subdirectory of a NewDirRegistryMux filesystem
+// containing the given ecosystem's registry data.
+func DirRegistryRoot(e rebuild.Ecosystem) string {
+	return dirRegistryRoots[e]
+}
+
+// NewDirRegistryMux returns a RegistryMux serving registry data from fs.
+//
+// Each ecosystem's data is read from the subdirectory given by DirRegistryRoot
+// using the layout documented on the corresponding DirRegistry.
+func NewDirRegistryMux(fs billy.Filesystem) (rebuild.RegistryMux, error) {
+	roots := make(map[rebuild.Ecosystem]billy.Filesystem)
+	for e, dir := range dirRegistryRoots {
+		sub, err := fs.Chroot(dir)
+		if err != nil {
+			return rebuild.RegistryMux{}, err
+		}
+		roots[e] = sub
+	}
+	return rebuild.RegistryMux{
+		Debian:   debianreg.DirRegistry{FS: roots[rebuild.Debian]},
+		CratesIO: cratesreg.DirRegistry{FS: roots[rebuild.CratesIO]},
+		NPM:      npmreg.DirRegistry{FS: roots[rebuild.NPM]},
+		PyPI:     pypireg.DirRegistry{FS: roots[rebuild.PyPI]},
+		Maven:    mavenreg.DirRegistry{FS: roots[rebuild.Maven]},
+	}, nil
+}
+
 var AllRebuilders = map[rebuild.Ecosystem]rebuild.Rebuilder{
 	rebuild.NPM:      &npmrb.Rebuilder{},
 	rebuild.PyPI:     &pypirb.Rebuilder{},
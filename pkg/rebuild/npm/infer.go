@@ -4,9 +4,12 @@
 package npm
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"path"
 	"path/filepath"
@@ -22,6 +25,7 @@ import (
 	"github.com/google/oss-rebuild/internal/uri"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	npmreg "github.com/google/oss-rebuild/pkg/registry/npm"
+	"github.com/google/oss-rebuild/pkg/vcs/gitscan"
 	"github.com/pkg/errors"
 )
 
@@ -125,7 +129,12 @@ func PickNPMVersion(meta *npmreg.NPMVersion) (string, error) {
 	return npmv, nil
 }
 
-func InferLocation(t rebuild.Target, vmeta *npmreg.NPMVersion, rcfg *rebuild.RepoConfig) (loc rebuild.Location, versionOverride string, err error) {
+// InferLocation determines the repo location from which the package was built.
+//
+// If no location can be determined from the registry metadata, tags, or the
+// package.json history, the contents of the package tarball are matched against
+// the repo's commits. A nil tarball disables this fallback.
+func InferLocation(t rebuild.Target, vmeta *npmreg.NPMVersion, rcfg *rebuild.RepoConfig, tarball func() (io.ReadCloser, error)) (loc rebuild.Location, versionOverride string, err error) {
 	// Initialize location with repo URI from config
 	loc = rebuild.Location{
 		Repo: rcfg.URI,
@@ -211,6 +220,16 @@ func InferLocation(t rebuild.Target, vmeta *npmreg.NPMVersion, rcfg *rebuild.Rep
 		}
 		fallthrough
 	default:
+		if tarball != nil {
+			if ref, newPath, err := findSourceMatch(rcfg.Repository, t, loc.Dir, tarball); err != nil {
+				log.Printf("source match heuristic failed: %v", err)
+			} else {
+				log.Printf("using source match heuristic ref: %s", ref[:9])
+				loc.Ref = ref
+				loc.Dir = filepath.Dir(newPath)
+				return loc, "", nil
+			}
+		}
 		if badVersionRef != "" {
 			log.Printf("using version override recovery: %s", badVersionRef[:9])
 			c, _ = rcfg.Repository.CommitObject(plumbing.NewHash(badVersionRef))
@@ -225,6 +244,36 @@ func InferLocation(t rebuild.Target, vmeta *npmreg.NPMVersion, rcfg *rebuild.Rep
 	}
 }
 
+// findSourceMatch finds the commit whose tree contains the most of the files in
+// the package tarball and a package.json with the expected name and version.
+func findSourceMatch(repo *git.Repository, t rebuild.Target, guess string, tarball func() (io.ReadCloser, error)) (ref, pkgPath string, err error) {
+	r, err := tarball()
+	if err != nil {
+		return "", "", errors.Wrap(err, "fetching tarball")
+	}
+	defer r.Close()
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return "", "", errors.Wrap(err, "initializing gzip reader")
+	}
+	defer gzr.Close()
+	hashes, err := gitscan.BlobHashesFromTar(tar.NewReader(gzr))
+	if err != nil {
+		return "", "", errors.Wrap(err, "hashing tarball contents")
+	}
+	m, err := gitscan.FindSourceMatch(repo, hashes, func(c *object.Commit) error {
+		p, err := findAndValidatePackageJSON(repo, c, t.Package, t.Version, guess)
+		if err == nil {
+			pkgPath = p
+		}
+		return err
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return m.Commit, pkgPath, nil
+}
+
 func (Rebuilder) InferStrategy(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux, rcfg *rebuild.RepoConfig, hint rebuild.Strategy) (rebuild.Strategy, error) {
 	name, version := t.Package, t.Version
 	vmeta, err := mux.NPM.Version(ctx, name, version)
@@ -245,7 +294,8 @@ func (Rebuilder) InferStrategy(ctx context.Context, t rebuild.Target, mux rebuil
 			loc.Dir = lh.Dir
 		}
 	} else {
-		loc, versionOverride, err = InferLocation(t, vmeta, rcfg)
+		tarball := func() (io.ReadCloser, error) { return mux.NPM.Artifact(ctx, name, version) }
+		loc, versionOverride, err = InferLocation(t, vmeta, rcfg, tarball)
 		if err != nil {
 			return nil, err
 		}
@@ -352,23 +402,24 @@ func findPackageJSON(repo *git.Repository, c *object.Commit, pkg string) (*npmre
 			return &pkgJSON, path, nil
 		}
 	}
-	grs, err := repo.Grep(&git.GrepOptions{
-		CommitHash: c.Hash,
-		PathSpecs:  []*regexp.Regexp{regexp.MustCompile(".*/package.json$")},
-		Patterns:   []*regexp.Regexp{regexp.MustCompile(fmt.Sprintf(`"name":\s*"%s"`, pkg))},
-	})
+	// NOTE: Unlike git.Repository.Grep, only the candidate files are loaded.
+	files, err := gitx.Files(repo, c, regexp.MustCompile(".*/package.json$").MatchString)
 	if err != nil {
 		return nil, "", err
 	}
+	pattern := regexp.MustCompile(fmt.Sprintf(`"name":\s*"%s"`, pkg))
 	var names []string
 	var pkgJSONs []npmreg.PackageJSON
-	for _, gr := range grs {
-		pkgJSON, err := getPackageJSON(t, gr.FileName)
+	for _, f := range files {
+		if contents, err := f.Contents(); err != nil || !pattern.MatchString(contents) {
+			continue
+		}
+		pkgJSON, err := getPackageJSON(t, f.Name)
 		if err != nil {
 			continue
 		}
 		if pkg == pkgJSON.Name {
-			names = append(names, gr.FileName)
+			names = append(names, f.Name)
 			pkgJSONs = append(pkgJSONs, pkgJSON)
 		}
 	}
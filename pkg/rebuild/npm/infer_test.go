@@ -4,7 +4,10 @@
 package npm
 
 import (
+	"archive/tar"
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"strings"
 	"testing"
@@ -13,6 +16,8 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/internal/gitx/gitxtest"
 	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/google/oss-rebuild/pkg/archive"
+	"github.com/google/oss-rebuild/pkg/archive/archivetest"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	reg "github.com/google/oss-rebuild/pkg/registry/npm"
 )
@@ -176,6 +181,7 @@ func TestInferStrategy_NPM(t *testing.T) {
 		repoYAML        string
 		versionMetadata string
 		packageMetadata string
+		tarball         []archive.TarEntry
 		locationHint    *rebuild.LocationHint
 		wantCommitID    string
 		wantStrategyFn  func(commitID string) rebuild.Strategy
@@ -262,6 +268,49 @@ func TestInferStrategy_NPM(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:    "NPMPackBuild - ref from source match",
+			pkg:     "test-package",
+			version: "1.0.0",
+			repoYAML: `commits:
+  - id: initial-commit
+    files:
+      package.json: |
+        {"name": "test-package", "version": "0.9.0"}
+      index.js: "module.exports = 1;"
+  - id: release
+    parent: initial-commit
+    files:
+      package.json: |
+        {"name": "test-package", "version": "1.0.0"}
+      index.js: "module.exports = 2;"
+  - id: docs
+    parent: release
+    files:
+      README.md: "docs"
+  - id: feature
+    parent: docs
+    files:
+      index.js: "module.exports = 3;"
+`,
+			versionMetadata: `{"name":"test-package","version":"1.0.0","_npmVersion":"8.1.2","dist":{"tarball":"https://registry.npmjs.org/test-package/-/test-package-1.0.0.tgz"}}`,
+			tarball: []archive.TarEntry{
+				{Header: &tar.Header{Name: "package/package.json"}, Body: []byte("{\"name\": \"test-package\", \"version\": \"1.0.0\"}\n")},
+				{Header: &tar.Header{Name: "package/index.js"}, Body: []byte("module.exports = 2;")},
+				{Header: &tar.Header{Name: "package/dist/index.min.js"}, Body: []byte("generated")},
+			},
+			wantCommitID: "release",
+			wantStrategyFn: func(commitID string) rebuild.Strategy {
+				return &NPMPackBuild{
+					Location: rebuild.Location{
+						Repo: "https://github.com/test-org/test-package",
+						Ref:  commitID,
+						Dir:  ".",
+					},
+					NPMVersion: "8.1.2",
+				}
+			},
+		},
 		{
 			name:    "NPMCustomBuild - from build script",
 			pkg:     "test-package",
@@ -364,7 +413,10 @@ func TestInferStrategy_NPM(t *testing.T) {
 `,
 			versionMetadata: `{"name":"test-package","version":"1.0.0","_npmVersion":"8.0.0","dist":{"tarball":"url6"},"gitHead":"INSERT_COMMIT_ID"}`,
 			packageMetadata: `{"name":"test-package","time":{"1.0.0":"2023-01-01T12:00:00.000Z"}}`,
-			wantErr:         true,
+			tarball: []archive.TarEntry{
+				{Header: &tar.Header{Name: "package/package.json"}, Body: []byte(`{"name": "test-package", "version": "1.0.0"}`)},
+			},
+			wantErr: true,
 		},
 		{
 			name:    "Error - missing upload time for custom build",
@@ -451,6 +503,25 @@ func TestInferStrategy_NPM(t *testing.T) {
 				},
 				URLValidator: httpxtest.NewURLValidator(t),
 			}
+			if tc.tarball != nil {
+				var vmeta reg.NPMVersion
+				if err := json.Unmarshal([]byte(tc.versionMetadata), &vmeta); err != nil {
+					t.Fatalf("parsing version metadata: %v", err)
+				}
+				client.Calls = append(client.Calls, httpxtest.Call{
+					URL: "https://registry.npmjs.org/" + tc.pkg + "/" + tc.version,
+					Response: &http.Response{
+						StatusCode: 200,
+						Body:       httpxtest.Body(tc.versionMetadata),
+					},
+				}, httpxtest.Call{
+					URL: vmeta.Dist.URL,
+					Response: &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(must(archivetest.TgzFile(tc.tarball))),
+					},
+				})
+			}
 			if tc.packageMetadata != "" {
 				client.Calls = append(client.Calls, httpxtest.Call{
 					URL: "https://registry.npmjs.org/" + tc.pkg,
@@ -4,8 +4,11 @@
 package pypi
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"cmp"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -23,6 +26,7 @@ import (
 	"github.com/google/oss-rebuild/internal/uri"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	pypireg "github.com/google/oss-rebuild/pkg/registry/pypi"
+	"github.com/google/oss-rebuild/pkg/vcs/gitscan"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/pkg/errors"
 )
@@ -154,7 +158,15 @@ func extractPyProjectRequirements(ctx context.Context, tree *object.Tree) ([]str
 	return reqs, nil
 }
 
-func findGitRef(pkg string, version string, rcfg *rebuild.RepoConfig) (string, error) {
+// minSourceMatchFraction is the minimum fraction of the sdist's files that must
+// be present in a commit for the source match heuristic to select it.
+//
+// Since versions derived from git tags are not declared in the tree, a commit
+// may be selected without a version check, so this is set to exclude commits
+// sharing only incidental files e.g. a LICENSE.
+const minSourceMatchFraction = 0.5
+
+func findGitRef(pkg string, version string, rcfg *rebuild.RepoConfig, sdist func() (*pypireg.Artifact, io.ReadCloser, error)) (string, error) {
 	tagHeuristic, err := rebuild.FindTagMatch(pkg, version, rcfg.Repository)
 	log.Printf("Version: %s, tag hash: \"%s\"", version, tagHeuristic)
 	if err != nil {
@@ -162,7 +174,16 @@ func findGitRef(pkg string, version string, rcfg *rebuild.RepoConfig) (string, e
 	}
 	// TODO: Look for the project.toml and check for version number.
 	if tagHeuristic == "" {
-		return "", errors.New("no git ref")
+		if sdist == nil {
+			return "", errors.New("no git ref")
+		}
+		ref, err := findSourceMatch(rcfg.Repository, version, sdist)
+		if err != nil {
+			log.Printf("source match heuristic failed: %v", err)
+			return "", errors.New("no git ref")
+		}
+		log.Printf("using source match heuristic ref: %s", ref[:9])
+		return ref, nil
 	}
 	_, err = rcfg.Repository.CommitObject(plumbing.NewHash(tagHeuristic))
 	if err != nil {
@@ -176,6 +197,147 @@ func findGitRef(pkg string, version string, rcfg *rebuild.RepoConfig) (string, e
 	return tagHeuristic, nil
 }
 
+// findSourceMatch finds the commit whose tree contains the most of the files in
+// the sdist and does not declare a version other than the expected one.
+func findSourceMatch(repo *git.Repository, version string, sdist func() (*pypireg.Artifact, io.ReadCloser, error)) (string, error) {
+	a, r, err := sdist()
+	if err != nil {
+		return "", errors.Wrap(err, "fetching sdist")
+	}
+	defer r.Close()
+	var hashes []plumbing.Hash
+	switch {
+	case strings.HasSuffix(a.Filename, ".tar.gz"):
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return "", errors.Wrap(err, "initializing gzip reader")
+		}
+		defer gzr.Close()
+		hashes, err = gitscan.BlobHashesFromTar(tar.NewReader(gzr))
+		if err != nil {
+			return "", errors.Wrap(err, "hashing sdist contents")
+		}
+	case strings.HasSuffix(a.Filename, ".zip"):
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return "", errors.Wrap(err, "reading sdist")
+		}
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			return "", errors.Wrap(err, "initializing zip reader")
+		}
+		hashes, err = gitscan.BlobHashesFromZip(zr)
+		if err != nil {
+			return "", errors.Wrap(err, "hashing sdist contents")
+		}
+	default:
+		return "", errors.Errorf("unsupported sdist format: %s", a.Filename)
+	}
+	m, err := gitscan.FindSourceMatch(repo, hashes, func(c *object.Commit) error {
+		declared, err := declaredVersion(c)
+		if err != nil {
+			return err
+		}
+		if declared != "" && declared != version {
+			return errors.Errorf("mismatched version [expected=%s,actual=%s]", version, declared)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	// Candidates are ranked by match count so no other can exceed this one.
+	if m.Fraction() < minSourceMatchFraction {
+		return "", errors.Errorf("best match below threshold [matched=%d/%d]", m.Matched, m.Total)
+	}
+	return m.Commit, nil
+}
+
+var (
+	setupCfgSectionRegex = re.MustCompile(`^\[(.*)\]\s*$`)
+	setupCfgVersionRegex = re.MustCompile(`^version\s*[=:]\s*(.*?)\s*$`)
+	pkgInfoVersionRegex  = re.MustCompile(`(?m)^Version:\s*(.*?)\s*$`)
+)
+
+// declaredVersion returns the static version declared at the root of the
+// commit's tree by pyproject.toml, setup.cfg or PKG-INFO.
+//
+// An empty version is returned if none is declared, as is the case for those
+// derived from git tags at build time e.g. by setuptools-scm.
+func declaredVersion(c *object.Commit) (string, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return "", errors.Wrap(err, "reading tree")
+	}
+	contents := func(name string) (string, bool, error) {
+		f, err := tree.File(name)
+		if err == object.ErrFileNotFound {
+			return "", false, nil
+		} else if err != nil {
+			return "", false, errors.Wrapf(err, "finding %s", name)
+		}
+		s, err := f.Contents()
+		if err != nil {
+			return "", false, errors.Wrapf(err, "reading %s", name)
+		}
+		return s, true, nil
+	}
+	if s, ok, err := contents("pyproject.toml"); err != nil {
+		return "", err
+	} else if ok {
+		var pyProject struct {
+			Project struct {
+				Version string `toml:"version"`
+			} `toml:"project"`
+			Tool struct {
+				Poetry struct {
+					Version string `toml:"version"`
+				} `toml:"poetry"`
+			} `toml:"tool"`
+		}
+		if err := toml.Unmarshal([]byte(s), &pyProject); err != nil {
+			return "", errors.Wrap(err, "parsing pyproject.toml")
+		}
+		if v := cmp.Or(pyProject.Project.Version, pyProject.Tool.Poetry.Version); v != "" {
+			return v, nil
+		}
+	}
+	if s, ok, err := contents("setup.cfg"); err != nil {
+		return "", err
+	} else if ok {
+		var section string
+		for _, line := range strings.Split(s, "\n") {
+			if m := setupCfgSectionRegex.FindStringSubmatch(line); m != nil {
+				section = m[1]
+			} else if m := setupCfgVersionRegex.FindStringSubmatch(line); m != nil && section == "metadata" {
+				// Versions read from a file or attribute are not static.
+				if strings.HasPrefix(m[1], "attr:") || strings.HasPrefix(m[1], "file:") {
+					break
+				}
+				return m[1], nil
+			}
+		}
+	}
+	if s, ok, err := contents("PKG-INFO"); err != nil {
+		return "", err
+	} else if ok {
+		if m := pkgInfoVersionRegex.FindStringSubmatch(s); m != nil {
+			return m[1], nil
+		}
+	}
+	return "", nil
+}
+
+// FindSourceDistribution returns the sdist artifact from the given version's releases.
+func FindSourceDistribution(artifacts []pypireg.Artifact) (*pypireg.Artifact, error) {
+	for _, r := range artifacts {
+		if r.PackageType == "sdist" {
+			return &r, nil
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
 // FindPureWheel returns the pure wheel artifact from the given version's releases.
 func FindPureWheel(artifacts []pypireg.Artifact) (*pypireg.Artifact, error) {
 	for _, r := range artifacts {
@@ -247,7 +409,15 @@ func (Rebuilder) InferStrategy(ctx context.Context, t rebuild.Target, mux rebuil
 			dir = rcfg.Dir
 		}
 	} else {
-		ref, err = findGitRef(release.Name, version, rcfg)
+		sdist := func() (*pypireg.Artifact, io.ReadCloser, error) {
+			a, err := FindSourceDistribution(release.Artifacts)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "finding sdist")
+			}
+			r, err := mux.PyPI.Artifact(ctx, name, version, a.Filename)
+			return a, r, err
+		}
+		ref, err = findGitRef(release.Name, version, rcfg, sdist)
 		if err != nil {
 			return cfg, err
 		}
@@ -308,6 +478,7 @@ func (Rebuilder) InferStrategy(ctx context.Context, t rebuild.Target, mux rebuil
 			Ref:  ref,
 		},
 		Requirements: reqs,
+		RegistryTime: a.UploadTime,
 	}, nil
 }
 
@@ -0,0 +1,238 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package pypi
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/oss-rebuild/internal/gitx/gitxtest"
+	"github.com/google/oss-rebuild/pkg/archive"
+	"github.com/google/oss-rebuild/pkg/archive/archivetest"
+	pypireg "github.com/google/oss-rebuild/pkg/registry/pypi"
+)
+
+const sourceMatchRepo = `commits:
+  - id: initial-commit
+    files:
+      pyproject.toml: |
+        [project]
+        name = "absl-py"
+        version = "1.0.0"
+      absl/flags.py: "flags = 1"
+      LICENSE: "license"
+  - id: release
+    parent: initial-commit
+    files:
+      absl/app.py: "app = 1"
+  - id: bump
+    parent: release
+    files:
+      pyproject.toml: |
+        [project]
+        name = "absl-py"
+        version = "2.0.0"
+      absl/logging.py: "logging = 1"
+`
+
+func TestFindSourceMatch(t *testing.T) {
+	files := map[string]string{
+		"pyproject.toml": "[project]\nname = \"absl-py\"\nversion = \"1.0.0\"\n",
+		"absl/flags.py":  "flags = 1",
+		"absl/app.py":    "app = 1",
+		"PKG-INFO":       "Metadata-Version: 2.1\nName: absl-py\nVersion: 1.0.0\n",
+	}
+	for _, tc := range []struct {
+		name     string
+		version  string
+		filename string
+		files    map[string]string
+		wantRef  string
+		wantErr  bool
+	}{
+		{
+			name:     "tar.gz",
+			version:  "1.0.0",
+			filename: "absl-py-1.0.0.tar.gz",
+			files:    files,
+			wantRef:  "release",
+		},
+		{
+			name:     "zip",
+			version:  "1.0.0",
+			filename: "absl-py-1.0.0.zip",
+			files:    files,
+			wantRef:  "release",
+		},
+		{
+			name:     "top candidate with mismatched version skipped",
+			version:  "1.0.0",
+			filename: "absl-py-1.0.0.tar.gz",
+			// The bump commit contains the most of these files but declares 2.0.0.
+			files: map[string]string{
+				"absl/flags.py":   "flags = 1",
+				"absl/app.py":     "app = 1",
+				"absl/logging.py": "logging = 1",
+			},
+			wantRef: "release",
+		},
+		{
+			name:     "below threshold",
+			version:  "1.0.0",
+			filename: "absl-py-1.0.0.tar.gz",
+			files: map[string]string{
+				"LICENSE":        "license",
+				"absl/other.py":  "other = 1",
+				"absl/extra.py":  "extra = 1",
+				"absl/unused.py": "unused = 1",
+			},
+			wantErr: true,
+		},
+		{
+			name:     "no matching files",
+			version:  "1.0.0",
+			filename: "absl-py-1.0.0.tar.gz",
+			files:    map[string]string{"absl/other.py": "other = 1"},
+			wantErr:  true,
+		},
+		{
+			name:     "no candidate with matching version",
+			version:  "3.0.0",
+			filename: "absl-py-3.0.0.tar.gz",
+			files:    files,
+			wantErr:  true,
+		},
+		{
+			name:     "unsupported format",
+			version:  "1.0.0",
+			filename: "absl-py-1.0.0.tar.bz2",
+			files:    files,
+			wantErr:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, err := gitxtest.CreateRepoFromYAML(sourceMatchRepo, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf *bytes.Buffer
+			if strings.HasSuffix(tc.filename, ".zip") {
+				var entries []archive.ZipEntry
+				for name, body := range tc.files {
+					entries = append(entries, archive.ZipEntry{FileHeader: &zip.FileHeader{Name: "absl-py-" + tc.version + "/" + name}, Body: []byte(body)})
+				}
+				buf, err = archivetest.ZipFile(entries)
+			} else {
+				var entries []archive.TarEntry
+				for name, body := range tc.files {
+					entries = append(entries, archive.TarEntry{Header: &tar.Header{Name: "absl-py-" + tc.version + "/" + name, Typeflag: tar.TypeReg, Mode: 0644}, Body: []byte(body)})
+				}
+				buf, err = archivetest.TgzFile(entries)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sdist := func() (*pypireg.Artifact, io.ReadCloser, error) {
+				return &pypireg.Artifact{Filename: tc.filename, PackageType: "sdist"}, io.NopCloser(buf), nil
+			}
+			ref, err := findSourceMatch(repo.Repository, tc.version, sdist)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("findSourceMatch() = %s, want error", ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findSourceMatch() error = %v", err)
+			}
+			if want := repo.Commits[tc.wantRef].String(); ref != want {
+				t.Errorf("findSourceMatch() = %s, want %s (%s)", ref, want, tc.wantRef)
+			}
+		})
+	}
+}
+
+func TestDeclaredVersion(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files string
+		want  string
+	}{
+		{
+			name: "pyproject.toml project",
+			files: `      pyproject.toml: |
+        [project]
+        version = "1.2.3"
+`,
+			want: "1.2.3",
+		},
+		{
+			name: "pyproject.toml poetry",
+			files: `      pyproject.toml: |
+        [tool.poetry]
+        version = "1.2.3"
+`,
+			want: "1.2.3",
+		},
+		{
+			name: "pyproject.toml dynamic falls back to setup.cfg",
+			files: `      pyproject.toml: |
+        [project]
+        dynamic = ["version"]
+      setup.cfg: |
+        [options]
+        version = 9.9.9
+        [metadata]
+        name = absl-py
+        version = 1.2.3
+`,
+			want: "1.2.3",
+		},
+		{
+			name: "setup.cfg attr",
+			files: `      setup.cfg: |
+        [metadata]
+        version = attr: absl.__version__
+`,
+			want: "",
+		},
+		{
+			name: "PKG-INFO",
+			files: `      PKG-INFO: |
+        Metadata-Version: 2.1
+        Name: absl-py
+        Version: 1.2.3
+`,
+			want: "1.2.3",
+		},
+		{
+			name: "none",
+			files: `      setup.py: "setup()"
+`,
+			want: "",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, err := gitxtest.CreateRepoFromYAML("commits:\n  - id: only\n    files:\n"+tc.files, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := repo.CommitObject(repo.Commits["only"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := declaredVersion(c)
+			if err != nil {
+				t.Fatalf("declaredVersion() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("declaredVersion() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -19,4 +19,5 @@ const (
 	GCBCancelDeadlineID
 	GCBWaitDeadlineID
 	CratesRegistryStubID
+	CloneFilterID
 )
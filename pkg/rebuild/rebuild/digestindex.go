@@ -0,0 +1,334 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package rebuild
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	gcs "cloud.google.com/go/storage"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// DigestIndex maps artifact digests to the targets whose attestations describe them.
+type DigestIndex interface {
+	// Add records that the artifact with the given digest is described by t.
+	Add(ctx context.Context, digest string, t Target) error
+	// Remove deletes the record that the artifact with the given digest is described by t.
+	Remove(ctx context.Context, digest string, t Target) error
+	// Lookup returns the targets recorded for the given digest.
+	Lookup(ctx context.Context, digest string) ([]Target, error)
+}
+
+// ErrDigestNotFound indicates that no targets are recorded for a digest.
+var ErrDigestNotFound = errors.New("digest not found")
+
+// digestIndexEntry is the serialized record of the targets for a single digest.
+type digestIndexEntry struct {
+	Targets []Target `json:"targets"`
+}
+
+// add inserts t into the entry and returns whether the entry changed.
+func (e *digestIndexEntry) add(t Target) bool {
+	if slices.Contains(e.Targets, t) {
+		return false
+	}
+	e.Targets = append(e.Targets, t)
+	return true
+}
+
+// remove deletes t from the entry and returns whether the entry changed.
+func (e *digestIndexEntry) remove(t Target) bool {
+	i := slices.Index(e.Targets, t)
+	if i < 0 {
+		return false
+	}
+	e.Targets = slices.Delete(e.Targets, i, i+1)
+	return true
+}
+
+// digestIndexPath returns the index-relative path for a digest of the form "sha256:<hex>".
+func digestIndexPath(digest string) (string, error) {
+	algo, value, ok := strings.Cut(digest, ":")
+	if !ok {
+		return "", errors.Errorf("malformed digest %q: expected <algorithm>:<hex>", digest)
+	}
+	if algo != "sha256" {
+		return "", errors.Errorf("unsupported digest algorithm: %s", algo)
+	}
+	if b, err := hex.DecodeString(value); err != nil || len(b) != 32 {
+		return "", errors.Errorf("malformed sha256 digest: %s", value)
+	}
+	return path.Join("digests", algo, strings.ToLower(value)+".json"), nil
+}
+
+// FilesystemDigestIndex is a DigestIndex stored in a billy.Filesystem.
+type FilesystemDigestIndex struct {
+	fs billy.Filesystem
+	mu sync.Mutex
+}
+
+// NewFilesystemDigestIndex creates a new FilesystemDigestIndex.
+func NewFilesystemDigestIndex(fs billy.Filesystem) *FilesystemDigestIndex {
+	return &FilesystemDigestIndex{fs: fs}
+}
+
+var _ DigestIndex = &FilesystemDigestIndex{}
+
+func (idx *FilesystemDigestIndex) read(p string) (*digestIndexEntry, error) {
+	b, err := util.ReadFile(idx.fs, p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrDigestNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var e digestIndexEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, errors.Wrap(err, "decoding index entry")
+	}
+	return &e, nil
+}
+
+// Add records that the artifact with the given digest is described by t.
+func (idx *FilesystemDigestIndex) Add(ctx context.Context, digest string, t Target) error {
+	p, err := digestIndexPath(digest)
+	if err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	e, err := idx.read(p)
+	if errors.Is(err, ErrDigestNotFound) {
+		e = &digestIndexEntry{}
+	} else if err != nil {
+		return errors.Wrap(err, "reading index entry")
+	}
+	if !e.add(t) {
+		return nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "encoding index entry")
+	}
+	return util.WriteFile(idx.fs, p, b, 0644)
+}
+
+// Remove deletes the record that the artifact with the given digest is described by t.
+//
+// Entries left without targets are deleted.
+func (idx *FilesystemDigestIndex) Remove(ctx context.Context, digest string, t Target) error {
+	p, err := digestIndexPath(digest)
+	if err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	e, err := idx.read(p)
+	if errors.Is(err, ErrDigestNotFound) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "reading index entry")
+	}
+	if !e.remove(t) {
+		return nil
+	}
+	if len(e.Targets) == 0 {
+		return idx.fs.Remove(p)
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "encoding index entry")
+	}
+	return util.WriteFile(idx.fs, p, b, 0644)
+}
+
+// Lookup returns the targets recorded for the given digest.
+func (idx *FilesystemDigestIndex) Lookup(ctx context.Context, digest string) ([]Target, error) {
+	p, err := digestIndexPath(digest)
+	if err != nil {
+		return nil, err
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	e, err := idx.read(p)
+	if err != nil {
+		return nil, err
+	}
+	return e.Targets, nil
+}
+
+// GCSDigestIndex is a DigestIndex stored in GCS.
+//
+// Concurrent updates to an entry are serialized using object generation preconditions.
+type GCSDigestIndex struct {
+	gcsClient *gcs.Client
+	bucket    string
+	prefix    string
+}
+
+// maxIndexUpdateAttempts bounds the retries of an index update that loses a race with a concurrent writer.
+const maxIndexUpdateAttempts = 5
+
+// NewGCSDigestIndexFromClient creates a new GCSDigestIndex rooted at the provided gs:// prefix.
+func NewGCSDigestIndexFromClient(client *gcs.Client, prefix string) *GCSDigestIndex {
+	idx := &GCSDigestIndex{gcsClient: client}
+	idx.bucket, idx.prefix, _ = strings.Cut(strings.TrimPrefix(prefix, "gs://"), "/")
+	return idx
+}
+
+// NewGCSDigestIndex creates a new GCSDigestIndex rooted at the provided gs:// prefix.
+func NewGCSDigestIndex(ctx context.Context, prefix string) (*GCSDigestIndex, error) {
+	var gcsOpts []option.ClientOption
+	if opts, ok := ctx.Value(GCSClientOptionsID).([]option.ClientOption); ok {
+		gcsOpts = append(gcsOpts, opts...)
+	}
+	client, err := gcs.NewClient(ctx, gcsOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating GCS client")
+	}
+	return NewGCSDigestIndexFromClient(client, prefix), nil
+}
+
+var _ DigestIndex = &GCSDigestIndex{}
+
+func (idx *GCSDigestIndex) object(digest string) (*gcs.ObjectHandle, error) {
+	p, err := digestIndexPath(digest)
+	if err != nil {
+		return nil, err
+	}
+	return idx.gcsClient.Bucket(idx.bucket).Object(path.Join(idx.prefix, p)), nil
+}
+
+// read returns the entry and the generation against which it was read.
+func (idx *GCSDigestIndex) read(ctx context.Context, obj *gcs.ObjectHandle) (*digestIndexEntry, int64, error) {
+	r, err := obj.NewReader(ctx)
+	if err == gcs.ErrObjectNotExist {
+		return nil, 0, ErrDigestNotFound
+	} else if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	var e digestIndexEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, 0, errors.Wrap(err, "decoding index entry")
+	}
+	return &e, r.Attrs.Generation, nil
+}
+
+// Add records that the artifact with the given digest is described by t.
+func (idx *GCSDigestIndex) Add(ctx context.Context, digest string, t Target) error {
+	obj, err := idx.object(digest)
+	if err != nil {
+		return err
+	}
+	for range maxIndexUpdateAttempts {
+		e, gen, err := idx.read(ctx, obj)
+		cond := gcs.Conditions{GenerationMatch: gen}
+		if errors.Is(err, ErrDigestNotFound) {
+			e, cond = &digestIndexEntry{}, gcs.Conditions{DoesNotExist: true}
+		} else if err != nil {
+			return errors.Wrap(err, "reading index entry")
+		}
+		if !e.add(t) {
+			return nil
+		}
+		b, err := json.Marshal(e)
+		if err != nil {
+			return errors.Wrap(err, "encoding index entry")
+		}
+		w := obj.If(cond).NewWriter(ctx)
+		w.ContentType = "application/json"
+		if _, err := io.Copy(w, bytes.NewReader(b)); err != nil {
+			w.Close()
+			return errors.Wrap(err, "writing index entry")
+		}
+		err = w.Close()
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			continue
+		} else if err != nil {
+			return errors.Wrap(err, "writing index entry")
+		}
+		return nil
+	}
+	return errors.New("too much contention updating index entry")
+}
+
+// Remove deletes the record that the artifact with the given digest is described by t.
+//
+// Entries left without targets are deleted.
+func (idx *GCSDigestIndex) Remove(ctx context.Context, digest string, t Target) error {
+	obj, err := idx.object(digest)
+	if err != nil {
+		return err
+	}
+	for range maxIndexUpdateAttempts {
+		e, gen, err := idx.read(ctx, obj)
+		if errors.Is(err, ErrDigestNotFound) {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "reading index entry")
+		}
+		if !e.remove(t) {
+			return nil
+		}
+		cond := obj.If(gcs.Conditions{GenerationMatch: gen})
+		if len(e.Targets) == 0 {
+			err = cond.Delete(ctx)
+			if err == gcs.ErrObjectNotExist {
+				return nil
+			}
+		} else {
+			var b []byte
+			if b, err = json.Marshal(e); err != nil {
+				return errors.Wrap(err, "encoding index entry")
+			}
+			w := cond.NewWriter(ctx)
+			w.ContentType = "application/json"
+			if _, err := io.Copy(w, bytes.NewReader(b)); err != nil {
+				w.Close()
+				return errors.Wrap(err, "writing index entry")
+			}
+			err = w.Close()
+		}
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			continue
+		} else if err != nil {
+			return errors.Wrap(err, "updating index entry")
+		}
+		return nil
+	}
+	return errors.New("too much contention updating index entry")
+}
+
+// Lookup returns the targets recorded for the given digest.
+func (idx *GCSDigestIndex) Lookup(ctx context.Context, digest string) ([]Target, error) {
+	obj, err := idx.object(digest)
+	if err != nil {
+		return nil, err
+	}
+	e, _, err := idx.read(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return e.Targets, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package rebuild
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilesystemDigestIndex(t *testing.T) {
+	ctx := context.Background()
+	idx := NewFilesystemDigestIndex(memfs.New())
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	t1 := Target{Ecosystem: NPM, Package: "pkg", Version: "1.0.0", Artifact: "pkg-1.0.0.tgz"}
+	t2 := Target{Ecosystem: NPM, Package: "@scope/pkg", Version: "1.0.0", Artifact: "pkg-1.0.0.tgz"}
+	if _, err := idx.Lookup(ctx, digest); !errors.Is(err, ErrDigestNotFound) {
+		t.Fatalf("Lookup() on empty index error = %v, want ErrDigestNotFound", err)
+	}
+	for _, tgt := range []Target{t1, t2, t1} {
+		if err := idx.Add(ctx, digest, tgt); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	got, err := idx.Lookup(ctx, "sha256:"+strings.Repeat("AB", 32))
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if diff := cmp.Diff([]Target{t1, t2}, got); diff != "" {
+		t.Errorf("Lookup() mismatch (-want +got):\n%s", diff)
+	}
+	if err := idx.Remove(ctx, digest, t1); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	got, err = idx.Lookup(ctx, digest)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if diff := cmp.Diff([]Target{t2}, got); diff != "" {
+		t.Errorf("Lookup() after Remove() mismatch (-want +got):\n%s", diff)
+	}
+	// Removing an absent target is a no-op and removing the last deletes the entry.
+	for _, tgt := range []Target{t1, t2} {
+		if err := idx.Remove(ctx, digest, tgt); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+	}
+	if _, err := idx.Lookup(ctx, digest); !errors.Is(err, ErrDigestNotFound) {
+		t.Errorf("Lookup() after removing all targets error = %v, want ErrDigestNotFound", err)
+	}
+	if err := idx.Remove(ctx, digest, t1); err != nil {
+		t.Errorf("Remove() on absent entry error = %v", err)
+	}
+	for _, bad := range []string{"abcd", "sha512:" + strings.Repeat("ab", 64), "sha256:xyz", "sha256:abcd"} {
+		if err := idx.Add(ctx, bad, t1); err == nil {
+			t.Errorf("Add(%q) succeeded", bad)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/go-git/go-billy/v5/util"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
 	"github.com/go-git/go-git/v5/storage"
 	"github.com/go-git/go-git/v5/storage/filesystem"
 	"github.com/google/oss-rebuild/internal/gitx"
@@ -100,7 +101,9 @@ func allTags(repo *git.Repository) (tags []string, err error) {
 // LoadRepo attempts to either reuse the local or load the remote repo specified in CloneOptions.
 //
 // If rebuild.RepoCacheClientID is present, a Git cache service will be used
-// instead of the remote defined in CloneOptions.URL.
+// instead of the remote defined in CloneOptions.URL. Otherwise, if
+// rebuild.CloneFilterID is present, a partial clone omitting the filtered
+// objects will be performed.
 func LoadRepo(ctx context.Context, pkg string, s storage.Storer, fs billy.Filesystem, opt git.CloneOptions) (*git.Repository, error) {
 	var r *git.Repository
 	r, err := gitx.Reuse(ctx, s, fs, &opt)
@@ -133,6 +136,12 @@ func LoadRepo(ctx context.Context, pkg string, s storage.Storer, fs billy.Filesy
 				return nil, errors.Wrap(err, "using repo cache")
 			}
 			log.Printf("Using cached repository [pkg=%s,repoURL=%s]\n", pkg, opt.URL)
+		} else if filter, ok := ctx.Value(CloneFilterID).(packp.Filter); ok && filter != "" {
+			r, err = gitx.PartialClone(filter)(ctx, s, fs, &opt)
+			if err != nil {
+				return nil, errors.Wrap(err, "partially cloning repo")
+			}
+			log.Printf("Using partially cloned repository [pkg=%s,repoURL=%s,filter=%s]\n", pkg, opt.URL, filter)
 		} else {
 			r, err = gitx.Clone(ctx, s, fs, &opt)
 			if err != nil {
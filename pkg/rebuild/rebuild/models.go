@@ -82,6 +82,8 @@ type Timings struct {
 	Source        time.Duration
 	Infer         time.Duration
 	Build         time.Duration
+	// CloneBytes is the size of the cloned repository's git storage.
+	CloneBytes int64
 }
 
 func (t Timings) Total() time.Duration {
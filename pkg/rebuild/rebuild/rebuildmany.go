@@ -151,6 +151,7 @@ func RebuildMany(ctx context.Context, rebuilder Rebuilder, inputs []Input, regis
 		}
 		if verdicts[i].Timings.CloneEstimate == time.Duration(0) {
 			verdicts[i].Timings.CloneEstimate = verdicts[i-1].Timings.CloneEstimate
+			verdicts[i].Timings.CloneBytes = verdicts[i-1].Timings.CloneBytes
 		}
 	}
 	return verdicts, nil
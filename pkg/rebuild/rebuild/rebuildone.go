@@ -60,6 +60,11 @@ func RebuildOne(ctx context.Context, r Rebuilder, input Input, mux RegistryMux,
 		}
 		*rcfg = newRepo
 		verdict.Timings.CloneEstimate = time.Since(cloneStart)
+		if size, err := gitx.StorageSize(s); err != nil {
+			log.Printf("[%s] Failed to measure repo size: %v\n", t.Package, err)
+		} else {
+			verdict.Timings.CloneBytes = size
+		}
 	} else {
 		// Do a fresh checkout to wipe any cruft from previous builds.
 		_, err = gitx.Reuse(ctx, s, fs, &git.CloneOptions{URL: rcfg.URI, RecurseSubmodules: git.DefaultSubmoduleRecursionDepth})
@@ -5,8 +5,8 @@ package rebuild
 
 import (
 	"context"
-	"errors"
 	"log"
+	"net/url"
 	"strings"
 
 	cacheinternal "github.com/google/oss-rebuild/internal/cache"
@@ -16,6 +16,7 @@ import (
 	"github.com/google/oss-rebuild/pkg/registry/maven"
 	"github.com/google/oss-rebuild/pkg/registry/npm"
 	"github.com/google/oss-rebuild/pkg/registry/pypi"
+	"github.com/pkg/errors"
 )
 
 // RegistryMux offers a unified accessor for package registries.
@@ -25,39 +26,156 @@ type RegistryMux struct {
 	CratesIO cratesio.Registry
 	Maven    maven.Registry
 	Debian   debian.Registry
+	// Config describes the non-default registries in use, if any.
+	Config RegistryConfig
+}
+
+// RegistryURL returns the URL of the configured registry serving the target
+// or the empty string if it is served by the ecosystem's public registry.
+func (m RegistryMux) RegistryURL(t Target) string {
+	if e := m.Config.Endpoint(t.Ecosystem, t.Package); e != nil {
+		return e.URL
+	}
+	return ""
+}
+
+// WithConfig returns a new RegistryMux that serves packages from the
+// registries configured in cfg and otherwise from those in m.
+func (m RegistryMux) WithConfig(client httpx.BasicClient, cfg RegistryConfig) (RegistryMux, error) {
+	newmux := m
+	newmux.Config = cfg
+	for eco, endpoints := range cfg {
+		var npmRegs []scoped[npm.Registry]
+		var pypiRegs []scoped[pypi.Registry]
+		var mavenRegs []scoped[maven.Registry]
+		for _, e := range endpoints {
+			u, err := url.Parse(e.URL)
+			if err != nil {
+				return newmux, errors.Wrapf(err, "parsing %s registry URL", eco)
+			}
+			c := client
+			if auth := e.Authorization(); auth != "" {
+				c = &httpx.WithAuthorization{BasicClient: client, Host: u.Host, Authorization: auth}
+			}
+			switch eco {
+			case NPM:
+				npmRegs = append(npmRegs, scoped[npm.Registry]{e, npm.HTTPRegistry{Client: c, URL: u}})
+			case PyPI:
+				pypiRegs = append(pypiRegs, scoped[pypi.Registry]{e, pypi.HTTPRegistry{Client: c, URL: u}})
+			case Maven:
+				mavenRegs = append(mavenRegs, scoped[maven.Registry]{e, maven.HTTPRegistry{Client: c, URL: u}})
+			default:
+				return newmux, errors.Errorf("registry configuration unsupported for %s", eco)
+			}
+		}
+		switch eco {
+		case NPM:
+			newmux.NPM = scopedNPM{npmRegs, m.NPM}
+		case PyPI:
+			newmux.PyPI = scopedPyPI{pypiRegs, m.PyPI}
+		case Maven:
+			newmux.Maven = scopedMaven{mavenRegs, m.Maven}
+		}
+	}
+	return newmux, nil
 }
 
 // RegistryMuxWithCache returns a new RegistryMux with the provided cache wrapping each registry.
 func RegistryMuxWithCache(registry RegistryMux, c cacheinternal.Cache) (RegistryMux, error) {
-	var newmux RegistryMux
-	if httpreg, ok := registry.NPM.(npm.HTTPRegistry); ok {
-		newmux.NPM = npm.HTTPRegistry{Client: httpx.NewCachedClient(httpreg.Client, c)}
-	} else {
-		return newmux, errors.New("unknown npm registry type")
+	newmux := RegistryMux{Config: registry.Config}
+	var err error
+	if newmux.NPM, err = npmWithCache(registry.NPM, c); err != nil {
+		return newmux, err
 	}
-	if httpreg, ok := registry.PyPI.(pypi.HTTPRegistry); ok {
-		newmux.PyPI = pypi.HTTPRegistry{Client: httpx.NewCachedClient(httpreg.Client, c)}
-	} else {
-		return newmux, errors.New("unknown PyPI registry type")
+	if newmux.PyPI, err = pypiWithCache(registry.PyPI, c); err != nil {
+		return newmux, err
 	}
 	if httpreg, ok := registry.CratesIO.(cratesio.HTTPRegistry); ok {
 		newmux.CratesIO = cratesio.HTTPRegistry{Client: httpx.NewCachedClient(httpreg.Client, c)}
+	} else if dirreg, ok := registry.CratesIO.(cratesio.DirRegistry); ok {
+		newmux.CratesIO = dirreg
 	} else {
 		return newmux, errors.New("unknown crates.io registry type")
 	}
-	if httpreg, ok := registry.Maven.(maven.HTTPRegistry); ok {
-		newmux.Maven = maven.HTTPRegistry{Client: httpx.NewCachedClient(httpreg.Client, c)}
-	} else {
-		return newmux, errors.New("unknown Maven Central registry type")
+	if newmux.Maven, err = mavenWithCache(registry.Maven, c); err != nil {
+		return newmux, err
 	}
 	if httpreg, ok := registry.Debian.(debian.HTTPRegistry); ok {
 		newmux.Debian = debian.HTTPRegistry{Client: httpx.NewCachedClient(httpreg.Client, c)}
+	} else if dirreg, ok := registry.Debian.(debian.DirRegistry); ok {
+		newmux.Debian = dirreg
 	} else {
 		return newmux, errors.New("unknown debian registry type")
 	}
 	return newmux, nil
 }
 
+func npmWithCache(registry npm.Registry, c cacheinternal.Cache) (npm.Registry, error) {
+	switch r := registry.(type) {
+	case npm.HTTPRegistry:
+		return npm.HTTPRegistry{Client: httpx.NewCachedClient(r.Client, c), URL: r.URL}, nil
+	case scopedNPM:
+		var err error
+		newreg := scopedNPM{registries: make([]scoped[npm.Registry], len(r.registries))}
+		for i, s := range r.registries {
+			newreg.registries[i].endpoint = s.endpoint
+			if newreg.registries[i].registry, err = npmWithCache(s.registry, c); err != nil {
+				return nil, err
+			}
+		}
+		newreg.fallback, err = npmWithCache(r.fallback, c)
+		return newreg, err
+	case npm.DirRegistry:
+		return r, nil
+	default:
+		return nil, errors.New("unknown npm registry type")
+	}
+}
+
+func pypiWithCache(registry pypi.Registry, c cacheinternal.Cache) (pypi.Registry, error) {
+	switch r := registry.(type) {
+	case pypi.HTTPRegistry:
+		return pypi.HTTPRegistry{Client: httpx.NewCachedClient(r.Client, c), URL: r.URL}, nil
+	case scopedPyPI:
+		var err error
+		newreg := scopedPyPI{registries: make([]scoped[pypi.Registry], len(r.registries))}
+		for i, s := range r.registries {
+			newreg.registries[i].endpoint = s.endpoint
+			if newreg.registries[i].registry, err = pypiWithCache(s.registry, c); err != nil {
+				return nil, err
+			}
+		}
+		newreg.fallback, err = pypiWithCache(r.fallback, c)
+		return newreg, err
+	case pypi.DirRegistry:
+		return r, nil
+	default:
+		return nil, errors.New("unknown PyPI registry type")
+	}
+}
+
+func mavenWithCache(registry maven.Registry, c cacheinternal.Cache) (maven.Registry, error) {
+	switch r := registry.(type) {
+	case maven.HTTPRegistry:
+		return maven.HTTPRegistry{Client: httpx.NewCachedClient(r.Client, c), URL: r.URL}, nil
+	case scopedMaven:
+		var err error
+		newreg := scopedMaven{registries: make([]scoped[maven.Registry], len(r.registries))}
+		for i, s := range r.registries {
+			newreg.registries[i].endpoint = s.endpoint
+			if newreg.registries[i].registry, err = mavenWithCache(s.registry, c); err != nil {
+				return nil, err
+			}
+		}
+		newreg.fallback, err = mavenWithCache(r.fallback, c)
+		return newreg, err
+	case maven.DirRegistry:
+		return r, nil
+	default:
+		return nil, errors.New("unknown Maven Central registry type")
+	}
+}
+
 func warmCacheforArtifact(ctx context.Context, registry RegistryMux, t Target) {
 	switch t.Ecosystem {
 	case NPM:
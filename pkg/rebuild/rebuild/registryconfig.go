@@ -0,0 +1,189 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package rebuild
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/oss-rebuild/pkg/registry/maven"
+	"github.com/google/oss-rebuild/pkg/registry/npm"
+	"github.com/google/oss-rebuild/pkg/registry/pypi"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// RegistryEndpoint describes a package registry other than the ecosystem's public registry.
+type RegistryEndpoint struct {
+	// URL is the base URL of the registry API.
+	URL string `yaml:"url"`
+	// Scopes restricts the registry to packages whose names begin with one of
+	// the provided prefixes e.g. "@myorg/" for npm or "com.example:" for Maven.
+	// If empty, the registry serves all packages.
+	Scopes []string `yaml:"scopes,omitempty"`
+	// Username and Password are used for HTTP basic authentication.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Token is used for HTTP bearer authentication and takes precedence over Username and Password.
+	Token string `yaml:"token,omitempty"`
+}
+
+// Serves returns whether the endpoint is configured to serve pkg.
+func (e RegistryEndpoint) Serves(pkg string) bool {
+	if len(e.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range e.Scopes {
+		if strings.HasPrefix(pkg, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorization returns the Authorization header value for requests to the endpoint, if any.
+func (e RegistryEndpoint) Authorization() string {
+	switch {
+	case e.Token != "":
+		return "Bearer " + e.Token
+	case e.Username != "" || e.Password != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(e.Username+":"+e.Password))
+	default:
+		return ""
+	}
+}
+
+// RegistryConfig maps an ecosystem to its configured registries in order of precedence.
+//
+// Packages are served only by the first registry whose scopes match. There is
+// intentionally no fallback to lower-precedence registries or to the public
+// registry when the matching registry lacks a package: doing so would permit
+// a public package to shadow a private one (i.e. dependency confusion).
+type RegistryConfig map[Ecosystem][]RegistryEndpoint
+
+// Endpoint returns the registry configured to serve pkg or nil if pkg is
+// served by the ecosystem's public registry.
+func (c RegistryConfig) Endpoint(eco Ecosystem, pkg string) *RegistryEndpoint {
+	for i, e := range c[eco] {
+		if e.Serves(pkg) {
+			return &c[eco][i]
+		}
+	}
+	return nil
+}
+
+// ParseRegistryConfig reads a YAML RegistryConfig.
+//
+// Environment variable references in credentials (e.g. "${NPM_TOKEN}") are
+// expanded so that secrets need not be stored in the file.
+func ParseRegistryConfig(r io.Reader) (RegistryConfig, error) {
+	var cfg RegistryConfig
+	if err := yaml.NewDecoder(r).Decode(&cfg); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "decoding registry config")
+	}
+	for eco, endpoints := range cfg {
+		switch eco {
+		case NPM, PyPI, Maven:
+		default:
+			return nil, errors.Errorf("registry configuration unsupported for %s", eco)
+		}
+		for i := range endpoints {
+			e := &endpoints[i]
+			if u, err := url.Parse(e.URL); err != nil {
+				return nil, errors.Wrapf(err, "parsing %s registry URL", eco)
+			} else if u.Scheme != "https" && u.Scheme != "http" {
+				return nil, errors.Errorf("unsupported %s registry URL: %s", eco, e.URL)
+			}
+			e.Username = os.ExpandEnv(e.Username)
+			e.Password = os.ExpandEnv(e.Password)
+			e.Token = os.ExpandEnv(e.Token)
+		}
+	}
+	return cfg, nil
+}
+
+// scoped is a registry serving the packages matched by an endpoint.
+type scoped[T any] struct {
+	endpoint RegistryEndpoint
+	registry T
+}
+
+// choose returns the registry serving pkg, if any, and otherwise the fallback.
+func choose[T any](registries []scoped[T], fallback T, pkg string) T {
+	for _, s := range registries {
+		if s.endpoint.Serves(pkg) {
+			return s.registry
+		}
+	}
+	return fallback
+}
+
+type scopedNPM struct {
+	registries []scoped[npm.Registry]
+	fallback   npm.Registry
+}
+
+func (r scopedNPM) Package(ctx context.Context, pkg string) (*npm.NPMPackage, error) {
+	return choose(r.registries, r.fallback, pkg).Package(ctx, pkg)
+}
+
+func (r scopedNPM) Version(ctx context.Context, pkg, version string) (*npm.NPMVersion, error) {
+	return choose(r.registries, r.fallback, pkg).Version(ctx, pkg, version)
+}
+
+func (r scopedNPM) Artifact(ctx context.Context, pkg, version string) (io.ReadCloser, error) {
+	return choose(r.registries, r.fallback, pkg).Artifact(ctx, pkg, version)
+}
+
+var _ npm.Registry = scopedNPM{}
+
+type scopedPyPI struct {
+	registries []scoped[pypi.Registry]
+	fallback   pypi.Registry
+}
+
+func (r scopedPyPI) Project(ctx context.Context, pkg string) (*pypi.Project, error) {
+	return choose(r.registries, r.fallback, pkg).Project(ctx, pkg)
+}
+
+func (r scopedPyPI) Release(ctx context.Context, pkg, version string) (*pypi.Release, error) {
+	return choose(r.registries, r.fallback, pkg).Release(ctx, pkg, version)
+}
+
+func (r scopedPyPI) Artifact(ctx context.Context, pkg, version, filename string) (io.ReadCloser, error) {
+	return choose(r.registries, r.fallback, pkg).Artifact(ctx, pkg, version, filename)
+}
+
+var _ pypi.Registry = scopedPyPI{}
+
+type scopedMaven struct {
+	registries []scoped[maven.Registry]
+	fallback   maven.Registry
+}
+
+func (r scopedMaven) PackageMetadata(ctx context.Context, pkg string) (*maven.MavenPackage, error) {
+	return choose(r.registries, r.fallback, pkg).PackageMetadata(ctx, pkg)
+}
+
+func (r scopedMaven) PackageVersion(ctx context.Context, pkg, version string) (*maven.MavenVersion, error) {
+	return choose(r.registries, r.fallback, pkg).PackageVersion(ctx, pkg, version)
+}
+
+func (r scopedMaven) ReleaseFile(ctx context.Context, pkg, version, filetype string) (io.ReadCloser, error) {
+	return choose(r.registries, r.fallback, pkg).ReleaseFile(ctx, pkg, version, filetype)
+}
+
+func (r scopedMaven) ReleaseURL(ctx context.Context, pkg, version, filetype string) (string, error) {
+	return choose(r.registries, r.fallback, pkg).ReleaseURL(ctx, pkg, version, filetype)
+}
+
+func (r scopedMaven) Artifact(ctx context.Context, pkg, version, filetype string) (io.ReadCloser, error) {
+	return choose(r.registries, r.fallback, pkg).Artifact(ctx, pkg, version, filetype)
+}
+
+var _ maven.Registry = scopedMaven{}
@@ -0,0 +1,175 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package rebuild
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/internal/httpx"
+	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/google/oss-rebuild/pkg/registry/npm"
+)
+
+func TestParseRegistryConfig(t *testing.T) {
+	t.Setenv("TEST_NPM_TOKEN", "s3cret")
+	testCases := []struct {
+		name    string
+		config  string
+		want    RegistryConfig
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			config: `
+npm:
+  - url: https://npm.example.com/
+    scopes: ["@myorg/"]
+    token: ${TEST_NPM_TOKEN}
+  - url: https://mirror.example.com/npm/
+maven:
+  - url: https://maven.example.com/releases/
+    scopes: ["com.example:"]
+    username: user
+    password: pass
+`,
+			want: RegistryConfig{
+				NPM: {
+					{URL: "https://npm.example.com/", Scopes: []string{"@myorg/"}, Token: "s3cret"},
+					{URL: "https://mirror.example.com/npm/"},
+				},
+				Maven: {
+					{URL: "https://maven.example.com/releases/", Scopes: []string{"com.example:"}, Username: "user", Password: "pass"},
+				},
+			},
+		},
+		{
+			name:   "empty",
+			config: "",
+		},
+		{
+			name:    "unsupported ecosystem",
+			config:  "cratesio:\n  - url: https://crates.example.com/\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid URL",
+			config:  "npm:\n  - url: npm.example.com\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseRegistryConfig(strings.NewReader(tc.config))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseRegistryConfig() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseRegistryConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRegistryConfigEndpoint(t *testing.T) {
+	cfg := RegistryConfig{
+		NPM: {
+			{URL: "https://npm.example.com/", Scopes: []string{"@myorg/", "@other/"}},
+			{URL: "https://mirror.example.com/"},
+		},
+		PyPI: {
+			{URL: "https://pypi.example.com/", Scopes: []string{"internal-"}},
+		},
+	}
+	testCases := []struct {
+		eco  Ecosystem
+		pkg  string
+		want string
+	}{
+		{NPM, "@myorg/pkg", "https://npm.example.com/"},
+		{NPM, "@other/pkg", "https://npm.example.com/"},
+		{NPM, "express", "https://mirror.example.com/"},
+		{PyPI, "internal-tools", "https://pypi.example.com/"},
+		{PyPI, "requests", ""},
+		{Maven, "com.example:lib", ""},
+	}
+	for _, tc := range testCases {
+		mux := RegistryMux{Config: cfg}
+		if got := mux.RegistryURL(Target{Ecosystem: tc.eco, Package: tc.pkg}); got != tc.want {
+			t.Errorf("RegistryURL(%s, %s) = %q, want %q", tc.eco, tc.pkg, got, tc.want)
+		}
+	}
+}
+
+func TestRegistryEndpointAuthorization(t *testing.T) {
+	testCases := []struct {
+		endpoint RegistryEndpoint
+		want     string
+	}{
+		{RegistryEndpoint{}, ""},
+		{RegistryEndpoint{Token: "tok", Username: "user"}, "Bearer tok"},
+		{RegistryEndpoint{Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz"},
+	}
+	for _, tc := range testCases {
+		if got := tc.endpoint.Authorization(); got != tc.want {
+			t.Errorf("Authorization() = %q, want %q", got, tc.want)
+		}
+	}
+}
+
+// authRecorder records the Authorization header of each request.
+type authRecorder struct {
+	httpx.BasicClient
+	auths []string
+}
+
+func (c *authRecorder) Do(req *http.Request) (*http.Response, error) {
+	c.auths = append(c.auths, req.Header.Get("Authorization"))
+	return c.BasicClient.Do(req)
+}
+
+func TestRegistryMuxWithConfig(t *testing.T) {
+	client := &httpxtest.MockClient{
+		Calls: []httpxtest.Call{
+			{
+				Method: "GET",
+				URL:    "https://npm.example.com/@myorg/pkg",
+				Response: &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       httpxtest.Body(`{"name": "@myorg/pkg"}`),
+				},
+			},
+			{
+				Method: "GET",
+				URL:    "https://registry.npmjs.org/express",
+				Response: &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       httpxtest.Body(`{"name": "express"}`),
+				},
+			},
+		},
+		URLValidator: httpxtest.NewURLValidator(t),
+	}
+	recorder := &authRecorder{BasicClient: client}
+	cfg := RegistryConfig{NPM: {{URL: "https://npm.example.com/", Scopes: []string{"@myorg/"}, Token: "tok"}}}
+	mux, err := RegistryMux{NPM: npm.HTTPRegistry{Client: recorder}}.WithConfig(recorder, cfg)
+	if err != nil {
+		t.Fatalf("WithConfig() error = %v", err)
+	}
+	for _, pkg := range []string{"@myorg/pkg", "express"} {
+		p, err := mux.NPM.Package(context.Background(), pkg)
+		if err != nil {
+			t.Fatalf("Package(%s) error = %v", pkg, err)
+		}
+		if p.Name != pkg {
+			t.Errorf("Package(%s) name = %s", pkg, p.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"Bearer tok", ""}, recorder.auths); diff != "" {
+		t.Errorf("Authorization mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -46,11 +46,21 @@ const (
 	ContainerImageAsset AssetType = "image.tgz"
 	// ProxyNetlogAsset is the network activity from the rebuild process.
 	ProxyNetlogAsset AssetType = "netlog.json"
+	// ProxyViolationsAsset is the list of requests from the rebuild process that violated the audited network policy.
+	ProxyViolationsAsset AssetType = "proxy-violations.json"
 	// TetragonLogAsset is the log of all tetragon events.
 	TetragonLogAsset AssetType = "tetragon.jsonl"
 
 	// AttestationBundleAsset is the signed attestation bundle generated for a rebuild.
 	AttestationBundleAsset AssetType = "rebuild.intoto.jsonl"
+	// VSAAsset is the signed SLSA Verification Summary Attestation derived from the attestation bundle.
+	VSAAsset AssetType = "vsa.intoto.jsonl"
+	// RebuildFailureAsset is the signed attestation recording a failed rebuild.
+	RebuildFailureAsset AssetType = "failure.intoto.jsonl"
+	// TransparencyLogProofAsset is the proof of the attestation bundle's inclusion in a transparency log.
+	TransparencyLogProofAsset AssetType = "rebuild.tlog.json"
+	// RevocationAsset is the signed revocation record of the attestation bundle.
+	RevocationAsset AssetType = "revocation.intoto.jsonl"
 
 	// BuildDef is the build definition, including strategy.
 	BuildDef AssetType = "build.yaml"
@@ -93,6 +103,13 @@ type AssetStore interface {
 	Writer(ctx context.Context, a Asset) (io.WriteCloser, error)
 }
 
+// DeletableAssetStore is an asset store whose assets can be deleted.
+type DeletableAssetStore interface {
+	AssetStore
+	// Delete removes the asset, returning ErrAssetNotFound if it does not exist.
+	Delete(ctx context.Context, a Asset) error
+}
+
 // LocatableAssetStore is an asset store whose assets can be identified with a URL.
 type LocatableAssetStore interface {
 	AssetStore
@@ -211,7 +228,20 @@ func (s *GCSStore) Writer(ctx context.Context, a Asset) (io.WriteCloser, error)
 	return w, nil
 }
 
+// Delete removes the given asset.
+func (s *GCSStore) Delete(ctx context.Context, a Asset) error {
+	path := s.resourcePath(a)
+	if err := s.gcsClient.Bucket(s.bucket).Object(path).Delete(ctx); err != nil {
+		if err == gcs.ErrObjectNotExist {
+			err = stderrors.Join(err, ErrAssetNotFound)
+		}
+		return errors.Wrapf(err, "deleting %s", path)
+	}
+	return nil
+}
+
 var _ LocatableAssetStore = &GCSStore{}
+var _ DeletableAssetStore = &GCSStore{}
 
 // FilesystemAssetStore will store assets in a billy.Filesystem
 type FilesystemAssetStore struct {
@@ -250,7 +280,19 @@ func (s *FilesystemAssetStore) Writer(ctx context.Context, a Asset) (io.WriteClo
 	return f, nil
 }
 
+// Delete removes the given asset.
+func (s *FilesystemAssetStore) Delete(ctx context.Context, a Asset) error {
+	if err := s.fs.Remove(s.resourcePath(a)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = stderrors.Join(err, ErrAssetNotFound)
+		}
+		return errors.Wrapf(err, "deleting %v", a)
+	}
+	return nil
+}
+
 var _ LocatableAssetStore = &FilesystemAssetStore{}
+var _ DeletableAssetStore = &FilesystemAssetStore{}
 
 // NewFilesystemAssetStoreWithRunID creates a new FilesystemAssetStore.
 func NewFilesystemAssetStoreWithRunID(fs billy.Filesystem, runID string) *FilesystemAssetStore {
@@ -7,6 +7,7 @@ package schema
 
 import (
 	"encoding/hex"
+	"strings"
 	"time"
 
 	"github.com/google/oss-rebuild/internal/api"
@@ -314,6 +315,30 @@ var _ api.Message = AnalyzeRebuildRequest{}
 
 func (req AnalyzeRebuildRequest) Validate() error { return nil }
 
+// RevokeBundleRequest is a request to revoke the attestation bundle for a target.
+type RevokeBundleRequest struct {
+	Ecosystem rebuild.Ecosystem `form:",required"`
+	Package   string            `form:",required"`
+	Version   string            `form:",required"`
+	Artifact  string            `form:",required"`
+	Reason    string            `form:",required"`
+}
+
+var _ api.Message = RevokeBundleRequest{}
+
+func (req RevokeBundleRequest) Validate() error {
+	if strings.TrimSpace(req.Reason) == "" {
+		return errors.New("reason must be provided")
+	}
+	return nil
+}
+
+// RevokeBundleResponse describes an issued revocation.
+type RevokeBundleResponse struct {
+	BundleDigest string
+	RevokedAt    time.Time
+}
+
 // Execution mode describes the manner in which a rebuild happens.
 type ExecutionMode string
 
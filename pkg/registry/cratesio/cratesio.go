@@ -76,20 +76,32 @@ func (r HTTPRegistry) Crate(ctx context.Context, pkg string) (*Crate, error) {
 	if resp.StatusCode != 200 {
 		return nil, errors.Wrap(errors.New(resp.Status), "fetching crate metadata")
 	}
+	return decodeCrate(resp.Body)
+}
+
+func decodeCrate(r io.Reader) (*Crate, error) {
 	var c Crate
-	if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
 		return nil, err
 	}
 	for i := range c.Versions {
-		downloadPath, err := url.Parse(c.Versions[i].DownloadPath)
-		if err != nil {
-			return nil, errors.Wrap(err, "parsing version download path")
+		if err := c.Versions[i].resolveDownloadURL(); err != nil {
+			return nil, err
 		}
-		c.Versions[i].DownloadURL = registryURL.ResolveReference(downloadPath).String()
 	}
 	return &c, nil
 }
 
+// resolveDownloadURL populates DownloadURL from DownloadPath.
+func (v *Version) resolveDownloadURL() error {
+	downloadPath, err := url.Parse(v.DownloadPath)
+	if err != nil {
+		return errors.Wrap(err, "parsing version download path")
+	}
+	v.DownloadURL = registryURL.ResolveReference(downloadPath).String()
+	return nil
+}
+
 // Version provides all API information related to the given version of a crate.
 func (r HTTPRegistry) Version(ctx context.Context, pkg, version string) (*CrateVersion, error) {
 	pathURL, err := url.Parse(path.Join("/api/v1/crates", pkg, version))
@@ -108,11 +120,9 @@ func (r HTTPRegistry) Version(ctx context.Context, pkg, version string) (*CrateV
 	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
 		return nil, err
 	}
-	downloadPath, err := url.Parse(v.DownloadPath)
-	if err != nil {
-		return nil, errors.Wrap(err, "parsing version download path")
+	if err := v.resolveDownloadURL(); err != nil {
+		return nil, err
 	}
-	v.DownloadURL = registryURL.ResolveReference(downloadPath).String()
 	return &v, nil
 }
 
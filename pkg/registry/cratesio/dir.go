@@ -0,0 +1,75 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cratesio
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/pkg/errors"
+)
+
+// DirRegistry is a Registry implementation that serves crates from a directory.
+//
+// The directory has the following layout:
+//
+//	{crate}/crate.json       the API crate document i.e. crates.io/api/v1/crates/{crate}
+//	{crate}/{version}.crate  the crate archive for each available version
+type DirRegistry struct {
+	FS billy.Filesystem
+}
+
+// CratePath returns the path of the crate document within a DirRegistry.
+func CratePath(pkg string) string {
+	return path.Join(pkg, "crate.json")
+}
+
+// ArtifactPath returns the path of a version's crate archive within a DirRegistry.
+func ArtifactPath(pkg, version string) string {
+	return path.Join(pkg, version+".crate")
+}
+
+func (r DirRegistry) open(name string) (io.ReadCloser, error) {
+	f, err := r.FS.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(err, "%s not found", name)
+	}
+	return f, err
+}
+
+// Crate provides all API information related to the given crate.
+func (r DirRegistry) Crate(ctx context.Context, pkg string) (*Crate, error) {
+	f, err := r.open(CratePath(pkg))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeCrate(f)
+}
+
+// Version provides all API information related to the given version of a crate.
+//
+// The version information is that listed in the crate document.
+func (r DirRegistry) Version(ctx context.Context, pkg, version string) (*CrateVersion, error) {
+	c, err := r.Crate(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range c.Versions {
+		if v.Version == version {
+			return &CrateVersion{Version: v}, nil
+		}
+	}
+	return nil, errors.Errorf("version %s not found", version)
+}
+
+// Artifact provides the crate artifact associated with a specific crate version.
+func (r DirRegistry) Artifact(ctx context.Context, pkg, version string) (io.ReadCloser, error) {
+	return r.open(ArtifactPath(pkg, version))
+}
+
+var _ Registry = &DirRegistry{}
@@ -0,0 +1,65 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cratesio
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDirRegistry(t *testing.T) {
+	fs := memfs.New()
+	crateJSON := `{
+		"crate": {"id": "serde", "repository": "https://github.com/serde-rs/serde"},
+		"versions": [
+			{"num": "1.0.1", "dl_path": "/api/v1/crates/serde/1.0.1/download", "created_at": "2017-04-20T00:00:00Z"},
+			{"num": "1.0.0", "dl_path": "/api/v1/crates/serde/1.0.0/download", "created_at": "2017-04-19T00:00:00Z"}
+		]
+	}`
+	if err := util.WriteFile(fs, "serde/crate.json", []byte(crateJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.WriteFile(fs, "serde/1.0.0.crate", []byte("crate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	r := DirRegistry{FS: fs}
+	c, err := r.Crate(ctx, "serde")
+	if err != nil {
+		t.Fatalf("Crate() error = %v", err)
+	}
+	if len(c.Versions) != 2 || c.Repository != "https://github.com/serde-rs/serde" {
+		t.Errorf("Crate() = %+v", c)
+	}
+	v, err := r.Version(ctx, "serde", "1.0.0")
+	if err != nil {
+		t.Fatalf("Version() error = %v", err)
+	}
+	want := &CrateVersion{Version: Version{
+		Version:      "1.0.0",
+		DownloadPath: "/api/v1/crates/serde/1.0.0/download",
+		DownloadURL:  "https://crates.io/api/v1/crates/serde/1.0.0/download",
+		Created:      time.Date(2017, 4, 19, 0, 0, 0, 0, time.UTC),
+	}}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("Version() mismatch (-want +got):\n%s", diff)
+	}
+	a, err := r.Artifact(ctx, "serde", "1.0.0")
+	if err != nil {
+		t.Fatalf("Artifact() error = %v", err)
+	}
+	defer a.Close()
+	if content, _ := io.ReadAll(a); string(content) != "crate" {
+		t.Errorf("Artifact() = %q, want %q", content, "crate")
+	}
+	if _, err := r.Version(ctx, "serde", "2.0.0"); err == nil {
+		t.Error("Version() for missing version succeeded")
+	}
+}
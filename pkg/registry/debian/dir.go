@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package debian
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/google/oss-rebuild/internal/urlx"
+	"github.com/pkg/errors"
+)
+
+// DirRegistry is a Registry implementation that serves packages from a directory.
+//
+// The directory has the following layout:
+//
+//	{name}/{name}_{version}.dsc  the source package's control file
+//	{name}/{artifact}            each available binary package built from the source package
+//
+// Artifact URLs are reported as those on snapshot.debian.org which are
+// addressed by the SHA-1 digest of the artifact.
+type DirRegistry struct {
+	FS billy.Filesystem
+}
+
+// DSCPath returns the path of the source package's control file within a DirRegistry.
+func DSCPath(name, version string) (string, error) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(name, fmt.Sprintf("%s_%s.dsc", name, v.String())), nil
+}
+
+// ArtifactPath returns the path of a binary package within a DirRegistry.
+func ArtifactPath(name, artifact string) string {
+	return path.Join(name, artifact)
+}
+
+func (r DirRegistry) open(name string) (io.ReadCloser, error) {
+	f, err := r.FS.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(err, "%s not found", name)
+	}
+	return f, err
+}
+
+// DSC returns the upstream URL and contents of the source package's control file.
+func (r DirRegistry) DSC(ctx context.Context, component, name, version string) (string, *DSC, error) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return "", nil, err
+	}
+	dscPath, err := DSCPath(name, version)
+	if err != nil {
+		return "", nil, err
+	}
+	f, err := r.open(dscPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	d, err := parseDSC(f)
+	return guessDSCURL(component, name, v), d, err
+}
+
+// ArtifactURL returns the snapshot.debian.org URL of the binary package.
+func (r DirRegistry) ArtifactURL(ctx context.Context, name, artifact string) (string, error) {
+	f, err := r.open(ArtifactPath(name, artifact))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrap(err, "hashing artifact")
+	}
+	artifactURL := urlx.Copy(snapshotURL)
+	artifactURL.Path += path.Join("file", hex.EncodeToString(h.Sum(nil)))
+	return artifactURL.String(), nil
+}
+
+// Artifact returns the package artifact for the given package version.
+func (r DirRegistry) Artifact(ctx context.Context, component, name, artifact string) (io.ReadCloser, error) {
+	return r.open(ArtifactPath(name, artifact))
+}
+
+var _ Registry = &DirRegistry{}
@@ -0,0 +1,60 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package debian
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDirRegistry(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		"xz-utils/xz-utils_5.2.4-1.dsc":       "Format: 3.0 (quilt)\nSource: xz-utils\nBinary: xz-utils\n",
+		"xz-utils/xz-utils_5.2.4-1_amd64.deb": "deb",
+	}
+	for name, content := range files {
+		if err := util.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	r := DirRegistry{FS: fs}
+	dscURL, dsc, err := r.DSC(ctx, "main", "xz-utils", "5.2.4-1")
+	if err != nil {
+		t.Fatalf("DSC() error = %v", err)
+	}
+	if want := "https://deb.debian.org/debian/pool/main/x/xz-utils/xz-utils_5.2.4-1.dsc"; dscURL != want {
+		t.Errorf("DSC() url = %v, want %v", dscURL, want)
+	}
+	wantDSC := &DSC{Stanzas: []ControlStanza{{Fields: map[string][]string{
+		"Format": {"3.0 (quilt)"},
+		"Source": {"xz-utils"},
+		"Binary": {"xz-utils"},
+	}}}}
+	if diff := cmp.Diff(wantDSC, dsc); diff != "" {
+		t.Errorf("DSC() mismatch (-want +got):\n%s", diff)
+	}
+	artifactURL, err := r.ArtifactURL(ctx, "xz-utils", "xz-utils_5.2.4-1_amd64.deb")
+	if err != nil {
+		t.Fatalf("ArtifactURL() error = %v", err)
+	}
+	// SHA-1 of "deb".
+	if want := "https://snapshot.debian.org/file/a1008d558888eeb62b8a6795fdda462db0b50df0"; artifactURL != want {
+		t.Errorf("ArtifactURL() = %v, want %v", artifactURL, want)
+	}
+	a, err := r.Artifact(ctx, "main", "xz-utils", "xz-utils_5.2.4-1_amd64.deb")
+	if err != nil {
+		t.Fatalf("Artifact() error = %v", err)
+	}
+	defer a.Close()
+	if content, _ := io.ReadAll(a); string(content) != "deb" {
+		t.Errorf("Artifact() = %q, want %q", content, "deb")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package maven
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/pkg/errors"
+)
+
+// DirRegistry is a Registry implementation that serves packages from a
+// directory laid out like a Maven repository.
+//
+// In addition to the repository's files, the directory has a version document:
+//
+//	{group/path}/{artifact}/maven-metadata.xml              the package metadata
+//	{group/path}/{artifact}/{version}/version.json          the search API document for the version
+//	{group/path}/{artifact}/{version}/{artifact}-{version}* each available release file
+//
+// Release URLs are reported as those on Maven Central.
+type DirRegistry struct {
+	FS billy.Filesystem
+}
+
+// MetadataPath returns the path of the package metadata within a DirRegistry.
+func MetadataPath(pkg string) (string, error) {
+	g, a, found := strings.Cut(pkg, ":")
+	if !found {
+		return "", errors.New("package identifier not of form 'group:artifact'")
+	}
+	return path.Join(strings.ReplaceAll(g, ".", "/"), a, "maven-metadata.xml"), nil
+}
+
+// VersionPath returns the path of the version document within a DirRegistry.
+func VersionPath(pkg, version string) (string, error) {
+	return ArtifactPath(pkg, version, "version.json")
+}
+
+func (r DirRegistry) open(name string) (io.ReadCloser, error) {
+	f, err := r.FS.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(err, "%s not found", name)
+	}
+	return f, err
+}
+
+// PackageVersion returns the metadata for a Maven package version.
+func (r DirRegistry) PackageVersion(ctx context.Context, pkg, version string) (*MavenVersion, error) {
+	name, err := VersionPath(pkg, version)
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var v MavenVersion
+	if err := json.NewDecoder(f).Decode(&v); err != nil {
+		return nil, err
+	}
+	v.Published = time.UnixMilli(v.PublishedMilli)
+	return &v, nil
+}
+
+// PackageMetadata returns the metadata for a Maven package.
+func (r DirRegistry) PackageMetadata(ctx context.Context, pkg string) (*MavenPackage, error) {
+	name, err := MetadataPath(pkg)
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeMetadata(f)
+}
+
+// ReleaseFile returns a release file for a Maven package version.
+func (r DirRegistry) ReleaseFile(ctx context.Context, pkg, version string, typ string) (io.ReadCloser, error) {
+	_, a, found := strings.Cut(pkg, ":")
+	if !found {
+		return nil, errors.New("package identifier not of form 'group:artifact'")
+	}
+	return r.Artifact(ctx, pkg, version, fmt.Sprintf("%s-%s%s", a, version, typ))
+}
+
+// ReleaseURL returns the Maven Central URL for the release file for a Maven package version.
+func (r DirRegistry) ReleaseURL(ctx context.Context, pkg, version, typ string) (string, error) {
+	return HTTPRegistry{}.ReleaseURL(ctx, pkg, version, typ)
+}
+
+// Artifact returns file that is part of the Maven release.
+func (r DirRegistry) Artifact(ctx context.Context, pkg, version, artifact string) (io.ReadCloser, error) {
+	name, err := ArtifactPath(pkg, version, artifact)
+	if err != nil {
+		return nil, err
+	}
+	return r.open(name)
+}
+
+var _ Registry = &DirRegistry{}
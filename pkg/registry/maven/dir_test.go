@@ -0,0 +1,73 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package maven
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDirRegistry(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		"com/example/lib/maven-metadata.xml": `<metadata>
+  <groupId>com.example</groupId>
+  <artifactId>lib</artifactId>
+  <versioning>
+    <versions><version>1.0.0</version></versions>
+    <lastUpdated>20210601120000</lastUpdated>
+  </versioning>
+</metadata>`,
+		"com/example/lib/1.0.0/version.json":  `{"g": "com.example", "a": "lib", "v": "1.0.0", "timestamp": 1622548800000, "ec": [".jar", ".pom"]}`,
+		"com/example/lib/1.0.0/lib-1.0.0.jar": "jar",
+	}
+	for name, content := range files {
+		if err := util.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	r := DirRegistry{FS: fs}
+	m, err := r.PackageMetadata(ctx, "com.example:lib")
+	if err != nil {
+		t.Fatalf("PackageMetadata() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"1.0.0"}, m.Versions); diff != "" {
+		t.Errorf("PackageMetadata() versions mismatch (-want +got):\n%s", diff)
+	}
+	if want := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC); !m.LastUpdated.Equal(want) {
+		t.Errorf("PackageMetadata() LastUpdated = %v, want %v", m.LastUpdated, want)
+	}
+	v, err := r.PackageVersion(ctx, "com.example:lib", "1.0.0")
+	if err != nil {
+		t.Fatalf("PackageVersion() error = %v", err)
+	}
+	if !v.Published.Equal(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)) || v.Files[0] != TypeJar {
+		t.Errorf("PackageVersion() = %+v", v)
+	}
+	f, err := r.ReleaseFile(ctx, "com.example:lib", "1.0.0", TypeJar)
+	if err != nil {
+		t.Fatalf("ReleaseFile() error = %v", err)
+	}
+	defer f.Close()
+	if content, _ := io.ReadAll(f); string(content) != "jar" {
+		t.Errorf("ReleaseFile() = %q, want %q", content, "jar")
+	}
+	u, err := r.ReleaseURL(ctx, "com.example:lib", "1.0.0", TypeJar)
+	if err != nil {
+		t.Fatalf("ReleaseURL() error = %v", err)
+	}
+	if want := "https://repo1.maven.org/maven2/com/example/lib/1.0.0/lib-1.0.0.jar"; u != want {
+		t.Errorf("ReleaseURL() = %v, want %v", u, want)
+	}
+	if _, err := r.ReleaseFile(ctx, "com.example:lib", "1.0.0", TypePOM); err == nil {
+		t.Error("ReleaseFile() for missing file succeeded")
+	}
+}
@@ -11,7 +11,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"path"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,8 +30,15 @@ var (
 	// Maven path component.
 	registryContentPathURL = urlx.MustParse("remotecontent")
 	registrySearchPathURL  = urlx.MustParse(path.Join("solrsearch", "select"))
+	// Matches the entries of a repository directory listing and, if present, their listed time.
+	// Example: <a href="lib-1.0.0.pom" title="lib-1.0.0.pom">lib-1.0.0.pom</a>        2021-06-01 12:00       1024
+	listingEntryPattern = regexp.MustCompile(`<a href="([^"]+)"[^>]*>[^<]*</a>(?:\s+(\d{4}-\d{2}-\d{2} \d{2}:\d{2}))?`)
+	// Checksum and signature files are not reported as version files.
+	listingIgnoredExts = []string{".md5", ".sha1", ".sha256", ".sha512", ".asc"}
 )
 
+const listingTimeFormat = "2006-01-02 15:04"
+
 // MavenPackage is a Maven package.
 type MavenPackage struct {
 	MavenMetadata `xml:"metadata"`
@@ -88,6 +98,18 @@ type Registry interface {
 // HTTPRegistry is a Registry implementation that uses the search.maven.org HTTP API.
 type HTTPRegistry struct {
 	Client httpx.BasicClient
+	// URL is the base URL of a Maven repository to use instead of Maven Central.
+	//
+	// Such repositories are not expected to provide a search API so version
+	// metadata is derived from the version's directory listing.
+	URL *url.URL
+}
+
+func (r HTTPRegistry) repositoryURL() *url.URL {
+	if r.URL != nil {
+		return r.URL
+	}
+	return releaseURL
 }
 
 var _ Registry = &HTTPRegistry{}
@@ -98,6 +120,9 @@ func (r HTTPRegistry) PackageVersion(ctx context.Context, pkg, version string) (
 	if !found {
 		return nil, errors.New("package identifier not of form 'group:artifact'")
 	}
+	if r.URL != nil {
+		return r.repositoryVersion(ctx, g, a, version)
+	}
 
 	pathUrl := registryURL.ResolveReference(registrySearchPathURL)
 	params := pathUrl.Query()
@@ -131,6 +156,55 @@ func (r HTTPRegistry) PackageVersion(ctx context.Context, pkg, version string) (
 	return result, nil
 }
 
+// repositoryVersion returns the metadata for a Maven package version using only the repository's files.
+//
+// The files are read from the version's directory listing. The publish time is
+// that listed for the version's POM file and is left unset if none is listed.
+func (r HTTPRegistry) repositoryVersion(ctx context.Context, g, a, version string) (*MavenVersion, error) {
+	dir, err := ArtifactPath(g+":"+a, version, "")
+	if err != nil {
+		return nil, err
+	}
+	// NOTE: The trailing slash is required by repositories that redirect directory requests.
+	listingURL := r.repositoryURL().JoinPath(dir).String() + "/"
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, listingURL, nil)
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, errors.Wrap(errors.New(resp.Status), "fetching package version")
+	}
+	listing, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading version listing")
+	}
+	result := &MavenVersion{GroupID: g, ArtifactID: a, Version: version}
+	prefix := a + "-" + version
+	for _, m := range listingEntryPattern.FindAllStringSubmatch(string(listing), -1) {
+		href, err := url.PathUnescape(m[1])
+		if err != nil || strings.HasSuffix(href, "/") {
+			continue
+		}
+		typ, found := strings.CutPrefix(path.Base(href), prefix)
+		if !found || typ == "" || slices.Contains(listingIgnoredExts, path.Ext(typ)) {
+			continue
+		}
+		result.Files = append(result.Files, typ)
+		if typ == TypePOM && m[2] != "" {
+			if published, err := time.Parse(listingTimeFormat, m[2]); err == nil {
+				result.Published = published
+				result.PublishedMilli = published.UnixMilli()
+			}
+		}
+	}
+	if !slices.Contains(result.Files, TypePOM) {
+		return nil, errors.New("not found")
+	}
+	return result, nil
+}
+
 // PackageMetadata returns the metadata for a Maven package.
 func (r HTTPRegistry) PackageMetadata(ctx context.Context, pkg string) (result *MavenPackage, err error) {
 	content, err := r.ReleaseFile(ctx, pkg, "maven", TypeMetadata)
@@ -138,7 +212,11 @@ func (r HTTPRegistry) PackageMetadata(ctx context.Context, pkg string) (result *
 		return nil, err
 	}
 	defer content.Close()
-	err = xml.NewDecoder(content).Decode(&result)
+	return decodeMetadata(content)
+}
+
+func decodeMetadata(r io.Reader) (result *MavenPackage, err error) {
+	err = xml.NewDecoder(r).Decode(&result)
 	if err != nil {
 		return nil, err
 	}
@@ -166,23 +244,34 @@ func (r HTTPRegistry) ReleaseFile(ctx context.Context, pkg, version string, typ
 
 // ReleaseURL returns the URL for the release file for a Maven package version.
 func (r HTTPRegistry) ReleaseURL(ctx context.Context, pkg, version, typ string) (string, error) {
-	g, a, found := strings.Cut(pkg, ":")
+	_, a, found := strings.Cut(pkg, ":")
 	if !found {
 		return "", errors.New("package identifier not of form 'group:artifact'")
 	}
-	artifactPath := path.Join(strings.ReplaceAll(g, ".", "/"), a, version, fmt.Sprintf("%s-%s%s", a, version, typ))
-	artifactURL := releaseURL.JoinPath(artifactPath)
+	artifactPath, err := ArtifactPath(pkg, version, fmt.Sprintf("%s-%s%s", a, version, typ))
+	if err != nil {
+		return "", err
+	}
+	artifactURL := r.repositoryURL().JoinPath(artifactPath)
 	return artifactURL.String(), nil
 }
 
-// Artifact returns file that is part of the Maven release.
-func (r HTTPRegistry) Artifact(ctx context.Context, pkg, version, artifact string) (io.ReadCloser, error) {
+// ArtifactPath returns the path of a release file relative to the root of a Maven repository.
+func ArtifactPath(pkg, version, artifact string) (string, error) {
 	g, a, found := strings.Cut(pkg, ":")
 	if !found {
-		return nil, errors.New("package identifier not of form 'group:artifact'")
+		return "", errors.New("package identifier not of form 'group:artifact'")
+	}
+	return path.Join(strings.ReplaceAll(g, ".", "/"), a, version, artifact), nil
+}
+
+// Artifact returns file that is part of the Maven release.
+func (r HTTPRegistry) Artifact(ctx context.Context, pkg, version, artifact string) (io.ReadCloser, error) {
+	artifactPath, err := ArtifactPath(pkg, version, artifact)
+	if err != nil {
+		return nil, err
 	}
-	artifactPath := path.Join(strings.ReplaceAll(g, ".", "/"), a, version, artifact)
-	artifactURL := releaseURL.JoinPath(artifactPath)
+	artifactURL := r.repositoryURL().JoinPath(artifactPath)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artifactURL.String(), nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating artifact URL")
@@ -5,7 +5,14 @@ package maven
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/google/oss-rebuild/internal/urlx"
 )
 
 func TestReleaseURL(t *testing.T) {
@@ -14,6 +21,7 @@ func TestReleaseURL(t *testing.T) {
 		artifact string
 		version  string
 		filetype string
+		url      *url.URL
 		want     string
 	}{
 		{
@@ -23,16 +31,76 @@ func TestReleaseURL(t *testing.T) {
 			filetype: TypePOM,
 			want:     "https://repo1.maven.org/maven2/com/google/guava/guava/33.4.8-jre/guava-33.4.8-jre.pom",
 		},
+		{
+			test:     "custom_repository",
+			artifact: "com.example:lib",
+			version:  "1.0.0",
+			filetype: TypeJar,
+			url:      urlx.MustParse("https://artifactory.example.com/artifactory/maven-remote/"),
+			want:     "https://artifactory.example.com/artifactory/maven-remote/com/example/lib/1.0.0/lib-1.0.0.jar",
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.test, func(t *testing.T) {
-			r := HTTPRegistry{}
-			url, err := r.ReleaseURL(context.Background(), tc.artifact, tc.version, tc.filetype)
+			r := HTTPRegistry{URL: tc.url}
+			got, err := r.ReleaseURL(context.Background(), tc.artifact, tc.version, tc.filetype)
 			if err != nil {
 				t.Fatalf("ReleaseURL() error = %v", err)
 			}
-			if url != tc.want {
-				t.Errorf("ReleaseURL() = %v, want %v", url, tc.want)
+			if got != tc.want {
+				t.Errorf("ReleaseURL() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHTTPRegistry_PackageVersion_Repository(t *testing.T) {
+	published := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		listing string
+		want    *MavenVersion
+	}{
+		{
+			name: "listing with times",
+			listing: `<pre><a href="../">../</a>
+<a href="lib-1.0.0-sources.jar" title="lib-1.0.0-sources.jar">lib-1.0.0-sources.jar</a>        2021-06-01 12:00       512
+<a href="lib-1.0.0-sources.jar.sha1" title="lib-1.0.0-sources.jar.sha1">lib-1.0.0-sources.jar.sha1</a>        2021-06-01 12:00        40
+<a href="lib-1.0.0.aar" title="lib-1.0.0.aar">lib-1.0.0.aar</a>        2021-06-01 12:00      2048
+<a href="lib-1.0.0.pom" title="lib-1.0.0.pom">lib-1.0.0.pom</a>        2021-06-01 12:00      1024
+<a href="lib-1.0.0.pom.asc" title="lib-1.0.0.pom.asc">lib-1.0.0.pom.asc</a>        2021-06-01 12:00       488
+</pre>`,
+			want: &MavenVersion{GroupID: "com.example", ArtifactID: "lib", Version: "1.0.0", PublishedMilli: published.UnixMilli(), Published: published, Files: []string{TypeSources, ".aar", TypePOM}},
+		},
+		{
+			name: "listing without times",
+			listing: `<ul>
+<li><a href="https://artifactory.example.com/maven-remote/com/example/lib/1.0.0/lib-1.0.0.jar">lib-1.0.0.jar</a></li>
+<li><a href="https://artifactory.example.com/maven-remote/com/example/lib/1.0.0/lib-1.0.0.pom">lib-1.0.0.pom</a></li>
+</ul>`,
+			want: &MavenVersion{GroupID: "com.example", ArtifactID: "lib", Version: "1.0.0", Files: []string{TypeJar, TypePOM}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &httpxtest.MockClient{
+				Calls: []httpxtest.Call{
+					{
+						URL: "https://artifactory.example.com/maven-remote/com/example/lib/1.0.0/",
+						Response: &http.Response{
+							StatusCode: 200,
+							Body:       httpxtest.Body(tc.listing),
+						},
+					},
+				},
+				URLValidator: httpxtest.NewURLValidator(t),
+			}
+			r := HTTPRegistry{Client: mockClient, URL: urlx.MustParse("https://artifactory.example.com/maven-remote")}
+			got, err := r.PackageVersion(context.Background(), "com.example:lib", "1.0.0")
+			if err != nil {
+				t.Fatalf("PackageVersion() error = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("PackageVersion() mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
@@ -0,0 +1,85 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package npm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/pkg/errors"
+)
+
+// DirRegistry is a Registry implementation that serves packages from a directory.
+//
+// The directory has the following layout:
+//
+//	{pkg}/package.json   the registry's package document i.e. registry.npmjs.org/{pkg}
+//	{pkg}/{version}.tgz  the package tarball for each available version
+type DirRegistry struct {
+	FS billy.Filesystem
+}
+
+// PackagePath returns the path of the package document within a DirRegistry.
+func PackagePath(pkg string) string {
+	return path.Join(pkg, "package.json")
+}
+
+// ArtifactPath returns the path of a version's tarball within a DirRegistry.
+func ArtifactPath(pkg, version string) string {
+	return path.Join(pkg, version+".tgz")
+}
+
+func (r DirRegistry) open(name string) (io.ReadCloser, error) {
+	f, err := r.FS.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(err, "%s not found", name)
+	}
+	return f, err
+}
+
+// Package returns the package metadata for the given package.
+func (r DirRegistry) Package(ctx context.Context, pkg string) (*NPMPackage, error) {
+	f, err := r.open(PackagePath(pkg))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodePackage(f)
+}
+
+// Version returns the package metadata for the given package version.
+//
+// The version document is that embedded in the package document.
+func (r DirRegistry) Version(ctx context.Context, pkg, version string) (*NPMVersion, error) {
+	content, err := util.ReadFile(r.FS, PackagePath(pkg))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(err, "%s not found", PackagePath(pkg))
+	} else if err != nil {
+		return nil, err
+	}
+	var p struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	if err := json.Unmarshal(content, &p); err != nil {
+		return nil, err
+	}
+	raw, ok := p.Versions[version]
+	if !ok {
+		return nil, errors.Errorf("version %s not found", version)
+	}
+	return decodeVersion(bytes.NewReader(raw))
+}
+
+// Artifact returns the package artifact for the given package version.
+func (r DirRegistry) Artifact(ctx context.Context, pkg, version string) (io.ReadCloser, error) {
+	return r.open(ArtifactPath(pkg, version))
+}
+
+var _ Registry = &DirRegistry{}
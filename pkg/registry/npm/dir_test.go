@@ -0,0 +1,72 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package npm
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDirRegistry(t *testing.T) {
+	fs := memfs.New()
+	packageJSON := `{
+		"name": "@scope/pkg",
+		"dist-tags": {"latest": "1.0.0"},
+		"versions": {
+			"1.0.0": {
+				"name": "@scope/pkg",
+				"version": "1.0.0",
+				"repository": "https://github.com/example/pkg",
+				"dist": {"tarball": "https://registry.npmjs.org/@scope/pkg/-/pkg-1.0.0.tgz"}
+			}
+		}
+	}`
+	if err := util.WriteFile(fs, "@scope/pkg/package.json", []byte(packageJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.WriteFile(fs, "@scope/pkg/1.0.0.tgz", []byte("tarball"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	r := DirRegistry{FS: fs}
+	p, err := r.Package(ctx, "@scope/pkg")
+	if err != nil {
+		t.Fatalf("Package() error = %v", err)
+	}
+	if diff := cmp.Diff("https://github.com/example/pkg", p.Versions["1.0.0"].Repository.URL); diff != "" {
+		t.Errorf("Package() repository mismatch (-want +got):\n%s", diff)
+	}
+	v, err := r.Version(ctx, "@scope/pkg", "1.0.0")
+	if err != nil {
+		t.Fatalf("Version() error = %v", err)
+	}
+	want := &NPMVersion{
+		Name:       "@scope/pkg",
+		Version:    "1.0.0",
+		Dist:       Dist{URL: "https://registry.npmjs.org/@scope/pkg/-/pkg-1.0.0.tgz"},
+		Repository: Repository{URL: "https://github.com/example/pkg"},
+	}
+	if diff := cmp.Diff(want, v); diff != "" {
+		t.Errorf("Version() mismatch (-want +got):\n%s", diff)
+	}
+	a, err := r.Artifact(ctx, "@scope/pkg", "1.0.0")
+	if err != nil {
+		t.Fatalf("Artifact() error = %v", err)
+	}
+	defer a.Close()
+	if content, _ := io.ReadAll(a); string(content) != "tarball" {
+		t.Errorf("Artifact() = %q, want %q", content, "tarball")
+	}
+	if _, err := r.Version(ctx, "@scope/pkg", "2.0.0"); err == nil {
+		t.Error("Version() for missing version succeeded")
+	}
+	if _, err := r.Package(ctx, "missing"); err == nil {
+		t.Error("Package() for missing package succeeded")
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"path"
 	"time"
 
 	"github.com/google/oss-rebuild/internal/httpx"
@@ -78,15 +77,20 @@ type Registry interface {
 // HTTPRegistry is a Registry implementation that uses the npmjs.org HTTP API.
 type HTTPRegistry struct {
 	Client httpx.BasicClient
+	// URL is the base URL of the registry. If nil, registry.npmjs.org is used.
+	URL *url.URL
+}
+
+func (r HTTPRegistry) baseURL() *url.URL {
+	if r.URL != nil {
+		return r.URL
+	}
+	return registryURL
 }
 
 // Package returns the package metadata for the given package.
 func (r HTTPRegistry) Package(ctx context.Context, pkg string) (*NPMPackage, error) {
-	pathURL, err := url.Parse(path.Join("/", pkg))
-	if err != nil {
-		return nil, err
-	}
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, registryURL.ResolveReference(pathURL).String(), nil)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL().JoinPath(pkg).String(), nil)
 	resp, err := r.Client.Do(req)
 	if err != nil {
 		return nil, err
@@ -94,18 +98,17 @@ func (r HTTPRegistry) Package(ctx context.Context, pkg string) (*NPMPackage, err
 	if resp.StatusCode != 200 {
 		return nil, errors.Wrap(errors.New(resp.Status), "fetching package")
 	}
+	return decodePackage(resp.Body)
+}
+
+func decodePackage(r io.Reader) (*NPMPackage, error) {
 	var p NPMPackage
-	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
 		return nil, err
 	}
 	for s, v := range p.Versions {
-		if len(v.RawRepository) > 0 {
-			if err := json.Unmarshal(v.RawRepository, &v.Repository); err != nil {
-				// Try to parse out legacy unstructured URL format.
-				if err := json.Unmarshal(v.RawRepository, &v.Repository.URL); err != nil {
-					return nil, err
-				}
-			}
+		if err := decodeRepository(v.RawRepository, &v.Repository); err != nil {
+			return nil, err
 		}
 		v.RawRepository = nil
 		p.Versions[s] = v
@@ -113,13 +116,21 @@ func (r HTTPRegistry) Package(ctx context.Context, pkg string) (*NPMPackage, err
 	return &p, nil
 }
 
+func decodeRepository(raw json.RawMessage, repo *Repository) error {
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, repo); err != nil {
+			// Try to parse out legacy unstructured URL format.
+			if err := json.Unmarshal(raw, &repo.URL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Version returns the package metadata for the given package version.
 func (r HTTPRegistry) Version(ctx context.Context, pkg, version string) (*NPMVersion, error) {
-	pathURL, err := url.Parse(path.Join("/", pkg, version))
-	if err != nil {
-		return nil, err
-	}
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, registryURL.ResolveReference(pathURL).String(), nil)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL().JoinPath(pkg, version).String(), nil)
 	resp, err := r.Client.Do(req)
 	if err != nil {
 		return nil, err
@@ -127,17 +138,16 @@ func (r HTTPRegistry) Version(ctx context.Context, pkg, version string) (*NPMVer
 	if resp.StatusCode != 200 {
 		return nil, errors.Wrap(errors.New(resp.Status), "fetching version")
 	}
+	return decodeVersion(resp.Body)
+}
+
+func decodeVersion(r io.Reader) (*NPMVersion, error) {
 	var v NPMVersion
-	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
 		return nil, err
 	}
-	if len(v.RawRepository) > 0 {
-		if err := json.Unmarshal(v.RawRepository, &v.Repository); err != nil {
-			// Try to parse out legacy unstructured URL format.
-			if err := json.Unmarshal(v.RawRepository, &v.Repository.URL); err != nil {
-				return nil, err
-			}
-		}
+	if err := decodeRepository(v.RawRepository, &v.Repository); err != nil {
+		return nil, err
 	}
 	v.RawRepository = nil
 	return &v, nil
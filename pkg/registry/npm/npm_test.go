@@ -8,15 +8,18 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/google/oss-rebuild/internal/urlx"
 )
 
 func TestHTTPRegistry_Package(t *testing.T) {
 	testCases := []struct {
 		name        string
+		url         *url.URL
 		pkg         string
 		call        httpxtest.Call
 		expected    *NPMPackage
@@ -68,6 +71,23 @@ func TestHTTPRegistry_Package(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Custom registry with scoped package",
+			url:  urlx.MustParse("https://artifactory.example.com/api/npm/npm-remote/"),
+			pkg:  "@corp/lib",
+			call: httpxtest.Call{
+				URL: "https://artifactory.example.com/api/npm/npm-remote/@corp/lib",
+				Response: &http.Response{
+					StatusCode: 200,
+					Body:       httpxtest.Body(`{"name":"@corp/lib","dist-tags":{"latest":"1.0.0"},"versions":{"1.0.0":{"version":"1.0.0"}}}`),
+				},
+			},
+			expected: &NPMPackage{
+				Name:     "@corp/lib",
+				DistTags: DistTags{Latest: "1.0.0"},
+				Versions: map[string]Release{"1.0.0": {Version: "1.0.0"}},
+			},
+		},
 		{
 			name: "HTTP Error",
 			pkg:  "express",
@@ -103,7 +123,7 @@ func TestHTTPRegistry_Package(t *testing.T) {
 				Calls:        []httpxtest.Call{tc.call},
 				URLValidator: httpxtest.NewURLValidator(t),
 			}
-			actual, err := HTTPRegistry{Client: mockClient}.Package(context.Background(), tc.pkg)
+			actual, err := HTTPRegistry{Client: mockClient, URL: tc.url}.Package(context.Background(), tc.pkg)
 			if err != nil && tc.expectedErr != nil && err.Error() != tc.expectedErr.Error() {
 				t.Errorf("Error mismatch: got %v, want %v", err, tc.expectedErr)
 			}
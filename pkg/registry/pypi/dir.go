@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package pypi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/pkg/errors"
+)
+
+// DirRegistry is a Registry implementation that serves packages from a directory.
+//
+// The directory has the following layout:
+//
+//	{pkg}/project.json              the JSON API project document i.e. pypi.org/pypi/{pkg}/json
+//	{pkg}/{version}/release.json    the JSON API release document i.e. pypi.org/pypi/{pkg}/{version}/json
+//	{pkg}/{version}/{filename}      each available artifact of the release
+type DirRegistry struct {
+	FS billy.Filesystem
+}
+
+// ProjectPath returns the path of the project document within a DirRegistry.
+func ProjectPath(pkg string) string {
+	return path.Join(pkg, "project.json")
+}
+
+// ReleasePath returns the path of the release document within a DirRegistry.
+func ReleasePath(pkg, version string) string {
+	return path.Join(pkg, version, "release.json")
+}
+
+// ArtifactPath returns the path of a release artifact within a DirRegistry.
+func ArtifactPath(pkg, version, filename string) string {
+	return path.Join(pkg, version, filename)
+}
+
+func (r DirRegistry) open(name string) (io.ReadCloser, error) {
+	f, err := r.FS.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(err, "%s not found", name)
+	}
+	return f, err
+}
+
+// Project provides all API information related to the given package.
+func (r DirRegistry) Project(ctx context.Context, pkg string) (*Project, error) {
+	f, err := r.open(ProjectPath(pkg))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var p Project
+	if err := json.NewDecoder(f).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Release provides all API information related to the given version of a package.
+func (r DirRegistry) Release(ctx context.Context, pkg, version string) (*Release, error) {
+	f, err := r.open(ReleasePath(pkg, version))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var release Release
+	if err := json.NewDecoder(f).Decode(&release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// Artifact provides the artifact associated with a specific package version.
+func (r DirRegistry) Artifact(ctx context.Context, pkg, version, filename string) (io.ReadCloser, error) {
+	return r.open(ArtifactPath(pkg, version, filename))
+}
+
+var _ Registry = &DirRegistry{}
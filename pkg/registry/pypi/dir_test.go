@@ -0,0 +1,55 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package pypi
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDirRegistry(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		"absl-py/project.json":                         `{"info": {"name": "absl-py", "version": "2.0.0"}, "releases": {"1.0.0": [{"filename": "absl_py-1.0.0-py3-none-any.whl"}]}}`,
+		"absl-py/1.0.0/release.json":                   `{"info": {"name": "absl-py", "version": "1.0.0"}, "urls": [{"filename": "absl_py-1.0.0-py3-none-any.whl"}]}`,
+		"absl-py/1.0.0/absl_py-1.0.0-py3-none-any.whl": "wheel",
+	}
+	for name, content := range files {
+		if err := util.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	r := DirRegistry{FS: fs}
+	p, err := r.Project(ctx, "absl-py")
+	if err != nil {
+		t.Fatalf("Project() error = %v", err)
+	}
+	if diff := cmp.Diff(&Project{Info: Info{Name: "absl-py", Version: "2.0.0"}, Releases: map[string][]Artifact{"1.0.0": {{Filename: "absl_py-1.0.0-py3-none-any.whl"}}}}, p); diff != "" {
+		t.Errorf("Project() mismatch (-want +got):\n%s", diff)
+	}
+	release, err := r.Release(ctx, "absl-py", "1.0.0")
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if diff := cmp.Diff(&Release{Info: Info{Name: "absl-py", Version: "1.0.0"}, Artifacts: []Artifact{{Filename: "absl_py-1.0.0-py3-none-any.whl"}}}, release); diff != "" {
+		t.Errorf("Release() mismatch (-want +got):\n%s", diff)
+	}
+	a, err := r.Artifact(ctx, "absl-py", "1.0.0", "absl_py-1.0.0-py3-none-any.whl")
+	if err != nil {
+		t.Fatalf("Artifact() error = %v", err)
+	}
+	defer a.Close()
+	if content, _ := io.ReadAll(a); string(content) != "wheel" {
+		t.Errorf("Artifact() = %q, want %q", content, "wheel")
+	}
+	if _, err := r.Release(ctx, "absl-py", "2.0.0"); err == nil {
+		t.Error("Release() for missing version succeeded")
+	}
+}
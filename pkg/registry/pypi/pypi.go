@@ -10,7 +10,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"path"
 	"time"
 
 	"github.com/google/oss-rebuild/internal/httpx"
@@ -70,15 +69,20 @@ type Registry interface {
 // HTTPRegistry is a Registry implementation that uses the pypi.org HTTP API.
 type HTTPRegistry struct {
 	Client httpx.BasicClient
+	// URL is the base URL of the registry's JSON API. If nil, pypi.org is used.
+	URL *url.URL
+}
+
+func (r HTTPRegistry) baseURL() *url.URL {
+	if r.URL != nil {
+		return r.URL
+	}
+	return registryURL
 }
 
 // Project provides all API information related to the given package.
 func (r HTTPRegistry) Project(ctx context.Context, pkg string) (*Project, error) {
-	pathURL, err := url.Parse(path.Join("/pypi", pkg, "json"))
-	if err != nil {
-		return nil, err
-	}
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, registryURL.ResolveReference(pathURL).String(), nil)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL().JoinPath("pypi", pkg, "json").String(), nil)
 	resp, err := r.Client.Do(req)
 	if err != nil {
 		return nil, err
@@ -95,11 +99,7 @@ func (r HTTPRegistry) Project(ctx context.Context, pkg string) (*Project, error)
 
 // Release provides all API information related to the given version of a package.
 func (r HTTPRegistry) Release(ctx context.Context, pkg, version string) (*Release, error) {
-	pathURL, err := url.Parse(path.Join("/pypi", pkg, version, "json"))
-	if err != nil {
-		return nil, err
-	}
-	req, _ := http.NewRequest(http.MethodGet, registryURL.ResolveReference(pathURL).String(), nil)
+	req, _ := http.NewRequest(http.MethodGet, r.baseURL().JoinPath("pypi", pkg, version, "json").String(), nil)
 	resp, err := r.Client.Do(req)
 	if err != nil {
 		return nil, err
@@ -8,10 +8,12 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/google/oss-rebuild/internal/urlx"
 )
 
 func TestHTTPRegistry_Project(t *testing.T) {
@@ -107,6 +109,7 @@ func TestHTTPRegistry_Project(t *testing.T) {
 func TestHTTPRegistry_Release(t *testing.T) {
 	testCases := []struct {
 		name        string
+		url         *url.URL
 		pkg         string
 		version     string
 		call        httpxtest.Call
@@ -142,6 +145,17 @@ func TestHTTPRegistry_Release(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "Custom registry",
+			url:     urlx.MustParse("https://artifactory.example.com/api/pypi/pypi-remote/"),
+			pkg:     "requests",
+			version: "2.31.0",
+			call: httpxtest.Call{
+				URL:      "https://artifactory.example.com/api/pypi/pypi-remote/pypi/requests/2.31.0/json",
+				Response: &http.Response{StatusCode: 200, Body: httpxtest.Body(`{"info": {"name": "requests", "version": "2.31.0"}}`)},
+			},
+			expected: &Release{Info: Info{Name: "requests", Version: "2.31.0"}},
+		},
 		{
 			name:    "HTTP Error",
 			pkg:     "requests",
@@ -179,7 +193,7 @@ func TestHTTPRegistry_Release(t *testing.T) {
 				Calls:        []httpxtest.Call{tc.call},
 				URLValidator: httpxtest.NewURLValidator(t),
 			}
-			actual, err := HTTPRegistry{Client: mockClient}.Release(context.Background(), tc.pkg, tc.version)
+			actual, err := HTTPRegistry{Client: mockClient, URL: tc.url}.Release(context.Background(), tc.pkg, tc.version)
 			if err != nil && tc.expectedErr != nil && err.Error() != tc.expectedErr.Error() {
 				t.Errorf("Error mismatch: got %v, want %v", err, tc.expectedErr)
 			}
@@ -4,9 +4,11 @@
 package gitscan
 
 import (
+	"archive/tar"
 	"archive/zip"
 	"context"
 	"io"
+	"log"
 	"sort"
 	"time"
 
@@ -15,6 +17,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/google/oss-rebuild/internal/bitmap"
+	"github.com/google/oss-rebuild/internal/gitx"
 	"github.com/pkg/errors"
 )
 
@@ -46,22 +49,96 @@ func BlobHashesFromZip(zr *zip.Reader) (files []plumbing.Hash, err error) {
 	return files, nil
 }
 
+// BlobHashesFromTar computes the git blob hashes for all regular files in the provided tar archive.
+func BlobHashesFromTar(tr *tar.Reader) (files []plumbing.Hash, err error) {
+	for {
+		var hdr *tar.Header
+		hdr, err = tr.Next()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		h := plumbing.NewHasher(plumbing.BlobObject, hdr.Size)
+		if _, err = io.CopyN(h, tr, hdr.Size); err != nil {
+			return nil, err
+		}
+		files = append(files, h.Sum())
+	}
+}
+
+// presentBlobs returns the subset of hashes present as files in the repository.
+//
+// For a partial clone, presence is determined from tree entries rather than
+// blob lookups so the blobs omitted from the clone are not required or fetched.
+func presentBlobs(r *git.Repository, hashes []plumbing.Hash) ([]plumbing.Hash, error) {
+	if _, ok := r.Storer.(*gitx.PromisorStorer); !ok {
+		var present []plumbing.Hash
+		seen := make(map[plumbing.Hash]bool)
+		for _, h := range hashes {
+			if seen[h] {
+				continue
+			}
+			seen[h] = true
+			if _, err := r.BlobObject(h); err == plumbing.ErrObjectNotFound {
+				// Skip files not present in repo.
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			present = append(present, h)
+		}
+		return present, nil
+	}
+	wanted := make(map[plumbing.Hash]bool)
+	for _, h := range hashes {
+		wanted[h] = false
+	}
+	ti, err := r.TreeObjects()
+	if err != nil {
+		return nil, err
+	}
+	err = ti.ForEach(func(t *object.Tree) error {
+		for _, e := range t.Entries {
+			switch e.Mode {
+			case filemode.Dir, filemode.Submodule, filemode.Symlink:
+				continue
+			}
+			if _, ok := wanted[e.Hash]; ok {
+				wanted[e.Hash] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var present []plumbing.Hash
+	for _, h := range hashes {
+		if wanted[h] {
+			present = append(present, h)
+			// Avoid duplicates.
+			wanted[h] = false
+		}
+	}
+	return present, nil
+}
+
 // LazyTreeCount searches all TreeObjects in the repository for the number of matches against the input files provided.
 type LazyTreeCount struct{}
 
 // Search returns the set of matching commits along with the number of matches.
 func (c LazyTreeCount) Search(ctx context.Context, r *git.Repository, hashes []plumbing.Hash) (closest []string, matched, total int, err error) {
 	files := make(map[plumbing.Hash]bool)
-	for _, h := range hashes {
-		_, err = r.BlobObject(h)
-		if err == plumbing.ErrObjectNotFound {
-			// Skip files not present in repo.
-			err = nil
-		} else if err != nil {
-			return
-		} else {
-			files[h] = true
-		}
+	present, err := presentBlobs(r, hashes)
+	if err != nil {
+		return
+	}
+	for _, h := range present {
+		files[h] = true
 	}
 	total = len(files)
 	if total == 0 {
@@ -191,46 +268,22 @@ type ExactTreeCount struct{}
 func (s ExactTreeCount) Search(ctx context.Context, r *git.Repository, hashes []plumbing.Hash) (closest []string, matched, total int, err error) {
 	// Find subset of file hashes that are present in the repo.
 	files := make(map[plumbing.Hash]int)
-	var i int
-	for _, h := range hashes {
-		_, err = r.BlobObject(h)
-		if err == plumbing.ErrObjectNotFound {
-			// Skip files not present in repo.
-			err = nil
-		} else if err != nil {
-			return
-		} else {
-			files[h] = i
-			i++
-		}
+	present, err := presentBlobs(r, hashes)
+	if err != nil {
+		return
 	}
-	// Iterate over repo trees to create an ordering.
-	trees := make(map[plumbing.Hash]int)
-	i = 0
-	for ti, _ := r.TreeObjects(); ; {
-		t, err := ti.Next()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			panic(err)
-		} else {
-			trees[t.Hash] = i
-			i++
-		}
+	for i, h := range present {
+		files[h] = i
 	}
 	total = len(files)
 	if total == 0 {
 		err = errors.New("repo contains no matching files")
 		return
 	}
-	// Recursively build set of matching files for each tree.
-	matches := bitmap.NewBatch(len(files), len(trees))
-	processed := bitmap.New(len(trees))
-	ti, _ := r.TreeObjects()
-	ti.ForEach(func(t *object.Tree) error {
-		processTree(t, files, trees, matches, processed)
-		return nil
-	})
+	trees, matches, processed, err := matchTrees(r, files)
+	if err != nil {
+		return
+	}
 	// Search through all commits for the one whose tree has the most matches.
 	var ci object.CommitIter
 	ci, err = r.CommitObjects()
@@ -256,6 +309,40 @@ func (s ExactTreeCount) Search(ctx context.Context, r *git.Repository, hashes []
 	return
 }
 
+// matchTrees determines the set of files present in each tree in the repository.
+//
+// Trees are identified by their index in the returned map and files by their
+// value in the provided map.
+func matchTrees(r *git.Repository, files map[plumbing.Hash]int) (trees map[plumbing.Hash]int, matches []bitmap.Bitmap, processed *bitmap.Bitmap, err error) {
+	// Iterate over repo trees to create an ordering.
+	trees = make(map[plumbing.Hash]int)
+	ti, err := r.TreeObjects()
+	if err != nil {
+		return
+	}
+	var i int
+	err = ti.ForEach(func(t *object.Tree) error {
+		trees[t.Hash] = i
+		i++
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	// Recursively build set of matching files for each tree.
+	matches = bitmap.NewBatch(len(files), len(trees))
+	processed = bitmap.New(len(trees))
+	ti, err = r.TreeObjects()
+	if err != nil {
+		return
+	}
+	err = ti.ForEach(func(t *object.Tree) error {
+		processTree(t, files, trees, matches, processed)
+		return nil
+	})
+	return
+}
+
 // processTree recursively determines the presence of a set of files in the given git Tree and records them.
 func processTree(t *object.Tree, files, trees map[plumbing.Hash]int, matches []bitmap.Bitmap, processed *bitmap.Bitmap) {
 	if processed.Get(trees[t.Hash]) {
@@ -279,3 +366,150 @@ func processTree(t *object.Tree, files, trees map[plumbing.Hash]int, matches []b
 	}
 	processed.Set(trees[t.Hash])
 }
+
+// Match describes the input files present in a commit's tree.
+type Match struct {
+	Commit string
+	// Matched is the number of distinct input files present in the tree.
+	Matched int
+	// Total is the number of distinct input files.
+	Total int
+}
+
+// Fraction returns the fraction of the input files present in the tree.
+func (m Match) Fraction() float64 {
+	if m.Total == 0 {
+		return 0
+	}
+	return float64(m.Matched) / float64(m.Total)
+}
+
+// RankCommits returns up to n commits ordered by the fraction of the input files present in their trees.
+//
+// Among commits with equal matches, older commits are ordered first since a
+// release is most likely cut from the first commit containing its contents.
+// Commits with equal timestamps are ordered by their distance from the root.
+// Commits matching no files are omitted.
+func RankCommits(r *git.Repository, hashes []plumbing.Hash, n int) ([]Match, error) {
+	present, err := presentBlobs(r, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if len(present) == 0 {
+		return nil, errors.New("repo contains no matching files")
+	}
+	files := make(map[plumbing.Hash]int)
+	for i, h := range present {
+		files[h] = i
+	}
+	distinct := make(map[plumbing.Hash]bool)
+	for _, h := range hashes {
+		distinct[h] = true
+	}
+	trees, matches, processed, err := matchTrees(r, files)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		Match
+		when       time.Time
+		generation int
+	}
+	var candidates []candidate
+	parents := make(map[plumbing.Hash][]plumbing.Hash)
+	ci, err := r.CommitObjects()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating commit iterator")
+	}
+	err = ci.ForEach(func(c *object.Commit) error {
+		if !processed.Get(trees[c.TreeHash]) {
+			return errors.New("unprocessed tree")
+		}
+		parents[c.Hash] = c.ParentHashes
+		if count := matches[trees[c.TreeHash]].Count(); count > 0 {
+			candidates = append(candidates, candidate{Match: Match{Commit: c.Hash.String(), Matched: count, Total: len(distinct)}, when: c.Committer.When})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	generations := commitGenerations(parents)
+	for i := range candidates {
+		candidates[i].generation = generations[plumbing.NewHash(candidates[i].Commit)]
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Matched != b.Matched {
+			return a.Matched > b.Matched
+		}
+		if !a.when.Equal(b.when) {
+			return a.when.Before(b.when)
+		}
+		if a.generation != b.generation {
+			return a.generation < b.generation
+		}
+		return a.Commit < b.Commit
+	})
+	ranked := make([]Match, 0, min(n, len(candidates)))
+	for _, c := range candidates[:min(n, len(candidates))] {
+		ranked = append(ranked, c.Match)
+	}
+	return ranked, nil
+}
+
+// sourceMatchCandidates is the number of top ranked commits considered by FindSourceMatch.
+const sourceMatchCandidates = 10
+
+// FindSourceMatch returns the highest ranked commit by RankCommits among the
+// top candidates that is accepted by validate, e.g. as declaring the expected
+// package version.
+func FindSourceMatch(r *git.Repository, hashes []plumbing.Hash, validate func(*object.Commit) error) (*Match, error) {
+	matches, err := RankCommits(r, hashes, sourceMatchCandidates)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		c, err := r.CommitObject(plumbing.NewHash(m.Commit))
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving commit %s", m.Commit)
+		}
+		if err := validate(c); err != nil {
+			log.Printf("source match candidate invalid [ref=%s,matched=%d/%d]: %v", m.Commit[:9], m.Matched, m.Total, err)
+			continue
+		}
+		log.Printf("source match found [ref=%s,matched=%d/%d]", m.Commit[:9], m.Matched, m.Total)
+		return &m, nil
+	}
+	return nil, errors.New("no matching commit")
+}
+
+// commitGenerations computes the length of the longest path from each commit to a root commit.
+func commitGenerations(parents map[plumbing.Hash][]plumbing.Hash) map[plumbing.Hash]int {
+	generations := make(map[plumbing.Hash]int)
+	for h := range parents {
+		// Iterative DFS to avoid deep recursion on long histories.
+		stack := []plumbing.Hash{h}
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if _, ok := generations[top]; ok {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			gen, ready := 0, true
+			for _, p := range parents[top] {
+				if pg, ok := generations[p]; ok {
+					gen = max(gen, pg+1)
+				} else if _, known := parents[p]; known {
+					stack = append(stack, p)
+					ready = false
+				}
+			}
+			if ready {
+				generations[top] = gen
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return generations
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package gitscan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/internal/gitx/gitxtest"
+	"github.com/google/oss-rebuild/pkg/archive"
+	"github.com/google/oss-rebuild/pkg/archive/archivetest"
+	"github.com/pkg/errors"
+)
+
+func blobHash(s string) plumbing.Hash {
+	return plumbing.ComputeHash(plumbing.BlobObject, []byte(s))
+}
+
+func TestBlobHashesFromTar(t *testing.T) {
+	buf, err := archivetest.TarFile([]archive.TarEntry{
+		{Header: &tar.Header{Name: "pkg/", Typeflag: tar.TypeDir, Mode: 0755}},
+		{Header: &tar.Header{Name: "pkg/a.txt", Typeflag: tar.TypeReg, Mode: 0644}, Body: []byte("a")},
+		{Header: &tar.Header{Name: "pkg/link", Typeflag: tar.TypeSymlink, Linkname: "a.txt"}},
+		{Header: &tar.Header{Name: "pkg/hard", Typeflag: tar.TypeLink, Linkname: "pkg/a.txt"}},
+		{Header: &tar.Header{Name: "pkg/empty.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := BlobHashesFromTar(tar.NewReader(buf))
+	if err != nil {
+		t.Fatalf("BlobHashesFromTar() error = %v", err)
+	}
+	if diff := cmp.Diff([]plumbing.Hash{blobHash("a"), blobHash("")}, got); diff != "" {
+		t.Errorf("BlobHashesFromTar() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBlobHashesFromZip(t *testing.T) {
+	buf, err := archivetest.ZipFile([]archive.ZipEntry{
+		{FileHeader: &zip.FileHeader{Name: "pkg/"}},
+		{FileHeader: &zip.FileHeader{Name: "pkg/a.txt"}, Body: []byte("a")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := BlobHashesFromZip(zr)
+	if err != nil {
+		t.Fatalf("BlobHashesFromZip() error = %v", err)
+	}
+	if diff := cmp.Diff([]plumbing.Hash{blobHash("a")}, got); diff != "" {
+		t.Errorf("BlobHashesFromZip() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// rankRepo has commits matching 1, 2 and 2 of the files "a", "b" and "missing".
+const rankRepo = `commits:
+  - id: first
+    files:
+      a.txt: "a"
+  - id: second
+    parent: first
+    files:
+      dir/b.txt: "b"
+  - id: third
+    parent: second
+    files:
+      c.txt: "c"
+`
+
+// addCommit stores a commit of tree at the given time.
+func addCommit(t *testing.T, r *git.Repository, tree plumbing.Hash, when time.Time, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+	sig := object.Signature{Name: "test", When: when}
+	c := &object.Commit{Author: sig, Committer: sig, Message: "test", TreeHash: tree, ParentHashes: parents}
+	obj := r.Storer.NewEncodedObject()
+	if err := c.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	h, err := r.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestPresentBlobs(t *testing.T) {
+	repo, err := gitxtest.CreateRepoFromYAML(rankRepo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := presentBlobs(repo.Repository, []plumbing.Hash{blobHash("b"), blobHash("missing"), blobHash("a"), blobHash("b")})
+	if err != nil {
+		t.Fatalf("presentBlobs() error = %v", err)
+	}
+	if diff := cmp.Diff([]plumbing.Hash{blobHash("b"), blobHash("a")}, got); diff != "" {
+		t.Errorf("presentBlobs() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRankCommits(t *testing.T) {
+	repo, err := gitxtest.CreateRepoFromYAML(rankRepo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.CommitObject(repo.Commits["second"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := repo.CommitObject(repo.Commits["third"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Commits of equally matching trees but later than those in rankRepo.
+	later := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	laterA := addCommit(t, repo.Repository, second.TreeHash, later)
+	laterB := addCommit(t, repo.Repository, second.TreeHash, later, laterA)
+	laterRoot := addCommit(t, repo.Repository, third.TreeHash, later)
+	sameTime := []string{laterA.String(), laterRoot.String()}
+	sort.Strings(sameTime)
+	hashes := []plumbing.Hash{blobHash("a"), blobHash("b"), blobHash("missing"), blobHash("a")}
+	got, err := RankCommits(repo.Repository, hashes, 10)
+	if err != nil {
+		t.Fatalf("RankCommits() error = %v", err)
+	}
+	want := []Match{
+		// Equal matches and time are ordered by generation.
+		{Commit: repo.Commits["second"].String(), Matched: 2, Total: 3},
+		{Commit: repo.Commits["third"].String(), Matched: 2, Total: 3},
+		// Later commits follow regardless of generation and then by hash.
+		{Commit: sameTime[0], Matched: 2, Total: 3},
+		{Commit: sameTime[1], Matched: 2, Total: 3},
+		{Commit: laterB.String(), Matched: 2, Total: 3},
+		{Commit: repo.Commits["first"].String(), Matched: 1, Total: 3},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RankCommits() mismatch (-want +got):\n%s", diff)
+	}
+	t.Run("limit", func(t *testing.T) {
+		got, err := RankCommits(repo.Repository, hashes, 2)
+		if err != nil {
+			t.Fatalf("RankCommits() error = %v", err)
+		}
+		if diff := cmp.Diff(want[:2], got); diff != "" {
+			t.Errorf("RankCommits() mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("no matching files", func(t *testing.T) {
+		if _, err := RankCommits(repo.Repository, []plumbing.Hash{blobHash("missing")}, 2); err == nil {
+			t.Error("RankCommits() succeeded with no matching files")
+		}
+	})
+}
+
+func TestFindSourceMatch(t *testing.T) {
+	repo, err := gitxtest.CreateRepoFromYAML(rankRepo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := []plumbing.Hash{blobHash("a"), blobHash("b")}
+	var validated []string
+	got, err := FindSourceMatch(repo.Repository, hashes, func(c *object.Commit) error {
+		validated = append(validated, c.Hash.String())
+		if c.Hash == repo.Commits["second"] {
+			return errors.New("wrong version")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FindSourceMatch() error = %v", err)
+	}
+	if diff := cmp.Diff(&Match{Commit: repo.Commits["third"].String(), Matched: 2, Total: 2}, got); diff != "" {
+		t.Errorf("FindSourceMatch() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{repo.Commits["second"].String(), repo.Commits["third"].String()}, validated); diff != "" {
+		t.Errorf("validated commits mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := FindSourceMatch(repo.Repository, hashes, func(*object.Commit) error { return errors.New("invalid") }); err == nil {
+		t.Error("FindSourceMatch() succeeded with no valid candidates")
+	}
+}
+
+func TestCommitGenerations(t *testing.T) {
+	h := func(s string) plumbing.Hash { return plumbing.ComputeHash(plumbing.CommitObject, []byte(s)) }
+	parents := map[plumbing.Hash][]plumbing.Hash{
+		h("root"):  nil,
+		h("a"):     {h("root")},
+		h("b"):     {h("a")},
+		h("merge"): {h("b"), h("root")},
+		// Parents absent from the map, e.g. beyond a shallow boundary, are ignored.
+		h("shallow"): {h("unknown")},
+	}
+	got := commitGenerations(parents)
+	want := map[plumbing.Hash]int{
+		h("root"):    0,
+		h("a"):       1,
+		h("b"):       2,
+		h("merge"):   3,
+		h("shallow"): 0,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("commitGenerations() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMatchTrees(t *testing.T) {
+	repo, err := gitxtest.CreateRepoFromYAML(rankRepo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[plumbing.Hash]int{blobHash("a"): 0, blobHash("b"): 1}
+	trees, matches, processed, err := matchTrees(repo.Repository, files)
+	if err != nil {
+		t.Fatalf("matchTrees() error = %v", err)
+	}
+	for id, want := range map[string][]bool{
+		"first":  {true, false},
+		"second": {true, true},
+		"third":  {true, true},
+	} {
+		c, err := repo.CommitObject(repo.Commits[id])
+		if err != nil {
+			t.Fatal(err)
+		}
+		i, ok := trees[c.TreeHash]
+		if !ok || !processed.Get(i) {
+			t.Fatalf("tree of %s not processed", id)
+		}
+		got := []bool{matches[i].Get(0), matches[i].Get(1)}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("matches of %s mismatch (-want +got):\n%s", id, diff)
+		}
+	}
+	// The subtree holding only b.txt matches only b.
+	second, err := repo.CommitObject(repo.Commits["second"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := second.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := tree.Tree("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := trees[dir.Hash]; matches[i].Get(0) || !matches[i].Get(1) {
+		t.Errorf("matches of dir = [%v %v], want [false true]", matches[i].Get(0), matches[i].Get(1))
+	}
+}
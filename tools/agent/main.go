@@ -123,7 +123,8 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to clone repo: %v", err)
 		}
-		loc, _, err = npm.InferLocation(tg, vmeta, &rebuild.RepoConfig{Repository: repo, URI: repoURL})
+		tarball := func() (io.ReadCloser, error) { return reg.Artifact(ctx, tg.Package, tg.Version) }
+		loc, _, err = npm.InferLocation(tg, vmeta, &rebuild.RepoConfig{Repository: repo, URI: repoURL}, tarball)
 		if err != nil {
 			log.Fatalf("Failed to infer location: %v", err)
 		}
@@ -34,10 +34,15 @@ type localExecutionService struct {
 	prebuildURL string
 	store       rebuild.LocatableAssetStore
 	logsink     io.Writer
+	registry    *rebuild.RegistryMux
 }
 
-func NewLocalExecutionService(prebuildURL string, store rebuild.LocatableAssetStore, logsink io.Writer) ExecutionService {
-	return &localExecutionService{prebuildURL: prebuildURL, store: store, logsink: logsink}
+// NewLocalExecutionService returns an ExecutionService that rebuilds packages on the local machine.
+//
+// If registry is non-nil, it is used in place of the live package registries
+// and upstream artifacts are read from it rather than fetched.
+func NewLocalExecutionService(prebuildURL string, store rebuild.LocatableAssetStore, logsink io.Writer, registry *rebuild.RegistryMux) ExecutionService {
+	return &localExecutionService{prebuildURL: prebuildURL, store: store, logsink: logsink, registry: registry}
 }
 
 func (s *localExecutionService) RebuildPackage(ctx context.Context, req schema.RebuildPackageRequest) (*schema.Verdict, error) {
@@ -51,6 +56,9 @@ func (s *localExecutionService) RebuildPackage(ctx context.Context, req schema.R
 		return nil, errors.New("syscall monitor not supported")
 	}
 	mux := meta.NewRegistryMux(httpx.NewCachedClient(http.DefaultClient, &cache.CoalescingMemoryCache{}))
+	if s.registry != nil {
+		mux = *s.registry
+	}
 	t := rebuild.Target{Ecosystem: req.Ecosystem, Package: req.Package, Version: req.Version, Artifact: req.Artifact}
 	if req.Artifact == "" {
 		switch t.Ecosystem {
@@ -85,7 +93,7 @@ func (s *localExecutionService) RebuildPackage(ctx context.Context, req schema.R
 	verdict.StrategyOneof = schema.NewStrategyOneOf(strategy)
 	if err := executeBuild(ctx, t, strategy, s.store, buildOpts{PrebuildURL: s.prebuildURL, LogSink: s.logsink}); err != nil {
 		verdict.Message = err.Error()
-	} else if err := compare(ctx, t, s.store, mux); err != nil {
+	} else if err := compare(ctx, t, s.store, mux, s.registry != nil); err != nil {
 		verdict.Message = err.Error()
 	}
 	return verdict, nil
@@ -150,7 +158,47 @@ func executeBuild(ctx context.Context, t rebuild.Target, strategy rebuild.Strate
 	return nil
 }
 
-func compare(ctx context.Context, t rebuild.Target, store rebuild.LocatableAssetStore, mux rebuild.RegistryMux) error {
+// registryArtifactClient is a BasicClient serving a single artifact URL from a registry.
+type registryArtifactClient struct {
+	URL  string
+	Open func() (io.ReadCloser, error)
+}
+
+func (c registryArtifactClient) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.String() != c.URL {
+		return nil, errors.Errorf("%s not available from registry", req.URL)
+	}
+	r, err := c.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: http.StatusText(http.StatusOK), Body: r}, nil
+}
+
+// registryArtifact returns the target's artifact from the registry.
+func registryArtifact(ctx context.Context, t rebuild.Target, mux rebuild.RegistryMux) (io.ReadCloser, error) {
+	switch t.Ecosystem {
+	case rebuild.NPM:
+		return mux.NPM.Artifact(ctx, t.Package, t.Version)
+	case rebuild.PyPI:
+		return mux.PyPI.Artifact(ctx, t.Package, t.Version, t.Artifact)
+	case rebuild.CratesIO:
+		return mux.CratesIO.Artifact(ctx, t.Package, t.Version)
+	case rebuild.Debian:
+		component, name, err := debian.ParseComponent(t.Package)
+		if err != nil {
+			return nil, err
+		}
+		return mux.Debian.Artifact(ctx, component, name, t.Artifact)
+	default:
+		return nil, errors.Errorf("unsupported ecosystem: %s", t.Ecosystem)
+	}
+}
+
+// compare checks the rebuilt artifact against upstream.
+//
+// If offline, the upstream artifact is read from mux rather than fetched.
+func compare(ctx context.Context, t rebuild.Target, store rebuild.LocatableAssetStore, mux rebuild.RegistryMux, offline bool) error {
 	if _, err := store.Reader(ctx, rebuild.RebuildAsset.For(t)); err != nil {
 		return errors.Wrap(err, "accessing rebuild artifact")
 	}
@@ -203,6 +251,10 @@ func compare(ctx context.Context, t rebuild.Target, store rebuild.LocatableAsset
 	if upstreamURL == "" {
 		return errors.New("couldn't determine upstream URL")
 	}
+	if offline {
+		open := func() (io.ReadCloser, error) { return registryArtifact(ctx, t, mux) }
+		ctx = context.WithValue(ctx, rebuild.HTTPBasicClientID, registryArtifactClient{URL: upstreamURL, Open: open})
+	}
 	hashes := []crypto.Hash{crypto.SHA256}
 	if t.Ecosystem == rebuild.NPM {
 		hashes = append(hashes, crypto.SHA512)
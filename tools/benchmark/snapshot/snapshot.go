@@ -0,0 +1,272 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package snapshot captures the registry data used by a benchmark for offline use.
+//
+// Snapshots are written in the layout read by meta.NewDirRegistryMux.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/google/oss-rebuild/internal/httpx"
+	debianrb "github.com/google/oss-rebuild/pkg/rebuild/debian"
+	"github.com/google/oss-rebuild/pkg/rebuild/meta"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
+	"github.com/google/oss-rebuild/pkg/registry/cratesio"
+	"github.com/google/oss-rebuild/pkg/registry/debian"
+	"github.com/google/oss-rebuild/pkg/registry/maven"
+	"github.com/google/oss-rebuild/pkg/registry/npm"
+	"github.com/google/oss-rebuild/pkg/registry/pypi"
+	"github.com/google/oss-rebuild/tools/benchmark"
+	"github.com/pkg/errors"
+)
+
+// recorder is a BasicClient that retains the body of the most recent response.
+type recorder struct {
+	httpx.BasicClient
+	last []byte
+}
+
+func (r *recorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.BasicClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.last = body
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// Snapshotter copies registry data into a directory.
+type Snapshotter struct {
+	rec *recorder
+	dst billy.Filesystem
+}
+
+// New returns a Snapshotter fetching from the public registries using client
+// and writing to dst.
+func New(client httpx.BasicClient, dst billy.Filesystem) *Snapshotter {
+	return &Snapshotter{rec: &recorder{BasicClient: client}, dst: dst}
+}
+
+// Snapshot writes the registry data for all packages in the set.
+//
+// Failures for individual packages are logged and do not stop the snapshot.
+// An error is returned if any package could not be captured.
+func (s *Snapshotter) Snapshot(ctx context.Context, set benchmark.PackageSet) error {
+	var failed int
+	for _, p := range set.Packages {
+		for i, v := range p.Versions {
+			var artifact string
+			if i < len(p.Artifacts) {
+				artifact = p.Artifacts[i]
+			}
+			t := rebuild.Target{Ecosystem: rebuild.Ecosystem(p.Ecosystem), Package: p.Name, Version: v, Artifact: artifact}
+			if err := s.Target(ctx, t); err != nil {
+				log.Printf("Failed to snapshot %s %s@%s: %v", t.Ecosystem, t.Package, t.Version, err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("failed to snapshot %d targets", failed)
+	}
+	return nil
+}
+
+// Target writes the registry data for the given target.
+//
+// If the target's artifact is empty, all of a PyPI release's artifacts are
+// captured. Debian binary packages are only captured when named by the target.
+func (s *Snapshotter) Target(ctx context.Context, t rebuild.Target) error {
+	root, err := s.dst.Chroot(meta.DirRegistryRoot(t.Ecosystem))
+	if err != nil {
+		return err
+	}
+	switch t.Ecosystem {
+	case rebuild.NPM:
+		return s.npm(ctx, root, t)
+	case rebuild.PyPI:
+		return s.pypi(ctx, root, t)
+	case rebuild.CratesIO:
+		return s.cratesio(ctx, root, t)
+	case rebuild.Maven:
+		return s.maven(ctx, root, t)
+	case rebuild.Debian:
+		return s.debian(ctx, root, t)
+	default:
+		return errors.Errorf("unsupported ecosystem: %s", t.Ecosystem)
+	}
+}
+
+// copyTo writes the contents of r to name within fs.
+func copyTo(fs billy.Filesystem, name string, r io.ReadCloser) error {
+	defer r.Close()
+	if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := fs.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+// writeLast writes the most recently recorded response body to name within fs.
+func (s *Snapshotter) writeLast(fs billy.Filesystem, name string) error {
+	if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	return util.WriteFile(fs, name, s.rec.last, 0644)
+}
+
+func (s *Snapshotter) npm(ctx context.Context, fs billy.Filesystem, t rebuild.Target) error {
+	reg := npm.HTTPRegistry{Client: s.rec}
+	if _, err := reg.Package(ctx, t.Package); err != nil {
+		return errors.Wrap(err, "fetching package")
+	}
+	if err := s.writeLast(fs, npm.PackagePath(t.Package)); err != nil {
+		return err
+	}
+	r, err := reg.Artifact(ctx, t.Package, t.Version)
+	if err != nil {
+		return errors.Wrap(err, "fetching artifact")
+	}
+	return copyTo(fs, npm.ArtifactPath(t.Package, t.Version), r)
+}
+
+func (s *Snapshotter) pypi(ctx context.Context, fs billy.Filesystem, t rebuild.Target) error {
+	reg := pypi.HTTPRegistry{Client: s.rec}
+	if _, err := reg.Project(ctx, t.Package); err != nil {
+		return errors.Wrap(err, "fetching project")
+	}
+	if err := s.writeLast(fs, pypi.ProjectPath(t.Package)); err != nil {
+		return err
+	}
+	release, err := reg.Release(ctx, t.Package, t.Version)
+	if err != nil {
+		return errors.Wrap(err, "fetching release")
+	}
+	if err := s.writeLast(fs, pypi.ReleasePath(t.Package, t.Version)); err != nil {
+		return err
+	}
+	for _, a := range release.Artifacts {
+		if t.Artifact != "" && a.Filename != t.Artifact {
+			continue
+		}
+		r, err := reg.Artifact(ctx, t.Package, t.Version, a.Filename)
+		if err != nil {
+			return errors.Wrapf(err, "fetching artifact %s", a.Filename)
+		}
+		if err := copyTo(fs, pypi.ArtifactPath(t.Package, t.Version, a.Filename), r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Snapshotter) cratesio(ctx context.Context, fs billy.Filesystem, t rebuild.Target) error {
+	reg := cratesio.HTTPRegistry{Client: s.rec}
+	if _, err := reg.Crate(ctx, t.Package); err != nil {
+		return errors.Wrap(err, "fetching crate")
+	}
+	if err := s.writeLast(fs, cratesio.CratePath(t.Package)); err != nil {
+		return err
+	}
+	r, err := reg.Artifact(ctx, t.Package, t.Version)
+	if err != nil {
+		return errors.Wrap(err, "fetching artifact")
+	}
+	return copyTo(fs, cratesio.ArtifactPath(t.Package, t.Version), r)
+}
+
+func (s *Snapshotter) maven(ctx context.Context, fs billy.Filesystem, t rebuild.Target) error {
+	reg := maven.HTTPRegistry{Client: s.rec}
+	if _, err := reg.PackageMetadata(ctx, t.Package); err != nil {
+		return errors.Wrap(err, "fetching metadata")
+	}
+	name, err := maven.MetadataPath(t.Package)
+	if err != nil {
+		return err
+	}
+	if err := s.writeLast(fs, name); err != nil {
+		return err
+	}
+	v, err := reg.PackageVersion(ctx, t.Package, t.Version)
+	if err != nil {
+		return errors.Wrap(err, "fetching version")
+	}
+	// Persist the version document rather than the raw response since it may
+	// have been derived from the repository instead of the search API.
+	v.PublishedMilli = v.Published.UnixMilli()
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if name, err = maven.VersionPath(t.Package, t.Version); err != nil {
+		return err
+	}
+	if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+	if err := util.WriteFile(fs, name, content, 0644); err != nil {
+		return err
+	}
+	_, a, _ := strings.Cut(t.Package, ":")
+	for _, typ := range v.Files {
+		if name, err = maven.ArtifactPath(t.Package, t.Version, a+"-"+t.Version+typ); err != nil {
+			return err
+		}
+		r, err := reg.ReleaseFile(ctx, t.Package, t.Version, typ)
+		if err != nil {
+			return errors.Wrapf(err, "fetching %s release file", typ)
+		}
+		if err := copyTo(fs, name, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Snapshotter) debian(ctx context.Context, fs billy.Filesystem, t rebuild.Target) error {
+	component, name, err := debianrb.ParseComponent(t.Package)
+	if err != nil {
+		return err
+	}
+	reg := debian.HTTPRegistry{Client: s.rec}
+	if _, _, err := reg.DSC(ctx, component, name, t.Version); err != nil {
+		return errors.Wrap(err, "fetching dsc")
+	}
+	dscPath, err := debian.DSCPath(name, t.Version)
+	if err != nil {
+		return err
+	}
+	if err := s.writeLast(fs, dscPath); err != nil {
+		return err
+	}
+	if t.Artifact == "" {
+		return nil
+	}
+	r, err := reg.Artifact(ctx, component, name, t.Artifact)
+	if err != nil {
+		return errors.Wrap(err, "fetching artifact")
+	}
+	return copyTo(fs, debian.ArtifactPath(name, t.Artifact), r)
+}
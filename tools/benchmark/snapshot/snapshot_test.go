@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/internal/httpx/httpxtest"
+	"github.com/google/oss-rebuild/pkg/rebuild/meta"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
+	"github.com/google/oss-rebuild/tools/benchmark"
+)
+
+func TestSnapshot(t *testing.T) {
+	packageJSON := `{"name": "pkg", "versions": {"1.0.0": {"name": "pkg", "version": "1.0.0", "dist": {"tarball": "https://registry.npmjs.org/pkg/-/pkg-1.0.0.tgz"}}}}`
+	versionJSON := `{"name": "pkg", "version": "1.0.0", "dist": {"tarball": "https://registry.npmjs.org/pkg/-/pkg-1.0.0.tgz"}}`
+	crateJSON := `{"crate": {"id": "krate"}, "versions": [{"num": "0.1.0", "dl_path": "/api/v1/crates/krate/0.1.0/download"}]}`
+	client := &httpxtest.MockClient{
+		Calls: []httpxtest.Call{
+			{URL: "https://registry.npmjs.org/pkg", Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(packageJSON)}},
+			{URL: "https://registry.npmjs.org/pkg/1.0.0", Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(versionJSON)}},
+			{URL: "https://registry.npmjs.org/pkg/-/pkg-1.0.0.tgz", Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body("tarball")}},
+			{URL: "https://crates.io/api/v1/crates/krate", Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(crateJSON)}},
+			{URL: "https://crates.io/api/v1/crates/krate/0.1.0", Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body(`{"version": {"num": "0.1.0", "dl_path": "/api/v1/crates/krate/0.1.0/download"}}`)}},
+			{URL: "https://crates.io/api/v1/crates/krate/0.1.0/download", Response: &http.Response{StatusCode: http.StatusOK, Body: httpxtest.Body("crate")}},
+		},
+		URLValidator: httpxtest.NewURLValidator(t),
+	}
+	fs := memfs.New()
+	set := benchmark.PackageSet{
+		Packages: []benchmark.Package{
+			{Ecosystem: string(rebuild.NPM), Name: "pkg", Versions: []string{"1.0.0"}},
+			{Ecosystem: string(rebuild.CratesIO), Name: "krate", Versions: []string{"0.1.0"}},
+		},
+	}
+	ctx := context.Background()
+	if err := New(client, fs).Snapshot(ctx, set); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	mux, err := meta.NewDirRegistryMux(fs)
+	if err != nil {
+		t.Fatalf("NewDirRegistryMux() error = %v", err)
+	}
+	v, err := mux.NPM.Version(ctx, "pkg", "1.0.0")
+	if err != nil {
+		t.Fatalf("NPM.Version() error = %v", err)
+	}
+	if diff := cmp.Diff("https://registry.npmjs.org/pkg/-/pkg-1.0.0.tgz", v.Dist.URL); diff != "" {
+		t.Errorf("NPM.Version() tarball mismatch (-want +got):\n%s", diff)
+	}
+	for _, tc := range []struct {
+		name string
+		open func() (io.ReadCloser, error)
+		want string
+	}{
+		{"npm", func() (io.ReadCloser, error) { return mux.NPM.Artifact(ctx, "pkg", "1.0.0") }, "tarball"},
+		{"cratesio", func() (io.ReadCloser, error) { return mux.CratesIO.Artifact(ctx, "krate", "0.1.0") }, "crate"},
+	} {
+		r, err := tc.open()
+		if err != nil {
+			t.Fatalf("%s Artifact() error = %v", tc.name, err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		if string(content) != tc.want {
+			t.Errorf("%s Artifact() = %q, want %q", tc.name, content, tc.want)
+		}
+	}
+	c, err := mux.CratesIO.Crate(ctx, "krate")
+	if err != nil {
+		t.Fatalf("CratesIO.Crate() error = %v", err)
+	}
+	if diff := cmp.Diff("https://crates.io/api/v1/crates/krate/0.1.0/download", c.Versions[0].DownloadURL); diff != "" {
+		t.Errorf("CratesIO.Crate() download URL mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -48,12 +48,15 @@ import (
 	"github.com/google/oss-rebuild/pkg/build"
 	"github.com/google/oss-rebuild/pkg/build/local"
 	"github.com/google/oss-rebuild/pkg/feed"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+	"github.com/google/oss-rebuild/pkg/proxy/policy"
 	"github.com/google/oss-rebuild/pkg/rebuild/meta"
 	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
 	"github.com/google/oss-rebuild/pkg/rebuild/schema"
 	"github.com/google/oss-rebuild/pkg/registry/cratesio/index"
 	"github.com/google/oss-rebuild/tools/benchmark"
 	"github.com/google/oss-rebuild/tools/benchmark/run"
+	"github.com/google/oss-rebuild/tools/benchmark/snapshot"
 	"github.com/google/oss-rebuild/tools/ctl/gradle"
 	"github.com/google/oss-rebuild/tools/ctl/ide"
 	agentide "github.com/google/oss-rebuild/tools/ctl/ide/agent"
@@ -61,6 +64,7 @@ import (
 	"github.com/google/oss-rebuild/tools/ctl/localfiles"
 	"github.com/google/oss-rebuild/tools/ctl/migrations"
 	"github.com/google/oss-rebuild/tools/ctl/pipe"
+	"github.com/google/oss-rebuild/tools/ctl/policygen"
 	"github.com/google/oss-rebuild/tools/ctl/rundex"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -469,12 +473,90 @@ var export = &cobra.Command{
 	},
 }
 
+var synthesizePolicy = &cobra.Command{
+	Use:   "synthesize-policy -project <ID> -run <ID> [-pattern <regex>] [-max-paths-per-host N] [-format=policy|report] [--max-concurrency N]",
+	Short: "Synthesize a proxy network policy from the network logs of a run",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if *runFlag == "" {
+			log.Fatal("--run must be provided")
+		}
+		runID := *runFlag
+		ctx := cmd.Context()
+		fireDex, err := rundex.NewFirestore(ctx, *project)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var rebuilds []rundex.Rebuild
+		{
+			req, err := buildFetchRebuildRequest("", runID, "", *pattern, false, false)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("Querying results for [run=%v,pattern=%s]", req.Runs, req.Opts.Pattern)
+			rebuilds, err = fireDex.FetchRebuilds(ctx, req)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		log.Printf("Fetched %d rebuilds", len(rebuilds))
+		store, err := localfiles.AssetStore(runID)
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "creating asset store"))
+		}
+		mux := meta.NewRegistryMux(http.DefaultClient)
+		butler := localfiles.NewButler(*metadataBucket, *logsBucket, *debugStorage, mux, localfiles.AssetStore)
+		type netlogResult struct {
+			rebuild rundex.Rebuild
+			log     *netlog.NetworkActivityLog
+			err     error
+		}
+		p := pipe.ParInto(*maxConcurrency, pipe.FromSlice(rebuilds), func(in rundex.Rebuild, out chan<- netlogResult) {
+			res := netlogResult{rebuild: in}
+			defer func() { out <- res }()
+			// NOTE: We hardcode wasSmoketest=false for the same reason as in export.
+			if _, err := butler.Fetch(ctx, runID, false, rebuild.ProxyNetlogAsset.For(in.Target())); err != nil {
+				res.err = errors.Wrap(err, "fetching network log")
+				return
+			}
+			res.log, res.err = policygen.ReadNetlog(ctx, store, in.Target())
+		})
+		var logs []*netlog.NetworkActivityLog
+		for res := range p.Out() {
+			if res.err != nil {
+				log.Printf("%s: %v", res.rebuild.ID(), res.err)
+				continue
+			}
+			logs = append(logs, res.log)
+		}
+		log.Printf("Read %d network logs", len(logs))
+		policy.RegisterBuiltinRules()
+		report := policygen.Synthesize(logs, policygen.Options{MaxPathsPerHost: *maxPathsPerHost})
+		if len(report.Unclassified) > 0 {
+			log.Printf("%d unclassified requests were excluded from the policy", len(report.Unclassified))
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		switch *format {
+		case "", "policy":
+			err = enc.Encode(report.Policy)
+		case "report":
+			err = enc.Encode(report)
+		default:
+			log.Fatalf("unsupported format: %s", *format)
+		}
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "encoding policy"))
+		}
+	},
+}
+
 func isCloudRun(u *url.URL) bool {
 	return strings.HasSuffix(u.Host, ".run.app")
 }
 
 var runBenchmark = &cobra.Command{
-	Use:   "run-bench smoketest|attest -api <URI>  [-local -prebuild-bucket <BUCKET> -prebuild-version <VERSION>] [-format=summary|csv] <benchmark.json>",
+	Use:   "run-bench smoketest|attest -api <URI>  [-local -prebuild-bucket <BUCKET> -prebuild-version <VERSION> [-registry-dir <DIR>]] [-format=summary|csv] <benchmark.json>",
 	Short: "Run benchmark",
 	Args:  cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -499,6 +581,9 @@ var runBenchmark = &cobra.Command{
 		var dex rundex.Writer
 		var executor run.ExecutionService
 		concurrency := *maxConcurrency
+		if *registryDir != "" && !*buildLocal {
+			log.Fatal("--registry-dir is only supported with --local")
+		}
 		if *buildLocal {
 			now := time.Now().UTC()
 			runID = now.Format(time.RFC3339)
@@ -512,7 +597,15 @@ var runBenchmark = &cobra.Command{
 			}
 			// TODO: Validate this.
 			prebuildURL := fmt.Sprintf("https://%s.storage.googleapis.com/%s", *prebuildBucket, *prebuildVersion)
-			executor = run.NewLocalExecutionService(prebuildURL, store, cmd.OutOrStdout())
+			var registry *rebuild.RegistryMux
+			if *registryDir != "" {
+				mux, err := meta.NewDirRegistryMux(osfs.New(*registryDir))
+				if err != nil {
+					log.Fatal(errors.Wrap(err, "loading registry directory"))
+				}
+				registry = &mux
+			}
+			executor = run.NewLocalExecutionService(prebuildURL, store, cmd.OutOrStdout(), registry)
 			dex = rundex.NewLocalClient(localfiles.Rundex())
 			if err := dex.WriteRun(ctx, rundex.FromRun(schema.Run{
 				ID:            runID,
@@ -866,6 +959,51 @@ var runOne = &cobra.Command{
 	},
 }
 
+var revoke = &cobra.Command{
+	Use:   "revoke --api <URI> --ecosystem <ecosystem> --package <name> --version <version> --artifact <name> --reason <reason>",
+	Short: "Revoke a published attestation bundle",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if *ecosystem == "" || *pkg == "" || *version == "" || *artifact == "" {
+			log.Fatal("ecosystem, package, version, and artifact must be provided")
+		}
+		if *reason == "" {
+			log.Fatal("reason must be provided")
+		}
+		if *apiUri == "" {
+			log.Fatal("API endpoint not provided")
+		}
+		apiURL, err := url.Parse(*apiUri)
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "parsing API endpoint"))
+		}
+		ctx := cmd.Context()
+		var client *http.Client
+		if strings.Contains(apiURL.Host, "run.app") {
+			// If the api is on Cloud Run, we need to use an authorized client.
+			apiURL.Scheme = "https"
+			client, err = oauth.AuthorizedUserIDClient(ctx)
+			if err != nil {
+				log.Fatal(errors.Wrap(err, "creating authorized HTTP client"))
+			}
+		} else {
+			client = http.DefaultClient
+		}
+		stub := api.Stub[schema.RevokeBundleRequest, schema.RevokeBundleResponse](client, apiURL.JoinPath("revoke"))
+		resp, err := stub(ctx, schema.RevokeBundleRequest{
+			Ecosystem: rebuild.Ecosystem(*ecosystem),
+			Package:   *pkg,
+			Version:   *version,
+			Artifact:  *artifact,
+			Reason:    *reason,
+		})
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "revoking bundle"))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Revoked bundle sha256:%s at %s\n", resp.BundleDigest, resp.RevokedAt.Format(time.RFC3339))
+	},
+}
+
 var listRuns = &cobra.Command{
 	Use:   "list-runs -project <ID> [ -bench <benchmark.json> ]",
 	Short: "List runs",
@@ -1140,6 +1278,25 @@ var infer = &cobra.Command{
 	},
 }
 
+var snapshotRegistries = &cobra.Command{
+	Use:   "snapshot-registries <benchmark.json> <dir>",
+	Short: "Copy the registry data used by a benchmark to a local directory",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		set, err := benchmark.ReadBenchmark(args[0])
+		if err != nil {
+			log.Fatal(errors.Wrap(err, "reading benchmark file"))
+		}
+		if err := os.MkdirAll(args[1], 0o755); err != nil {
+			log.Fatal(errors.Wrap(err, "creating output directory"))
+		}
+		log.Printf("Snapshotting registry data for %d artifacts...\n", set.Count)
+		if err := snapshot.New(http.DefaultClient, osfs.New(args[1])).Snapshot(cmd.Context(), set); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
 var getGradleGAV = &cobra.Command{
 	Use:   "get-gradle-gav --repository <URI> --ref <ref>",
 	Short: "Extracts GAV coordinates from a Gradle project at a given commit",
@@ -1601,6 +1758,7 @@ var (
 	artifact          = flag.String("artifact", "", "the artifact name")
 	verbose           = flag.Bool("v", false, "verbose output")
 	bench             = flag.String("bench", "", "a path to a benchmark file for filtering or execution")
+	registryDir       = flag.String("registry-dir", "", "a directory of registry data, as written by snapshot-registries, to use in place of the live registries")
 	debugStorage      = flag.String("debug-storage", "", "the gcs bucket to find debug logs and artifacts")
 	logsBucket        = flag.String("logs-bucket", "", "the gcs bucket where gcb logs are stored")
 	metadataBucket    = flag.String("metadata-bucket", "", "the gcs bucket where rebuild output is stored")
@@ -1646,6 +1804,11 @@ var (
 	destination  = flag.String("destination", "", "the destination for the export, e.g. gs://bucket/prefix")
 	exportRundex = flag.Bool("rundex", false, "whether to include the rundex in the export")
 	retrySession = flag.String("retry-session", "", "the session to retry")
+	// Synthesize policy
+	maxPathsPerHost = flag.Int("max-paths-per-host", policygen.DefaultMaxPathsPerHost, "number of distinct registry paths on a host above which the whole host is allowed")
+
+	// Revoke
+	reason = flag.String("reason", "", "the reason for the revocation")
 )
 
 func init() {
@@ -1661,6 +1824,7 @@ func init() {
 	runBenchmark.Flags().AddGoFlag(flag.Lookup("task-queue-email"))
 	runBenchmark.Flags().AddGoFlag(flag.Lookup("use-network-proxy"))
 	runBenchmark.Flags().AddGoFlag(flag.Lookup("use-syscall-monitor"))
+	runBenchmark.Flags().AddGoFlag(flag.Lookup("registry-dir"))
 
 	runOne.Flags().AddGoFlag(flag.Lookup("api"))
 	runOne.Flags().AddGoFlag(flag.Lookup("strategy"))
@@ -1671,6 +1835,13 @@ func init() {
 	runOne.Flags().AddGoFlag(flag.Lookup("version"))
 	runOne.Flags().AddGoFlag(flag.Lookup("artifact"))
 
+	revoke.Flags().AddGoFlag(flag.Lookup("api"))
+	revoke.Flags().AddGoFlag(flag.Lookup("ecosystem"))
+	revoke.Flags().AddGoFlag(flag.Lookup("package"))
+	revoke.Flags().AddGoFlag(flag.Lookup("version"))
+	revoke.Flags().AddGoFlag(flag.Lookup("artifact"))
+	revoke.Flags().AddGoFlag(flag.Lookup("reason"))
+
 	getResults.Flags().AddGoFlag(flag.Lookup("run"))
 	getResults.Flags().AddGoFlag(flag.Lookup("bench"))
 	getResults.Flags().AddGoFlag(flag.Lookup("prefix"))
@@ -1694,6 +1865,16 @@ func init() {
 	export.Flags().AddGoFlag(flag.Lookup("rundex"))
 	export.Flags().AddGoFlag(flag.Lookup("max-concurrency"))
 
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("run"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("pattern"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("project"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("debug-storage"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("logs-bucket"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("metadata-bucket"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("max-paths-per-host"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("format"))
+	synthesizePolicy.Flags().AddGoFlag(flag.Lookup("max-concurrency"))
+
 	tui.Flags().AddGoFlag(flag.Lookup("project"))
 	tui.Flags().AddGoFlag(flag.Lookup("llm-project"))
 	tui.Flags().AddGoFlag(flag.Lookup("debug-storage"))
@@ -1772,16 +1953,19 @@ func init() {
 	rootCmd.AddCommand(tui)
 	rootCmd.AddCommand(getResults)
 	rootCmd.AddCommand(export)
+	rootCmd.AddCommand(synthesizePolicy)
 	rootCmd.AddCommand(listRuns)
 	rootCmd.AddCommand(getSessions)
 	rootCmd.AddCommand(viewSession)
 	// Rebuild logic
 	rootCmd.AddCommand(infer)
 	rootCmd.AddCommand(getGradleGAV)
+	rootCmd.AddCommand(snapshotRegistries)
 	// Infra tools
 	rootCmd.AddCommand(migrate)
 	rootCmd.AddCommand(setTrackedPackagesCmd)
 	rootCmd.AddCommand(getTrackedPackagesCmd)
+	rootCmd.AddCommand(revoke)
 }
 
 func main() {
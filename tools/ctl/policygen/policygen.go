@@ -0,0 +1,153 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package policygen synthesizes proxy network policies from recorded rebuild traffic.
+package policygen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+
+	"github.com/google/oss-rebuild/internal/netclassify"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+	"github.com/google/oss-rebuild/pkg/proxy/policy"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxPathsPerHost is the default threshold above which a host's unclassified-package paths are collapsed.
+const DefaultMaxPathsPerHost = 20
+
+// Options configures policy synthesis.
+type Options struct {
+	// MaxPathsPerHost is the number of distinct non-package paths on a host
+	// above which the host is allowed in its entirety rather than per path.
+	MaxPathsPerHost int
+}
+
+// RequestCount is a request observed in one or more network logs.
+type RequestCount struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Count  int    `json:"count"`
+}
+
+// Report is the result of policy synthesis.
+type Report struct {
+	// Policy allows the classified traffic observed across all logs.
+	Policy policy.Policy `json:"policy"`
+	// Logs is the number of network logs from which the policy was synthesized.
+	Logs int `json:"logs"`
+	// Requests is the total number of requests across all logs.
+	Requests int `json:"requests"`
+	// Unclassified contains the requests that could not be attributed to a
+	// known package or registry endpoint and so are not allowed by Policy.
+	Unclassified []RequestCount `json:"unclassified"`
+}
+
+// ReadNetlog reads the network log recorded for t from store.
+func ReadNetlog(ctx context.Context, store rebuild.ReadOnlyAssetStore, t rebuild.Target) (*netlog.NetworkActivityLog, error) {
+	r, err := store.Reader(ctx, rebuild.ProxyNetlogAsset.For(t))
+	if err != nil {
+		return nil, errors.Wrap(err, "opening network log")
+	}
+	defer r.Close()
+	var log netlog.NetworkActivityLog
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, errors.Wrap(err, "decoding network log")
+	}
+	return &log, nil
+}
+
+// Synthesize generates a minimal policy allowing the traffic in logs.
+//
+// Requests are classified by netclassify. Package downloads become a single
+// PackageRule, pinned to the observed version unless several versions of a
+// package were fetched. Registry requests that are not package downloads,
+// such as metadata lookups, are allowed by exact host and path. Requests that
+// cannot be classified are reported rather than allowed.
+func Synthesize(logs []*netlog.NetworkActivityLog, opts Options) *Report {
+	if opts.MaxPathsPerHost <= 0 {
+		opts.MaxPathsPerHost = DefaultMaxPathsPerHost
+	}
+	report := &Report{Logs: len(logs), Unclassified: []RequestCount{}}
+	methods := make(map[string]bool)
+	versions := make(map[string]map[string]bool) // package pURL -> versioned pURLs
+	paths := make(map[string]map[string]bool)    // host -> paths
+	unclassified := make(map[[2]string]int)      // [method, URL] -> count
+	for _, l := range logs {
+		for _, req := range l.HTTPRequests {
+			report.Requests++
+			u := (&url.URL{Scheme: req.Scheme, Host: req.Host, Path: req.Path}).String()
+			c, err := netclassify.ClassifyURL(u)
+			switch {
+			case err == nil:
+				pkg := fmt.Sprintf("pkg:%s/%s", c.Ecosystem, c.Name)
+				if versions[pkg] == nil {
+					versions[pkg] = make(map[string]bool)
+				}
+				versions[pkg][c.PURL] = true
+			case errors.Is(err, netclassify.ErrSkipped):
+				if paths[req.Host] == nil {
+					paths[req.Host] = make(map[string]bool)
+				}
+				paths[req.Host][req.Path] = true
+			default:
+				unclassified[[2]string{req.Method, u}]++
+				continue
+			}
+			methods[req.Method] = true
+		}
+	}
+	if len(methods) > 0 {
+		report.Policy.AllOf = append(report.Policy.AllOf, &policy.MethodRule{Methods: sortedKeys(methods)})
+	}
+	var packages []string
+	for pkg, purls := range versions {
+		if len(purls) > 1 {
+			packages = append(packages, pkg)
+		} else {
+			packages = append(packages, sortedKeys(purls)...)
+		}
+	}
+	if len(packages) > 0 {
+		slices.Sort(packages)
+		report.Policy.AnyOf = append(report.Policy.AnyOf, &policy.PackageRule{Packages: packages})
+	}
+	for _, host := range sortedKeys(paths) {
+		if len(paths[host]) > opts.MaxPathsPerHost {
+			report.Policy.AnyOf = append(report.Policy.AnyOf, &policy.URLMatchRule{Host: host, HostMatch: policy.FullMatch, Path: "", PathMatch: policy.PrefixMatch})
+			continue
+		}
+		for _, p := range sortedKeys(paths[host]) {
+			report.Policy.AnyOf = append(report.Policy.AnyOf, &policy.URLMatchRule{Host: host, HostMatch: policy.FullMatch, Path: p, PathMatch: policy.FullMatch})
+		}
+	}
+	for k, count := range unclassified {
+		report.Unclassified = append(report.Unclassified, RequestCount{Method: k[0], URL: k[1], Count: count})
+	}
+	sort.Slice(report.Unclassified, func(i, j int) bool {
+		a, b := report.Unclassified[i], report.Unclassified[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		return a.Method < b.Method
+	})
+	return report
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Google LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package policygen
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/oss-rebuild/pkg/proxy/netlog"
+	"github.com/google/oss-rebuild/pkg/proxy/policy"
+	"github.com/google/oss-rebuild/pkg/rebuild/rebuild"
+)
+
+// expressNetlog is the network log of an npm rebuild fetching express and its metadata.
+const expressNetlog = `{
+  "HTTPRequests": [
+    {"Method": "GET", "Scheme": "https", "Host": "registry.npmjs.org", "Path": "/express/4.17.1", "StatusCode": 200},
+    {"Method": "GET", "Scheme": "https", "Host": "registry.npmjs.org", "Path": "/express/-/express-4.17.1.tgz", "StatusCode": 200},
+    {"Method": "GET", "Scheme": "https", "Host": "registry.npmjs.org", "Path": "/@scope/pkg/-/pkg-1.0.0.tgz", "StatusCode": 200},
+    {"Method": "GET", "Scheme": "https", "Host": "example.com", "Path": "/install.sh", "StatusCode": 200}
+  ],
+  "DNSQueries": [],
+  "TCPConnections": []
+}`
+
+// expressUpgradeNetlog is the network log of a second rebuild fetching a later express.
+const expressUpgradeNetlog = `{
+  "HTTPRequests": [
+    {"Method": "GET", "Scheme": "https", "Host": "registry.npmjs.org", "Path": "/express/-/express-4.18.0.tgz", "StatusCode": 200},
+    {"Method": "HEAD", "Scheme": "https", "Host": "registry.npmjs.org", "Path": "/express/4.18.0", "StatusCode": 200},
+    {"Method": "GET", "Scheme": "https", "Host": "example.com", "Path": "/install.sh", "StatusCode": 200},
+    {"Method": "POST", "Scheme": "https", "Host": "example.com", "Path": "/telemetry", "StatusCode": 204}
+  ],
+  "DNSQueries": [],
+  "TCPConnections": []
+}`
+
+func parseNetlog(t *testing.T, s string) *netlog.NetworkActivityLog {
+	t.Helper()
+	var l netlog.NetworkActivityLog
+	if err := json.Unmarshal([]byte(s), &l); err != nil {
+		t.Fatal(err)
+	}
+	return &l
+}
+
+// registryPaths returns a rule allowing exactly each of the paths on registry.npmjs.org.
+func registryPaths(paths ...string) []policy.Rule {
+	var rules []policy.Rule
+	for _, p := range paths {
+		rules = append(rules, &policy.URLMatchRule{Host: "registry.npmjs.org", HostMatch: policy.FullMatch, Path: p, PathMatch: policy.FullMatch})
+	}
+	return rules
+}
+
+func TestSynthesize(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		logs []string
+		opts Options
+		want *Report
+	}{
+		{
+			name: "no logs",
+			want: &Report{Unclassified: []RequestCount{}},
+		},
+		{
+			name: "single version pinned",
+			logs: []string{expressNetlog},
+			want: &Report{
+				Policy: policy.Policy{
+					AllOf: []policy.Rule{&policy.MethodRule{Methods: []string{"GET"}}},
+					AnyOf: append([]policy.Rule{
+						&policy.PackageRule{Packages: []string{"pkg:npm/@scope/pkg@1.0.0", "pkg:npm/express@4.17.1"}},
+					}, registryPaths("/express/4.17.1")...),
+				},
+				Logs:         1,
+				Requests:     4,
+				Unclassified: []RequestCount{{Method: "GET", URL: "https://example.com/install.sh", Count: 1}},
+			},
+		},
+		{
+			name: "multiple versions unpinned",
+			logs: []string{expressNetlog, expressUpgradeNetlog},
+			want: &Report{
+				Policy: policy.Policy{
+					AllOf: []policy.Rule{&policy.MethodRule{Methods: []string{"GET", "HEAD"}}},
+					AnyOf: append([]policy.Rule{
+						&policy.PackageRule{Packages: []string{"pkg:npm/@scope/pkg@1.0.0", "pkg:npm/express"}},
+					}, registryPaths("/express/4.17.1", "/express/4.18.0")...),
+				},
+				Logs:     2,
+				Requests: 8,
+				// Ordered by count and then URL. Methods of unclassified requests are not allowed.
+				Unclassified: []RequestCount{
+					{Method: "GET", URL: "https://example.com/install.sh", Count: 2},
+					{Method: "POST", URL: "https://example.com/telemetry", Count: 1},
+				},
+			},
+		},
+		{
+			name: "paths collapsed above threshold",
+			logs: []string{expressNetlog, expressUpgradeNetlog},
+			opts: Options{MaxPathsPerHost: 1},
+			want: &Report{
+				Policy: policy.Policy{
+					AllOf: []policy.Rule{&policy.MethodRule{Methods: []string{"GET", "HEAD"}}},
+					AnyOf: []policy.Rule{
+						&policy.PackageRule{Packages: []string{"pkg:npm/@scope/pkg@1.0.0", "pkg:npm/express"}},
+						&policy.URLMatchRule{Host: "registry.npmjs.org", HostMatch: policy.FullMatch, Path: "", PathMatch: policy.PrefixMatch},
+					},
+				},
+				Logs:     2,
+				Requests: 8,
+				Unclassified: []RequestCount{
+					{Method: "GET", URL: "https://example.com/install.sh", Count: 2},
+					{Method: "POST", URL: "https://example.com/telemetry", Count: 1},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs []*netlog.NetworkActivityLog
+			for _, l := range tc.logs {
+				logs = append(logs, parseNetlog(t, l))
+			}
+			got := Synthesize(logs, tc.opts)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Synthesize() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadNetlog(t *testing.T) {
+	ctx := context.Background()
+	target := rebuild.Target{Ecosystem: rebuild.NPM, Package: "express", Version: "4.17.1", Artifact: "express-4.17.1.tgz"}
+	for _, tc := range []struct {
+		name    string
+		content *string
+		want    *netlog.NetworkActivityLog
+		wantErr bool
+	}{
+		{
+			name:    "fixture",
+			content: ptr(expressNetlog),
+			want:    parseNetlog(t, expressNetlog),
+		},
+		{
+			name:    "missing",
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			content: ptr(`{"HTTPRequests": [`),
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := rebuild.NewFilesystemAssetStore(memfs.New())
+			if tc.content != nil {
+				w, err := store.Writer(ctx, rebuild.ProxyNetlogAsset.For(target))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := io.Copy(w, strings.NewReader(*tc.content)); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := ReadNetlog(ctx, store, target)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ReadNetlog() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ReadNetlog() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }